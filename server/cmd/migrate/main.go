@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/migration"
+)
+
+func main() {
+	// Define command line flags
+	up := flag.Bool("up", false, "Apply the baseline schema and all pending migrations")
+	down := flag.Bool("down", false, "Roll back the most recently applied migrations")
+	steps := flag.Int("steps", 1, "Number of migrations to roll back with -down")
+	status := flag.Bool("status", false, "Show which migrations have been applied")
+	help := flag.Bool("help", false, "Show help")
+
+	flag.Parse()
+
+	// Show help if requested
+	if *help || (!*up && !*down && !*status) {
+		flag.Usage()
+		return
+	}
+
+	// Load configuration
+	log.Println("🔧 Loading configuration...")
+	cfg := config.LoadConfig()
+
+	// Initialize database
+	log.Println("🗄️  Initializing database...")
+	config.InitDatabase(cfg)
+	db := config.GetDB()
+
+	switch {
+	case *up:
+		log.Println("📊 Applying migrations...")
+		if err := migration.Migrate(db); err != nil {
+			log.Fatal("Failed to apply migrations:", err)
+		}
+		log.Println("✅ Database is up to date")
+
+	case *down:
+		if *steps < 1 {
+			log.Fatal("-steps must be at least 1")
+		}
+		log.Printf("↩️  Rolling back %d migration(s)...", *steps)
+		rolledBack, err := migration.NewDefaultMigrator(db).Down(*steps)
+		if err != nil {
+			log.Fatal("Failed to roll back migrations:", err)
+		}
+		if len(rolledBack) == 0 {
+			log.Println("ℹ️  No applied migrations to roll back")
+		}
+
+	case *status:
+		statuses, err := migration.NewDefaultMigrator(db).Status()
+		if err != nil {
+			log.Fatal("Failed to read migration status:", err)
+		}
+		if len(statuses) == 0 {
+			log.Println("ℹ️  No versioned migrations defined")
+		}
+		for _, s := range statuses {
+			if s.Applied {
+				log.Printf("✅ %04d %s (applied %s)", s.Version, s.Name, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				log.Printf("⏳ %04d %s (pending)", s.Version, s.Name)
+			}
+		}
+	}
+}
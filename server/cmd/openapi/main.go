@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/openapi"
+)
+
+func main() {
+	// Define command line flags
+	general := flag.String("general", "cmd/server/main.go", "File with the API-wide annotations")
+	handlers := flag.String("handlers", "internal/handlers", "Directory of annotated handlers")
+	models := flag.String("models", "internal/models", "Directory of the request and response models")
+	trimPrefix := flag.String("trim-prefix", "/api", "Prefix removed from @Router paths")
+	out := flag.String("out", "internal/docs/openapi.json", "Output file")
+
+	flag.Parse()
+
+	spec, err := openapi.GenerateJSON(openapi.Options{
+		GeneralInfoFile: *general,
+		HandlersDir:     *handlers,
+		ModelsDir:       *models,
+		TrimPrefix:      *trimPrefix,
+	})
+	if err != nil {
+		log.Fatal("❌ Failed to generate OpenAPI spec: ", err)
+	}
+
+	if err := os.WriteFile(*out, spec, 0o644); err != nil {
+		log.Fatal("❌ Failed to write OpenAPI spec: ", err)
+	}
+	log.Printf("✅ Wrote %s", *out)
+}
@@ -15,6 +15,11 @@ func main() {
 	force := flag.Bool("force", false, "Force reseeding even if data exists")
 	clean := flag.Bool("clean", false, "Clean the database (remove seeded data)")
 	help := flag.Bool("help", false, "Show help")
+	users := flag.Int("users", 0, "Number of users to generate (default: curated sample data)")
+	posts := flag.Int("posts", 0, "Number of posts to generate (default: curated sample data)")
+	comments := flag.Int("comments", 3, "Maximum comments per generated post")
+	randSeed := flag.Int64("rand-seed", 1, "Random seed for generated data; the same seed reproduces the same dataset")
+	file := flag.String("file", "", "JSON file with generation options (users, posts, comments_per_post, seed)")
 
 	flag.Parse()
 
@@ -24,6 +29,29 @@ func main() {
 		return
 	}
 
+	// Generation options come from the file, with explicit flags taking
+	// precedence
+	opts := seeder.Options{CommentsPerPost: *comments, Seed: *randSeed}
+	if *file != "" {
+		fileOpts, err := seeder.LoadOptions(*file)
+		if err != nil {
+			log.Fatal("Failed to load seed file:", err)
+		}
+		opts = fileOpts
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "users":
+			opts.Users = *users
+		case "posts":
+			opts.Posts = *posts
+		case "comments":
+			opts.CommentsPerPost = *comments
+		case "rand-seed":
+			opts.Seed = *randSeed
+		}
+	})
+
 	// Load configuration
 	log.Println("🔧 Loading configuration...")
 	cfg := config.LoadConfig()
@@ -34,7 +62,7 @@ func main() {
 
 	// Run database migrations
 	log.Println("📊 Running database migrations...")
-	if err := migration.RunMigrations(); err != nil {
+	if err := migration.RunMigrations(cfg.Admin); err != nil {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
@@ -53,7 +81,7 @@ func main() {
 	if *seed {
 		log.Println("🌱 Seeding database...")
 		s := seeder.NewSeeder()
-		if err := s.RunSeeder(*force); err != nil {
+		if err := s.RunSeeder(*force, opts); err != nil {
 			log.Fatal("Failed to seed database:", err)
 		}
 		log.Println("✅ Database seeding completed!")
@@ -1,27 +1,50 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/migration"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/router"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/version"
 )
 
+// @title Golang Multi-User Blog API
+// @version 1.0
+// @description REST API for a multi-user blog: accounts, posts, tags, comments and moderation.
+// @BasePath /api/v1
+//
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and the access token.
 func main() {
 	// Load configuration
 	log.Println("🔧 Loading configuration...")
 	cfg := config.LoadConfig()
 
+	// Validate configuration before touching the database
+	warnings, err := cfg.Validate()
+	for _, warning := range warnings {
+		log.Printf("⚠️  Config warning: %s", warning)
+	}
+	if err != nil {
+		log.Fatal("❌ ", err)
+	}
+
 	// Initialize database
 	log.Println("🗄️  Initializing database...")
 	config.InitDatabase(cfg)
 
 	// Run database migrations
 	log.Println("📊 Running database migrations...")
-	if err := migration.RunMigrations(); err != nil {
+	if err := migration.RunMigrations(cfg.Admin); err != nil {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
@@ -30,6 +53,12 @@ func main() {
 	r := router.NewRouter(cfg)
 	appRouter := r.SetupRoutes()
 
+	// Start background jobs such as the newsletter digest. They stop on
+	// SIGINT or SIGTERM, which also shut the server down.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	r.StartJobs(ctx)
+
 	// Configure server
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -42,22 +71,44 @@ func main() {
 	// Print startup information
 	log.Printf("🚀 Golang Multi-User Blog Server starting on port %s", cfg.Port)
 	log.Printf("📱 Environment: %s", cfg.App.Environment)
+	log.Printf("🏷️  Version: %s (commit %s, built %s)", version.Version, version.Commit, version.BuildTime)
 	log.Printf("📄 Health Check: http://localhost:%s/health", cfg.Port)
-	log.Printf("🔗 API Base URL: http://localhost:%s/api", cfg.Port)
+	log.Printf("🔗 API Base URL: http://localhost:%s/api/v1", cfg.Port)
 	log.Printf("👤 Auth Endpoints:")
-	log.Printf("   📝 Register: POST http://localhost:%s/api/auth/register", cfg.Port)
-	log.Printf("   🔑 Login: POST http://localhost:%s/api/auth/login", cfg.Port)
-	log.Printf("   👥 Profile: GET http://localhost:%s/api/auth/profile", cfg.Port)
+	log.Printf("   📝 Register: POST http://localhost:%s/api/v1/auth/register", cfg.Port)
+	log.Printf("   🔑 Login: POST http://localhost:%s/api/v1/auth/login", cfg.Port)
+	log.Printf("   👥 Profile: GET http://localhost:%s/api/v1/auth/profile", cfg.Port)
 	log.Printf("📝 Blog Endpoints:")
-	log.Printf("   📚 Posts: GET http://localhost:%s/api/posts", cfg.Port)
-	log.Printf("   📄 Published: GET http://localhost:%s/api/posts/published", cfg.Port)
-	log.Printf("   🔍 Search: GET http://localhost:%s/api/posts/search?q=query", cfg.Port)
-	log.Printf("💾 Database: PostgreSQL on %s:%d", cfg.Database.Host, cfg.Database.Port)
+	log.Printf("   📚 Posts: GET http://localhost:%s/api/v1/posts", cfg.Port)
+	log.Printf("   📄 Published: GET http://localhost:%s/api/v1/posts/published", cfg.Port)
+	log.Printf("   🔍 Search: GET http://localhost:%s/api/v1/posts/search?q=query", cfg.Port)
+	if cfg.Database.Driver == config.DBDriverSQLite {
+		log.Printf("💾 Database: SQLite (%s)", cfg.Database.DBName)
+	} else {
+		log.Printf("💾 Database: PostgreSQL on %s:%d", cfg.Database.Host, cfg.Database.Port)
+	}
 	log.Println("")
 	log.Println("🎉 Server is ready to accept connections!")
 
 	// Start server
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatal("❌ Server failed to start:", err)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("❌ Server failed to start:", err)
+		}
+	}()
+
+	// Stop taking requests, then let the ones in flight and the queued
+	// webhook deliveries finish
+	<-ctx.Done()
+	stop()
+	log.Println("🛑 Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  Server shutdown: %v", err)
+	}
+	if err := r.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  Webhook deliveries still queued at shutdown: %v", err)
 	}
+	log.Println("👋 Server stopped")
 }
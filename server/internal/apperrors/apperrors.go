@@ -0,0 +1,63 @@
+// Package apperrors defines the kinds of errors services return, so handlers
+// can pick a status code with errors.Is instead of matching error text.
+package apperrors
+
+import "errors"
+
+// Error kinds. Match them with errors.Is; wrapping with %w keeps the kind.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrForbidden    = errors.New("forbidden")
+	ErrConflict     = errors.New("conflict")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrValidation   = errors.New("validation failed")
+	ErrBadRequest   = errors.New("bad request")
+)
+
+// Error is an error of a given kind with a message that is safe to show to
+// clients
+type Error struct {
+	kind    error
+	message string
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+// Unwrap returns the error's kind, so errors.Is(err, ErrNotFound) matches
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// NotFound returns an ErrNotFound error with the given message
+func NotFound(message string) error {
+	return &Error{kind: ErrNotFound, message: message}
+}
+
+// Forbidden returns an ErrForbidden error with the given message
+func Forbidden(message string) error {
+	return &Error{kind: ErrForbidden, message: message}
+}
+
+// Conflict returns an ErrConflict error with the given message
+func Conflict(message string) error {
+	return &Error{kind: ErrConflict, message: message}
+}
+
+// Unauthorized returns an ErrUnauthorized error with the given message
+func Unauthorized(message string) error {
+	return &Error{kind: ErrUnauthorized, message: message}
+}
+
+// Validation returns an ErrValidation error with the given message
+func Validation(message string) error {
+	return &Error{kind: ErrValidation, message: message}
+}
+
+// BadRequest returns an ErrBadRequest error with the given message, for
+// requests that can't be understood, such as a malformed query parameter.
+// Use Validation for requests that are understood but rejected.
+func BadRequest(message string) error {
+	return &Error{kind: ErrBadRequest, message: message}
+}
@@ -0,0 +1,21 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_Kinds(t *testing.T) {
+	err := NotFound("post not found")
+	assert.Equal(t, "post not found", err.Error())
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.False(t, errors.Is(err, ErrForbidden))
+
+	// The kind survives wrapping
+	wrapped := fmt.Errorf("failed to update post: %w", err)
+	assert.True(t, errors.Is(wrapped, ErrNotFound))
+	assert.True(t, errors.Is(wrapped, err))
+}
@@ -0,0 +1,78 @@
+// Package breach checks passwords against the Have I Been Pwned corpus of
+// passwords exposed in data breaches.
+package breach
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+)
+
+// Checker reports whether a password is known to have been breached
+type Checker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// NewChecker returns a checker querying the breach API configured in cfg,
+// or one that reports no password as breached when the check is disabled
+func NewChecker(cfg config.PasswordConfig) Checker {
+	if !cfg.CheckBreached {
+		return nopChecker{}
+	}
+	return &rangeChecker{
+		baseURL: strings.TrimRight(cfg.BreachAPIURL, "/"),
+		client:  &http.Client{Timeout: cfg.BreachTimeout},
+	}
+}
+
+type nopChecker struct{}
+
+func (nopChecker) Breached(ctx context.Context, password string) (bool, error) {
+	return false, nil
+}
+
+// rangeChecker uses the k-anonymity range API: only the first five hex
+// characters of the password's SHA-1 hash leave the server, and the
+// matching suffixes are compared locally
+type rangeChecker struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (c *rangeChecker) Breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides how many suffixes share the prefix from observers
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach API returned status %d", resp.StatusCode)
+	}
+
+	// Each line is SUFFIX:COUNT; padding entries have a count of 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if found && strings.EqualFold(candidate, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
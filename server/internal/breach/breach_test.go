@@ -0,0 +1,84 @@
+package breach_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/breach"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hashOf returns the upper-case hex SHA-1 of password, as the range API
+// uses it
+func hashOf(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestChecker_Range(t *testing.T) {
+	breachedHash := hashOf("password123")
+	paddedHash := hashOf("padding-only")
+
+	var prefixes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		prefixes = append(prefixes, prefix)
+		assert.Equal(t, "true", r.Header.Get("Add-Padding"))
+
+		for _, hash := range []string{breachedHash, paddedHash} {
+			if hash[:5] == prefix {
+				count := 2470
+				if hash == paddedHash {
+					count = 0
+				}
+				fmt.Fprintf(w, "0000000000000000000000000000000000A:3\r\n%s:%d\r\n", hash[5:], count)
+				return
+			}
+		}
+		fmt.Fprint(w, "0000000000000000000000000000000000A:3\r\n")
+	}))
+	defer server.Close()
+
+	checker := breach.NewChecker(config.PasswordConfig{CheckBreached: true, BreachAPIURL: server.URL + "/", BreachTimeout: time.Second})
+
+	breached, err := checker.Breached(context.Background(), "password123")
+	require.NoError(t, err)
+	assert.True(t, breached)
+
+	breached, err = checker.Breached(context.Background(), "padding-only")
+	require.NoError(t, err)
+	assert.False(t, breached, "padding entries have a zero count")
+
+	breached, err = checker.Breached(context.Background(), "c0rrect-horse-battery")
+	require.NoError(t, err)
+	assert.False(t, breached)
+
+	// Only the hash prefix is sent
+	assert.Equal(t, []string{breachedHash[:5], paddedHash[:5], hashOf("c0rrect-horse-battery")[:5]}, prefixes)
+}
+
+func TestChecker_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	checker := breach.NewChecker(config.PasswordConfig{CheckBreached: true, BreachAPIURL: server.URL, BreachTimeout: time.Second})
+	_, err := checker.Breached(context.Background(), "password123")
+	assert.Error(t, err)
+}
+
+func TestChecker_Disabled(t *testing.T) {
+	breached, err := breach.NewChecker(config.PasswordConfig{}).Breached(context.Background(), "password123")
+	require.NoError(t, err)
+	assert.False(t, breached)
+}
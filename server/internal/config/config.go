@@ -1,12 +1,21 @@
 package config
 
 import (
+	"crypto/rsa"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -14,30 +23,258 @@ import (
 )
 
 type Config struct {
-	Port     string
-	GinMode  string
-	Database DatabaseConfig
-	JWT      JWTConfig
-	App      AppConfig
+	Port       string
+	GinMode    string
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	Cookie     CookieConfig
+	CORS       CORSConfig
+	Cache      CacheConfig
+	Mail       MailConfig
+	Newsletter NewsletterConfig
+	Webhooks   WebhookConfig
+	Pagination PaginationConfig
+	Comments   CommentConfig
+	Password   PasswordConfig
+	Content    ContentConfig
+	Admin      AdminConfig
+	Users      UserConfig
+	App        AppConfig
 }
 
+// Supported database drivers
+const (
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite"
+)
+
 type DatabaseConfig struct {
+	// Driver is postgres (default) or sqlite. With sqlite, DBName is the
+	// database file path, or ":memory:" for a throwaway in-memory database.
+	Driver   string
 	Host     string
 	Port     int
 	User     string
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// LogLevel is the GORM logger level: silent, error, warn or info.
+	// Queries slower than SlowQueryThreshold are logged at warn.
+	LogLevel           string
+	SlowQueryThreshold time.Duration
+
+	// Connection pool settings
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
 }
 
+// Supported JWT signing algorithms
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+)
+
 type JWTConfig struct {
+	// Algorithm is the signing algorithm, HS256 (default) or RS256
+	Algorithm string
 	Secret    string
-	ExpiresIn time.Duration
+
+	// ExpiresIn is the access token lifetime. Refresh tokens last
+	// RefreshExpiresIn, or RememberMeExpiresIn when the user asks to be
+	// remembered at login. Draft preview tokens last PreviewExpiresIn, and
+	// links confirming a new email address EmailChangeExpiresIn.
+	ExpiresIn            time.Duration
+	RefreshExpiresIn     time.Duration
+	RememberMeExpiresIn  time.Duration
+	PreviewExpiresIn     time.Duration
+	EmailChangeExpiresIn time.Duration
+
+	// RevokedPurgeInterval is how often the revocations of tokens that have
+	// since expired are deleted
+	RevokedPurgeInterval time.Duration
+
+	// RS256 key pair, loaded from PEM files at startup
+	PrivateKeyPath string
+	PublicKeyPath  string
+	PrivateKey     *rsa.PrivateKey
+	PublicKey      *rsa.PublicKey
+}
+
+// CookieConfig controls the optional httpOnly auth cookie used by browser
+// clients. Header-based auth keeps working whether or not it's enabled.
+type CookieConfig struct {
+	Enabled  bool
+	Name     string
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// CORSConfig tunes the CORS headers. AllowedOrigins lists the origins allowed
+// to send credentials such as the auth cookie; MaxAge is how long browsers may
+// cache a preflight response; ExposeHeaders lists the response headers
+// scripts on other origins are allowed to read.
+type CORSConfig struct {
+	AllowedOrigins []string
+	MaxAge         time.Duration
+	ExposeHeaders  []string
+}
+
+// defaultCORSExposeHeaders are the headers exposed when CORS_EXPOSE_HEADERS
+// isn't set
+var defaultCORSExposeHeaders = []string{"Location", "Link", "ETag", "Retry-After", "Deprecation", "Idempotent-Replayed"}
+
+// CacheConfig controls the in-memory caches. Published post responses
+// served to anonymous readers are dropped when their post changes and
+// otherwise kept for ResponseTTL. The full and popular tag lists are kept
+// for TagListTTL, or until a tag changes; zero turns their cache off.
+type CacheConfig struct {
+	ResponseTTL time.Duration
+	TagListTTL  time.Duration
+}
+
+// MailConfig configures outgoing email. Without an SMTP host, messages are
+// written to the log instead of sent.
+type MailConfig struct {
+	SMTPHost string
+	SMTPPort int
+	Username string
+	Password string
+	From     string
+}
+
+// NewsletterConfig controls the digest of top posts mailed to subscribers.
+// Each digest covers the posts published during the last Interval.
+type NewsletterConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	PostLimit int
+}
+
+// WebhookConfig lists the endpoints notified of blog events. Payloads are
+// signed with Secret so receivers can verify they came from this server.
+// Workers deliver them concurrently from a queue holding up to QueueSize
+// deliveries.
+type WebhookConfig struct {
+	URLs       []string
+	Secret     string
+	MaxRetries int
+	Timeout    time.Duration
+	Workers    int
+	QueueSize  int
+}
+
+// PaginationConfig sets the page size used when a request doesn't pass
+// per_page, and the largest page size a request may ask for
+type PaginationConfig struct {
+	DefaultPerPage int
+	MaxPerPage     int
+
+	// Strict rejects invalid or out-of-range page and per_page values with
+	// a 400 instead of falling back to the defaults and limits
+	Strict bool
+}
+
+// CommentConfig controls comment moderation. With RequireApproval off, new
+// comments that pass the spam checks are approved as soon as they're posted.
+// A comment reported by ReportThreshold readers goes back to pending for
+// re-moderation. Authors may edit a comment for EditWindow after posting it;
+// zero lets them edit it at any time. Replies nest at most MaxDepth levels
+// below a top-level comment; zero allows any depth. Comment content is at
+// most MaxLength characters; zero allows any length. With RejectDuplicates
+// on, a comment repeating one its author left on the same post within
+// DuplicateWindow is refused as spam.
+type CommentConfig struct {
+	RequireApproval  bool
+	ReportThreshold  int
+	EditWindow       time.Duration
+	MaxDepth         int
+	MaxLength        int
+	RejectDuplicates bool
+	DuplicateWindow  time.Duration
+}
+
+// PasswordConfig is the policy new passwords must meet: at least MinLength
+// characters, with an upper-case letter, a lower-case letter, a digit or a
+// symbol when the matching Require field is set. RejectCommon refuses
+// passwords from a bundled list of the most common ones. CheckBreached also
+// looks them up in the breach API at BreachAPIURL, allowing them if it
+// doesn't answer within BreachTimeout.
+type PasswordConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	RejectCommon  bool
+	CheckBreached bool
+	BreachAPIURL  string
+	BreachTimeout time.Duration
+}
+
+// ContentConfig is the sanitization policy for post content. Inline HTML
+// and links with unsafe schemes such as javascript: are always removed.
+// Images are kept when AllowImages is set, and with TrustedIframes, trusted
+// authors (admins) may embed iframes from IframeHosts. Listings shorten
+// excerpts to at most MaxExcerptLength characters when asked to. Post
+// content is at most MaxPostLength characters; zero allows any length. With
+// AuthorOnlyFields, a post's status and view count are only shown to its
+// author and admins.
+type ContentConfig struct {
+	AllowImages      bool
+	TrustedIframes   bool
+	IframeHosts      []string
+	MaxExcerptLength int
+	MaxPostLength    int
+	AuthorOnlyFields bool
+}
+
+// defaultIframeHosts are the embed hosts allowed when CONTENT_IFRAME_HOSTS
+// isn't set
+var defaultIframeHosts = []string{"www.youtube.com", "www.youtube-nocookie.com", "player.vimeo.com"}
+
+// AdminConfig holds the credentials of the admin account created on first
+// start. Outside development both must be set; in development they fall
+// back to admin@blog.com / admin123456. The account has to change its
+// password on first login either way.
+type AdminConfig struct {
+	Email    string
+	Password string
+}
+
+// Development-only fallback credentials for the default admin
+const (
+	devAdminEmail    = "admin@blog.com"
+	devAdminPassword = "admin123456"
+)
+
+// UserConfig controls account housekeeping. Users an admin scheduled for
+// deletion are checked for purging every PurgeInterval.
+type UserConfig struct {
+	PurgeInterval time.Duration
 }
 
 type AppConfig struct {
 	Environment string
 	LogLevel    string
+
+	// Name is the blog's title, used in feeds
+	Name string
+
+	// BaseURL is the public address of the frontend, used for links in
+	// emails and feeds
+	BaseURL string
+
+	// DocsUIEnabled serves the interactive API docs at /api/docs
+	DocsUIEnabled bool
+
+	// TrustedProxies are the IPs and CIDR ranges of the proxies and load
+	// balancers in front of the server. The client IP is only read from
+	// X-Forwarded-For and X-Real-IP on requests coming from one of them;
+	// when empty, the headers are ignored.
+	TrustedProxies []string
 }
 
 var DB *gorm.DB
@@ -54,47 +291,398 @@ func LoadConfig() *Config {
 		log.Fatal("Invalid DB_PORT value")
 	}
 
-	jwtExpiresIn, err := time.ParseDuration(getEnv("JWT_EXPIRES_IN", "24h"))
+	jwtConfig := JWTConfig{
+		Algorithm:            strings.ToUpper(getEnv("JWT_ALGORITHM", JWTAlgorithmHS256)),
+		Secret:               getEnv("JWT_SECRET", "your-super-secret-jwt-key"),
+		ExpiresIn:            getDurationEnv("JWT_EXPIRES_IN", "24h"),
+		RefreshExpiresIn:     getDurationEnv("JWT_REFRESH_EXPIRES_IN", "168h"),
+		RememberMeExpiresIn:  getDurationEnv("JWT_REMEMBER_ME_EXPIRES_IN", "720h"),
+		PreviewExpiresIn:     getDurationEnv("JWT_PREVIEW_EXPIRES_IN", "72h"),
+		EmailChangeExpiresIn: getDurationEnv("JWT_EMAIL_CHANGE_EXPIRES_IN", "24h"),
+		RevokedPurgeInterval: getDurationEnv("JWT_REVOKED_PURGE_INTERVAL", "1h"),
+		PrivateKeyPath:       getEnv("JWT_PRIVATE_KEY_PATH", ""),
+		PublicKeyPath:        getEnv("JWT_PUBLIC_KEY_PATH", ""),
+	}
+
+	switch jwtConfig.Algorithm {
+	case JWTAlgorithmHS256:
+	case JWTAlgorithmRS256:
+		if err := jwtConfig.loadRSAKeys(); err != nil {
+			log.Fatal("Failed to load JWT keys: ", err)
+		}
+	default:
+		log.Fatalf("Invalid JWT_ALGORITHM value %q (expected HS256 or RS256)", jwtConfig.Algorithm)
+	}
+
+	sameSite, err := parseSameSite(getEnv("AUTH_COOKIE_SAMESITE", "lax"))
 	if err != nil {
-		log.Fatal("Invalid JWT_EXPIRES_IN value")
+		log.Fatal("Invalid AUTH_COOKIE_SAMESITE value: ", err)
+	}
+
+	appEnv := getEnv("APP_ENV", "development")
+	defaultDBLogLevel := "warn"
+	if appEnv == "development" {
+		defaultDBLogLevel = "info"
+	}
+
+	iframeHosts := getListEnv("CONTENT_IFRAME_HOSTS")
+	if len(iframeHosts) == 0 {
+		iframeHosts = defaultIframeHosts
+	}
+
+	exposeHeaders := getListEnv("CORS_EXPOSE_HEADERS")
+	if len(exposeHeaders) == 0 {
+		exposeHeaders = defaultCORSExposeHeaders
+	}
+
+	// Only development gets fallback admin credentials
+	defaultAdminEmail, defaultAdminPassword := "", ""
+	if appEnv == "development" {
+		defaultAdminEmail, defaultAdminPassword = devAdminEmail, devAdminPassword
+	}
+
+	// SQLite allows a single writer, so by default serialize access through
+	// one connection rather than failing with "database is locked"
+	dbDriver := strings.ToLower(getEnv("DB_DRIVER", DBDriverPostgres))
+	defaultMaxOpenConns := "100"
+	if dbDriver == DBDriverSQLite {
+		defaultMaxOpenConns = "1"
 	}
 
 	return &Config{
 		Port:    getEnv("PORT", "8080"),
 		GinMode: getEnv("GIN_MODE", "debug"),
 		Database: DatabaseConfig{
+			Driver:   dbDriver,
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     dbPort,
 			User:     getEnv("DB_USER", "postgres"),
 			Password: getEnv("DB_PASSWORD", "postgres"),
 			DBName:   getEnv("DB_NAME", "golang_multiuser_blog"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			LogLevel:           strings.ToLower(getEnv("DB_LOG_LEVEL", defaultDBLogLevel)),
+			SlowQueryThreshold: getDurationEnv("DB_SLOW_QUERY_THRESHOLD", "200ms"),
+
+			MaxIdleConns:    getIntEnv("DB_MAX_IDLE_CONNS", "10"),
+			MaxOpenConns:    getIntEnv("DB_MAX_OPEN_CONNS", defaultMaxOpenConns),
+			ConnMaxLifetime: getDurationEnv("DB_CONN_MAX_LIFETIME", "1h"),
+		},
+		JWT: jwtConfig,
+		Cookie: CookieConfig{
+			Enabled:  getBoolEnv("AUTH_COOKIE_ENABLED", false),
+			Name:     getEnv("AUTH_COOKIE_NAME", "access_token"),
+			Domain:   getEnv("AUTH_COOKIE_DOMAIN", ""),
+			Secure:   getBoolEnv("AUTH_COOKIE_SECURE", true),
+			SameSite: sameSite,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: getListEnv("CORS_ALLOWED_ORIGINS"),
+			MaxAge:         getDurationEnv("CORS_MAX_AGE", "2h"),
+			ExposeHeaders:  exposeHeaders,
+		},
+		Cache: CacheConfig{
+			ResponseTTL: getDurationEnv("RESPONSE_CACHE_TTL", "30s"),
+			TagListTTL:  getNonNegativeDurationEnv("TAG_CACHE_TTL", "1m"),
+		},
+		Mail: MailConfig{
+			SMTPHost: getEnv("SMTP_HOST", ""),
+			SMTPPort: getIntEnv("SMTP_PORT", "587"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("MAIL_FROM", "no-reply@localhost"),
+		},
+		Newsletter: NewsletterConfig{
+			Enabled:   getBoolEnv("NEWSLETTER_ENABLED", false),
+			Interval:  getDurationEnv("NEWSLETTER_INTERVAL", "168h"),
+			PostLimit: getIntEnv("NEWSLETTER_POST_LIMIT", "5"),
+		},
+		Webhooks: WebhookConfig{
+			URLs:       getListEnv("WEBHOOK_URLS"),
+			Secret:     getEnv("WEBHOOK_SECRET", ""),
+			MaxRetries: getIntEnv("WEBHOOK_MAX_RETRIES", "3"),
+			Timeout:    getDurationEnv("WEBHOOK_TIMEOUT", "5s"),
+			Workers:    getIntEnv("WEBHOOK_WORKERS", "4"),
+			QueueSize:  getIntEnv("WEBHOOK_QUEUE_SIZE", "100"),
+		},
+		Pagination: PaginationConfig{
+			DefaultPerPage: getIntEnv("PAGINATION_DEFAULT", "10"),
+			MaxPerPage:     getIntEnv("PAGINATION_MAX", "100"),
+			Strict:         getBoolEnv("PAGINATION_STRICT", false),
+		},
+		Comments: CommentConfig{
+			RequireApproval:  getBoolEnv("COMMENTS_REQUIRE_APPROVAL", true),
+			ReportThreshold:  getIntEnv("COMMENTS_REPORT_THRESHOLD", "3"),
+			EditWindow:       getNonNegativeDurationEnv("COMMENTS_EDIT_WINDOW", "15m"),
+			MaxDepth:         getIntEnv("COMMENTS_MAX_DEPTH", "5"),
+			MaxLength:        getIntEnv("COMMENTS_MAX_LENGTH", "1000"),
+			RejectDuplicates: getBoolEnv("COMMENTS_REJECT_DUPLICATES", true),
+			DuplicateWindow:  getDurationEnv("COMMENTS_DUPLICATE_WINDOW", "10m"),
+		},
+		Password: PasswordConfig{
+			MinLength:     getIntEnv("PASSWORD_MIN_LENGTH", "8"),
+			RequireUpper:  getBoolEnv("PASSWORD_REQUIRE_UPPER", false),
+			RequireLower:  getBoolEnv("PASSWORD_REQUIRE_LOWER", false),
+			RequireDigit:  getBoolEnv("PASSWORD_REQUIRE_DIGIT", false),
+			RequireSymbol: getBoolEnv("PASSWORD_REQUIRE_SYMBOL", false),
+			RejectCommon:  getBoolEnv("PASSWORD_REJECT_COMMON", false),
+			CheckBreached: getBoolEnv("PASSWORD_CHECK_BREACHED", false),
+			BreachAPIURL:  getEnv("PASSWORD_BREACH_API_URL", "https://api.pwnedpasswords.com"),
+			BreachTimeout: getDurationEnv("PASSWORD_BREACH_TIMEOUT", "3s"),
 		},
-		JWT: JWTConfig{
-			Secret:    getEnv("JWT_SECRET", "your-super-secret-jwt-key"),
-			ExpiresIn: jwtExpiresIn,
+		Content: ContentConfig{
+			AllowImages:      getBoolEnv("CONTENT_ALLOW_IMAGES", true),
+			TrustedIframes:   getBoolEnv("CONTENT_TRUSTED_IFRAMES", false),
+			IframeHosts:      iframeHosts,
+			MaxExcerptLength: getIntEnv("CONTENT_MAX_EXCERPT_LENGTH", "500"),
+			MaxPostLength:    getIntEnv("CONTENT_MAX_POST_LENGTH", "100000"),
+			AuthorOnlyFields: getBoolEnv("CONTENT_AUTHOR_ONLY_FIELDS", false),
+		},
+		Admin: AdminConfig{
+			Email:    getEnv("ADMIN_EMAIL", defaultAdminEmail),
+			Password: getEnv("ADMIN_PASSWORD", defaultAdminPassword),
+		},
+		Users: UserConfig{
+			PurgeInterval: getDurationEnv("USER_PURGE_INTERVAL", "1h"),
 		},
 		App: AppConfig{
-			Environment: getEnv("APP_ENV", "development"),
+			Environment: appEnv,
 			LogLevel:    getEnv("LOG_LEVEL", "info"),
+			Name:        getEnv("APP_NAME", "Multiuser Blog"),
+			BaseURL:     strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:3000"), "/"),
+
+			DocsUIEnabled:  getBoolEnv("DOCS_UI_ENABLED", appEnv == "development"),
+			TrustedProxies: getListEnv("TRUSTED_PROXIES"),
 		},
 	}
 }
 
+// minJWTSecretLength is the shortest HS256 secret accepted outside development
+const minJWTSecretLength = 32
+
+// defaultJWTSecrets are the placeholder secrets shipped in code and in
+// .env.example, which must never be used outside development
+var defaultJWTSecrets = []string{
+	"your-super-secret-jwt-key",
+	"your-super-secret-jwt-key-change-in-production",
+}
+
+// IsDevelopment reports whether the app is running in the development
+// environment
+func (c *Config) IsDevelopment() bool {
+	return c.App.Environment == "development"
+}
+
+// Validate checks the configuration for invalid or insecure values. Invalid
+// values are always returned as an error. Insecure settings, like the
+// default JWT secret, are errors outside development and warnings in it.
+func (c *Config) Validate() ([]string, error) {
+	var problems, insecure []string
+
+	if !isValidPort(c.Port) {
+		problems = append(problems, fmt.Sprintf("PORT %q must be a number between 1 and 65535", c.Port))
+	}
+
+	switch c.Database.Driver {
+	case DBDriverPostgres, "":
+		if c.Database.Host == "" {
+			problems = append(problems, "DB_HOST is required")
+		}
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("DB_PORT %d must be between 1 and 65535", c.Database.Port))
+		}
+		if c.Database.User == "" {
+			problems = append(problems, "DB_USER is required")
+		}
+		if c.Database.DBName == "" {
+			problems = append(problems, "DB_NAME is required")
+		}
+		switch c.Database.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			problems = append(problems, fmt.Sprintf("DB_SSLMODE %q is not a valid Postgres sslmode", c.Database.SSLMode))
+		}
+	case DBDriverSQLite:
+		if c.Database.DBName == "" {
+			problems = append(problems, "DB_NAME is required")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("DB_DRIVER %q must be postgres or sqlite", c.Database.Driver))
+	}
+
+	if c.Database.MaxOpenConns < 1 {
+		problems = append(problems, fmt.Sprintf("DB_MAX_OPEN_CONNS %d must be at least 1", c.Database.MaxOpenConns))
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(problems, fmt.Sprintf("DB_MAX_IDLE_CONNS %d must be between 0 and DB_MAX_OPEN_CONNS (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns))
+	}
+
+	if c.Database.LogLevel != "" {
+		if _, err := gormLogLevel(c.Database.LogLevel); err != nil {
+			problems = append(problems, fmt.Sprintf("DB_LOG_LEVEL: %v", err))
+		}
+	}
+
+	if c.JWT.Algorithm == JWTAlgorithmHS256 || c.JWT.Algorithm == "" {
+		for _, secret := range defaultJWTSecrets {
+			if c.JWT.Secret == secret {
+				insecure = append(insecure, "JWT_SECRET is set to the default placeholder value")
+				break
+			}
+		}
+		if len(c.JWT.Secret) < minJWTSecretLength {
+			insecure = append(insecure, fmt.Sprintf("JWT_SECRET must be at least %d characters", minJWTSecretLength))
+		}
+	}
+
+	if c.Newsletter.Enabled && c.Newsletter.PostLimit < 1 {
+		problems = append(problems, fmt.Sprintf("NEWSLETTER_POST_LIMIT %d must be at least 1", c.Newsletter.PostLimit))
+	}
+
+	if c.Comments.ReportThreshold < 1 {
+		problems = append(problems, fmt.Sprintf("COMMENTS_REPORT_THRESHOLD %d must be at least 1", c.Comments.ReportThreshold))
+	}
+	if c.Comments.MaxDepth < 0 {
+		problems = append(problems, fmt.Sprintf("COMMENTS_MAX_DEPTH %d must not be negative", c.Comments.MaxDepth))
+	}
+
+	if c.Password.MinLength < 1 {
+		problems = append(problems, fmt.Sprintf("PASSWORD_MIN_LENGTH %d must be at least 1", c.Password.MinLength))
+	}
+	if c.Password.CheckBreached {
+		if u, err := url.Parse(c.Password.BreachAPIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("PASSWORD_BREACH_API_URL %q must be an http or https URL", c.Password.BreachAPIURL))
+		}
+	}
+
+	if c.Content.MaxExcerptLength < 1 {
+		problems = append(problems, fmt.Sprintf("CONTENT_MAX_EXCERPT_LENGTH %d must be at least 1", c.Content.MaxExcerptLength))
+	}
+
+	if c.Pagination.DefaultPerPage < 1 {
+		problems = append(problems, fmt.Sprintf("PAGINATION_DEFAULT %d must be at least 1", c.Pagination.DefaultPerPage))
+	}
+	if c.Pagination.MaxPerPage < c.Pagination.DefaultPerPage {
+		problems = append(problems, fmt.Sprintf("PAGINATION_MAX %d must be at least PAGINATION_DEFAULT (%d)", c.Pagination.MaxPerPage, c.Pagination.DefaultPerPage))
+	}
+
+	for _, proxy := range c.App.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES entry %q must be an IP address or CIDR range", proxy))
+			}
+		}
+	}
+
+	if len(c.Webhooks.URLs) > 0 {
+		if c.Webhooks.Secret == "" {
+			problems = append(problems, "WEBHOOK_SECRET is required when WEBHOOK_URLS is set")
+		}
+		for _, raw := range c.Webhooks.URLs {
+			if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				problems = append(problems, fmt.Sprintf("WEBHOOK_URLS entry %q must be an http or https URL", raw))
+			}
+		}
+		if c.Webhooks.MaxRetries < 0 {
+			problems = append(problems, fmt.Sprintf("WEBHOOK_MAX_RETRIES %d must not be negative", c.Webhooks.MaxRetries))
+		}
+		if c.Webhooks.Workers < 1 {
+			problems = append(problems, fmt.Sprintf("WEBHOOK_WORKERS %d must be at least 1", c.Webhooks.Workers))
+		}
+		if c.Webhooks.QueueSize < 1 {
+			problems = append(problems, fmt.Sprintf("WEBHOOK_QUEUE_SIZE %d must be at least 1", c.Webhooks.QueueSize))
+		}
+	}
+
+	if c.Admin.Email == "" || c.Admin.Password == "" {
+		insecure = append(insecure, "ADMIN_EMAIL and ADMIN_PASSWORD are required")
+	} else {
+		if c.Admin.Password == devAdminPassword {
+			insecure = append(insecure, "ADMIN_PASSWORD is set to the development default")
+		}
+		if len(c.Admin.Password) < 8 {
+			problems = append(problems, "ADMIN_PASSWORD must be at least 8 characters")
+		}
+	}
+
+	// Requests authenticated by the cookie aren't checked for CSRF, so other
+	// sites must not be able to send it
+	if c.Cookie.Enabled && c.Cookie.SameSite == http.SameSiteNoneMode {
+		problems = append(problems, "AUTH_COOKIE_SAMESITE=none would let other sites send the auth cookie (use lax or strict)")
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+			problems = append(problems, fmt.Sprintf("CORS_ALLOWED_ORIGINS entry %q must be an origin like https://blog.example.com", origin))
+		}
+	}
+
+	var warnings []string
+	if c.IsDevelopment() {
+		warnings = insecure
+	} else {
+		problems = append(problems, insecure...)
+	}
+
+	if len(problems) > 0 {
+		return warnings, fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return warnings, nil
+}
+
+// isValidPort reports whether port is a usable TCP port number
+func isValidPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
+
+// loadRSAKeys reads the RS256 key pair from the configured PEM files
+func (c *JWTConfig) loadRSAKeys() error {
+	if c.PrivateKeyPath == "" || c.PublicKeyPath == "" {
+		return errors.New("JWT_PRIVATE_KEY_PATH and JWT_PUBLIC_KEY_PATH are required for RS256")
+	}
+
+	privatePEM, err := os.ReadFile(c.PrivateKeyPath)
+	if err != nil {
+		return fmt.Errorf("reading private key: %w", err)
+	}
+	c.PrivateKey, err = jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+	if err != nil {
+		return fmt.Errorf("parsing private key: %w", err)
+	}
+
+	publicPEM, err := os.ReadFile(c.PublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("reading public key: %w", err)
+	}
+	c.PublicKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+	if err != nil {
+		return fmt.Errorf("parsing public key: %w", err)
+	}
+
+	return nil
+}
+
 // InitDatabase initializes the database connection
 func InitDatabase(config *Config) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
-		config.Database.Host,
-		config.Database.User,
-		config.Database.Password,
-		config.Database.DBName,
-		config.Database.Port,
-		config.Database.SSLMode,
-	)
-
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	dialector, err := newDialector(config.Database)
+	if err != nil {
+		log.Fatal("Failed to configure database: ", err)
+	}
+
+	logLevel, err := gormLogLevel(config.Database.LogLevel)
+	if err != nil {
+		log.Fatal("Invalid DB_LOG_LEVEL value: ", err)
+	}
+
+	DB, err = gorm.Open(dialector, &gorm.Config{
+		// Map driver constraint errors to gorm.ErrDuplicatedKey and friends
+		TranslateError: true,
+		Logger: logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold:             config.Database.SlowQueryThreshold,
+			LogLevel:                  logLevel,
+			IgnoreRecordNotFoundError: true,
+			Colorful:                  true,
+		}),
 	})
 
 	if err != nil {
@@ -107,19 +695,171 @@ func InitDatabase(config *Config) {
 		log.Fatal("Failed to get database instance:", err)
 	}
 
-	// Set connection pool settings
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	configurePool(sqlDB, config.Database)
 
 	log.Println("✅ Database connection established successfully")
 }
 
+// configurePool applies the connection pool settings to a database handle
+func configurePool(sqlDB *sql.DB, db DatabaseConfig) {
+	sqlDB.SetMaxIdleConns(db.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(db.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(db.ConnMaxLifetime)
+}
+
+// gormLogLevel maps a log level name to the GORM logger level. "debug" is
+// accepted as an alias of info so DB_LOG_LEVEL can share values with
+// LOG_LEVEL.
+func gormLogLevel(level string) (logger.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent, nil
+	case "error":
+		return logger.Error, nil
+	case "warn", "warning":
+		return logger.Warn, nil
+	case "info", "debug":
+		return logger.Info, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q (expected silent, error, warn or info)", level)
+	}
+}
+
+// newDialector builds the GORM dialector for the configured driver
+func newDialector(db DatabaseConfig) (gorm.Dialector, error) {
+	switch db.Driver {
+	case DBDriverPostgres, "":
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+			db.Host,
+			db.User,
+			db.Password,
+			db.DBName,
+			db.Port,
+			db.SSLMode,
+		)
+		return postgres.Open(dsn), nil
+	case DBDriverSQLite:
+		return sqlite.Open(sqliteDSN(db.DBName)), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q (expected postgres or sqlite)", db.Driver)
+	}
+}
+
+// sqliteDSN builds a SQLite DSN for a database file, with foreign keys
+// enforced to match Postgres
+func sqliteDSN(path string) string {
+	if path == ":memory:" {
+		return "file::memory:?cache=shared&_pragma=foreign_keys(1)"
+	}
+	return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)", path)
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
 }
 
+// getDurationEnv reads a duration environment variable, exiting at startup
+// if it doesn't parse or isn't positive
+func getDurationEnv(key, fallback string) time.Duration {
+	value := getEnv(key, fallback)
+	duration, err := parsePositiveDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s value %q: %v", key, value, err)
+	}
+	return duration
+}
+
+// parsePositiveDuration parses a Go duration string such as "24h" and
+// rejects zero or negative values
+func parsePositiveDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+	return duration, nil
+}
+
+// getNonNegativeDurationEnv reads a duration environment variable that may
+// be zero, usually to turn something off, exiting at startup if it doesn't
+// parse or is negative
+func getNonNegativeDurationEnv(key, fallback string) time.Duration {
+	value := getEnv(key, fallback)
+	duration, err := parseNonNegativeDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s value %q: %v", key, value, err)
+	}
+	return duration
+}
+
+// parseNonNegativeDuration parses a Go duration string such as "15m",
+// accepting a bare "0", and rejects negative values
+func parseNonNegativeDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, errors.New("duration must not be negative")
+	}
+	return duration, nil
+}
+
+// getIntEnv reads an integer environment variable, exiting at startup if it
+// doesn't parse
+func getIntEnv(key, fallback string) int {
+	value := getEnv(key, fallback)
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s value %q", key, value)
+	}
+	return parsed
+}
+
+// getListEnv splits a comma-separated environment variable, dropping empty
+// entries
+func getListEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getBoolEnv reads a boolean environment variable, exiting at startup if it
+// doesn't parse
+func getBoolEnv(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid %s value %q", key, value)
+	}
+	return parsed
+}
+
+// parseSameSite maps a SameSite setting (lax, strict or none) to its
+// cookie mode
+func parseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("unknown SameSite mode %q (expected lax, strict or none)", value)
+	}
+}
+
 // getEnv gets environment variable with fallback
 func getEnv(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestParsePositiveDuration(t *testing.T) {
+	duration, err := parsePositiveDuration("24h")
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, duration)
+
+	for _, value := range []string{"", "tomorrow", "0s", "-1h"} {
+		_, err := parsePositiveDuration(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseNonNegativeDuration(t *testing.T) {
+	for value, want := range map[string]time.Duration{"0": 0, "0s": 0, "15m": 15 * time.Minute} {
+		duration, err := parseNonNegativeDuration(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, duration, value)
+	}
+
+	for _, value := range []string{"", "soon", "-1m"} {
+		_, err := parseNonNegativeDuration(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLoadConfig_TagCacheOff(t *testing.T) {
+	t.Setenv("TAG_CACHE_TTL", "0")
+
+	cfg := LoadConfig()
+	assert.Zero(t, cfg.Cache.TagListTTL)
+}
+
+func TestLoadConfig_UnlimitedCommentEdits(t *testing.T) {
+	t.Setenv("COMMENTS_EDIT_WINDOW", "0")
+
+	cfg := LoadConfig()
+	assert.Zero(t, cfg.Comments.EditWindow)
+}
+
+func TestNewDialector(t *testing.T) {
+	dialector, err := newDialector(DatabaseConfig{Driver: DBDriverPostgres})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", dialector.Name())
+
+	dialector, err = newDialector(DatabaseConfig{Driver: DBDriverSQLite, DBName: ":memory:"})
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", dialector.Name())
+
+	_, err = newDialector(DatabaseConfig{Driver: "mysql"})
+	assert.Error(t, err)
+}
+
+// validConfig returns a production config that passes validation
+func validConfig() *Config {
+	return &Config{
+		Port: "8080",
+		Database: DatabaseConfig{
+			Driver:  DBDriverPostgres,
+			Host:    "db.internal",
+			Port:    5432,
+			User:    "blog",
+			DBName:  "blog",
+			SSLMode: "require",
+
+			MaxIdleConns:    10,
+			MaxOpenConns:    100,
+			ConnMaxLifetime: time.Hour,
+		},
+		JWT: JWTConfig{
+			Algorithm: JWTAlgorithmHS256,
+			Secret:    "0123456789abcdef0123456789abcdef",
+		},
+		Pagination: PaginationConfig{DefaultPerPage: 10, MaxPerPage: 100},
+		Comments:   CommentConfig{ReportThreshold: 3},
+		Password:   PasswordConfig{MinLength: 8},
+		Content:    ContentConfig{MaxExcerptLength: 500},
+		Admin:      AdminConfig{Email: "ops@example.com", Password: "k3ep-it-s3cret"},
+		App:        AppConfig{Environment: "production"},
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("valid production config", func(t *testing.T) {
+		warnings, err := validConfig().Validate()
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{"default secret", func(c *Config) { c.JWT.Secret = "your-super-secret-jwt-key" }, "default placeholder"},
+		{"example secret", func(c *Config) { c.JWT.Secret = "your-super-secret-jwt-key-change-in-production" }, "default placeholder"},
+		{"short secret", func(c *Config) { c.JWT.Secret = "short" }, "at least 32 characters"},
+		{"bad port", func(c *Config) { c.Port = "http" }, "PORT"},
+		{"port out of range", func(c *Config) { c.Port = "70000" }, "PORT"},
+		{"bad db port", func(c *Config) { c.Database.Port = 0 }, "DB_PORT"},
+		{"missing db host", func(c *Config) { c.Database.Host = "" }, "DB_HOST"},
+		{"bad sslmode", func(c *Config) { c.Database.SSLMode = "sometimes" }, "DB_SSLMODE"},
+		{"no open conns", func(c *Config) { c.Database.MaxOpenConns = 0 }, "DB_MAX_OPEN_CONNS"},
+		{"idle above open", func(c *Config) { c.Database.MaxIdleConns = 200 }, "DB_MAX_IDLE_CONNS"},
+		{"negative idle", func(c *Config) { c.Database.MaxIdleConns = -1 }, "DB_MAX_IDLE_CONNS"},
+		{"unknown driver", func(c *Config) { c.Database.Driver = "mysql" }, "DB_DRIVER"},
+		{"no default page size", func(c *Config) { c.Pagination.DefaultPerPage = 0 }, "PAGINATION_DEFAULT"},
+		{"max page size below default", func(c *Config) { c.Pagination.MaxPerPage = 5 }, "PAGINATION_MAX"},
+		{"no report threshold", func(c *Config) { c.Comments.ReportThreshold = 0 }, "COMMENTS_REPORT_THRESHOLD"},
+		{"negative comment depth", func(c *Config) { c.Comments.MaxDepth = -1 }, "COMMENTS_MAX_DEPTH"},
+		{"zero password length", func(c *Config) { c.Password.MinLength = 0 }, "PASSWORD_MIN_LENGTH"},
+		{"bad breach API URL", func(c *Config) { c.Password.CheckBreached, c.Password.BreachAPIURL = true, "pwned" }, "PASSWORD_BREACH_API_URL"},
+		{"no excerpt length", func(c *Config) { c.Content.MaxExcerptLength = 0 }, "CONTENT_MAX_EXCERPT_LENGTH"},
+		{"bad trusted proxy", func(c *Config) { c.App.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"} }, "TRUSTED_PROXIES"},
+		{"webhook without secret", func(c *Config) {
+			c.Webhooks = WebhookConfig{URLs: []string{"https://hooks.example.com/blog"}}
+		}, "WEBHOOK_SECRET"},
+		{"no webhook workers", func(c *Config) {
+			c.Webhooks = WebhookConfig{URLs: []string{"https://hooks.example.com/blog"}, Secret: "s3cret", QueueSize: 100}
+		}, "WEBHOOK_WORKERS"},
+		{"bad webhook url", func(c *Config) {
+			c.Webhooks = WebhookConfig{URLs: []string{"ftp://hooks.example.com"}, Secret: "s3cret"}
+		}, "WEBHOOK_URLS"},
+		{"cross-site cookie", func(c *Config) {
+			c.Cookie = CookieConfig{Enabled: true, Name: "access_token", Secure: true, SameSite: http.SameSiteNoneMode}
+		}, "AUTH_COOKIE_SAMESITE"},
+		{"bad CORS origin", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://blog.example.com/app"} }, "CORS_ALLOWED_ORIGINS"},
+		{"missing admin email", func(c *Config) { c.Admin.Email = "" }, "ADMIN_EMAIL"},
+		{"missing admin password", func(c *Config) { c.Admin.Password = "" }, "ADMIN_PASSWORD"},
+		{"development admin password", func(c *Config) { c.Admin.Password = "admin123456" }, "development default"},
+		{"short admin password", func(c *Config) { c.Admin.Password = "short" }, "at least 8 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			_, err := cfg.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	t.Run("insecure secret only warns in development", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.App.Environment = "development"
+		cfg.JWT.Secret = "your-super-secret-jwt-key"
+
+		warnings, err := cfg.Validate()
+		require.NoError(t, err)
+		assert.Len(t, warnings, 2)
+	})
+
+	t.Run("admin credentials only required outside development", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.App.Environment = "development"
+		cfg.Admin = AdminConfig{}
+
+		warnings, err := cfg.Validate()
+		require.NoError(t, err)
+		assert.Len(t, warnings, 1)
+	})
+
+	t.Run("invalid values fail even in development", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.App.Environment = "development"
+		cfg.Port = "not-a-port"
+
+		_, err := cfg.Validate()
+		require.Error(t, err)
+	})
+
+	t.Run("secret not required for RS256", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.JWT = JWTConfig{Algorithm: JWTAlgorithmRS256}
+
+		_, err := cfg.Validate()
+		require.NoError(t, err)
+	})
+}
+
+func TestGormLogLevel(t *testing.T) {
+	tests := map[string]logger.LogLevel{
+		"silent": logger.Silent,
+		"error":  logger.Error,
+		"warn":   logger.Warn,
+		"WARN":   logger.Warn,
+		"info":   logger.Info,
+		"debug":  logger.Info,
+	}
+
+	for level, want := range tests {
+		got, err := gormLogLevel(level)
+		require.NoError(t, err, level)
+		assert.Equal(t, want, got, level)
+	}
+
+	_, err := gormLogLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestConfigurePool(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	configurePool(sqlDB, DatabaseConfig{
+		MaxIdleConns:    2,
+		MaxOpenConns:    5,
+		ConnMaxLifetime: time.Minute,
+	})
+
+	assert.Equal(t, 5, sqlDB.Stats().MaxOpenConnections)
+}
@@ -0,0 +1,18 @@
+// Package docs embeds the generated OpenAPI document and the page that
+// renders it.
+package docs
+
+import _ "embed"
+
+//go:generate go run ../../cmd/openapi -general ../../cmd/server/main.go -handlers ../handlers -models ../models -out openapi.json
+
+// OpenAPI is the API's OpenAPI 3 document. Regenerate it with
+// `go generate ./internal/docs` after changing handler annotations.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// SwaggerUI is an HTML page rendering OpenAPI with Swagger UI
+//
+//go:embed swagger.html
+var SwaggerUI []byte
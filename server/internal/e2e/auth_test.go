@@ -1,4 +1,3 @@
-package e2e
 //go:build e2e
 
 package e2e
@@ -25,14 +24,16 @@ var (
 )
 
 func TestMain(m *testing.M) {
-	// Set test environment
+	// Set test environment. Database settings can be overridden, e.g.
+	// DB_DRIVER=sqlite DB_NAME=:memory: to run without Postgres.
 	os.Setenv("GIN_MODE", "test")
-	os.Setenv("DB_HOST", "localhost")
-	os.Setenv("DB_PORT", "5432")
-	os.Setenv("DB_USER", "postgres")
-	os.Setenv("DB_PASSWORD", "postgres")
-	os.Setenv("DB_NAME", "golang_multiuser_blog_e2e_test")
-	os.Setenv("DB_SSLMODE", "disable")
+	setDefaultEnv("DB_DRIVER", "postgres")
+	setDefaultEnv("DB_HOST", "localhost")
+	setDefaultEnv("DB_PORT", "5432")
+	setDefaultEnv("DB_USER", "postgres")
+	setDefaultEnv("DB_PASSWORD", "postgres")
+	setDefaultEnv("DB_NAME", "golang_multiuser_blog_e2e_test")
+	setDefaultEnv("DB_SSLMODE", "disable")
 	os.Setenv("JWT_SECRET", "test-secret-key-for-e2e")
 	os.Setenv("PORT", "8081")
 
@@ -43,7 +44,7 @@ func TestMain(m *testing.M) {
 	config.InitDatabase(cfg)
 
 	// Run migrations
-	err := migration.RunMigrations()
+	err := migration.RunMigrations(cfg.Admin)
 	if err != nil {
 		panic("Failed to run migrations: " + err.Error())
 	}
@@ -65,23 +66,28 @@ func TestMain(m *testing.M) {
 }
 
 func TestAuthFlow(t *testing.T) {
+	// Where registration says the new account can be read
+	var location string
+
 	// Test user registration
 	t.Run("RegisterUser", func(t *testing.T) {
 		userReq := &models.UserCreateRequest{
 			FirstName: "John",
 			LastName:  "Doe",
 			Email:     "john.doe.e2e@example.com",
-			Username:  "johndoe_e2e",
+			Username:  "johndoee2e",
 			Password:  "password123",
 			Bio:       "Test user for E2E testing",
 		}
 
 		jsonReq, _ := json.Marshal(userReq)
-		resp, err := http.Post(testServer.URL+"/api/auth/register", "application/json", bytes.NewBuffer(jsonReq))
+		resp, err := http.Post(testServer.URL+"/api/v1/auth/register", "application/json", bytes.NewBuffer(jsonReq))
 		require.NoError(t, err)
 		defer resp.Body.Close()
 
 		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		location = resp.Header.Get("Location")
+		assert.Equal(t, "/api/v1/auth/profile", location)
 
 		var registerResp models.APIResponse
 		err = json.NewDecoder(resp.Body).Decode(&registerResp)
@@ -92,12 +98,12 @@ func TestAuthFlow(t *testing.T) {
 	// Test user login
 	t.Run("LoginUser", func(t *testing.T) {
 		loginReq := &models.UserLoginRequest{
-			EmailOrUsername: "johndoe_e2e",
+			EmailOrUsername: "johndoee2e",
 			Password:        "password123",
 		}
 
 		jsonReq, _ := json.Marshal(loginReq)
-		resp, err := http.Post(testServer.URL+"/api/auth/login", "application/json", bytes.NewBuffer(jsonReq))
+		resp, err := http.Post(testServer.URL+"/api/v1/auth/login", "application/json", bytes.NewBuffer(jsonReq))
 		require.NoError(t, err)
 		defer resp.Body.Close()
 
@@ -115,9 +121,9 @@ func TestAuthFlow(t *testing.T) {
 		require.True(t, ok)
 		assert.NotEmpty(t, token)
 
-		// Test get profile with token
+		// Test get profile, at the registration's Location, with token
 		t.Run("GetProfile", func(t *testing.T) {
-			req, _ := http.NewRequest("GET", testServer.URL+"/api/auth/profile", nil)
+			req, _ := http.NewRequest("GET", testServer.URL+location, nil)
 			req.Header.Set("Authorization", "Bearer "+token)
 
 			client := &http.Client{Timeout: 10 * time.Second}
@@ -132,6 +138,25 @@ func TestAuthFlow(t *testing.T) {
 			require.NoError(t, err)
 			assert.True(t, profileResp.Success)
 		})
+
+		// Test that logging out revokes the token
+		t.Run("Logout", func(t *testing.T) {
+			client := &http.Client{Timeout: 10 * time.Second}
+
+			req, _ := http.NewRequest("POST", testServer.URL+"/api/v1/auth/logout", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			req, _ = http.NewRequest("GET", testServer.URL+"/api/v1/auth/profile", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err = client.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		})
 	})
 }
 
@@ -149,4 +174,11 @@ func TestHealthCheck(t *testing.T) {
 	success, ok := healthResp["success"].(bool)
 	require.True(t, ok)
 	assert.True(t, success)
-}
\ No newline at end of file
+}
+
+// setDefaultEnv sets an environment variable unless it's already set
+func setDefaultEnv(key, value string) {
+	if _, ok := os.LookupEnv(key); !ok {
+		os.Setenv(key, value)
+	}
+}
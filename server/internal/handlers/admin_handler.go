@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
 )
 
@@ -22,35 +25,70 @@ func NewAdminHandler(userService service.UserService) *AdminHandler {
 
 // GetUsers godoc
 // @Summary Get all users (Admin only)
-// @Description Get a paginated list of all users
+// @Description Get a paginated list of users, optionally filtered by a search term and by status
 // @Tags Admin
 // @Produce json
 // @Security BearerAuth
+// @Param q query string false "Case-insensitive substring of the username, first or last name, or email"
+// @Param is_active query bool false "Only active (true) or deactivated (false) users"
+// @Param is_admin query bool false "Only admins (true) or regular users (false)"
+// @Param deleted query bool false "List the users scheduled for deletion instead" default(false)
+// @Param sort query string false "Order: created_at (newest first), last_login_at (most recent first) or username; ID order by default" Enums(created_at, last_login_at, username)
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
-// @Success 200 {object} models.PaginatedResponse{data=[]models.UserResponse}
+// @Success 200 {object} models.PaginatedResponse{data=[]models.AdminUserResponse}
+// @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
 // @Failure 403 {object} models.APIResponse
 // @Router /api/admin/users [get]
 func (h *AdminHandler) GetUsers(c *gin.Context) {
 	page, perPage := middleware.GetPaginationParams(c)
 
-	users, pagination, err := h.userService.GetUsers(page, perPage)
+	filter := models.UserFilter{Query: c.Query("q")}
+	for name, flag := range map[string]**bool{"is_active": &filter.IsActive, "is_admin": &filter.IsAdmin} {
+		value, err := optionalBoolQuery(c, name)
+		if err != nil {
+			respond.Error(c, http.StatusBadRequest, name+" must be true or false")
+			return
+		}
+		*flag = value
+	}
+	deleted, err := strconv.ParseBool(c.DefaultQuery("deleted", "false"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve users",
-		})
+		respond.Error(c, http.StatusBadRequest, "deleted must be true or false")
+		return
+	}
+	filter.Deleted = deleted
+
+	users, pagination, err := h.userService.GetUsers(filter, models.UserSort(c.Query("sort")), page, perPage)
+	if err != nil {
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success:    true,
 		Data:       users,
-		Pagination: pagination,
+		Pagination: withPaginationLinks(c, pagination),
 	})
 }
 
+// optionalBoolQuery parses a boolean query parameter, returning nil when it
+// isn't set
+func optionalBoolQuery(c *gin.Context, name string) (*bool, error) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
 // GetUser godoc
 // @Summary Get user by ID (Admin only)
 // @Description Get a specific user by their ID
@@ -62,24 +100,19 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 // @Failure 401 {object} models.APIResponse
 // @Failure 403 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
 // @Router /api/admin/users/{id} [get]
 func (h *AdminHandler) GetUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid user ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
 	user, err := h.userService.GetUserByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error:   "User not found",
-		})
+		respondLookupError(c, err, "user")
 		return
 	}
 
@@ -91,54 +124,92 @@ func (h *AdminHandler) GetUser(c *gin.Context) {
 
 // DeactivateUser godoc
 // @Summary Deactivate user (Admin only)
-// @Description Deactivate a user account
+// @Description Deactivate a user account. With delete_after_days the user is
+// @Description also hidden straight away and permanently deleted once that
+// @Description many days have passed, unless restored first. Their posts and
+// @Description comments then move to the "deleteduser" placeholder account.
 // @Tags Admin
+// @Accept json
 // @Security BearerAuth
 // @Param id path int true "User ID"
+// @Param request body models.UserDeactivateRequest false "Optional deletion schedule"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
 // @Failure 403 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/admin/users/{id}/deactivate [post]
 func (h *AdminHandler) DeactivateUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid user ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
 	// Prevent admin from deactivating themselves
 	currentUserID, _ := middleware.GetUserID(c)
 	if currentUserID == uint(id) {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "You cannot deactivate your own account",
-		})
+		respond.Error(c, http.StatusBadRequest, "You cannot deactivate your own account")
 		return
 	}
 
-	err = h.userService.DeactivateUser(uint(id))
-	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "user not found" {
-			statusCode = http.StatusNotFound
+	var req models.UserDeactivateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respond.Error(c, http.StatusBadRequest, "Invalid request format")
+			return
 		}
+	}
+
+	err = h.userService.DeactivateUser(uint(id), &req)
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	message := "User deactivated successfully"
+	if req.DeleteAfterDays > 0 {
+		message = fmt.Sprintf("User deactivated and scheduled for deletion in %d day(s)", req.DeleteAfterDays)
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+	})
+}
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+// RestoreUser godoc
+// @Summary Restore user (Admin only)
+// @Description Cancel the scheduled deletion of a user. The account stays deactivated until activated.
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/admin/users/{id}/restore [post]
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.userService.RestoreUser(uint(id)); err != nil {
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "User deactivated successfully",
+		Message: "User restored successfully",
 	})
 }
 
@@ -158,24 +229,15 @@ func (h *AdminHandler) ActivateUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid user ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
 	err = h.userService.ActivateUser(uint(id))
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "user not found" {
-			statusCode = http.StatusNotFound
-		}
+		statusCode := errorStatus(err, http.StatusBadRequest)
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
 
@@ -185,6 +247,73 @@ func (h *AdminHandler) ActivateUser(c *gin.Context) {
 	})
 }
 
+// DeleteUser godoc
+// @Summary Delete user (Admin only)
+// @Description Delete a user account. With cascade=true the user's posts and
+// @Description comments, and the comments on their posts, are deleted too;
+// @Description with reassign=true they move to a placeholder "deleteduser"
+// @Description account. A user with posts or comments and neither flag gets a
+// @Description 409 with the counts. Admins can't delete themselves or the last admin.
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param cascade query bool false "Delete the user's posts and comments" default(false)
+// @Param reassign query bool false "Move the user's posts and comments to the placeholder account" default(false)
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse{data=models.UserHasContentResponse}
+// @Router /api/admin/users/{id} [delete]
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	cascade, err := strconv.ParseBool(c.DefaultQuery("cascade", "false"))
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "cascade must be true or false")
+		return
+	}
+	reassign, err := strconv.ParseBool(c.DefaultQuery("reassign", "false"))
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "reassign must be true or false")
+		return
+	}
+
+	// Prevent admin from deleting themselves
+	currentUserID, _ := middleware.GetUserID(c)
+	if currentUserID == uint(id) {
+		respond.Error(c, http.StatusBadRequest, "You cannot delete your own account")
+		return
+	}
+
+	err = h.userService.DeleteUser(uint(id), cascade, reassign)
+	if err != nil {
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		var data interface{}
+		var hasContent *service.UserHasContentError
+		if errors.As(err, &hasContent) {
+			data = models.UserHasContentResponse{
+				PostsCount:    hasContent.PostsCount,
+				CommentsCount: hasContent.CommentsCount,
+			}
+		}
+		respond.ErrorWithData(c, statusCode, err.Error(), data)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "User deleted successfully",
+	})
+}
+
 // GetUserStats godoc
 // @Summary Get user statistics (Admin only)
 // @Description Get comprehensive statistics about users
@@ -197,12 +326,9 @@ func (h *AdminHandler) ActivateUser(c *gin.Context) {
 // @Router /api/admin/users/stats [get]
 func (h *AdminHandler) GetUserStats(c *gin.Context) {
 	// Get all users to calculate statistics
-	users, _, err := h.userService.GetUsers(1, 10000) // Get all users
+	users, _, err := h.userService.GetUsers(models.UserFilter{}, "", 1, 10000) // Get all users
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve user statistics",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve user statistics")
 		return
 	}
 
@@ -252,12 +378,9 @@ func (h *AdminHandler) GetDashboardStats(c *gin.Context) {
 	// In a full implementation, you'd also get post, comment, and tag statistics
 
 	// Get user statistics
-	users, _, err := h.userService.GetUsers(1, 10000)
+	users, _, err := h.userService.GetUsers(models.UserFilter{}, "", 1, 10000)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve dashboard statistics",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve dashboard statistics")
 		return
 	}
 
@@ -291,3 +414,23 @@ func (h *AdminHandler) GetDashboardStats(c *gin.Context) {
 		Message: "Dashboard statistics retrieved successfully",
 	})
 }
+
+// UserCacheTag tags the cached responses of posts by the user with id,
+// which show their profile
+func UserCacheTag(id uint) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+// UserCacheTags lists the cached responses a user write invalidates: the
+// published listings and the posts by the user, whose author details
+// change. The user is the one in the path or, on routes without one, the
+// signed-in user.
+func UserCacheTags(c *gin.Context) []string {
+	tags := []string{PublishedPostsCacheTag}
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 32); err == nil {
+		tags = append(tags, UserCacheTag(uint(id)))
+	} else if userID, ok := middleware.GetUserID(c); ok {
+		tags = append(tags, UserCacheTag(userID))
+	}
+	return tags
+}
@@ -2,26 +2,34 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
 )
 
 type AuthHandler struct {
-	userService service.UserService
+	userService  service.UserService
+	tokenService service.TokenService
+	config       *config.Config
 }
 
-func NewAuthHandler(userService service.UserService) *AuthHandler {
+func NewAuthHandler(userService service.UserService, tokenService service.TokenService, config *config.Config) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
+		userService:  userService,
+		tokenService: tokenService,
+		config:       config,
 	}
 }
 
 // Register godoc
 // @Summary Register a new user
-// @Description Register a new user account
+// @Description Register a new user account. The Location header points at
+// @Description the profile, readable once the user has logged in.
 // @Tags Authentication
 // @Accept json
 // @Produce json
@@ -29,31 +37,22 @@ func NewAuthHandler(userService service.UserService) *AuthHandler {
 // @Success 201 {object} models.APIResponse{data=models.UserResponse}
 // @Failure 400 {object} models.APIResponse
 // @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.UserCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
 	user, err := h.userService.Register(&req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "email is already registered" || err.Error() == "username is already taken" {
-			statusCode = http.StatusConflict
-		}
-
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
+	setLocation(c, "/auth/profile")
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
 		Message: "User registered successfully",
@@ -71,31 +70,23 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Success 200 {object} models.APIResponse{data=models.AuthResponse}
 // @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.UserLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
 	authResponse, err := h.userService.Login(&req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "invalid credentials" || err.Error() == "account is deactivated" {
-			statusCode = http.StatusUnauthorized
-		}
-
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
+	h.setAuthCookie(c, authResponse)
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Login successful",
@@ -111,23 +102,18 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @Security BearerAuth
 // @Success 200 {object} models.APIResponse{data=models.UserResponse}
 // @Failure 401 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
 // @Router /api/auth/profile [get]
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	user, err := h.userService.GetProfile(userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error:   "User not found",
-		})
+		respondLookupError(c, err, "user")
 		return
 	}
 
@@ -149,37 +135,24 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 // @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
 // @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/auth/profile [put]
 func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	var req models.UserUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
 	user, err := h.userService.UpdateProfile(userID, &req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "email is already registered" || err.Error() == "username is already taken" {
-			statusCode = http.StatusConflict
-		}
-
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -201,41 +174,28 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/auth/change-password [post]
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	var req struct {
 		OldPassword string `json:"old_password" binding:"required"`
-		NewPassword string `json:"new_password" binding:"required,min=8"`
+		NewPassword string `json:"new_password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
 	err := h.userService.ChangePassword(userID, req.OldPassword, req.NewPassword)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "invalid current password" {
-			statusCode = http.StatusUnauthorized
-		}
-
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -245,42 +205,227 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	})
 }
 
+// ChangeEmail godoc
+// @Summary Request an email change
+// @Description Send a confirmation link to a new email address. The current email stays in use until the link is followed.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.EmailChangeRequest true "New email and current password"
+// @Success 202 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/auth/change-email [post]
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.EmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	user, err := h.userService.RequestEmailChange(userID, &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.APIResponse{
+		Success: true,
+		Message: "Confirmation link sent to the new email address",
+		Data:    user,
+	})
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm an email change
+// @Description Make the pending email from a confirmation link the account's email
+// @Tags Authentication
+// @Produce json
+// @Param token query string true "Token from the confirmation link"
+// @Success 200 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /api/auth/confirm-email-change [get]
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		respond.Error(c, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	user, err := h.userService.ConfirmEmailChange(token)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Email changed successfully",
+		Data:    user,
+	})
+}
+
 // RefreshToken godoc
 // @Summary Refresh JWT token
-// @Description Refresh an existing JWT token
+// @Description Exchange the refresh token issued at login for a new access
+// @Description token. Access tokens are rejected.
 // @Tags Authentication
 // @Accept json
 // @Produce json
-// @Param token body object{token=string} true "Token to refresh"
+// @Param token body object{token=string} true "Refresh token"
 // @Success 200 {object} models.APIResponse{data=models.AuthResponse}
 // @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
 // @Router /api/auth/refresh [post]
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req struct {
-		Token string `json:"token" binding:"required"`
+		Token string `json:"token"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+	// The auth cookie holds the access token, which can't be refreshed, so
+	// the refresh token always comes in the body
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	// A token revoked by logout can't be exchanged for a fresh one
+	if err := h.tokenService.CheckToken(req.Token); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	authResponse, err := h.userService.RefreshToken(req.Token)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respond.Error(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
+	h.setAuthCookie(c, authResponse)
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Token refreshed successfully",
 		Data:    authResponse,
 	})
 }
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke the access token the request is authenticated with, so
+// @Description it's rejected from then on even though it hasn't expired, and
+// @Description clear the auth cookie set in cookie mode. A refresh_token sent
+// @Description in the body is revoked too.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.LogoutRequest false "Refresh token to revoke"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Router /api/auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respond.Error(c, http.StatusBadRequest, "Invalid request format")
+			return
+		}
+	}
+
+	// An invalid refresh token fails the logout before anything is revoked
+	if req.RefreshToken != "" {
+		if err := h.tokenService.RevokeToken(req.RefreshToken); err != nil {
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+	}
+	if claims, ok := middleware.GetTokenClaims(c); ok {
+		if err := h.tokenService.Revoke(claims); err != nil {
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if h.config.Cookie.Enabled {
+		c.SetSameSite(h.config.Cookie.SameSite)
+		c.SetCookie(h.config.Cookie.Name, "", -1, "/", h.config.Cookie.Domain, h.config.Cookie.Secure, true)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Logged out successfully",
+	})
+}
+
+// setAuthCookie stores the access token in an httpOnly cookie when cookie
+// mode is enabled, so browser clients never need to touch it from JS
+func (h *AuthHandler) setAuthCookie(c *gin.Context, authResponse *models.AuthResponse) {
+	if !h.config.Cookie.Enabled {
+		return
+	}
+
+	c.SetSameSite(h.config.Cookie.SameSite)
+	c.SetCookie(h.config.Cookie.Name, authResponse.Token, authResponse.ExpiresIn, "/", h.config.Cookie.Domain, h.config.Cookie.Secure, true)
+}
+
+// CheckUsername godoc
+// @Summary Check username availability
+// @Description Report whether a username is still available for registration
+// @Tags Authentication
+// @Produce json
+// @Param username query string true "Username to check"
+// @Success 200 {object} models.APIResponse{data=models.AvailabilityResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 429 {object} models.APIResponse
+// @Router /api/auth/check-username [get]
+func (h *AuthHandler) CheckUsername(c *gin.Context) {
+	username := strings.TrimSpace(c.Query("username"))
+	if username == "" {
+		respond.Error(c, http.StatusBadRequest, "Username is required")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: models.AvailabilityResponse{
+			Available: h.userService.IsUsernameAvailable(username),
+		},
+	})
+}
+
+// CheckEmail godoc
+// @Summary Check email availability
+// @Description Report whether an email is still available for registration
+// @Tags Authentication
+// @Produce json
+// @Param email query string true "Email to check"
+// @Success 200 {object} models.APIResponse{data=models.AvailabilityResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 429 {object} models.APIResponse
+// @Router /api/auth/check-email [get]
+func (h *AuthHandler) CheckEmail(c *gin.Context) {
+	email := strings.TrimSpace(c.Query("email"))
+	if email == "" {
+		respond.Error(c, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: models.AvailabilityResponse{
+			Available: h.userService.IsEmailAvailable(email),
+		},
+	})
+}
@@ -5,11 +5,19 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/handlers"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -39,9 +47,9 @@ func (m *MockUserService) UpdateProfile(userID uint, req *models.UserUpdateReque
 	return args.Get(0).(*models.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) GetUsers(page, perPage int) ([]models.UserResponse, models.PaginationMeta, error) {
-	args := m.Called(page, perPage)
-	return args.Get(0).([]models.UserResponse), args.Get(1).(models.PaginationMeta), args.Error(2)
+func (m *MockUserService) GetUsers(filter models.UserFilter, sort models.UserSort, page, perPage int) ([]models.AdminUserResponse, models.PaginationMeta, error) {
+	args := m.Called(filter, sort, page, perPage)
+	return args.Get(0).([]models.AdminUserResponse), args.Get(1).(models.PaginationMeta), args.Error(2)
 }
 
 func (m *MockUserService) GetUserByID(id uint) (*models.UserResponse, error) {
@@ -49,7 +57,12 @@ func (m *MockUserService) GetUserByID(id uint) (*models.UserResponse, error) {
 	return args.Get(0).(*models.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) DeactivateUser(id uint) error {
+func (m *MockUserService) DeactivateUser(id uint, req *models.UserDeactivateRequest) error {
+	args := m.Called(id, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) RestoreUser(id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
@@ -59,16 +72,55 @@ func (m *MockUserService) ActivateUser(id uint) error {
 	return args.Error(0)
 }
 
+func (m *MockUserService) DeleteUser(id uint, cascade, reassign bool) error {
+	args := m.Called(id, cascade, reassign)
+	return args.Error(0)
+}
+
+func (m *MockUserService) PurgeDeletedUsers() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockUserService) ChangePassword(userID uint, oldPassword, newPassword string) error {
 	args := m.Called(userID, oldPassword, newPassword)
 	return args.Error(0)
 }
 
+func (m *MockUserService) RequestEmailChange(userID uint, req *models.EmailChangeRequest) (*models.UserResponse, error) {
+	args := m.Called(userID, req)
+	return args.Get(0).(*models.UserResponse), args.Error(1)
+}
+
+func (m *MockUserService) ConfirmEmailChange(token string) (*models.UserResponse, error) {
+	args := m.Called(token)
+	return args.Get(0).(*models.UserResponse), args.Error(1)
+}
+
 func (m *MockUserService) RefreshToken(token string) (*models.AuthResponse, error) {
 	args := m.Called(token)
 	return args.Get(0).(*models.AuthResponse), args.Error(1)
 }
 
+func (m *MockUserService) IsUsernameAvailable(username string) bool {
+	args := m.Called(username)
+	return args.Bool(0)
+}
+
+func (m *MockUserService) IsEmailAvailable(email string) bool {
+	args := m.Called(email)
+	return args.Bool(0)
+}
+
+// newTestAuthHandler wires an auth handler to userService, revoking tokens
+// in an in-memory database
+func newTestAuthHandler(t *testing.T, userService service.UserService, cfg *config.Config) *handlers.AuthHandler {
+	t.Helper()
+
+	tokens := service.NewTokenService(repository.NewTokenRepository(testutil.NewTestDB(t)), cfg)
+	return handlers.NewAuthHandler(userService, tokens, cfg)
+}
+
 func TestAuthHandler_Register(t *testing.T) {
 	// Skip integration tests in short mode
 	if testing.Short() {
@@ -80,7 +132,7 @@ func TestAuthHandler_Register(t *testing.T) {
 	t.Run("successful registration", func(t *testing.T) {
 		// Create mock service
 		mockService := new(MockUserService)
-		handler := handlers.NewAuthHandler(mockService)
+		handler := newTestAuthHandler(t, mockService, &config.Config{})
 
 		// Create test request
 		userReq := &models.UserCreateRequest{
@@ -117,13 +169,14 @@ func TestAuthHandler_Register(t *testing.T) {
 
 		// Assertions
 		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "/api/auth/profile", w.Header().Get("Location"))
 		mockService.AssertExpectations(t)
 	})
 
 	t.Run("invalid request format", func(t *testing.T) {
 		// Create mock service
 		mockService := new(MockUserService)
-		handler := handlers.NewAuthHandler(mockService)
+		handler := newTestAuthHandler(t, mockService, &config.Config{})
 
 		// Create invalid JSON request
 		invalidJSON := []byte(`{"invalid": json}`)
@@ -156,7 +209,7 @@ func TestAuthHandler_Login(t *testing.T) {
 	t.Run("successful login", func(t *testing.T) {
 		// Create mock service
 		mockService := new(MockUserService)
-		handler := handlers.NewAuthHandler(mockService)
+		handler := newTestAuthHandler(t, mockService, &config.Config{})
 
 		// Create test request
 		loginReq := &models.UserLoginRequest{
@@ -198,3 +251,162 @@ func TestAuthHandler_Login(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 }
+
+func TestAuthHandler_CheckEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("reports availability only", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := newTestAuthHandler(t, mockService, &config.Config{})
+		mockService.On("IsEmailAvailable", "John@Example.com").Return(false)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/v1/auth/check-email?email=John@Example.com", nil)
+
+		handler.CheckEmail(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"success":true,"data":{"available":false}}`, w.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing email", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := newTestAuthHandler(t, mockService, &config.Config{})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/v1/auth/check-email", nil)
+
+		handler.CheckEmail(c)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "IsEmailAvailable", mock.Anything)
+	})
+}
+
+func TestAuthHandler_CookieMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cookieConfig := &config.Config{
+		Cookie: config.CookieConfig{
+			Enabled:  true,
+			Name:     "access_token",
+			Domain:   "blog.example.com",
+			Secure:   true,
+			SameSite: http.SameSiteStrictMode,
+		},
+	}
+	authResponse := &models.AuthResponse{
+		Token:     "test.jwt.token",
+		TokenType: "Bearer",
+		ExpiresIn: 3600,
+	}
+
+	login := func(cfg *config.Config) *httptest.ResponseRecorder {
+		mockService := new(MockUserService)
+		handler := newTestAuthHandler(t, mockService, cfg)
+		mockService.On("Login", mock.AnythingOfType("*models.UserLoginRequest")).Return(authResponse, nil)
+
+		body := []byte(`{"email_or_username":"johndoe","password":"password123"}`)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Login(c)
+		return w
+	}
+
+	t.Run("login sets an httpOnly cookie", func(t *testing.T) {
+		w := login(cookieConfig)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.Equal(t, "access_token", cookies[0].Name)
+		require.Equal(t, "test.jwt.token", cookies[0].Value)
+		require.Equal(t, "blog.example.com", cookies[0].Domain)
+		require.Equal(t, 3600, cookies[0].MaxAge)
+		require.True(t, cookies[0].HttpOnly)
+		require.True(t, cookies[0].Secure)
+		require.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	})
+
+	t.Run("header mode sets no cookie", func(t *testing.T) {
+		w := login(&config.Config{})
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Empty(t, w.Result().Cookies())
+	})
+
+	t.Run("logout clears the cookie", func(t *testing.T) {
+		handler := newTestAuthHandler(t, new(MockUserService), cookieConfig)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/v1/auth/logout", nil)
+
+		handler.Logout(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.Equal(t, "access_token", cookies[0].Name)
+		require.Empty(t, cookies[0].Value)
+		require.Negative(t, cookies[0].MaxAge)
+	})
+}
+
+func TestAuthHandler_Logout_RevokesTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour}}
+	tokens := service.NewTokenService(repository.NewTokenRepository(testutil.NewTestDB(t)), cfg)
+	userService := new(MockUserService)
+	handler := handlers.NewAuthHandler(userService, tokens, cfg)
+
+	router := gin.New()
+	router.GET("/me", middleware.AuthMiddleware(cfg, tokens), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.POST("/logout", middleware.OptionalAuthMiddleware(cfg, tokens), handler.Logout)
+	router.POST("/refresh", handler.RefreshToken)
+
+	user := &models.User{ID: 4, Email: "leaver@example.com", Username: "leaver"}
+	access, err := utils.GenerateToken(user, cfg)
+	require.NoError(t, err)
+	refresh, err := utils.GenerateRefreshToken(user, cfg, 24*time.Hour)
+	require.NoError(t, err)
+	other, err := utils.GenerateToken(user, cfg)
+	require.NoError(t, err)
+
+	send := func(method, path, token, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, send("GET", "/me", access, ""))
+	require.Equal(t, http.StatusOK, send("POST", "/logout", access, `{"refresh_token":"`+refresh+`"}`))
+
+	// The token and its refresh token stop working before they expire
+	require.Equal(t, http.StatusUnauthorized, send("GET", "/me", access, ""))
+	require.Equal(t, http.StatusUnauthorized, send("POST", "/refresh", "", `{"token":"`+access+`"}`))
+	require.Equal(t, http.StatusUnauthorized, send("POST", "/refresh", "", `{"token":"`+refresh+`"}`))
+	userService.AssertNotCalled(t, "RefreshToken", mock.Anything)
+
+	// Other sessions of the same user carry on
+	require.Equal(t, http.StatusOK, send("GET", "/me", other, ""))
+
+	// Logging out twice, or without a token, is harmless
+	require.Equal(t, http.StatusOK, send("POST", "/logout", access, ""))
+	require.Equal(t, http.StatusOK, send("POST", "/logout", "", ""))
+	require.Equal(t, http.StatusBadRequest, send("POST", "/logout", other, `{"refresh_token":"not-a-token"}`))
+	require.Equal(t, http.StatusOK, send("GET", "/me", other, ""))
+}
@@ -3,10 +3,12 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
 )
 
@@ -22,82 +24,83 @@ func NewCommentHandler(commentService service.CommentService) *CommentHandler {
 
 // CreateComment godoc
 // @Summary Create a new comment
-// @Description Create a new comment or reply to an existing comment
+// @Description Create a new comment or reply to an existing comment. Without
+// @Description authentication the comment is posted as a guest and guest_name
+// @Description and guest_email are required. The Location header points at
+// @Description the post's comments.
 // @Tags Comments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param comment body models.CommentCreateRequest true "Comment data"
+// @Param Idempotency-Key header string false "Key making retries of this request return the original response"
 // @Success 201 {object} models.APIResponse{data=models.CommentResponse}
 // @Failure 400 {object} models.APIResponse
-// @Failure 401 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Failure 429 {object} models.APIResponse
 // @Router /api/comments [post]
 func (h *CommentHandler) CreateComment(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
-		return
-	}
-
 	var req models.CommentCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
-	comment, err := h.commentService.Create(userID, &req)
+	var comment *models.CommentResponse
+	var err error
+	if userID, exists := middleware.GetUserID(c); exists {
+		comment, err = h.commentService.Create(userID, &req)
+	} else {
+		comment, err = h.commentService.CreateGuest(&req)
+	}
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "post not found" || err.Error() == "parent comment not found" {
-			statusCode = http.StatusNotFound
-		}
-
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
+	c.Set(commentPostKey, comment.PostID)
+
+	message := "Comment created successfully"
+	if comment.Status == models.CommentStatusPending {
+		message = "Comment created successfully (pending approval)"
+	}
 
+	// Comments have no public address of their own, so point at the thread
+	setLocation(c, "/comments/post/%d", comment.PostID)
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
-		Message: "Comment created successfully (pending approval)",
+		Message: message,
 		Data:    comment,
 	})
 }
 
 // GetComment godoc
-// @Summary Get a comment by ID
-// @Description Get a specific comment by its ID
-// @Tags Comments
+// @Summary Get a comment by ID (Admin only)
+// @Description Get a specific comment by its ID, whatever its moderation status,
+// @Description with its first 10 approved replies. replies_count and has_more
+// @Description tell whether the rest have to be read from /replies.
+// @Tags Admin
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Comment ID"
 // @Success 200 {object} models.APIResponse{data=models.CommentResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
-// @Router /api/comments/{id} [get]
+// @Failure 500 {object} models.APIResponse
+// @Router /api/admin/comments/{id} [get]
 func (h *CommentHandler) GetComment(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid comment ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid comment ID")
 		return
 	}
 
-	comment, err := h.commentService.GetByID(uint(id))
+	comment, err := h.commentService.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error:   "Comment not found",
-		})
+		respondLookupError(c, err, "comment")
 		return
 	}
 
@@ -107,9 +110,50 @@ func (h *CommentHandler) GetComment(c *gin.Context) {
 	})
 }
 
+// GetCommentReplies godoc
+// @Summary Get a comment's replies (Admin only)
+// @Description Get paginated list of the approved direct replies to a comment, oldest first
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Comment ID"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.CommentResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/admin/comments/{id}/replies [get]
+func (h *CommentHandler) GetCommentReplies(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	page, perPage := middleware.GetPaginationParams(c)
+
+	replies, pagination, err := h.commentService.GetReplies(c.Request.Context(), uint(id), page, perPage)
+	if err != nil {
+		respondLookupError(c, err, "comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       replies,
+		Pagination: withPaginationLinks(c, pagination),
+	})
+}
+
 // UpdateComment godoc
 // @Summary Update a comment
-// @Description Update an existing comment
+// @Description Update an existing comment. Authors can only edit their
+// @Description comments for a configured window after posting (15 minutes by
+// @Description default); admins can always edit. Changing the content sets
+// @Description edited_at.
 // @Tags Comments
 // @Accept json
 // @Produce json
@@ -121,52 +165,35 @@ func (h *CommentHandler) GetComment(c *gin.Context) {
 // @Failure 401 {object} models.APIResponse
 // @Failure 403 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/comments/{id} [put]
 func (h *CommentHandler) UpdateComment(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid comment ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid comment ID")
 		return
 	}
 
 	var req models.CommentUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
 	isAdmin := middleware.IsAdmin(c)
 	comment, err := h.commentService.Update(uint(id), userID, &req, isAdmin)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "unauthorized: you can only update your own comments" {
-			statusCode = http.StatusForbidden
-		} else if err.Error() == "comment not found" {
-			statusCode = http.StatusNotFound
-		}
-
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
+	c.Set(commentPostKey, comment.PostID)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -190,39 +217,26 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 func (h *CommentHandler) DeleteComment(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid comment ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid comment ID")
 		return
 	}
 
 	isAdmin := middleware.IsAdmin(c)
-	err = h.commentService.Delete(uint(id), userID, isAdmin)
+	postID, err := h.commentService.Delete(uint(id), userID, isAdmin)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "unauthorized: you can only delete your own comments" {
-			statusCode = http.StatusForbidden
-		} else if err.Error() == "comment not found" {
-			statusCode = http.StatusNotFound
-		}
+		statusCode := errorStatus(err, http.StatusBadRequest)
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
+	c.Set(commentPostKey, postID)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -230,6 +244,55 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 	})
 }
 
+// ReportComment godoc
+// @Summary Report a comment
+// @Description Flag an approved comment as abusive. Each user can report a comment once; a comment reported often enough goes back to pending for re-moderation.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Comment ID"
+// @Param report body models.CommentReportRequest true "Report reason"
+// @Success 201 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/comments/{id}/report [post]
+func (h *CommentHandler) ReportComment(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	var req models.CommentReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	postID, err := h.commentService.ReportComment(uint(id), userID, &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	c.Set(commentPostKey, postID)
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Comment reported successfully",
+	})
+}
+
 // GetCommentsByPost godoc
 // @Summary Get comments for a post
 // @Description Get paginated comments for a specific post
@@ -241,38 +304,29 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 // @Success 200 {object} models.PaginatedResponse{data=[]models.CommentResponse}
 // @Failure 400 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
-// @Router /api/posts/{post_id}/comments [get]
+// @Router /api/comments/post/{post_id} [get]
 func (h *CommentHandler) GetCommentsByPost(c *gin.Context) {
 	postIDStr := c.Param("post_id")
 	postID, err := strconv.ParseUint(postIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid post ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
 	page, perPage := middleware.GetPaginationParams(c)
 
-	comments, pagination, err := h.commentService.GetByPost(uint(postID), page, perPage)
+	comments, pagination, err := h.commentService.GetByPost(c.Request.Context(), uint(postID), page, perPage)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "post not found" {
-			statusCode = http.StatusNotFound
-		}
+		statusCode := errorStatus(err, http.StatusInternalServerError)
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success:    true,
 		Data:       comments,
-		Pagination: pagination,
+		Pagination: withPaginationLinks(c, pagination),
 	})
 }
 
@@ -284,65 +338,163 @@ func (h *CommentHandler) GetCommentsByPost(c *gin.Context) {
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
+// @Param status query string false "Filter by status" Enums(all, pending, approved, rejected) default(all)
 // @Success 200 {object} models.PaginatedResponse{data=[]models.CommentResponse}
+// @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
 // @Router /api/comments/my-comments [get]
 func (h *CommentHandler) GetCommentsByAuthor(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	h.listAuthorComments(c, userID)
+}
+
+// GetUserComments godoc
+// @Summary Get a user's comments (Admin only)
+// @Description Get paginated comments by any user, in every status unless
+// @Description filtered, each with the title and slug of its post
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Param status query string false "Filter by status" Enums(all, pending, approved, rejected) default(all)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.CommentResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Router /api/admin/users/{id}/comments [get]
+func (h *CommentHandler) GetUserComments(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	h.listAuthorComments(c, uint(id))
+}
+
+// listAuthorComments responds with a page of authorID's comments, filtered by
+// the status query parameter
+func (h *CommentHandler) listAuthorComments(c *gin.Context, authorID uint) {
+	var status models.CommentStatus
+	switch statusStr := c.DefaultQuery("status", "all"); statusStr {
+	case "all":
+	case string(models.CommentStatusPending), string(models.CommentStatusApproved), string(models.CommentStatusRejected):
+		status = models.CommentStatus(statusStr)
+	default:
+		respond.Error(c, http.StatusBadRequest, "Invalid status, expected one of all, pending, approved or rejected")
 		return
 	}
 
 	page, perPage := middleware.GetPaginationParams(c)
 
-	comments, pagination, err := h.commentService.GetByAuthor(userID, page, perPage)
+	comments, pagination, err := h.commentService.GetByAuthor(c.Request.Context(), authorID, status, page, perPage)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve comments",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve comments")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success:    true,
 		Data:       comments,
-		Pagination: pagination,
+		Pagination: withPaginationLinks(c, pagination),
 	})
 }
 
 // GetPendingComments godoc
 // @Summary Get pending comments (Admin only)
-// @Description Get paginated list of comments pending approval
+// @Description Get paginated list of comments pending approval, oldest first, optionally only those on one post or created within a window
 // @Tags Comments
 // @Produce json
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
+// @Param post_id query int false "Only comments on this post"
+// @Param from query string false "Only comments created at or after this RFC3339 timestamp"
+// @Param to query string false "Only comments created at or before this RFC3339 timestamp"
 // @Success 200 {object} models.PaginatedResponse{data=[]models.CommentResponse}
+// @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
 // @Failure 403 {object} models.APIResponse
 // @Router /api/admin/comments/pending [get]
 func (h *CommentHandler) GetPendingComments(c *gin.Context) {
 	page, perPage := middleware.GetPaginationParams(c)
 
-	comments, pagination, err := h.commentService.GetPending(page, perPage)
+	var filter models.PendingCommentFilter
+	if postIDStr := c.Query("post_id"); postIDStr != "" {
+		postID, err := strconv.ParseUint(postIDStr, 10, 32)
+		if err != nil {
+			respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+			return
+		}
+		filter.PostID = uint(postID)
+	}
+	for name, bound := range map[string]**time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respond.Error(c, http.StatusBadRequest, name+" must be an RFC3339 timestamp")
+			return
+		}
+		*bound = &at
+	}
+
+	comments, pagination, err := h.commentService.GetPending(filter, page, perPage)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve pending comments",
-		})
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+		if statusCode == http.StatusInternalServerError {
+			respond.Error(c, statusCode, "Failed to retrieve pending comments")
+			return
+		}
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success:    true,
 		Data:       comments,
-		Pagination: pagination,
+		Pagination: withPaginationLinks(c, pagination),
+	})
+}
+
+// GetReportedComments godoc
+// @Summary Get reported comments (Admin only)
+// @Description Get paginated list of comments with unresolved reports, most reported first, with each report's reason. Approving or rejecting a comment resolves its reports.
+// @Tags Comments
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.ReportedCommentResponse}
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Router /api/admin/comments/reported [get]
+func (h *CommentHandler) GetReportedComments(c *gin.Context) {
+	page, perPage := middleware.GetPaginationParams(c)
+
+	comments, pagination, err := h.commentService.GetReported(page, perPage)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve reported comments")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       comments,
+		Pagination: withPaginationLinks(c, pagination),
 	})
 }
 
@@ -362,26 +514,18 @@ func (h *CommentHandler) ApproveComment(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid comment ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid comment ID")
 		return
 	}
 
 	comment, err := h.commentService.ApproveComment(uint(id))
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "comment not found" {
-			statusCode = http.StatusNotFound
-		}
+		statusCode := errorStatus(err, http.StatusBadRequest)
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
+	c.Set(commentPostKey, comment.PostID)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -406,26 +550,18 @@ func (h *CommentHandler) RejectComment(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid comment ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid comment ID")
 		return
 	}
 
 	comment, err := h.commentService.RejectComment(uint(id))
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "comment not found" {
-			statusCode = http.StatusNotFound
-		}
+		statusCode := errorStatus(err, http.StatusBadRequest)
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
+	c.Set(commentPostKey, comment.PostID)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -434,6 +570,46 @@ func (h *CommentHandler) RejectComment(c *gin.Context) {
 	})
 }
 
+// ApproveAllForPost godoc
+// @Summary Approve all pending comments on a post (Admin only)
+// @Description Approve every pending comment on a post at once, resolving any
+// @Description reports on them, and return how many were approved
+// @Tags Comments
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Success 200 {object} models.APIResponse{data=object{approved=int}}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /api/admin/posts/{id}/comments/approve-all [post]
+func (h *CommentHandler) ApproveAllForPost(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	approved, err := h.commentService.ApproveAllForPost(uint(id))
+	if err != nil {
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		respond.Error(c, statusCode, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Pending comments approved successfully",
+		Data: map[string]interface{}{
+			"approved": approved,
+		},
+	})
+}
+
 // GetPendingCount godoc
 // @Summary Get pending comments count (Admin only)
 // @Description Get the total number of comments pending approval
@@ -447,10 +623,7 @@ func (h *CommentHandler) RejectComment(c *gin.Context) {
 func (h *CommentHandler) GetPendingCount(c *gin.Context) {
 	count, err := h.commentService.GetPendingCount()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to get pending comments count",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to get pending comments count")
 		return
 	}
 
@@ -461,3 +634,18 @@ func (h *CommentHandler) GetPendingCount(c *gin.Context) {
 		},
 	})
 }
+
+// commentPostKey is the context key under which comment writes record the
+// post of the comment, for CommentCacheTags
+const commentPostKey = "comment_post_id"
+
+// CommentCacheTags lists the cached responses a comment write invalidates:
+// the published listings, whose comment counts change, and the responses of
+// the post the comment is on
+func CommentCacheTags(c *gin.Context) []string {
+	tags := []string{PublishedPostsCacheTag}
+	if postID := c.GetUint(commentPostKey); postID != 0 {
+		tags = append(tags, PostCacheTag(postID))
+	}
+	return tags
+}
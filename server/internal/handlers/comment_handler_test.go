@@ -0,0 +1,161 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/handlers"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentHandler_CreateComment_Location(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "locator")
+	now := time.Now()
+	post := &models.Post{Title: "Commented", Slug: "commented", Content: "Reply here", Status: models.PostStatusPublished, AuthorID: author.ID, PublishedAt: &now}
+	require.NoError(t, db.Create(post).Error)
+
+	commentService := service.NewCommentService(
+		repository.NewCommentRepository(db),
+		repository.NewPostRepository(db),
+		webhook.NewDispatcher(config.WebhookConfig{}),
+		config.CommentConfig{RequireApproval: true},
+	)
+	handler := handlers.NewCommentHandler(commentService)
+
+	r := gin.New()
+	signedIn := func(c *gin.Context) { c.Set("user_id", author.ID) }
+	r.POST("/api/v1/comments", signedIn, handler.CreateComment)
+	r.GET("/api/v1/comments/post/:post_id", middleware.PaginationMiddleware(config.PaginationConfig{DefaultPerPage: 10, MaxPerPage: 100}), handler.GetCommentsByPost)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/comments", strings.NewReader(fmt.Sprintf(`{"content":"Found it","post_id":%d}`, post.ID)))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	location := w.Header().Get("Location")
+	assert.Equal(t, fmt.Sprintf("/api/v1/comments/post/%d", post.ID), location)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+	assert.Equal(t, http.StatusOK, w.Code, "the Location can be read anonymously")
+}
+
+func TestCommentHandler_GetUserComments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "troubled")
+	admin := testutil.CreateUser(t, db, "moderator")
+	now := time.Now()
+	post := &models.Post{Title: "Heated thread", Slug: "heated-thread", Content: "Discuss politely", Status: models.PostStatusPublished, AuthorID: admin.ID, PublishedAt: &now}
+	require.NoError(t, db.Create(post).Error)
+
+	for _, status := range []models.CommentStatus{models.CommentStatusPending, models.CommentStatusRejected, models.CommentStatusApproved} {
+		require.NoError(t, db.Create(&models.Comment{Content: "A " + string(status) + " comment", Status: status, AuthorID: &author.ID, PostID: post.ID}).Error)
+	}
+
+	commentService := service.NewCommentService(
+		repository.NewCommentRepository(db),
+		repository.NewPostRepository(db),
+		webhook.NewDispatcher(config.WebhookConfig{}),
+		config.CommentConfig{RequireApproval: true},
+	)
+	handler := handlers.NewCommentHandler(commentService)
+
+	list := func(query string) (int, []models.CommentResponse) {
+		c, w := newPostTestContext(http.MethodGet, "/api/v1/admin/users/"+fmt.Sprint(author.ID)+"/comments"+query, nil)
+		c.Params = gin.Params{{Key: "id", Value: fmt.Sprint(author.ID)}}
+		c.Set("user_id", admin.ID)
+		c.Set("is_admin", true)
+		handler.GetUserComments(c)
+
+		var body struct {
+			Data []models.CommentResponse `json:"data"`
+		}
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		}
+		return w.Code, body.Data
+	}
+
+	t.Run("every status with post context", func(t *testing.T) {
+		code, comments := list("")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, comments, 3)
+
+		statuses := make([]models.CommentStatus, 0, len(comments))
+		for _, comment := range comments {
+			statuses = append(statuses, comment.Status)
+			assert.Equal(t, "Heated thread", comment.PostTitle)
+			assert.Equal(t, "heated-thread", comment.PostSlug)
+		}
+		assert.ElementsMatch(t, []models.CommentStatus{models.CommentStatusPending, models.CommentStatusRejected, models.CommentStatusApproved}, statuses)
+	})
+
+	t.Run("filtered by status", func(t *testing.T) {
+		code, comments := list("?status=rejected")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, comments, 1)
+		assert.Equal(t, models.CommentStatusRejected, comments[0].Status)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		code, _ := list("?status=spam")
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestCommentHandler_CreateComment_PasswordChangeRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "rotating")
+	author.MustChangePassword = true
+	now := time.Now()
+	post := &models.Post{Title: "Locked out", Slug: "locked-out", Content: "Change first", Status: models.PostStatusPublished, AuthorID: author.ID, PublishedAt: &now}
+	require.NoError(t, db.Create(post).Error)
+
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour}}
+	token, err := utils.GenerateToken(author, cfg)
+	require.NoError(t, err)
+
+	commentService := service.NewCommentService(
+		repository.NewCommentRepository(db),
+		repository.NewPostRepository(db),
+		webhook.NewDispatcher(config.WebhookConfig{}),
+		config.CommentConfig{},
+	)
+	router := gin.New()
+	router.POST("/comments", middleware.OptionalAuthMiddleware(cfg, nil), handlers.NewCommentHandler(commentService).CreateComment)
+
+	req := httptest.NewRequest(http.MethodPost, "/comments", strings.NewReader(fmt.Sprintf(`{"content":"Still here","post_id":%d}`, post.ID)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// The token doesn't count, so the comment needs guest details
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var count int64
+	require.NoError(t, db.Model(&models.Comment{}).Where("author_id = ?", author.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+)
+
+// errorStatus maps a typed service error to its HTTP status, falling back to
+// fallback for errors of no known kind
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperrors.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperrors.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperrors.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, apperrors.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, apperrors.ErrBadRequest):
+		return http.StatusBadRequest
+	}
+	return fallback
+}
+
+// respondError reports a failed service call with the status errorStatus
+// picks, listing the rejected fields when the request failed validation
+func respondError(c *gin.Context, err error, fallback int) {
+	var data interface{}
+	var invalid *service.ValidationError
+	if errors.As(err, &invalid) {
+		data = models.ValidationErrorResponse{Fields: invalid.Fields}
+	}
+	respond.ErrorWithData(c, errorStatus(err, fallback), err.Error(), data)
+}
+
+// respondLookupError reports a failed lookup of a single resource: 404 when
+// it doesn't exist, 401 when the credentials granting access to it were
+// rejected, 500 when the lookup itself failed
+func respondLookupError(c *gin.Context, err error, resource string) {
+	if errors.Is(err, apperrors.ErrNotFound) {
+		respond.Error(c, http.StatusNotFound, strings.ToUpper(resource[:1])+resource[1:]+" not found")
+		return
+	}
+	if errors.Is(err, apperrors.ErrUnauthorized) {
+		respond.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	respond.Error(c, http.StatusInternalServerError, "Failed to retrieve "+resource)
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+)
+
+// feedSize is how many of the latest posts a feed carries
+const feedSize = 20
+
+type FeedHandler struct {
+	postService    service.PostService
+	tagService     service.TagService
+	config         config.AppConfig
+	trustedProxies []*net.IPNet
+}
+
+func NewFeedHandler(postService service.PostService, tagService service.TagService, config config.AppConfig) *FeedHandler {
+	return &FeedHandler{
+		postService:    postService,
+		tagService:     tagService,
+		config:         config,
+		trustedProxies: parseTrustedProxies(config.TrustedProxies),
+	}
+}
+
+// parseTrustedProxies turns the trusted proxy IPs and CIDR ranges into
+// networks, skipping invalid entries, which config validation reports
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, proxy := range proxies {
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * net.IPv6len
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		} else if _, network, err := net.ParseCIDR(proxy); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// requestURL returns the absolute URL the request was made to. The
+// X-Forwarded-Proto and X-Forwarded-Host headers are only believed from a
+// trusted proxy, as for the client IP, so other clients can't make the server
+// link to an address of their choosing.
+func (h *FeedHandler) requestURL(c *gin.Context) string {
+	scheme, host := "http", c.Request.Host
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if h.fromTrustedProxy(c) {
+		if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+			host = forwarded
+		}
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, host, c.Request.URL.Path)
+}
+
+// fromTrustedProxy reports whether the request came straight from one of the
+// trusted proxies
+func (h *FeedHandler) fromTrustedProxy(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, network := range h.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// rssFeed is an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+}
+
+// jsonFeed is a JSON Feed 1.1 document (https://jsonfeed.org/version/1.1)
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	// Author is the JSON Feed 1.0 field, kept for older readers
+	Author *jsonFeedAuthor `json:"author,omitempty"`
+	Tags   []string        `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// feedChannel describes the feed a set of posts is published in
+type feedChannel struct {
+	title       string
+	link        string
+	description string
+}
+
+// GetRSSFeed godoc
+// @Summary RSS feed of the latest posts
+// @Description RSS 2.0 feed of the most recently published posts
+// @Tags Feeds
+// @Produce application/rss+xml
+// @Success 200 {string} string "RSS 2.0 feed"
+// @Failure 500 "Failed to build the feed"
+// @Router /api/feed/rss [get]
+func (h *FeedHandler) GetRSSFeed(c *gin.Context) {
+	posts, _, err := h.postService.GetPublishedPosts(c.Request.Context(), models.PostSortNewest, models.PublishedRange{}, false, 1, feedSize)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve posts")
+		return
+	}
+
+	h.writeRSS(c, feedChannel{
+		title:       h.config.Name,
+		link:        h.config.BaseURL,
+		description: "Latest posts from " + h.config.Name,
+	}, posts)
+}
+
+// GetJSONFeed godoc
+// @Summary JSON Feed of the latest posts
+// @Description JSON Feed 1.1 document of the most recently published posts,
+// @Description with each post's content rendered as HTML
+// @Tags Feeds
+// @Produce application/feed+json
+// @Success 200 {object} object "JSON Feed 1.1 document"
+// @Failure 500 "Failed to build the feed"
+// @Router /api/feed/json [get]
+func (h *FeedHandler) GetJSONFeed(c *gin.Context) {
+	posts, err := h.postService.GetLatestPublished(c.Request.Context(), feedSize)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve posts")
+		return
+	}
+
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       h.config.Name,
+		HomePageURL: h.config.BaseURL,
+		FeedURL:     h.requestURL(c),
+		Description: "Latest posts from " + h.config.Name,
+		Items:       make([]jsonFeedItem, 0, len(posts)),
+	}
+	for _, post := range posts {
+		author := jsonFeedAuthor{Name: post.Author.Username}
+		if name := strings.TrimSpace(post.Author.FirstName + " " + post.Author.LastName); name != "" {
+			author.Name = name
+		}
+
+		item := jsonFeedItem{
+			ID:           strconv.FormatUint(uint64(post.ID), 10),
+			URL:          fmt.Sprintf("%s/posts/%d", h.config.BaseURL, post.ID),
+			Title:        post.Title,
+			ContentHTML:  post.ContentHTML,
+			Summary:      post.Excerpt,
+			Image:        post.FeaturedImg,
+			DateModified: post.UpdatedAt.UTC().Format(time.RFC3339),
+			Authors:      []jsonFeedAuthor{author},
+			Author:       &author,
+		}
+		if post.PublishedAt != nil {
+			item.DatePublished = post.PublishedAt.UTC().Format(time.RFC3339)
+		}
+		for _, tag := range post.Tags {
+			item.Tags = append(item.Tags, tag.Name)
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	body, err := json.Marshal(feed)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to build the feed")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/feed+json; charset=utf-8", body)
+}
+
+// GetTagRSSFeed godoc
+// @Summary RSS feed of a tag's posts
+// @Description RSS 2.0 feed of the most recently published posts with the tag
+// @Tags Feeds
+// @Produce application/rss+xml
+// @Param slug path string true "Tag slug"
+// @Success 200 {string} string "RSS 2.0 feed"
+// @Failure 404 "Tag not found"
+// @Failure 500 "Failed to build the feed"
+// @Router /api/feed/rss/tag/{slug} [get]
+func (h *FeedHandler) GetTagRSSFeed(c *gin.Context) {
+	tag, err := h.tagService.GetBySlug(c.Param("slug"))
+	if err != nil {
+		respondLookupError(c, err, "tag")
+		return
+	}
+
+	posts, _, err := h.postService.GetPostsByTag(c.Request.Context(), tag.ID, models.PostSortNewest, 1, feedSize)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve posts")
+		return
+	}
+
+	description := tag.Description
+	if description == "" {
+		description = fmt.Sprintf("Latest posts tagged %s on %s", tag.Name, h.config.Name)
+	}
+	h.writeRSS(c, feedChannel{
+		title:       fmt.Sprintf("%s: %s", h.config.Name, tag.Name),
+		link:        fmt.Sprintf("%s/tags/%s", h.config.BaseURL, tag.Slug),
+		description: description,
+	}, posts)
+}
+
+// writeRSS renders posts as an RSS 2.0 feed of channel. Posts link to the
+// frontend at the configured base URL.
+func (h *FeedHandler) writeRSS(c *gin.Context, channel feedChannel, posts []models.PostListResponse) {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       channel.title,
+			Link:        channel.link,
+			Description: channel.description,
+		},
+	}
+
+	for _, post := range posts {
+		link := fmt.Sprintf("%s/posts/%d", h.config.BaseURL, post.ID)
+		item := rssItem{
+			Title:       post.Title,
+			Link:        link,
+			GUID:        link,
+			Description: post.Excerpt,
+		}
+		for _, tag := range post.Tags {
+			item.Categories = append(item.Categories, tag.Name)
+		}
+		if post.PublishedAt != nil {
+			item.PubDate = post.PublishedAt.UTC().Format(time.RFC1123Z)
+			if feed.Channel.LastBuildDate == "" {
+				feed.Channel.LastBuildDate = item.PubDate
+			}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to build the feed")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
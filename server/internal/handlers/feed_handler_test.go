@@ -0,0 +1,196 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/handlers"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rssDocument is the part of an RSS feed the tests look at
+type rssDocument struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Items       []struct {
+			Title string `xml:"title"`
+			Link  string `xml:"link"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+func TestFeedHandler_GetTagRSSFeed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "syndicator")
+	tags := []models.Tag{
+		{Name: "Go", Slug: "go", Description: "All about Go"},
+		{Name: "Rust", Slug: "rust"},
+	}
+	require.NoError(t, db.Create(&tags).Error)
+
+	postRepo := repository.NewPostRepository(db)
+	published := time.Now().Add(-time.Hour)
+	for _, p := range []struct {
+		title  string
+		status models.PostStatus
+		tagID  uint
+	}{
+		{"Go generics", models.PostStatusPublished, tags[0].ID},
+		{"Go draft", models.PostStatusDraft, tags[0].ID},
+		{"Rust lifetimes", models.PostStatusPublished, tags[1].ID},
+	} {
+		post := &models.Post{Title: p.title, Slug: utils.GenerateSlug(p.title), Content: "Feed content", Status: p.status, AuthorID: author.ID}
+		if p.status == models.PostStatusPublished {
+			post.PublishedAt = &published
+		}
+		require.NoError(t, db.Create(post).Error)
+		require.NoError(t, postRepo.AddTags(post.ID, []uint{p.tagID}))
+	}
+
+	tagRepo := repository.NewTagRepository(db)
+	postService := service.NewPostService(postRepo, tagRepo, repository.NewCommentRepository(db), webhook.NewDispatcher(config.WebhookConfig{}), mailer.New(config.MailConfig{}), config.ContentConfig{AllowImages: true}, config.JWTConfig{}, nil)
+	handler := handlers.NewFeedHandler(postService, service.NewTagService(tagRepo, 0), config.AppConfig{Name: "Test Blog", BaseURL: "https://blog.example"})
+
+	t.Run("lists only the tag's published posts", func(t *testing.T) {
+		c, w := newPostTestContext(http.MethodGet, "/api/v1/feed/rss/tag/go", gin.Params{{Key: "slug", Value: "go"}})
+		handler.GetTagRSSFeed(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/rss+xml")
+
+		var feed rssDocument
+		require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+		assert.Equal(t, "Test Blog: Go", feed.Channel.Title)
+		assert.Equal(t, "https://blog.example/tags/go", feed.Channel.Link)
+		assert.Equal(t, "All about Go", feed.Channel.Description)
+		require.Len(t, feed.Channel.Items, 1)
+		assert.Equal(t, "Go generics", feed.Channel.Items[0].Title)
+		assert.Contains(t, feed.Channel.Items[0].Link, "https://blog.example/posts/")
+	})
+
+	t.Run("the global feed lists every published post", func(t *testing.T) {
+		c, w := newPostTestContext(http.MethodGet, "/api/v1/feed/rss", nil)
+		handler.GetRSSFeed(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var feed rssDocument
+		require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+		assert.Equal(t, "Test Blog", feed.Channel.Title)
+		assert.Len(t, feed.Channel.Items, 2)
+	})
+
+	t.Run("unknown tag", func(t *testing.T) {
+		c, w := newPostTestContext(http.MethodGet, "/api/v1/feed/rss/tag/cobol", gin.Params{{Key: "slug", Value: "cobol"}})
+		handler.GetTagRSSFeed(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("errors are rendered as XML", func(t *testing.T) {
+		engine := gin.New()
+		engine.GET("/feed/rss/tag/:slug", respond.XMLRoute(), handler.GetTagRSSFeed)
+
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feed/rss/tag/cobol", nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/xml")
+
+		var body respond.XMLError
+		require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &body))
+		assert.False(t, body.Success)
+		assert.Equal(t, "Tag not found", body.Message)
+
+		// Unless the client asks for JSON
+		req := httptest.NewRequest(http.MethodGet, "/feed/rss/tag/cobol", nil)
+		req.Header.Set("Accept", "application/json")
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+		assert.JSONEq(t, `{"success":false,"error":"Tag not found"}`, w.Body.String())
+	})
+}
+
+func TestFeedHandler_GetJSONFeed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "jsonfeeder")
+	tag := models.Tag{Name: "Go", Slug: "go"}
+	require.NoError(t, db.Create(&tag).Error)
+
+	postRepo := repository.NewPostRepository(db)
+	published := time.Now().Add(-time.Hour)
+	post := &models.Post{Title: "Feeding JSON", Slug: "feeding-json", Content: "# Hello\n\nSome **bold** text", Excerpt: "Some bold text", Status: models.PostStatusPublished, AuthorID: author.ID, PublishedAt: &published}
+	require.NoError(t, db.Create(post).Error)
+	require.NoError(t, postRepo.AddTags(post.ID, []uint{tag.ID}))
+	require.NoError(t, db.Create(&models.Post{Title: "Still drafting", Slug: "still-drafting", Content: "Not in the feed", Status: models.PostStatusDraft, AuthorID: author.ID}).Error)
+
+	tagRepo := repository.NewTagRepository(db)
+	postService := service.NewPostService(postRepo, tagRepo, repository.NewCommentRepository(db), webhook.NewDispatcher(config.WebhookConfig{}), mailer.New(config.MailConfig{}), config.ContentConfig{AllowImages: true}, config.JWTConfig{}, nil)
+	handler := handlers.NewFeedHandler(postService, service.NewTagService(tagRepo, 0), config.AppConfig{Name: "Test Blog", BaseURL: "https://blog.example"})
+
+	c, w := newPostTestContext(http.MethodGet, "http://api.example/api/v1/feed/json", nil)
+	handler.GetJSONFeed(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/feed+json")
+
+	var feed map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &feed))
+	assert.Equal(t, "https://jsonfeed.org/version/1.1", feed["version"])
+	assert.Equal(t, "Test Blog", feed["title"])
+	assert.Equal(t, "https://blog.example", feed["home_page_url"])
+	assert.Equal(t, "http://api.example/api/v1/feed/json", feed["feed_url"])
+
+	items, ok := feed["items"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, items, 1, "drafts must not be in the feed")
+	item := items[0].(map[string]interface{})
+	assert.Equal(t, fmt.Sprint(post.ID), item["id"])
+	assert.Equal(t, fmt.Sprintf("https://blog.example/posts/%d", post.ID), item["url"])
+	assert.Equal(t, "Feeding JSON", item["title"])
+	assert.Equal(t, "<h1>Hello</h1>\n<p>Some <strong>bold</strong> text</p>", item["content_html"])
+	assert.Equal(t, published.UTC().Format(time.RFC3339), item["date_published"])
+	assert.Equal(t, []interface{}{"Go"}, item["tags"])
+	authors, ok := item["authors"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, authors, 1)
+	assert.NotEmpty(t, authors[0].(map[string]interface{})["name"])
+
+	feedURL := func(handler *handlers.FeedHandler) interface{} {
+		c, w := newPostTestContext(http.MethodGet, "http://api.example/api/v1/feed/json", nil)
+		c.Request.RemoteAddr = "192.0.2.1:443"
+		c.Request.Header.Set("X-Forwarded-Proto", "https")
+		c.Request.Header.Set("X-Forwarded-Host", "feeds.example")
+		handler.GetJSONFeed(c)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var feed map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &feed))
+		return feed["feed_url"]
+	}
+	assert.Equal(t, "http://api.example/api/v1/feed/json", feedURL(handler), "forwarded headers from untrusted clients are ignored")
+
+	proxied := handlers.NewFeedHandler(postService, service.NewTagService(tagRepo, 0), config.AppConfig{Name: "Test Blog", BaseURL: "https://blog.example", TrustedProxies: []string{"192.0.2.0/24"}})
+	assert.Equal(t, "https://feeds.example/api/v1/feed/json", feedURL(proxied))
+}
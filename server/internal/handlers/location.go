@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setLocation points the Location header of a 201 response at the created
+// resource, or of a 301 response at the resource's new address. path is
+// relative to the API root, as for apiPath.
+func setLocation(c *gin.Context, format string, args ...interface{}) {
+	c.Header("Location", apiPath(c, format, args...))
+}
+
+// setCanonical links a response found under an outdated address to the
+// resource's current one with a Link rel="canonical" header, keeping the
+// successor-version link of the deprecated /api prefix
+func setCanonical(c *gin.Context, format string, args ...interface{}) {
+	c.Writer.Header().Add("Link", fmt.Sprintf(`<%s>; rel="canonical"`, apiPath(c, format, args...)))
+}
+
+// apiPath returns path, relative to the API root, on the API version the
+// request came in on (/api/v1 or the legacy /api)
+func apiPath(c *gin.Context, format string, args ...interface{}) string {
+	root := "/api"
+	if strings.HasPrefix(c.Request.URL.Path, "/api/v1/") {
+		root = "/api/v1"
+	}
+	return root + fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+)
+
+type NewsletterHandler struct {
+	newsletterService service.NewsletterService
+}
+
+func NewNewsletterHandler(newsletterService service.NewsletterService) *NewsletterHandler {
+	return &NewsletterHandler{
+		newsletterService: newsletterService,
+	}
+}
+
+// Subscribe godoc
+// @Summary Subscribe to the newsletter
+// @Description Subscribe the authenticated user to the weekly digest of top posts
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.NewsletterSubscriptionResponse}
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/auth/newsletter/subscribe [post]
+func (h *NewsletterHandler) Subscribe(c *gin.Context) {
+	h.setSubscription(c, true)
+}
+
+// Unsubscribe godoc
+// @Summary Unsubscribe from the newsletter
+// @Description Stop sending the weekly digest to the authenticated user
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.NewsletterSubscriptionResponse}
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/auth/newsletter/unsubscribe [post]
+func (h *NewsletterHandler) Unsubscribe(c *gin.Context) {
+	h.setSubscription(c, false)
+}
+
+func (h *NewsletterHandler) setSubscription(c *gin.Context, subscribed bool) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var err error
+	message := "Subscribed to the newsletter"
+	if subscribed {
+		err = h.newsletterService.Subscribe(userID)
+	} else {
+		err = h.newsletterService.Unsubscribe(userID)
+		message = "Unsubscribed from the newsletter"
+	}
+	if err != nil {
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		respond.Error(c, statusCode, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    models.NewsletterSubscriptionResponse{Subscribed: subscribed},
+	})
+}
+
+// SendNow godoc
+// @Summary Send the newsletter now (Admin only)
+// @Description Compose and send the newsletter digest immediately instead of waiting for the schedule
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.NewsletterDigestResult}
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /api/admin/newsletter/send-now [post]
+func (h *NewsletterHandler) SendNow(c *gin.Context) {
+	result, err := h.newsletterService.SendDigest()
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to send newsletter")
+		return
+	}
+
+	message := "Newsletter sent"
+	if result.Posts == 0 {
+		message = "No posts to send"
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    result,
+	})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+)
+
+// withPaginationLinks adds links to the first, last, next and previous pages
+// to meta. They keep the request's path and query, changing only page.
+func withPaginationLinks(c *gin.Context, meta models.PaginationMeta) models.PaginationMeta {
+	pageURL := func(page int) string {
+		u := *c.Request.URL
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		u.RawQuery = query.Encode()
+		return u.RequestURI()
+	}
+
+	links := &models.PaginationLinks{
+		First: pageURL(1),
+		Last:  pageURL(max(meta.TotalPages, 1)),
+	}
+	if meta.HasNext {
+		links.Next = pageURL(meta.Page + 1)
+	}
+	if meta.HasPrev {
+		links.Prev = pageURL(meta.Page - 1)
+	}
+
+	meta.Links = links
+	return meta
+}
@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/breach"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/handlers"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Empty pages must serialize as [] rather than null
+func TestListHandlers_EmptyPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "quietauthor")
+	now := time.Now()
+	post := &models.Post{Title: "No replies yet", Slug: "no-replies-yet", Content: "Nobody has commented", Status: models.PostStatusPublished, AuthorID: author.ID, PublishedAt: &now}
+	require.NoError(t, db.Create(post).Error)
+
+	postRepo := repository.NewPostRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	webhooks := webhook.NewDispatcher(config.WebhookConfig{})
+	postService := service.NewPostService(postRepo, tagRepo, commentRepo, webhooks, mailer.New(config.MailConfig{}), config.ContentConfig{}, config.JWTConfig{}, nil)
+	commentService := service.NewCommentService(commentRepo, postRepo, webhooks, config.CommentConfig{RequireApproval: true})
+	userService := service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), mailer.New(config.MailConfig{}), &config.Config{})
+
+	postHandler := handlers.NewPostHandler(postService)
+	commentHandler := handlers.NewCommentHandler(commentService)
+	tagHandler := handlers.NewTagHandler(service.NewTagService(tagRepo, 0), postService)
+	adminHandler := handlers.NewAdminHandler(userService)
+
+	tests := []struct {
+		name    string
+		target  string
+		params  gin.Params
+		handler gin.HandlerFunc
+	}{
+		{"posts", "/api/v1/posts?status=draft&author_id=" + fmt.Sprint(author.ID), nil, func(c *gin.Context) {
+			c.Set("user_id", author.ID)
+			postHandler.GetPosts(c)
+		}},
+		{"published posts", "/api/v1/posts/published?published_before=2000-01-01T00:00:00Z", nil, postHandler.GetPublishedPosts},
+		{"post search", "/api/v1/posts/search?q=nothing-matches", nil, postHandler.SearchPosts},
+		{"comments", "/api/v1/comments/post/1", gin.Params{{Key: "post_id", Value: fmt.Sprint(post.ID)}}, commentHandler.GetCommentsByPost},
+		{"pending comments", "/api/v1/admin/comments/pending", nil, commentHandler.GetPendingComments},
+		{"tags", "/api/v1/tags", nil, tagHandler.GetTags},
+		{"users", "/api/v1/admin/users?q=nobody-by-that-name", nil, adminHandler.GetUsers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newPostTestContext(http.MethodGet, tt.target, tt.params)
+			tt.handler(c)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), `"data":[]`)
+		})
+	}
+}
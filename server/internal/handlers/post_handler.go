@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
 )
 
 type PostHandler struct {
@@ -28,38 +36,33 @@ func NewPostHandler(postService service.PostService) *PostHandler {
 // @Produce json
 // @Security BearerAuth
 // @Param post body models.PostCreateRequest true "Post data"
+// @Param Idempotency-Key header string false "Key making retries of this request return the original response"
 // @Success 201 {object} models.APIResponse{data=models.PostResponse}
 // @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/posts [post]
 func (h *PostHandler) CreatePost(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	var req models.PostCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
 	post, err := h.postService.Create(userID, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
+	setLocation(c, "/posts/%d", post.ID)
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
 		Message: "Post created successfully",
@@ -67,38 +70,140 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 	})
 }
 
+// maxImportSize bounds the Markdown documents ImportPost reads
+const maxImportSize = 1 << 20
+
+// ImportPost godoc
+// @Summary Import a post from Markdown
+// @Description Create a post from a Markdown document with YAML front matter,
+// @Description as written for static site generators. The front matter sets
+// @Description the title, slug, status (draft by default) and tags by name;
+// @Description tags that don't exist yet are created. The rest of the
+// @Description document is the content. With dry_run the document is only
+// @Description validated, and the post it would create is returned.
+// @Tags Posts
+// @Accept plain
+// @Produce json
+// @Security BearerAuth
+// @Param document body string true "Markdown document with front matter"
+// @Param dry_run query bool false "Validate without creating the post" default(false)
+// @Success 200 {object} models.APIResponse{data=models.PostImportPreview}
+// @Success 201 {object} models.APIResponse{data=models.PostResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Failure 413 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/posts/import [post]
+func (h *PostHandler) ImportPost(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "dry_run must be true or false")
+		return
+	}
+
+	document, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respond.Error(c, http.StatusRequestEntityTooLarge, "Document is too large")
+			return
+		}
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if dryRun {
+		preview, err := h.postService.PreviewImport(string(document))
+		if err != nil {
+			respondError(c, err, http.StatusBadRequest)
+			return
+		}
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Document is valid and can be imported",
+			Data:    preview,
+		})
+		return
+	}
+
+	post, err := h.postService.Import(userID, string(document))
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	setLocation(c, "/posts/%d", post.ID)
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Post imported successfully",
+		Data:    post,
+	})
+}
+
 // GetPost godoc
 // @Summary Get a post by ID
-// @Description Get a specific post by its ID
+// @Description Get a specific post by its ID. Drafts and archived posts are
+// @Description only visible to their author and admins, or with a preview
+// @Description token for the post.
 // @Tags Posts
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Post ID"
+// @Param include query string false "Set to comments to embed approved comments"
+// @Param preview query string false "Preview token granting read access to this post before it is published"
+// @Param If-None-Match header string false "ETag from a previous response"
 // @Success 200 {object} models.APIResponse{data=models.PostResponse}
+// @Success 304 "Not modified"
+// @Failure 401 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
 // @Router /api/posts/{id} [get]
 func (h *PostHandler) GetPost(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid post ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
-	post, err := h.postService.GetByID(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error:   "Post not found",
+	viewerID, _ := middleware.GetUserID(c)
+	post, err := h.postService.GetByID(c.Request.Context(), uint(id), viewerID, middleware.IsAdmin(c))
+	if errors.Is(err, apperrors.ErrNotFound) && c.Query("preview") != "" {
+		post, err = h.previewPost(c.Query("preview"), func(post *models.PostResponse) bool {
+			return post.ID == uint(id)
 		})
+	}
+	if err != nil {
+		respondLookupError(c, err, "post")
+		return
+	}
+
+	if !h.includeComments(c, post) {
 		return
 	}
 
-	// Increment view count for published posts
-	if post.Status == models.PostStatusPublished {
+	published := post.Status == models.PostStatusPublished
+	if !canSeeAuthorFields(c, post.IsEditor) {
+		post.HideAuthorFields()
+	}
+
+	// Revalidated requests are not counted as views
+	if notModified(c, postETag(post)) {
+		return
+	}
+
+	// Increment view count for published posts, and let anonymous readers
+	// share the response
+	if published {
 		go h.postService.IncrementViewCount(uint(id))
+		middleware.CacheResponse(c, postCacheTags(post)...)
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -107,30 +212,130 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 	})
 }
 
+// GetPostTags godoc
+// @Summary Get a post's tags
+// @Description Get only the tags of a post, each with its published post count, without the post content. Tags of drafts and archived posts are only visible to the author and admins.
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Success 200 {object} models.APIResponse{data=[]models.TagResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /api/posts/{id}/tags [get]
+func (h *PostHandler) GetPostTags(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	viewerID, _ := middleware.GetUserID(c)
+	tags, err := h.postService.GetTags(c.Request.Context(), uint(id), viewerID, middleware.IsAdmin(c))
+	if err != nil {
+		respondLookupError(c, err, "post")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    tags,
+	})
+}
+
+// GetPostCommenters godoc
+// @Summary Get a post's commenters
+// @Description Get the users with approved comments on a post, replies included, each with their number of comments, most active first. Guests are left out. Commenters of drafts and archived posts are only visible to the author and admins.
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Success 200 {object} models.APIResponse{data=[]models.CommenterResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /api/posts/{id}/commenters [get]
+func (h *PostHandler) GetPostCommenters(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	viewerID, _ := middleware.GetUserID(c)
+	commenters, err := h.postService.GetCommenters(c.Request.Context(), uint(id), viewerID, middleware.IsAdmin(c))
+	if err != nil {
+		respondLookupError(c, err, "post")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    commenters,
+	})
+}
+
 // GetPostBySlug godoc
 // @Summary Get a post by slug
-// @Description Get a specific post by its slug
+// @Description Get a specific post by its slug. Drafts and archived posts are
+// @Description only visible to their author and admins, or with a preview
+// @Description token for the post. A slug the post had
+// @Description before its title changed finds it too, with its current
+// @Description address in a Link rel="canonical" header.
 // @Tags Posts
 // @Produce json
+// @Security BearerAuth
 // @Param slug path string true "Post slug"
+// @Param include query string false "Set to comments to embed approved comments"
+// @Param preview query string false "Preview token granting read access to this post before it is published"
+// @Param If-None-Match header string false "ETag from a previous response"
 // @Success 200 {object} models.APIResponse{data=models.PostResponse}
+// @Success 304 "Not modified"
+// @Failure 401 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
 // @Router /api/posts/slug/{slug} [get]
 func (h *PostHandler) GetPostBySlug(c *gin.Context) {
 	slug := c.Param("slug")
 
-	post, err := h.postService.GetBySlug(slug)
-	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error:   "Post not found",
+	viewerID, _ := middleware.GetUserID(c)
+	post, err := h.postService.GetBySlug(c.Request.Context(), slug, viewerID, middleware.IsAdmin(c))
+	if errors.Is(err, apperrors.ErrNotFound) && c.Query("preview") != "" {
+		post, err = h.previewPost(c.Query("preview"), func(post *models.PostResponse) bool {
+			return post.Slug == slug
 		})
+	}
+	if err != nil {
+		respondLookupError(c, err, "post")
+		return
+	}
+
+	if post.Slug != slug {
+		setCanonical(c, "/posts/slug/%s", post.Slug)
+	}
+
+	if !h.includeComments(c, post) {
+		return
+	}
+
+	published := post.Status == models.PostStatusPublished
+	if !canSeeAuthorFields(c, post.IsEditor) {
+		post.HideAuthorFields()
+	}
+
+	// Revalidated requests are not counted as views
+	if notModified(c, postETag(post)) {
 		return
 	}
 
-	// Increment view count for published posts
-	if post.Status == models.PostStatusPublished {
+	// Increment view count for published posts, and let anonymous readers
+	// share the response
+	if published {
 		go h.postService.IncrementViewCount(post.ID)
+		middleware.CacheResponse(c, postCacheTags(post)...)
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -141,7 +346,8 @@ func (h *PostHandler) GetPostBySlug(c *gin.Context) {
 
 // UpdatePost godoc
 // @Summary Update a post
-// @Description Update an existing post
+// @Description Update an existing post. Empty fields are ignored and leave
+// @Description the post unchanged; use PATCH to clear a field.
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -153,50 +359,63 @@ func (h *PostHandler) GetPostBySlug(c *gin.Context) {
 // @Failure 401 {object} models.APIResponse
 // @Failure 403 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/posts/{id} [put]
 func (h *PostHandler) UpdatePost(c *gin.Context) {
+	h.updatePost(c, false)
+}
+
+// PatchPost godoc
+// @Summary Partially update a post
+// @Description Update only the fields present in the body. Omitted or null
+// @Description fields are left unchanged; an empty excerpt or featured_image
+// @Description clears it and an empty tag_ids list removes every tag.
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Param post body models.PostUpdateRequest true "Fields to change"
+// @Success 200 {object} models.APIResponse{data=models.PostResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/posts/{id} [patch]
+func (h *PostHandler) PatchPost(c *gin.Context) {
+	h.updatePost(c, true)
+}
+
+// updatePost applies a PUT or, when partial is set, a PATCH request. PUT
+// ignores empty fields, while PATCH applies every field that is present.
+func (h *PostHandler) updatePost(c *gin.Context, partial bool) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid post ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
 	var req models.PostUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
+	if !partial {
+		req.IgnoreEmpty()
+	}
 
 	isAdmin := middleware.IsAdmin(c)
 	post, err := h.postService.Update(uint(id), userID, &req, isAdmin)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "unauthorized: you can only update your own posts" {
-			statusCode = http.StatusForbidden
-		} else if err.Error() == "post not found" {
-			statusCode = http.StatusNotFound
-		}
-
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -222,37 +441,23 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 func (h *PostHandler) DeletePost(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid post ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
 	isAdmin := middleware.IsAdmin(c)
 	err = h.postService.Delete(uint(id), userID, isAdmin)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "unauthorized: you can only delete your own posts" {
-			statusCode = http.StatusForbidden
-		} else if err.Error() == "post not found" {
-			statusCode = http.StatusNotFound
-		}
+		statusCode := errorStatus(err, http.StatusBadRequest)
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
 
@@ -264,71 +469,262 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 
 // GetPosts godoc
 // @Summary Get posts
-// @Description Get a list of posts with pagination and filtering
+// @Description Get a list of posts with pagination and filtering. Drafts and
+// @Description archived posts are only listed for admins, or for users
+// @Description filtering by their own author_id; everyone else sees published
+// @Description posts only.
 // @Tags Posts
 // @Produce json
+// @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
 // @Param status query string false "Post status filter" Enums(draft, published, archived)
+// @Param published query bool false "Shorthand for the status: true lists published posts, false drafts and archived posts"
 // @Param author_id query int false "Author ID filter"
+// @Param published_after query string false "Only posts published at or after this RFC3339 time"
+// @Param published_before query string false "Only posts published at or before this RFC3339 time"
+// @Param excerpt_length query int false "Shorten excerpts to at most this many characters, on a word boundary"
 // @Success 200 {object} models.PaginatedResponse{data=[]models.PostListResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
 // @Router /api/posts [get]
 func (h *PostHandler) GetPosts(c *gin.Context) {
 	page, perPage := middleware.GetPaginationParams(c)
 
-	var status models.PostStatus
+	var filter models.PostFilter
 	if statusStr := c.Query("status"); statusStr != "" {
-		status = models.PostStatus(statusStr)
+		filter.Status = models.PostStatus(statusStr)
+	}
+
+	published, err := optionalBoolQuery(c, "published")
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "published must be true or false")
+		return
 	}
+	if published != nil && filter.Status != "" && (filter.Status == models.PostStatusPublished) != *published {
+		respond.Error(c, http.StatusBadRequest, "status and published contradict each other")
+		return
+	}
+	filter.Published = published
 
-	var authorID uint
 	if authorIDStr := c.Query("author_id"); authorIDStr != "" {
 		if id, err := strconv.ParseUint(authorIDStr, 10, 32); err == nil {
-			authorID = uint(id)
+			filter.AuthorID = uint(id)
+		}
+	}
+
+	if filter.PublishedRange, err = publishedRangeQuery(c); err != nil {
+		respond.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Unpublished posts are private to their author and admins
+	viewerID, _ := middleware.GetUserID(c)
+	if !middleware.IsAdmin(c) && (viewerID == 0 || filter.AuthorID != viewerID) {
+		if (filter.Status != "" && filter.Status != models.PostStatusPublished) || (published != nil && !*published) {
+			respond.Error(c, http.StatusForbidden, "You can only list your own unpublished posts")
+			return
 		}
+		filter.Status = models.PostStatusPublished
 	}
 
-	posts, pagination, err := h.postService.GetPosts(page, perPage, status, authorID)
+	posts, pagination, err := h.postService.GetPosts(c.Request.Context(), filter, page, perPage)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve posts",
-		})
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
 
+	projectPosts(c, posts)
+
 	c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success:    true,
 		Data:       posts,
-		Pagination: pagination,
+		Pagination: withPaginationLinks(c, pagination),
 	})
 }
 
+// projectPosts adapts a post listing to the request: excerpts are shortened
+// to the length requested with excerpt_length, on a word boundary, and
+// author-only fields are hidden from other readers
+func projectPosts(c *gin.Context, posts []models.PostListResponse) {
+	length := middleware.GetExcerptLength(c)
+	for i := range posts {
+		if length > 0 {
+			posts[i].Excerpt = utils.TruncateText(posts[i].Excerpt, length)
+		}
+		if !canSeeAuthorFields(c, posts[i].IsEditor) {
+			posts[i].HideAuthorFields()
+		}
+	}
+}
+
+// canSeeAuthorFields reports whether the viewer may see the author-only
+// fields of a post, whose isEditor checks for its author and co-authors:
+// always, unless the deployment keeps them private, and then only its
+// editors and admins
+func canSeeAuthorFields(c *gin.Context, isEditor func(userID uint) bool) bool {
+	if !middleware.AuthorOnlyFields(c) || middleware.IsAdmin(c) {
+		return true
+	}
+	viewerID, _ := middleware.GetUserID(c)
+	return isEditor(viewerID)
+}
+
+// publishedRangeQuery reads the published_after and published_before query
+// parameters, both RFC3339 timestamps
+func publishedRangeQuery(c *gin.Context) (models.PublishedRange, error) {
+	var published models.PublishedRange
+	for name, bound := range map[string]**time.Time{
+		"published_after":  &published.After,
+		"published_before": &published.Before,
+	} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return models.PublishedRange{}, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+		}
+		*bound = &at
+	}
+	return published, nil
+}
+
 // GetPublishedPosts godoc
 // @Summary Get published posts
-// @Description Get a list of published posts
+// @Description Get a list of published posts. sort=comments orders by the
+// @Description number of approved comments. Cursor pagination only supports
+// @Description the default newest-first order.
 // @Tags Posts
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
+// @Param sort query string false "Sort order" Enums(newest, oldest, popular, comments) default(newest)
+// @Param cursor query string false "Opaque cursor; pass an empty value to start cursor pagination, then the previous next_cursor"
+// @Param published_after query string false "Only posts published at or after this RFC3339 time"
+// @Param published_before query string false "Only posts published at or before this RFC3339 time"
+// @Param featured_first query bool false "List featured posts first, in their featured order; not supported with cursor pagination" default(false)
+// @Param excerpt_length query int false "Shorten excerpts to at most this many characters, on a word boundary"
 // @Success 200 {object} models.PaginatedResponse{data=[]models.PostListResponse}
+// @Success 200 {object} models.CursorPaginatedResponse{data=[]models.PostListResponse}
+// @Failure 400 {object} models.APIResponse
 // @Router /api/posts/published [get]
 func (h *PostHandler) GetPublishedPosts(c *gin.Context) {
 	page, perPage := middleware.GetPaginationParams(c)
+	sort := models.PostSort(c.Query("sort"))
+
+	published, err := publishedRangeQuery(c)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	posts, pagination, err := h.postService.GetPublishedPosts(page, perPage)
+	featuredFirst, err := strconv.ParseBool(c.DefaultQuery("featured_first", "false"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve posts",
+		respond.Error(c, http.StatusBadRequest, "featured_first must be true or false")
+		return
+	}
+
+	// Cursor pagination is opt-in: the presence of the cursor parameter
+	// (even empty, for the first page) switches away from offset pagination
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		if sort != "" && sort != models.PostSortNewest {
+			respond.Error(c, http.StatusBadRequest, "Cursor pagination only supports sort=newest")
+			return
+		}
+		if featuredFirst {
+			respond.Error(c, http.StatusBadRequest, "Cursor pagination doesn't support featured_first")
+			return
+		}
+
+		posts, pagination, err := h.postService.GetPublishedPostsByCursor(c.Request.Context(), cursor, published, perPage)
+		if err != nil {
+			statusCode := errorStatus(err, http.StatusInternalServerError)
+
+			respond.Error(c, statusCode, err.Error())
+			return
+		}
+
+		projectPosts(c, posts)
+		middleware.CacheResponse(c, PublishedPostsCacheTag)
+
+		c.JSON(http.StatusOK, models.CursorPaginatedResponse{
+			Success:    true,
+			Data:       posts,
+			Pagination: pagination,
 		})
 		return
 	}
 
+	posts, pagination, err := h.postService.GetPublishedPosts(c.Request.Context(), sort, published, featuredFirst, page, perPage)
+	if err != nil {
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		respond.Error(c, statusCode, err.Error())
+		return
+	}
+
+	projectPosts(c, posts)
+	middleware.CacheResponse(c, PublishedPostsCacheTag)
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       posts,
+		Pagination: withPaginationLinks(c, pagination),
+	})
+}
+
+// GetFeaturedPosts godoc
+// @Summary Get featured posts
+// @Description Get the published posts editors featured, in their featured order, then newest first
+// @Tags Posts
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Param excerpt_length query int false "Shorten excerpts to at most this many characters, on a word boundary"
+// @Success 200 {object} models.PaginatedResponse{data=[]models.PostListResponse}
+// @Failure 500 {object} models.APIResponse
+// @Router /api/posts/featured [get]
+func (h *PostHandler) GetFeaturedPosts(c *gin.Context) {
+	page, perPage := middleware.GetPaginationParams(c)
+
+	posts, pagination, err := h.postService.GetFeaturedPosts(c.Request.Context(), page, perPage)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve featured posts")
+		return
+	}
+
+	projectPosts(c, posts)
+
 	c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success:    true,
 		Data:       posts,
-		Pagination: pagination,
+		Pagination: withPaginationLinks(c, pagination),
+	})
+}
+
+// GetArchive godoc
+// @Summary Get the post archive
+// @Description Get the number of published posts in each month, newest month
+// @Description first, for archive navigation. Months without posts are left out.
+// @Tags Posts
+// @Produce json
+// @Success 200 {object} models.APIResponse{data=[]models.ArchiveCount}
+// @Failure 500 {object} models.APIResponse
+// @Router /api/posts/archive [get]
+func (h *PostHandler) GetArchive(c *gin.Context) {
+	counts, err := h.postService.GetArchive()
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve the archive")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    counts,
 	})
 }
 
@@ -338,35 +734,40 @@ func (h *PostHandler) GetPublishedPosts(c *gin.Context) {
 // @Tags Posts
 // @Produce json
 // @Param q query string true "Search query"
+// @Param highlight query bool false "Add match_excerpt and match_position with the first content match" default(false)
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
+// @Param excerpt_length query int false "Shorten excerpts to at most this many characters, on a word boundary"
 // @Success 200 {object} models.PaginatedResponse{data=[]models.PostListResponse}
+// @Failure 400 {object} models.APIResponse
 // @Router /api/posts/search [get]
 func (h *PostHandler) SearchPosts(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Search query is required",
-		})
+		respond.Error(c, http.StatusBadRequest, "Search query is required")
+		return
+	}
+
+	highlight, err := strconv.ParseBool(c.DefaultQuery("highlight", "false"))
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "highlight must be true or false")
 		return
 	}
 
 	page, perPage := middleware.GetPaginationParams(c)
 
-	posts, pagination, err := h.postService.SearchPosts(query, page, perPage)
+	posts, pagination, err := h.postService.SearchPosts(c.Request.Context(), query, highlight, page, perPage)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to search posts",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to search posts")
 		return
 	}
 
+	projectPosts(c, posts)
+
 	c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success:    true,
 		Data:       posts,
-		Pagination: pagination,
+		Pagination: withPaginationLinks(c, pagination),
 	})
 }
 
@@ -385,37 +786,23 @@ func (h *PostHandler) SearchPosts(c *gin.Context) {
 func (h *PostHandler) PublishPost(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid post ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
 	isAdmin := middleware.IsAdmin(c)
 	post, err := h.postService.Publish(uint(id), userID, isAdmin)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "unauthorized: you can only publish your own posts" {
-			statusCode = http.StatusForbidden
-		} else if err.Error() == "post not found" {
-			statusCode = http.StatusNotFound
-		}
+		statusCode := errorStatus(err, http.StatusBadRequest)
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
 
@@ -426,58 +813,632 @@ func (h *PostHandler) PublishPost(c *gin.Context) {
 	})
 }
 
-// UnpublishPost godoc
-// @Summary Unpublish a post
-// @Description Unpublish a published post
+// FeaturePost godoc
+// @Summary Feature a post (Admin only)
+// @Description Pin a post among the featured posts. order ranks it, lowest
+// @Description first; featuring an already featured post moves it. Drafts can
+// @Description be featured ahead of time and are listed once published.
 // @Tags Posts
+// @Accept json
+// @Produce json
 // @Security BearerAuth
 // @Param id path int true "Post ID"
+// @Param request body models.PostFeatureRequest false "Featured order"
 // @Success 200 {object} models.APIResponse{data=models.PostResponse}
 // @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
 // @Failure 403 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
-// @Router /api/posts/{id}/unpublish [post]
-func (h *PostHandler) UnpublishPost(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
-			Success: false,
-			Error:   "User not authenticated",
-		})
-		return
-	}
-
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/admin/posts/{id}/feature [post]
+func (h *PostHandler) FeaturePost(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid post ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
-	isAdmin := middleware.IsAdmin(c)
-	post, err := h.postService.Unpublish(uint(id), userID, isAdmin)
-	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "unauthorized: you can only unpublish your own posts" {
-			statusCode = http.StatusForbidden
-		} else if err.Error() == "post not found" {
-			statusCode = http.StatusNotFound
+	var req models.PostFeatureRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respond.Error(c, http.StatusBadRequest, "Invalid request format")
+			return
 		}
+	}
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+	post, err := h.postService.Feature(uint(id), &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "Post unpublished successfully",
+		Message: "Post featured successfully",
 		Data:    post,
 	})
 }
+
+// UnfeaturePost godoc
+// @Summary Unfeature a post (Admin only)
+// @Description Take a post off the featured posts
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Success 200 {object} models.APIResponse{data=models.PostResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/admin/posts/{id}/unfeature [post]
+func (h *PostHandler) UnfeaturePost(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	post, err := h.postService.Unfeature(uint(id))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Post unfeatured successfully",
+		Data:    post,
+	})
+}
+
+// AddCoAuthor godoc
+// @Summary Add a co-author to a post
+// @Description Give another user edit rights on the post. Co-authors can
+// @Description update, publish, unpublish, archive and delete it, and are
+// @Description listed in co_authors. Only the post's author and admins can
+// @Description add co-authors.
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Param request body models.PostCoAuthorRequest true "Co-author"
+// @Success 200 {object} models.APIResponse{data=models.PostResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/posts/{id}/co-authors [post]
+func (h *PostHandler) AddCoAuthor(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var req models.PostCoAuthorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	post, err := h.postService.AddCoAuthor(uint(id), userID, middleware.IsAdmin(c), &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Co-author added successfully",
+		Data:    post,
+	})
+}
+
+// RemoveCoAuthor godoc
+// @Summary Remove a co-author from a post
+// @Description Take away a co-author's edit rights on the post. Only the
+// @Description post's author and admins can remove co-authors.
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Param user_id path int true "Co-author's user ID"
+// @Success 200 {object} models.APIResponse{data=models.PostResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/posts/{id}/co-authors/{user_id} [delete]
+func (h *PostHandler) RemoveCoAuthor(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	coAuthorID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	post, err := h.postService.RemoveCoAuthor(uint(id), userID, middleware.IsAdmin(c), uint(coAuthorID))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Co-author removed successfully",
+		Data:    post,
+	})
+}
+
+// CreatePreviewToken godoc
+// @Summary Share a post preview
+// @Description Create a token that lets anyone read the post, for example a
+// @Description draft sent to reviewers, by passing it as ?preview= when
+// @Description getting the post. It expires after JWT_PREVIEW_EXPIRES_IN.
+// @Description Only the post's author and admins can create one.
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Success 201 {object} models.APIResponse{data=models.PreviewTokenResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/posts/{id}/preview-token [post]
+func (h *PostHandler) CreatePreviewToken(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	preview, err := h.postService.CreatePreviewToken(uint(id), userID, middleware.IsAdmin(c))
+	if err != nil {
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		respond.Error(c, statusCode, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Preview token created successfully",
+		Data:    preview,
+	})
+}
+
+// previewPost returns the post a ?preview= token was minted for, provided
+// requested confirms it is the post asked for. A token for another post
+// finds nothing.
+func (h *PostHandler) previewPost(token string, requested func(post *models.PostResponse) bool) (*models.PostResponse, error) {
+	post, err := h.postService.GetByPreviewToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !requested(post) {
+		return nil, apperrors.NotFound("post not found")
+	}
+	return post, nil
+}
+
+// UnpublishPost godoc
+// @Summary Unpublish a post
+// @Description Unpublish a published post. An admin unpublishing someone
+// @Description else's post may give a reason, which is logged and emailed
+// @Description to the author.
+// @Tags Posts
+// @Accept json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Param request body models.PostModerationRequest false "Reason for the author"
+// @Success 200 {object} models.APIResponse{data=models.PostResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/posts/{id}/unpublish [post]
+func (h *PostHandler) UnpublishPost(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	req, ok := bindModerationRequest(c)
+	if !ok {
+		return
+	}
+
+	isAdmin := middleware.IsAdmin(c)
+	post, err := h.postService.Unpublish(uint(id), userID, isAdmin, req)
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Post unpublished successfully",
+		Data:    post,
+	})
+}
+
+// ArchivePost godoc
+// @Summary Archive a post
+// @Description Archive a post, hiding it from public listings, search and feeds
+// @Description while keeping its original publish date. An admin archiving
+// @Description someone else's post may give a reason, which is logged and
+// @Description emailed to the author.
+// @Tags Posts
+// @Accept json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Param request body models.PostModerationRequest false "Reason for the author"
+// @Success 200 {object} models.APIResponse{data=models.PostResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/posts/{id}/archive [post]
+func (h *PostHandler) ArchivePost(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	req, ok := bindModerationRequest(c)
+	if !ok {
+		return
+	}
+
+	isAdmin := middleware.IsAdmin(c)
+	post, err := h.postService.Archive(uint(id), userID, isAdmin, req)
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Post archived successfully",
+		Data:    post,
+	})
+}
+
+// bindModerationRequest reads the optional body of an unpublish or archive
+// request, answering 400 when one is sent but isn't valid JSON
+func bindModerationRequest(c *gin.Context) (*models.PostModerationRequest, bool) {
+	var req models.PostModerationRequest
+	if c.Request.ContentLength == 0 {
+		return &req, true
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
+		return nil, false
+	}
+	return &req, true
+}
+
+// GetPostModerationLog godoc
+// @Summary Get a post's moderation log (Admin only)
+// @Description List the times admins unpublished or archived the post, newest first, with the reasons given
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Post ID"
+// @Success 200 {object} models.APIResponse{data=[]models.PostModerationResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/admin/posts/{id}/moderation-log [get]
+func (h *PostHandler) GetPostModerationLog(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	entries, err := h.postService.GetModerationLog(uint(id))
+	if err != nil {
+		respondLookupError(c, err, "moderation log")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    entries,
+	})
+}
+
+// GetArchivedPosts godoc
+// @Summary Get archived posts
+// @Description Get the current user's archived posts. Admins see every
+// @Description author's archived posts and can filter by author_id.
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Param author_id query int false "Author ID filter (admin only)"
+// @Param excerpt_length query int false "Shorten excerpts to at most this many characters, on a word boundary"
+// @Success 200 {object} models.PaginatedResponse{data=[]models.PostListResponse}
+// @Failure 401 {object} models.APIResponse
+// @Router /api/posts/archived [get]
+func (h *PostHandler) GetArchivedPosts(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	page, perPage := middleware.GetPaginationParams(c)
+
+	authorID := userID
+	if middleware.IsAdmin(c) {
+		authorID = 0
+		if id, err := strconv.ParseUint(c.Query("author_id"), 10, 32); err == nil {
+			authorID = uint(id)
+		}
+	}
+
+	posts, pagination, err := h.postService.GetPosts(c.Request.Context(), models.PostFilter{Status: models.PostStatusArchived, AuthorID: authorID}, page, perPage)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve posts")
+		return
+	}
+
+	projectPosts(c, posts)
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       posts,
+		Pagination: withPaginationLinks(c, pagination),
+	})
+}
+
+// GetMyPosts godoc
+// @Summary Get my posts
+// @Description Get the current user's own posts in any status, newest first
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Post status filter" Enums(draft, published, archived, all) default(all)
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Param excerpt_length query int false "Shorten excerpts to at most this many characters, on a word boundary"
+// @Success 200 {object} models.PaginatedResponse{data=[]models.PostListResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Router /api/auth/posts [get]
+func (h *PostHandler) GetMyPosts(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var status models.PostStatus
+	switch statusStr := c.DefaultQuery("status", "all"); statusStr {
+	case "all":
+	case string(models.PostStatusDraft), string(models.PostStatusPublished), string(models.PostStatusArchived):
+		status = models.PostStatus(statusStr)
+	default:
+		respond.Error(c, http.StatusBadRequest, "Invalid status, expected one of all, draft, published or archived")
+		return
+	}
+
+	page, perPage := middleware.GetPaginationParams(c)
+
+	posts, pagination, err := h.postService.GetPosts(c.Request.Context(), models.PostFilter{Status: status, AuthorID: userID}, page, perPage)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve posts")
+		return
+	}
+
+	projectPosts(c, posts)
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       posts,
+		Pagination: withPaginationLinks(c, pagination),
+	})
+}
+
+// postETag builds a weak ETag from the post's ID and last update time. It is
+// weak because counters such as view_count change without touching UpdatedAt.
+func postETag(post *models.PostResponse) string {
+	etag := fmt.Sprintf("%d-%d-%d", post.ID, post.UpdatedAt.UnixNano(), post.CommentsCount)
+
+	// Embedded comments change the body without touching the post, so fold
+	// their count and latest edit into the tag
+	if post.Comments != nil {
+		var latest time.Time
+		for _, comment := range post.Comments {
+			if comment.UpdatedAt.After(latest) {
+				latest = comment.UpdatedAt
+			}
+		}
+		etag += fmt.Sprintf("-c%d-%d", len(post.Comments), latest.UnixNano())
+	}
+
+	// Neither do changes to the co-authors or the neighbouring posts in a
+	// series
+	for _, coAuthor := range post.CoAuthors {
+		etag += fmt.Sprintf("-a%d", coAuthor.ID)
+	}
+	if post.Series != nil {
+		etag += fmt.Sprintf("-s%d", post.Series.ID)
+		for _, link := range []*models.SeriesPostLink{post.Series.Previous, post.Series.Next} {
+			if link != nil {
+				etag += fmt.Sprintf("-%d", link.ID)
+			} else {
+				etag += "-0"
+			}
+		}
+	}
+
+	// Readers without the author-only fields get a different body
+	if post.ViewCount == nil {
+		etag += "-h"
+	}
+
+	return fmt.Sprintf(`W/"%s"`, etag)
+}
+
+// includeComments embeds the post's approved comments when the request asks
+// for them with ?include=comments. It writes an error response and reports
+// false if they can't be loaded.
+func (h *PostHandler) includeComments(c *gin.Context, post *models.PostResponse) bool {
+	for _, include := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(include) != "comments" {
+			continue
+		}
+
+		if err := h.postService.AttachComments(c.Request.Context(), post); err != nil {
+			respond.Error(c, http.StatusInternalServerError, "Failed to load comments")
+			return false
+		}
+		break
+	}
+	return true
+}
+
+// notModified sets the ETag header and, when the request's If-None-Match
+// matches it, responds with 304 Not Modified and reports true
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+
+	if middleware.ETagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.AbortWithStatus(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// PublishedPostsCacheTag tags the cached published post listings
+const PublishedPostsCacheTag = "posts:published"
+
+// PostCacheTag tags the cached responses showing the post with id
+func PostCacheTag(id uint) string {
+	return fmt.Sprintf("post:%d", id)
+}
+
+// postCacheTags tags a cached response showing post: the post itself, its
+// authors and tags and, for navigation, its series
+func postCacheTags(post *models.PostResponse) []string {
+	tags := []string{PostCacheTag(post.ID), UserCacheTag(post.AuthorID)}
+	for _, coAuthor := range post.CoAuthors {
+		tags = append(tags, UserCacheTag(coAuthor.ID))
+	}
+	for _, tag := range post.Tags {
+		tags = append(tags, TagCacheTag(tag.ID))
+	}
+	if post.Series != nil {
+		tags = append(tags, SeriesCacheTag(post.Series.ID))
+	}
+	return tags
+}
+
+// PostCacheTags lists the cached responses a post write invalidates: the
+// published listings and, on routes with an :id, that post's own responses
+func PostCacheTags(c *gin.Context) []string {
+	tags := []string{PublishedPostsCacheTag}
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 32); err == nil {
+		tags = append(tags, PostCacheTag(uint(id)))
+	}
+	return tags
+}
+
+// CountCachedView counts a view of a post served from the response cache,
+// as GetPost and GetPostBySlug would have
+func (h *PostHandler) CountCachedView(c *gin.Context, response *middleware.CachedResponse) {
+	for _, tag := range response.Tags {
+		idStr, isPost := strings.CutPrefix(tag, "post:")
+		if id, err := strconv.ParseUint(idStr, 10, 32); isPost && err == nil {
+			go h.postService.IncrementViewCount(uint(id))
+			return
+		}
+	}
+}
+
+// GetMyStats godoc
+// @Summary Get author statistics
+// @Description Get the authenticated user's post counts by status, total views of
+// @Description their published posts, approved comments received and comments written
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.AuthorStatsResponse}
+// @Failure 401 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /api/auth/stats [get]
+func (h *PostHandler) GetMyStats(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	stats, err := h.postService.GetAuthorStats(userID)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
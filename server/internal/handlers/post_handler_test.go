@@ -0,0 +1,1100 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/handlers"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockPostService is a mock implementation of the PostService interface
+type MockPostService struct {
+	mock.Mock
+}
+
+func (m *MockPostService) Create(authorID uint, req *models.PostCreateRequest) (*models.PostResponse, error) {
+	args := m.Called(authorID, req)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) Import(authorID uint, document string) (*models.PostResponse, error) {
+	args := m.Called(authorID, document)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) PreviewImport(document string) (*models.PostImportPreview, error) {
+	args := m.Called(document)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PostImportPreview), args.Error(1)
+}
+
+func (m *MockPostService) GetByID(ctx context.Context, id, viewerID uint, isAdmin bool) (*models.PostResponse, error) {
+	args := m.Called(id, viewerID, isAdmin)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) GetTags(ctx context.Context, postID, viewerID uint, isAdmin bool) ([]models.TagResponse, error) {
+	args := m.Called(postID, viewerID, isAdmin)
+	return args.Get(0).([]models.TagResponse), args.Error(1)
+}
+
+func (m *MockPostService) GetCommenters(ctx context.Context, postID, viewerID uint, isAdmin bool) ([]models.CommenterResponse, error) {
+	args := m.Called(postID, viewerID, isAdmin)
+	return args.Get(0).([]models.CommenterResponse), args.Error(1)
+}
+
+func (m *MockPostService) GetBySlug(ctx context.Context, slug string, viewerID uint, isAdmin bool) (*models.PostResponse, error) {
+	args := m.Called(slug, viewerID, isAdmin)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) Update(postID, authorID uint, req *models.PostUpdateRequest, isAdmin bool) (*models.PostResponse, error) {
+	args := m.Called(postID, authorID, req, isAdmin)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) Delete(postID, authorID uint, isAdmin bool) error {
+	args := m.Called(postID, authorID, isAdmin)
+	return args.Error(0)
+}
+
+func (m *MockPostService) GetPosts(ctx context.Context, filter models.PostFilter, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	args := m.Called(filter, page, perPage)
+	return args.Get(0).([]models.PostListResponse), args.Get(1).(models.PaginationMeta), args.Error(2)
+}
+
+func (m *MockPostService) GetPublishedPosts(ctx context.Context, sort models.PostSort, published models.PublishedRange, featuredFirst bool, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	args := m.Called(sort, published, featuredFirst, page, perPage)
+	return args.Get(0).([]models.PostListResponse), args.Get(1).(models.PaginationMeta), args.Error(2)
+}
+
+func (m *MockPostService) GetFeaturedPosts(ctx context.Context, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	args := m.Called(page, perPage)
+	return args.Get(0).([]models.PostListResponse), args.Get(1).(models.PaginationMeta), args.Error(2)
+}
+
+func (m *MockPostService) GetPublishedPostsByCursor(ctx context.Context, cursor string, published models.PublishedRange, perPage int) ([]models.PostListResponse, models.CursorPaginationMeta, error) {
+	args := m.Called(cursor, published, perPage)
+	return args.Get(0).([]models.PostListResponse), args.Get(1).(models.CursorPaginationMeta), args.Error(2)
+}
+
+func (m *MockPostService) GetArchive() ([]models.ArchiveCount, error) {
+	args := m.Called()
+	return args.Get(0).([]models.ArchiveCount), args.Error(1)
+}
+
+func (m *MockPostService) CreatePreviewToken(postID, userID uint, isAdmin bool) (*models.PreviewTokenResponse, error) {
+	args := m.Called(postID, userID, isAdmin)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PreviewTokenResponse), args.Error(1)
+}
+
+func (m *MockPostService) GetByPreviewToken(token string) (*models.PostResponse, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) AttachTag(tagID uint, req *models.TagPostsRequest) (*models.TagPostsResponse, error) {
+	args := m.Called(tagID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TagPostsResponse), args.Error(1)
+}
+
+func (m *MockPostService) DetachTag(tagID uint, req *models.TagPostsRequest) (*models.TagPostsResponse, error) {
+	args := m.Called(tagID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TagPostsResponse), args.Error(1)
+}
+
+func (m *MockPostService) GetLatestPublished(ctx context.Context, limit int) ([]models.PostResponse, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) GetPostsByAuthor(ctx context.Context, authorID uint, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	args := m.Called(authorID, page, perPage)
+	return args.Get(0).([]models.PostListResponse), args.Get(1).(models.PaginationMeta), args.Error(2)
+}
+
+func (m *MockPostService) GetPostsByTag(ctx context.Context, tagID uint, sort models.PostSort, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	args := m.Called(tagID, sort, page, perPage)
+	return args.Get(0).([]models.PostListResponse), args.Get(1).(models.PaginationMeta), args.Error(2)
+}
+
+func (m *MockPostService) SearchPosts(ctx context.Context, query string, highlight bool, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	args := m.Called(query, highlight, page, perPage)
+	return args.Get(0).([]models.PostListResponse), args.Get(1).(models.PaginationMeta), args.Error(2)
+}
+
+func (m *MockPostService) IncrementViewCount(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockPostService) AttachComments(ctx context.Context, post *models.PostResponse) error {
+	args := m.Called(post)
+	return args.Error(0)
+}
+
+func (m *MockPostService) Publish(postID, authorID uint, isAdmin bool) (*models.PostResponse, error) {
+	args := m.Called(postID, authorID, isAdmin)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) Feature(postID uint, req *models.PostFeatureRequest) (*models.PostResponse, error) {
+	args := m.Called(postID, req)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) Unfeature(postID uint) (*models.PostResponse, error) {
+	args := m.Called(postID)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) AddCoAuthor(postID, userID uint, isAdmin bool, req *models.PostCoAuthorRequest) (*models.PostResponse, error) {
+	args := m.Called(postID, userID, isAdmin, req)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) RemoveCoAuthor(postID, userID uint, isAdmin bool, coAuthorID uint) (*models.PostResponse, error) {
+	args := m.Called(postID, userID, isAdmin, coAuthorID)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) Unpublish(postID, authorID uint, isAdmin bool, req *models.PostModerationRequest) (*models.PostResponse, error) {
+	args := m.Called(postID, authorID, isAdmin, req)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) Archive(postID, authorID uint, isAdmin bool, req *models.PostModerationRequest) (*models.PostResponse, error) {
+	args := m.Called(postID, authorID, isAdmin, req)
+	return args.Get(0).(*models.PostResponse), args.Error(1)
+}
+
+func (m *MockPostService) GetModerationLog(postID uint) ([]models.PostModerationResponse, error) {
+	args := m.Called(postID)
+	return args.Get(0).([]models.PostModerationResponse), args.Error(1)
+}
+
+func (m *MockPostService) GetAuthorStats(authorID uint) (*models.AuthorStatsResponse, error) {
+	args := m.Called(authorID)
+	return args.Get(0).(*models.AuthorStatsResponse), args.Error(1)
+}
+
+// newPostTestContext creates a gin context for calling a post handler directly
+func newPostTestContext(method, target string, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(method, target, nil)
+	c.Params = params
+
+	// Defaults normally set by PaginationMiddleware
+	c.Set("page", 1)
+	c.Set("per_page", 10)
+	return c, w
+}
+
+func TestPostHandler_GetPost_ETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := &models.PostResponse{
+		ID:        1,
+		Title:     "Conditional requests",
+		Slug:      "conditional-requests",
+		Status:    models.PostStatusPublished,
+		UpdatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("full response sets ETag", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+
+		mockService.On("GetByID", uint(1), uint(0), false).Return(post, nil)
+		mockService.On("IncrementViewCount", uint(1)).Return(nil).Maybe()
+
+		c, w := newPostTestContext("GET", "/api/v1/posts/1", gin.Params{{Key: "id", Value: "1"}})
+		handler.GetPost(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NotEmpty(t, w.Header().Get("ETag"))
+		require.Contains(t, w.Header().Get("ETag"), "W/")
+	})
+
+	t.Run("matching If-None-Match returns 304 without counting a view", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+
+		mockService.On("GetByID", uint(1), uint(0), false).Return(post, nil)
+		mockService.On("IncrementViewCount", uint(1)).Return(nil).Maybe()
+
+		// First request to obtain the ETag
+		c, w := newPostTestContext("GET", "/api/v1/posts/1", gin.Params{{Key: "id", Value: "1"}})
+		handler.GetPost(c)
+		etag := w.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		// Conditional request with the ETag
+		freshService := new(MockPostService)
+		handler = handlers.NewPostHandler(freshService)
+		freshService.On("GetByID", uint(1), uint(0), false).Return(post, nil)
+
+		c, w = newPostTestContext("GET", "/api/v1/posts/1", gin.Params{{Key: "id", Value: "1"}})
+		c.Request.Header.Set("If-None-Match", etag)
+		handler.GetPost(c)
+
+		require.Equal(t, http.StatusNotModified, w.Code)
+		require.Empty(t, w.Body.String())
+		require.Equal(t, etag, w.Header().Get("ETag"))
+		freshService.AssertNotCalled(t, "IncrementViewCount", mock.Anything)
+	})
+
+	t.Run("stale If-None-Match returns full response", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+
+		mockService.On("GetByID", uint(1), uint(0), false).Return(post, nil)
+		mockService.On("IncrementViewCount", uint(1)).Return(nil).Maybe()
+
+		c, w := newPostTestContext("GET", "/api/v1/posts/1", gin.Params{{Key: "id", Value: "1"}})
+		c.Request.Header.Set("If-None-Match", `W/"1-0"`)
+		handler.GetPost(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestPostHandler_GetPostBySlug_ETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := &models.PostResponse{
+		ID:        2,
+		Title:     "Slug lookups",
+		Slug:      "slug-lookups",
+		Status:    models.PostStatusPublished,
+		UpdatedAt: time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+	}
+
+	mockService := new(MockPostService)
+	handler := handlers.NewPostHandler(mockService)
+	mockService.On("GetBySlug", "slug-lookups", uint(0), false).Return(post, nil)
+	mockService.On("IncrementViewCount", uint(2)).Return(nil).Maybe()
+
+	c, w := newPostTestContext("GET", "/api/v1/posts/slug/slug-lookups", gin.Params{{Key: "slug", Value: "slug-lookups"}})
+	handler.GetPostBySlug(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	c, w = newPostTestContext("GET", "/api/v1/posts/slug/slug-lookups", gin.Params{{Key: "slug", Value: "slug-lookups"}})
+	c.Request.Header.Set("If-None-Match", etag)
+	handler.GetPostBySlug(c)
+	require.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestPostHandler_GetPostBySlug_Canonical(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := &models.PostResponse{ID: 4, Title: "Retitled", Slug: "retitled", Status: models.PostStatusPublished}
+
+	mockService := new(MockPostService)
+	handler := handlers.NewPostHandler(mockService)
+	mockService.On("GetBySlug", "original-title", uint(0), false).Return(post, nil)
+	mockService.On("GetBySlug", "retitled", uint(0), false).Return(post, nil)
+	mockService.On("IncrementViewCount", uint(4)).Return(nil).Maybe()
+
+	c, w := newPostTestContext("GET", "/api/v1/posts/slug/original-title", gin.Params{{Key: "slug", Value: "original-title"}})
+	handler.GetPostBySlug(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `</api/v1/posts/slug/retitled>; rel="canonical"`, w.Header().Get("Link"))
+
+	c, w = newPostTestContext("GET", "/api/v1/posts/slug/retitled", gin.Params{{Key: "slug", Value: "retitled"}})
+	handler.GetPostBySlug(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Link"))
+
+	t.Run("deprecated prefix keeps both links", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/api/posts/slug/:slug", middleware.DeprecationMiddleware("/api/v1"), handler.GetPostBySlug)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/posts/slug/original-title", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.ElementsMatch(t, []string{
+			`</api/v1>; rel="successor-version"`,
+			`</api/posts/slug/retitled>; rel="canonical"`,
+		}, w.Header().Values("Link"))
+	})
+}
+
+func TestPostHandler_GetPost_IncludeComments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newPost := func() *models.PostResponse {
+		return &models.PostResponse{
+			ID:            3,
+			Title:         "Embedded comments",
+			Status:        models.PostStatusPublished,
+			CommentsCount: 1,
+			UpdatedAt:     time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC),
+		}
+	}
+
+	t.Run("comments are only loaded when requested", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+		mockService.On("GetByID", uint(3), uint(0), false).Return(newPost(), nil)
+		mockService.On("IncrementViewCount", uint(3)).Return(nil).Maybe()
+
+		c, w := newPostTestContext("GET", "/api/v1/posts/3", gin.Params{{Key: "id", Value: "3"}})
+		handler.GetPost(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NotContains(t, w.Body.String(), `"comments":`)
+		mockService.AssertNotCalled(t, "AttachComments", mock.Anything)
+	})
+
+	t.Run("include=comments embeds comments and changes the ETag", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+		mockService.On("GetByID", uint(3), uint(0), false).Return(newPost(), nil)
+		mockService.On("IncrementViewCount", uint(3)).Return(nil).Maybe()
+		mockService.On("AttachComments", mock.AnythingOfType("*models.PostResponse")).Run(func(args mock.Arguments) {
+			post := args.Get(0).(*models.PostResponse)
+			post.Comments = []models.CommentResponse{{ID: 7, Content: "Nice post", UpdatedAt: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)}}
+		}).Return(nil)
+
+		c, w := newPostTestContext("GET", "/api/v1/posts/3", gin.Params{{Key: "id", Value: "3"}})
+		handler.GetPost(c)
+		plainETag := w.Header().Get("ETag")
+
+		c, w = newPostTestContext("GET", "/api/v1/posts/3?include=comments", gin.Params{{Key: "id", Value: "3"}})
+		handler.GetPost(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"content":"Nice post"`)
+		require.NotEqual(t, plainETag, w.Header().Get("ETag"))
+		mockService.AssertNumberOfCalls(t, "AttachComments", 1)
+	})
+}
+
+func TestPostHandler_UpdatePost_ErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"wrapped not found", fmt.Errorf("failed to update post: %w", apperrors.NotFound("post not found")), http.StatusNotFound},
+		{"forbidden", apperrors.Forbidden("unauthorized: you can only update your own posts"), http.StatusForbidden},
+		{"validation", &service.ValidationError{Fields: []models.ValidationError{{Field: "Title", Tag: "required", Message: "Title is required"}}}, http.StatusUnprocessableEntity},
+		{"bad request", apperrors.BadRequest("invalid cursor"), http.StatusBadRequest},
+		{"untyped", errors.New("something else"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPostService)
+			handler := handlers.NewPostHandler(mockService)
+			mockService.On("Update", uint(1), uint(2), mock.AnythingOfType("*models.PostUpdateRequest"), false).
+				Return((*models.PostResponse)(nil), tt.err)
+
+			c, w := newPostTestContext("PUT", "/api/v1/posts/1", gin.Params{{Key: "id", Value: "1"}})
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(`{"title":"Updated title"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", uint(2))
+
+			handler.UpdatePost(c)
+
+			require.Equal(t, tt.status, w.Code)
+			require.Contains(t, w.Body.String(), tt.err.Error())
+		})
+	}
+}
+
+func TestPostHandler_GetPost_LookupErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("missing post is 404", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+		mockService.On("GetByID", uint(9), uint(0), false).Return((*models.PostResponse)(nil), apperrors.NotFound("post not found"))
+
+		c, w := newPostTestContext("GET", "/api/v1/posts/9", gin.Params{{Key: "id", Value: "9"}})
+		handler.GetPost(c)
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("database failure is 500", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+		mockService.On("GetBySlug", "broken", uint(0), false).Return((*models.PostResponse)(nil), errors.New("driver: bad connection"))
+
+		c, w := newPostTestContext("GET", "/api/v1/posts/slug/broken", gin.Params{{Key: "slug", Value: "broken"}})
+		handler.GetPostBySlug(c)
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.NotContains(t, w.Body.String(), "bad connection")
+	})
+}
+
+func TestPostHandler_GetPost_PassesViewer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockPostService)
+	handler := handlers.NewPostHandler(mockService)
+	draft := &models.PostResponse{ID: 4, Status: models.PostStatusDraft}
+	mockService.On("GetByID", uint(4), uint(5), true).Return(draft, nil)
+
+	c, w := newPostTestContext("GET", "/api/v1/posts/4", gin.Params{{Key: "id", Value: "4"}})
+	c.Set("user_id", uint(5))
+	c.Set("is_admin", true)
+	handler.GetPost(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestPostHandler_GetPosts_Visibility(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("anonymous listing is limited to published posts", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+		mockService.On("GetPosts", models.PostFilter{Status: models.PostStatusPublished}, 1, 10).
+			Return([]models.PostListResponse{}, models.PaginationMeta{}, nil)
+
+		c, w := newPostTestContext("GET", "/api/v1/posts", nil)
+		handler.GetPosts(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("other authors' archived posts are forbidden", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+
+		c, w := newPostTestContext("GET", "/api/v1/posts?status=archived&author_id=3", nil)
+		c.Set("user_id", uint(5))
+		handler.GetPosts(c)
+
+		require.Equal(t, http.StatusForbidden, w.Code)
+		mockService.AssertNotCalled(t, "GetPosts", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("authors can list their own archived posts", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+		mockService.On("GetPosts", models.PostFilter{Status: models.PostStatusArchived, AuthorID: 5}, mock.Anything, mock.Anything).
+			Return([]models.PostListResponse{}, models.PaginationMeta{}, nil)
+
+		c, w := newPostTestContext("GET", "/api/v1/posts?status=archived&author_id=5", nil)
+		c.Set("user_id", uint(5))
+		handler.GetPosts(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("published=false is private like the statuses it stands for", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+
+		c, w := newPostTestContext("GET", "/api/v1/posts?published=false", nil)
+		handler.GetPosts(c)
+
+		require.Equal(t, http.StatusForbidden, w.Code)
+		mockService.AssertNotCalled(t, "GetPosts", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("published range", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+		after := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+		before := time.Date(2024, time.March, 31, 23, 59, 59, 0, time.UTC)
+		published := true
+		mockService.On("GetPosts", models.PostFilter{
+			Status:         models.PostStatusPublished,
+			Published:      &published,
+			PublishedRange: models.PublishedRange{After: &after, Before: &before},
+		}, 1, 10).Return([]models.PostListResponse{}, models.PaginationMeta{}, nil)
+
+		c, w := newPostTestContext("GET", "/api/v1/posts?published=true&published_after=2024-03-01T00:00:00Z&published_before=2024-03-31T23:59:59Z", nil)
+		handler.GetPosts(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	for _, query := range []string{"published_after=2024-03-01", "published_before=yesterday", "published=maybe", "status=draft&published=true"} {
+		t.Run("rejects "+query, func(t *testing.T) {
+			mockService := new(MockPostService)
+			handler := handlers.NewPostHandler(mockService)
+
+			c, w := newPostTestContext("GET", "/api/v1/posts?"+query, nil)
+			handler.GetPosts(c)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "GetPosts", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPostHandler_GetPublishedPosts_ExcerptLength(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	posts := []models.PostListResponse{
+		{ID: 1, Excerpt: "Go makes concurrency approachable with goroutines and channels"},
+		{ID: 2, Excerpt: "Short teaser"},
+	}
+
+	list := func(excerptLength int) []models.PostListResponse {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+		// The handler shortens the service's slice in place, so hand it a copy
+		page := append([]models.PostListResponse(nil), posts...)
+		mockService.On("GetPublishedPosts", models.PostSort(""), models.PublishedRange{}, false, 1, 10).
+			Return(page, models.PaginationMeta{}, nil)
+
+		c, w := newPostTestContext("GET", "/api/v1/posts/published", nil)
+		if excerptLength > 0 {
+			c.Set("excerpt_length", excerptLength)
+		}
+		handler.GetPublishedPosts(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var body struct {
+			Data []models.PostListResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body.Data
+	}
+
+	t.Run("truncated on a word boundary", func(t *testing.T) {
+		got := list(30)
+		assert.Equal(t, "Go makes concurrency...", got[0].Excerpt)
+		assert.LessOrEqual(t, len([]rune(got[0].Excerpt)), 30)
+		assert.Equal(t, "Short teaser", got[1].Excerpt)
+	})
+
+	t.Run("stored excerpt by default", func(t *testing.T) {
+		got := list(0)
+		assert.Equal(t, posts[0].Excerpt, got[0].Excerpt)
+	})
+}
+
+func TestPostHandler_GetPublishedPosts_PaginationLinks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		page  int
+		links models.PaginationLinks
+	}{
+		{"first page", 1, models.PaginationLinks{
+			First: "/api/v1/posts/published?page=1&per_page=10",
+			Last:  "/api/v1/posts/published?page=3&per_page=10",
+			Next:  "/api/v1/posts/published?page=2&per_page=10",
+		}},
+		{"middle page", 2, models.PaginationLinks{
+			First: "/api/v1/posts/published?page=1&per_page=10",
+			Last:  "/api/v1/posts/published?page=3&per_page=10",
+			Next:  "/api/v1/posts/published?page=3&per_page=10",
+			Prev:  "/api/v1/posts/published?page=1&per_page=10",
+		}},
+		{"last page", 3, models.PaginationLinks{
+			First: "/api/v1/posts/published?page=1&per_page=10",
+			Last:  "/api/v1/posts/published?page=3&per_page=10",
+			Prev:  "/api/v1/posts/published?page=2&per_page=10",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPostService)
+			handler := handlers.NewPostHandler(mockService)
+			mockService.On("GetPublishedPosts", models.PostSort(""), models.PublishedRange{}, false, tt.page, 10).
+				Return([]models.PostListResponse{}, utils.CalculatePagination(tt.page, 10, 25), nil)
+
+			c, w := newPostTestContext("GET", fmt.Sprintf("/api/v1/posts/published?per_page=10&page=%d", tt.page), nil)
+			c.Set("page", tt.page)
+			handler.GetPublishedPosts(c)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var body struct {
+				Pagination models.PaginationMeta `json:"pagination"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.page < 3, body.Pagination.HasNext)
+			assert.Equal(t, tt.page > 1, body.Pagination.HasPrev)
+			require.NotNil(t, body.Pagination.Links)
+			assert.Equal(t, tt.links, *body.Pagination.Links)
+		})
+	}
+}
+
+func TestPostHandler_GetMyPosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query  string
+		status models.PostStatus
+	}{
+		{"", ""},
+		{"?status=all", ""},
+		{"?status=draft", models.PostStatusDraft},
+		{"?status=published", models.PostStatusPublished},
+		{"?status=archived", models.PostStatusArchived},
+	}
+
+	for _, tt := range tests {
+		t.Run("status"+tt.query, func(t *testing.T) {
+			mockService := new(MockPostService)
+			handler := handlers.NewPostHandler(mockService)
+			mockService.On("GetPosts", models.PostFilter{Status: tt.status, AuthorID: 5}, 1, 10).
+				Return([]models.PostListResponse{}, models.PaginationMeta{}, nil)
+
+			c, w := newPostTestContext("GET", "/api/v1/auth/posts"+tt.query, nil)
+			c.Set("user_id", uint(5))
+			handler.GetMyPosts(c)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+
+	t.Run("invalid status", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := handlers.NewPostHandler(mockService)
+
+		c, w := newPostTestContext("GET", "/api/v1/auth/posts?status=deleted", nil)
+		c.Set("user_id", uint(5))
+		handler.GetMyPosts(c)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "GetPosts", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		handler := handlers.NewPostHandler(new(MockPostService))
+
+		c, w := newPostTestContext("GET", "/api/v1/auth/posts", nil)
+		handler.GetMyPosts(c)
+
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestPostHandler_CreatePost_Location(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, prefix := range []string{"/api/v1", "/api"} {
+		t.Run(prefix, func(t *testing.T) {
+			mockService := new(MockPostService)
+			handler := handlers.NewPostHandler(mockService)
+			mockService.On("Create", uint(5), mock.AnythingOfType("*models.PostCreateRequest")).
+				Return(&models.PostResponse{ID: 42}, nil)
+
+			c, w := newPostTestContext("POST", prefix+"/posts", nil)
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(`{"title":"Located","content":"Somewhere","status":"draft"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", uint(5))
+			handler.CreatePost(c)
+
+			require.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, prefix+"/posts/42", w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestPostHandler_PutVsPatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		method       string
+		handle       func(*handlers.PostHandler, *gin.Context)
+		clearExcerpt bool
+	}{
+		{"PUT ignores empty fields", "PUT", (*handlers.PostHandler).UpdatePost, false},
+		{"PATCH clears empty fields", "PATCH", (*handlers.PostHandler).PatchPost, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPostService)
+			handler := handlers.NewPostHandler(mockService)
+			mockService.On("Update", uint(1), uint(2), mock.MatchedBy(func(req *models.PostUpdateRequest) bool {
+				if req.Title != nil || req.Content != nil {
+					return false
+				}
+				if tt.clearExcerpt {
+					return req.Excerpt != nil && *req.Excerpt == ""
+				}
+				return req.Excerpt == nil
+			}), false).Return(&models.PostResponse{ID: 1}, nil)
+
+			c, w := newPostTestContext(tt.method, "/api/v1/posts/1", gin.Params{{Key: "id", Value: "1"}})
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(`{"excerpt":"","title":null}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", uint(2))
+			tt.handle(handler, c)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostHandler_AuthorOnlyFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "fieldauthor")
+	coAuthor := testutil.CreateUser(t, db, "fieldcoauthor")
+	reader := testutil.CreateUser(t, db, "fieldreader")
+	admin := testutil.CreateUser(t, db, "fieldadmin")
+
+	now := time.Now()
+	published := &models.Post{Title: "Published post", Slug: "published-post", Content: "Out in the open", Status: models.PostStatusPublished, AuthorID: author.ID, PublishedAt: &now}
+	require.NoError(t, db.Create(published).Error)
+	draft := &models.Post{Title: "Draft post", Slug: "draft-post", Content: "Work in progress", Status: models.PostStatusDraft, AuthorID: author.ID}
+	require.NoError(t, db.Create(draft).Error)
+	postRepo := repository.NewPostRepository(db)
+	for _, post := range []*models.Post{published, draft} {
+		require.NoError(t, postRepo.AddCoAuthor(post.ID, coAuthor.ID))
+	}
+
+	postService := service.NewPostService(postRepo, repository.NewTagRepository(db), repository.NewCommentRepository(db), webhook.NewDispatcher(config.WebhookConfig{}), mailer.New(config.MailConfig{}), config.ContentConfig{}, config.JWTConfig{}, nil)
+	handler := handlers.NewPostHandler(postService)
+
+	type viewer struct {
+		name    string
+		userID  uint
+		isAdmin bool
+	}
+	anonymous := viewer{name: "anonymous"}
+	viewers := []viewer{anonymous, {"author", author.ID, false}, {"co-author", coAuthor.ID, false}, {"other reader", reader.ID, false}, {"admin", admin.ID, true}}
+
+	// request serves the handler to viewer, with author-only fields on or off
+	request := func(v viewer, authorOnly bool, target string, params gin.Params, serve gin.HandlerFunc) *httptest.ResponseRecorder {
+		c, w := newPostTestContext(http.MethodGet, target, params)
+		if v.userID != 0 {
+			c.Set("user_id", v.userID)
+			c.Set("is_admin", v.isAdmin)
+		}
+		c.Set("author_only_fields", authorOnly)
+		serve(c)
+		return w
+	}
+
+	for _, v := range viewers {
+		shown := v.userID == author.ID || v.userID == coAuthor.ID || v.isAdmin
+		t.Run(v.name, func(t *testing.T) {
+			for _, post := range []*models.Post{published, draft} {
+				w := request(v, true, fmt.Sprintf("/api/v1/posts/%d", post.ID), gin.Params{{Key: "id", Value: fmt.Sprint(post.ID)}}, handler.GetPost)
+				if post.Status == models.PostStatusDraft && !shown {
+					assert.Equal(t, http.StatusNotFound, w.Code, "drafts stay hidden")
+					continue
+				}
+				require.Equal(t, http.StatusOK, w.Code)
+				var body struct {
+					Data map[string]interface{} `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, shown, body.Data["status"] != nil, "status of %s", post.Title)
+				assert.Equal(t, shown, body.Data["view_count"] != nil, "view_count of %s", post.Title)
+			}
+
+			w := request(v, true, "/api/v1/posts/published", nil, handler.GetPublishedPosts)
+			require.Equal(t, http.StatusOK, w.Code)
+			var list struct {
+				Data []map[string]interface{} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+			require.Len(t, list.Data, 1)
+			assert.Equal(t, shown, list.Data[0]["view_count"] != nil)
+			assert.Equal(t, shown, list.Data[0]["status"] != nil)
+		})
+	}
+
+	t.Run("everyone sees them by default", func(t *testing.T) {
+		w := request(anonymous, false, fmt.Sprintf("/api/v1/posts/%d", published.ID), gin.Params{{Key: "id", Value: fmt.Sprint(published.ID)}}, handler.GetPost)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"view_count":`)
+		assert.Contains(t, w.Body.String(), `"status":"published"`)
+	})
+}
+
+func TestPostHandler_PreviewToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "previewer")
+	reviewer := testutil.CreateUser(t, db, "reviewer")
+	var drafts []*models.Post
+	for _, title := range []string{"Shared draft", "Other draft"} {
+		draft := &models.Post{Title: title, Slug: utils.GenerateSlug(title), Content: "Not ready for everyone", Status: models.PostStatusDraft, AuthorID: author.ID}
+		require.NoError(t, db.Create(draft).Error)
+		drafts = append(drafts, draft)
+	}
+
+	newPostService := func(lifetime time.Duration) service.PostService {
+		return service.NewPostService(repository.NewPostRepository(db), repository.NewTagRepository(db), repository.NewCommentRepository(db),
+			webhook.NewDispatcher(config.WebhookConfig{}), mailer.New(config.MailConfig{}), config.ContentConfig{}, config.JWTConfig{Secret: "test-secret-key", PreviewExpiresIn: lifetime}, nil)
+	}
+	handler := handlers.NewPostHandler(newPostService(time.Hour))
+
+	// mint creates a preview token for post as userID
+	mint := func(h *handlers.PostHandler, post *models.Post, userID uint) (int, string) {
+		c, w := newPostTestContext(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/preview-token", post.ID), gin.Params{{Key: "id", Value: fmt.Sprint(post.ID)}})
+		c.Set("user_id", userID)
+		h.CreatePreviewToken(c)
+
+		var body struct {
+			Data models.PreviewTokenResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body.Data.Token
+	}
+	getByID := func(post *models.Post, token string) int {
+		c, w := newPostTestContext(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d?preview=%s", post.ID, token), gin.Params{{Key: "id", Value: fmt.Sprint(post.ID)}})
+		handler.GetPost(c)
+		return w.Code
+	}
+	getBySlug := func(post *models.Post, token string) int {
+		c, w := newPostTestContext(http.MethodGet, fmt.Sprintf("/api/v1/posts/slug/%s?preview=%s", post.Slug, token), gin.Params{{Key: "slug", Value: post.Slug}})
+		handler.GetPostBySlug(c)
+		return w.Code
+	}
+
+	code, token := mint(handler, drafts[0], author.ID)
+	require.Equal(t, http.StatusCreated, code)
+	require.NotEmpty(t, token)
+
+	t.Run("valid token reads the draft", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, getByID(drafts[0], token))
+		assert.Equal(t, http.StatusOK, getBySlug(drafts[0], token))
+		assert.Equal(t, http.StatusNotFound, getByID(drafts[0], ""), "drafts stay hidden without it")
+	})
+
+	t.Run("token for a different post", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, getByID(drafts[1], token))
+		assert.Equal(t, http.StatusNotFound, getBySlug(drafts[1], token))
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		code, expired := mint(handlers.NewPostHandler(newPostService(-time.Minute)), drafts[0], author.ID)
+		require.Equal(t, http.StatusCreated, code)
+
+		assert.Equal(t, http.StatusUnauthorized, getByID(drafts[0], expired))
+		assert.Equal(t, http.StatusUnauthorized, getBySlug(drafts[0], expired))
+	})
+
+	t.Run("only the author can share a preview", func(t *testing.T) {
+		code, _ := mint(handler, drafts[0], reviewer.ID)
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+}
+
+func TestPostHandler_ResponseCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := &models.PostResponse{ID: 1, Title: "Cached", Slug: "cached", Status: models.PostStatusPublished}
+	mockService := new(MockPostService)
+	mockService.On("GetByID", uint(1), uint(0), false).Return(post, nil)
+	mockService.On("GetBySlug", "cached", uint(0), false).Return(post, nil)
+	mockService.On("GetPublishedPosts", models.PostSort(""), models.PublishedRange{}, false, 1, 10).
+		Return([]models.PostListResponse{{ID: 1, Title: "Cached"}}, models.PaginationMeta{Page: 1, PerPage: 10, Total: 1, TotalPages: 1}, nil)
+	mockService.On("IncrementViewCount", uint(1)).Return(nil).Maybe()
+	mockService.On("Publish", uint(1), uint(7), false).Return(post, nil)
+
+	// Wired like the router's public and protected post routes
+	handler := handlers.NewPostHandler(mockService)
+	cache := middleware.NewResponseCache(middleware.NewMemoryResponseCacheStore(), time.Minute)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("page", 1)
+		c.Set("per_page", 10)
+		c.Next()
+	})
+	router.GET("/posts/published", cache.Middleware(nil), handler.GetPublishedPosts)
+	router.GET("/posts/:id", cache.Middleware(handler.CountCachedView), handler.GetPost)
+	router.GET("/posts/slug/:slug", cache.Middleware(handler.CountCachedView), handler.GetPostBySlug)
+	router.POST("/posts/:id/publish", func(c *gin.Context) {
+		c.Set("user_id", uint(7))
+		c.Next()
+	}, cache.Invalidate(handlers.PostCacheTags), handler.PublishPost)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+	targets := []string{"/posts/1", "/posts/slug/cached", "/posts/published"}
+
+	for _, target := range targets {
+		get(target)
+		assert.Equal(t, "HIT", get(target).Header().Get("X-Cache"), target)
+	}
+	mockService.AssertNumberOfCalls(t, "GetByID", 1)
+	mockService.AssertNumberOfCalls(t, "GetBySlug", 1)
+	mockService.AssertNumberOfCalls(t, "GetPublishedPosts", 1)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/posts/1/publish", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for _, target := range targets {
+		assert.Empty(t, get(target).Header().Get("X-Cache"), target)
+	}
+	mockService.AssertNumberOfCalls(t, "GetByID", 2)
+	mockService.AssertNumberOfCalls(t, "GetBySlug", 2)
+	mockService.AssertNumberOfCalls(t, "GetPublishedPosts", 2)
+}
+
+func TestPostHandler_ResponseCache_RelatedWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "cachedauthor")
+	tag := &models.Tag{Name: "Cached", Slug: "cached"}
+	require.NoError(t, db.Create(tag).Error)
+	now := time.Now()
+	post := &models.Post{Title: "Cached post", Slug: "cached-post", Content: "Served from memory", Status: models.PostStatusPublished, AuthorID: author.ID, PublishedAt: &now, Tags: []models.Tag{*tag}}
+	require.NoError(t, db.Create(post).Error)
+
+	postService := service.NewPostService(repository.NewPostRepository(db), repository.NewTagRepository(db), repository.NewCommentRepository(db), webhook.NewDispatcher(config.WebhookConfig{}), mailer.New(config.MailConfig{}), config.ContentConfig{}, config.JWTConfig{}, nil)
+	commentService := service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), webhook.NewDispatcher(config.WebhookConfig{}), config.CommentConfig{})
+	postHandler := handlers.NewPostHandler(postService)
+	commentHandler := handlers.NewCommentHandler(commentService)
+	tagHandler := handlers.NewTagHandler(service.NewTagService(repository.NewTagRepository(db), 0), postService)
+	other := &models.Tag{Name: "Fresh", Slug: "fresh"}
+	require.NoError(t, db.Create(other).Error)
+
+	// Wired like the router, with stand-ins for the tag and user handlers
+	cache := middleware.NewResponseCache(middleware.NewMemoryResponseCacheStore(), time.Minute)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := gin.New()
+	router.GET("/posts/:id", cache.Middleware(postHandler.CountCachedView), postHandler.GetPost)
+	router.POST("/comments", func(c *gin.Context) {
+		c.Set("user_id", author.ID)
+		c.Next()
+	}, cache.Invalidate(handlers.CommentCacheTags), commentHandler.CreateComment)
+	router.DELETE("/comments/:id", func(c *gin.Context) {
+		c.Set("user_id", author.ID)
+		c.Next()
+	}, cache.Invalidate(handlers.CommentCacheTags), commentHandler.DeleteComment)
+	router.PUT("/tags/:id", cache.Invalidate(handlers.TagCacheTags), ok)
+	router.POST("/tags/:id/attach", cache.Invalidate(handlers.TagCacheTags), tagHandler.AttachTagToPosts)
+	router.DELETE("/users/:id", cache.Invalidate(handlers.UserCacheTags), ok)
+	router.POST("/users/:id/deactivate", cache.Invalidate(handlers.UserCacheTags), ok)
+	router.PUT("/profile", func(c *gin.Context) {
+		c.Set("user_id", author.ID)
+		c.Next()
+	}, cache.Invalidate(handlers.UserCacheTags), ok)
+
+	target := fmt.Sprintf("/posts/%d", post.ID)
+	cached := func() bool {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Header().Get("X-Cache") == "HIT"
+	}
+	write := func(method, path, body string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Less(t, w.Code, 300, w.Body.String())
+	}
+
+	for _, tc := range []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		dropped bool
+	}{
+		{"comment on the post", http.MethodPost, "/comments", fmt.Sprintf(`{"content":"First!","post_id":%d}`, post.ID), true},
+		{"rename of its tag", http.MethodPut, fmt.Sprintf("/tags/%d", tag.ID), "", true},
+		{"rename of another tag", http.MethodPut, fmt.Sprintf("/tags/%d", other.ID+1), "", false},
+		{"a tag attached to it", http.MethodPost, fmt.Sprintf("/tags/%d/attach", other.ID), fmt.Sprintf(`{"post_ids":[%d]}`, post.ID), true},
+		{"its author's profile update", http.MethodPut, "/profile", "", true},
+		{"deactivation of its author", http.MethodPost, fmt.Sprintf("/users/%d/deactivate", author.ID), "", true},
+		{"deletion of its author", http.MethodDelete, fmt.Sprintf("/users/%d", author.ID), "", true},
+		{"deletion of another user", http.MethodDelete, fmt.Sprintf("/users/%d", author.ID+1), "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cached()
+			require.True(t, cached())
+
+			write(tc.method, tc.path, tc.body)
+			assert.Equal(t, !tc.dropped, cached())
+		})
+	}
+
+	t.Run("deletion of an approved comment", func(t *testing.T) {
+		var comment models.Comment
+		require.NoError(t, db.Where("post_id = ? AND status = ?", post.ID, models.CommentStatusApproved).First(&comment).Error)
+		read := func() (*httptest.ResponseRecorder, int) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target+"?include=comments", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+			var body struct {
+				Data models.PostResponse `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			return w, body.Data.CommentsCount
+		}
+		read()
+		w, count := read()
+		require.Equal(t, "HIT", w.Header().Get("X-Cache"))
+		require.Equal(t, 1, count)
+
+		write(http.MethodDelete, fmt.Sprintf("/comments/%d", comment.ID), "")
+		w, count = read()
+		assert.Empty(t, w.Header().Get("X-Cache"))
+		assert.Zero(t, count)
+	})
+}
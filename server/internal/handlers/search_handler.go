@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+)
+
+type SearchHandler struct {
+	searchService service.SearchService
+}
+
+func NewSearchHandler(searchService service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+	}
+}
+
+// Search godoc
+// @Summary Search posts, tags and users
+// @Description Search published posts, tags and active users at once. Results
+// @Description are grouped by type; each section is paginated separately with
+// @Description the same page and per_page. Sections that aren't requested
+// @Description with type are omitted.
+// @Tags Search
+// @Produce json
+// @Param q query string true "Search query"
+// @Param type query string false "Comma-separated sections to search: posts, tags, users (default all)"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per section" default(10)
+// @Success 200 {object} models.APIResponse{data=models.SearchResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /api/search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		respond.Error(c, http.StatusBadRequest, "Search query is required")
+		return
+	}
+
+	var types []models.SearchType
+	for _, t := range strings.Split(c.Query("type"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, models.SearchType(t))
+		}
+	}
+
+	page, perPage := middleware.GetPaginationParams(c)
+
+	results, err := h.searchService.Search(c.Request.Context(), query, types, page, perPage)
+	if err != nil {
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		respond.Error(c, statusCode, err.Error())
+		return
+	}
+	if results.Posts != nil {
+		projectPosts(c, results.Posts.Items)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    results,
+	})
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+)
+
+type SeriesHandler struct {
+	seriesService service.SeriesService
+}
+
+func NewSeriesHandler(seriesService service.SeriesService) *SeriesHandler {
+	return &SeriesHandler{
+		seriesService: seriesService,
+	}
+}
+
+// CreateSeries godoc
+// @Summary Create a series
+// @Description Create a series to group related posts in a reading order.
+// @Description The slug is derived from the title.
+// @Tags Series
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param series body models.SeriesCreateRequest true "Series data"
+// @Success 201 {object} models.APIResponse{data=models.SeriesResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/series [post]
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.SeriesCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	series, err := h.seriesService.Create(userID, &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	setLocation(c, "/series/%s", series.Slug)
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Series created successfully",
+		Data:    series,
+	})
+}
+
+// GetSeries godoc
+// @Summary Get series
+// @Description Get a paginated list of series, newest first, each with its
+// @Description number of published posts
+// @Tags Series
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.SeriesResponse}
+// @Failure 500 {object} models.APIResponse
+// @Router /api/series [get]
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	page, perPage := middleware.GetPaginationParams(c)
+
+	series, pagination, err := h.seriesService.GetSeries(c.Request.Context(), page, perPage)
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve series")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       series,
+		Pagination: withPaginationLinks(c, pagination),
+	})
+}
+
+// GetSeriesBySlug godoc
+// @Summary Get a series by slug
+// @Description Get a series with its published posts in reading order
+// @Tags Series
+// @Produce json
+// @Param slug path string true "Series slug"
+// @Success 200 {object} models.APIResponse{data=models.SeriesResponse}
+// @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /api/series/{slug} [get]
+func (h *SeriesHandler) GetSeriesBySlug(c *gin.Context) {
+	series, err := h.seriesService.GetBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		respondLookupError(c, err, "series")
+		return
+	}
+
+	projectPosts(c, series.Posts)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    series,
+	})
+}
+
+// UpdateSeries godoc
+// @Summary Update a series
+// @Description Update a series' title or description. A new title also
+// @Description changes the slug. Only the series' author and admins can
+// @Description update it.
+// @Tags Series
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Series ID"
+// @Param series body models.SeriesUpdateRequest true "Series data"
+// @Success 200 {object} models.APIResponse{data=models.SeriesResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/series/{id} [put]
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid series ID")
+		return
+	}
+
+	var req models.SeriesUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	series, err := h.seriesService.Update(uint(id), userID, middleware.IsAdmin(c), &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	projectPosts(c, series.Posts)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Series updated successfully",
+		Data:    series,
+	})
+}
+
+// DeleteSeries godoc
+// @Summary Delete a series
+// @Description Delete a series. Its posts are kept, outside any series. Only
+// @Description the series' author and admins can delete it.
+// @Tags Series
+// @Security BearerAuth
+// @Param id path int true "Series ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/series/{id} [delete]
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid series ID")
+		return
+	}
+
+	if err := h.seriesService.Delete(uint(id), userID, middleware.IsAdmin(c)); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Series deleted successfully",
+	})
+}
+
+// AddSeriesPost godoc
+// @Summary Add a post to a series
+// @Description Place a post in a series at order, lowest first; without an
+// @Description order it goes at the end. A post in another series moves to
+// @Description this one. The series' author must be able to edit the post;
+// @Description admins can add any post. The response lists every post in
+// @Description the series, drafts included.
+// @Tags Series
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Series ID"
+// @Param post_id path int true "Post ID"
+// @Param request body models.SeriesPostRequest false "Position in the series"
+// @Success 200 {object} models.APIResponse{data=models.SeriesResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/series/{id}/posts/{post_id} [put]
+func (h *SeriesHandler) AddSeriesPost(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid series ID")
+		return
+	}
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var req models.SeriesPostRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respond.Error(c, http.StatusBadRequest, "Invalid request format")
+			return
+		}
+	}
+
+	series, err := h.seriesService.AddPost(uint(id), userID, middleware.IsAdmin(c), uint(postID), &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	projectPosts(c, series.Posts)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Post added to series successfully",
+		Data:    series,
+	})
+}
+
+// RemoveSeriesPost godoc
+// @Summary Remove a post from a series
+// @Description Take a post out of a series; the post itself is kept. Only
+// @Description the series' author and admins can remove posts. The response
+// @Description lists every post left in the series, drafts included.
+// @Tags Series
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Series ID"
+// @Param post_id path int true "Post ID"
+// @Success 200 {object} models.APIResponse{data=models.SeriesResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/series/{id}/posts/{post_id} [delete]
+func (h *SeriesHandler) RemoveSeriesPost(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		respond.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid series ID")
+		return
+	}
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	series, err := h.seriesService.RemovePost(uint(id), userID, middleware.IsAdmin(c), uint(postID))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	projectPosts(c, series.Posts)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Post removed from series successfully",
+		Data:    series,
+	})
+}
+
+// SeriesCacheTag tags the cached responses of posts in the series with id,
+// whose series navigation changes with it
+func SeriesCacheTag(id uint) string {
+	return fmt.Sprintf("series:%d", id)
+}
+
+// SeriesCacheTags returns the cache tags a series route changes: the
+// series' posts, and the post named in the path being added or removed
+func SeriesCacheTags(c *gin.Context) []string {
+	var tags []string
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 32); err == nil {
+		tags = append(tags, SeriesCacheTag(uint(id)))
+	}
+	if postID, err := strconv.ParseUint(c.Param("post_id"), 10, 32); err == nil {
+		tags = append(tags, PostCacheTag(uint(postID)))
+	}
+	return tags
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+)
+
+type StatsHandler struct {
+	statsService service.StatsService
+}
+
+func NewStatsHandler(statsService service.StatsService) *StatsHandler {
+	return &StatsHandler{
+		statsService: statsService,
+	}
+}
+
+// GetSiteStats godoc
+// @Summary Get site stats
+// @Description Get the number of published posts, approved comments on them,
+// @Description tags and active authors with a published post. The numbers are
+// @Description cached briefly, so they can lag behind by up to a minute.
+// @Tags Stats
+// @Produce json
+// @Success 200 {object} models.APIResponse{data=models.SiteStatsResponse}
+// @Failure 500 {object} models.APIResponse
+// @Router /api/stats [get]
+func (h *StatsHandler) GetSiteStats(c *gin.Context) {
+	stats, err := h.statsService.GetSiteStats()
+	if err != nil {
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve site stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
@@ -1,22 +1,28 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
 )
 
 type TagHandler struct {
-	tagService service.TagService
+	tagService  service.TagService
+	postService service.PostService
 }
 
-func NewTagHandler(tagService service.TagService) *TagHandler {
+func NewTagHandler(tagService service.TagService, postService service.PostService) *TagHandler {
 	return &TagHandler{
-		tagService: tagService,
+		tagService:  tagService,
+		postService: postService,
 	}
 }
 
@@ -33,31 +39,22 @@ func NewTagHandler(tagService service.TagService) *TagHandler {
 // @Failure 401 {object} models.APIResponse
 // @Failure 403 {object} models.APIResponse
 // @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/admin/tags [post]
 func (h *TagHandler) CreateTag(c *gin.Context) {
 	var req models.TagCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
 	tag, err := h.tagService.Create(&req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "tag name is already taken" {
-			statusCode = http.StatusConflict
-		}
-
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
+	setLocation(c, "/tags/%d", tag.ID)
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
 		Message: "Tag created successfully",
@@ -73,24 +70,19 @@ func (h *TagHandler) CreateTag(c *gin.Context) {
 // @Param id path int true "Tag ID"
 // @Success 200 {object} models.APIResponse{data=models.TagResponse}
 // @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
 // @Router /api/tags/{id} [get]
 func (h *TagHandler) GetTag(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid tag ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid tag ID")
 		return
 	}
 
 	tag, err := h.tagService.GetByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error:   "Tag not found",
-		})
+		respondLookupError(c, err, "tag")
 		return
 	}
 
@@ -102,22 +94,35 @@ func (h *TagHandler) GetTag(c *gin.Context) {
 
 // GetTagBySlug godoc
 // @Summary Get a tag by slug
-// @Description Get a specific tag by its slug
+// @Description Get a specific tag by its slug. A slug the tag had before it
+// @Description was renamed answers 301 with the tag and its current slug in
+// @Description the Location header.
 // @Tags Tags
 // @Produce json
 // @Param slug path string true "Tag slug"
 // @Success 200 {object} models.APIResponse{data=models.TagResponse}
+// @Success 301 {object} models.APIResponse{data=models.TagResponse}
 // @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
 // @Router /api/tags/slug/{slug} [get]
 func (h *TagHandler) GetTagBySlug(c *gin.Context) {
 	slug := c.Param("slug")
 
 	tag, err := h.tagService.GetBySlug(slug)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		// The tag may have been renamed since the slug was handed out
+		if renamed, lookupErr := h.tagService.GetByFormerSlug(slug); lookupErr == nil {
+			setLocation(c, "/tags/slug/%s", renamed.Slug)
+			c.JSON(http.StatusMovedPermanently, models.APIResponse{
+				Success: true,
+				Message: "Tag has moved to " + renamed.Slug,
+				Data:    renamed,
+			})
+			return
+		}
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error:   "Tag not found",
-		})
+		respondLookupError(c, err, "tag")
 		return
 	}
 
@@ -142,40 +147,25 @@ func (h *TagHandler) GetTagBySlug(c *gin.Context) {
 // @Failure 403 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
 // @Failure 409 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
 // @Router /api/admin/tags/{id} [put]
 func (h *TagHandler) UpdateTag(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid tag ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid tag ID")
 		return
 	}
 
 	var req models.TagUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid request format",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
 	tag, err := h.tagService.Update(uint(id), &req)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "tag name is already taken" {
-			statusCode = http.StatusConflict
-		} else if err.Error() == "tag not found" {
-			statusCode = http.StatusNotFound
-		}
-
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -186,40 +176,122 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 	})
 }
 
+// AttachTagToPosts godoc
+// @Summary Add a tag to many posts (Admin only)
+// @Description Add a tag to every listed post in one transaction. Duplicate
+// @Description IDs are ignored; if any post doesn't exist nothing changes.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tag ID"
+// @Param posts body models.TagPostsRequest true "Posts to tag"
+// @Success 200 {object} models.APIResponse{data=models.TagPostsResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/admin/tags/{id}/attach [post]
+func (h *TagHandler) AttachTagToPosts(c *gin.Context) {
+	h.bulkTag(c, h.postService.AttachTag, "Tag attached successfully")
+}
+
+// DetachTagFromPosts godoc
+// @Summary Remove a tag from many posts (Admin only)
+// @Description Remove a tag from every listed post in one transaction.
+// @Description Duplicate IDs are ignored; if any post doesn't exist nothing
+// @Description changes.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tag ID"
+// @Param posts body models.TagPostsRequest true "Posts to untag"
+// @Success 200 {object} models.APIResponse{data=models.TagPostsResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse{data=models.ValidationErrorResponse}
+// @Router /api/admin/tags/{id}/detach [post]
+func (h *TagHandler) DetachTagFromPosts(c *gin.Context) {
+	h.bulkTag(c, h.postService.DetachTag, "Tag detached successfully")
+}
+
+// bulkTag serves the bulk attach and detach endpoints with change
+func (h *TagHandler) bulkTag(c *gin.Context, change func(tagID uint, req *models.TagPostsRequest) (*models.TagPostsResponse, error), message string) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+
+	var req models.TagPostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	result, err := change(uint(id), &req)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	postIDs := make([]uint, 0, len(result.Results))
+	for _, post := range result.Results {
+		postIDs = append(postIDs, post.PostID)
+	}
+	c.Set(taggedPostsKey, postIDs)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    result,
+	})
+}
+
 // DeleteTag godoc
 // @Summary Delete a tag (Admin only)
-// @Description Delete an existing tag and remove it from all posts
+// @Description Delete an existing tag. A tag attached to posts is only deleted,
+// @Description and removed from those posts, with force=true; otherwise the
+// @Description response is a 409 with the number of posts using it.
 // @Tags Tags
 // @Security BearerAuth
 // @Param id path int true "Tag ID"
+// @Param force query bool false "Delete even if the tag is attached to posts" default(false)
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.APIResponse
 // @Failure 401 {object} models.APIResponse
 // @Failure 403 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse{data=models.TagInUseResponse}
 // @Router /api/admin/tags/{id} [delete]
 func (h *TagHandler) DeleteTag(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid tag ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid tag ID")
 		return
 	}
 
-	err = h.tagService.Delete(uint(id))
+	force, err := strconv.ParseBool(c.DefaultQuery("force", "false"))
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "tag not found" {
-			statusCode = http.StatusNotFound
-		}
+		respond.Error(c, http.StatusBadRequest, "force must be true or false")
+		return
+	}
+
+	err = h.tagService.Delete(uint(id), force)
+	if err != nil {
+		statusCode := errorStatus(err, http.StatusBadRequest)
 
-		c.JSON(statusCode, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		var data interface{}
+		var inUse *service.TagInUseError
+		if errors.As(err, &inUse) {
+			data = models.TagInUseResponse{PostsCount: inUse.PostsCount}
+		}
+		respond.ErrorWithData(c, statusCode, err.Error(), data)
 		return
 	}
 
@@ -243,17 +315,14 @@ func (h *TagHandler) GetTags(c *gin.Context) {
 
 	tags, pagination, err := h.tagService.GetTags(page, perPage)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve tags",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve tags")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success:    true,
 		Data:       tags,
-		Pagination: pagination,
+		Pagination: withPaginationLinks(c, pagination),
 	})
 }
 
@@ -267,10 +336,7 @@ func (h *TagHandler) GetTags(c *gin.Context) {
 func (h *TagHandler) GetAllTags(c *gin.Context) {
 	tags, err := h.tagService.GetAllTags()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve tags",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve tags")
 		return
 	}
 
@@ -300,10 +366,7 @@ func (h *TagHandler) GetPopularTags(c *gin.Context) {
 
 	tags, err := h.tagService.GetPopularTags(limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve popular tags",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve popular tags")
 		return
 	}
 
@@ -315,42 +378,49 @@ func (h *TagHandler) GetPopularTags(c *gin.Context) {
 
 // GetPostsByTag godoc
 // @Summary Get posts by tag
-// @Description Get posts that have a specific tag
+// @Description Get published posts that have a specific tag
 // @Tags Tags
 // @Produce json
 // @Param id path int true "Tag ID"
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
+// @Param sort query string false "Sort order" Enums(newest, oldest, popular, comments) default(newest)
+// @Param excerpt_length query int false "Shorten excerpts to at most this many characters, on a word boundary"
 // @Success 200 {object} models.PaginatedResponse{data=[]models.PostListResponse}
+// @Failure 400 {object} models.APIResponse
 // @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
 // @Router /api/tags/{id}/posts [get]
 func (h *TagHandler) GetPostsByTag(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error:   "Invalid tag ID",
-		})
+		respond.Error(c, http.StatusBadRequest, "Invalid tag ID")
 		return
 	}
 
 	// First check if tag exists
-	_, err = h.tagService.GetByID(uint(id))
+	if _, err := h.tagService.GetByID(uint(id)); err != nil {
+		respondLookupError(c, err, "tag")
+		return
+	}
+
+	page, perPage := middleware.GetPaginationParams(c)
+	sort := models.PostSort(c.Query("sort"))
+
+	posts, pagination, err := h.postService.GetPostsByTag(c.Request.Context(), uint(id), sort, page, perPage)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error:   "Tag not found",
-		})
+		statusCode := errorStatus(err, http.StatusInternalServerError)
+
+		respond.Error(c, statusCode, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Tag found - use /api/posts?tag_id=" + idStr + " to get posts with this tag",
-		Data: map[string]interface{}{
-			"redirect_url": "/api/posts?tag_id=" + idStr,
-		},
+	projectPosts(c, posts)
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       posts,
+		Pagination: withPaginationLinks(c, pagination),
 	})
 }
 
@@ -368,20 +438,14 @@ func (h *TagHandler) GetTagStats(c *gin.Context) {
 	// Get all tags with post counts
 	allTags, err := h.tagService.GetAllTags()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve tag statistics",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve tag statistics")
 		return
 	}
 
 	// Get popular tags
 	popularTags, err := h.tagService.GetPopularTags(5)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to retrieve popular tags",
-		})
+		respond.Error(c, http.StatusInternalServerError, "Failed to retrieve popular tags")
 		return
 	}
 
@@ -416,3 +480,29 @@ func (h *TagHandler) GetTagStats(c *gin.Context) {
 		Data:    stats,
 	})
 }
+
+// TagCacheTag tags the cached responses of posts with the tag with id,
+// which show its name
+func TagCacheTag(id uint) string {
+	return fmt.Sprintf("tag:%d", id)
+}
+
+// taggedPostsKey is the context key under which the bulk attach and detach
+// endpoints record the posts they went through, for TagCacheTags
+const taggedPostsKey = "tagged_post_ids"
+
+// TagCacheTags lists the cached responses a tag write invalidates: the
+// published listings, the posts with the tag in the path and the posts it
+// was just attached to or detached from
+func TagCacheTags(c *gin.Context) []string {
+	tags := []string{PublishedPostsCacheTag}
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 32); err == nil {
+		tags = append(tags, TagCacheTag(uint(id)))
+	}
+	if value, ok := c.Get(taggedPostsKey); ok {
+		for _, postID := range value.([]uint) {
+			tags = append(tags, PostCacheTag(postID))
+		}
+	}
+	return tags
+}
@@ -0,0 +1,260 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/handlers"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagHandler_DeleteTag_InUse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "handlertagger")
+	tag := &models.Tag{Name: "Guarded", Slug: "guarded"}
+	require.NoError(t, db.Create(tag).Error)
+	post := &models.Post{Title: "Guarded post", Slug: "guarded-post", Content: "Tagged content", Status: models.PostStatusDraft, AuthorID: author.ID}
+	require.NoError(t, db.Create(post).Error)
+	require.NoError(t, repository.NewPostRepository(db).AddTags(post.ID, []uint{tag.ID}))
+
+	handler := handlers.NewTagHandler(service.NewTagService(repository.NewTagRepository(db), 0), new(MockPostService))
+	params := gin.Params{{Key: "id", Value: "1"}}
+
+	c, w := newPostTestContext(http.MethodDelete, "/api/admin/tags/1", params)
+	handler.DeleteTag(c)
+	require.Equal(t, http.StatusConflict, w.Code)
+	var body struct {
+		Data models.TagInUseResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, int64(1), body.Data.PostsCount)
+
+	c, w = newPostTestContext(http.MethodDelete, "/api/admin/tags/1?force=maybe", params)
+	handler.DeleteTag(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	c, w = newPostTestContext(http.MethodDelete, "/api/admin/tags/1?force=true", params)
+	handler.DeleteTag(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestTagHandler_CreateTag_InvalidBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	handler := handlers.NewTagHandler(service.NewTagService(repository.NewTagRepository(db), 0), new(MockPostService))
+
+	create := func(body string) (int, models.ValidationErrorResponse) {
+		c, w := newPostTestContext(http.MethodPost, "/api/admin/tags", nil)
+		c.Request.Body = io.NopCloser(strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.CreateTag(c)
+
+		var resp struct {
+			Data models.ValidationErrorResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp.Data
+	}
+
+	t.Run("malformed JSON", func(t *testing.T) {
+		code, data := create(`{"name": "Go",`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Empty(t, data.Fields)
+	})
+
+	t.Run("invalid fields", func(t *testing.T) {
+		code, data := create(`{"name": "G", "color": "blue"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, code)
+		require.Len(t, data.Fields, 1)
+		assert.Equal(t, "Name", data.Fields[0].Field)
+		assert.Equal(t, "min", data.Fields[0].Tag)
+	})
+
+	t.Run("invalid color", func(t *testing.T) {
+		code, data := create(`{"name": "Go", "color": "blue"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, code)
+		require.Len(t, data.Fields, 1)
+		assert.Equal(t, "Color", data.Fields[0].Field)
+	})
+}
+
+func TestTagHandler_GetPostsByTag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	tag := &models.Tag{Name: "Listed", Slug: "listed"}
+	require.NoError(t, db.Create(tag).Error)
+
+	postService := new(MockPostService)
+	handler := handlers.NewTagHandler(service.NewTagService(repository.NewTagRepository(db), 0), postService)
+
+	posts := []models.PostListResponse{{ID: 7, Title: "Tagged post"}}
+	meta := models.PaginationMeta{Page: 1, PerPage: 10, Total: 1, TotalPages: 1}
+	postService.On("GetPostsByTag", tag.ID, models.PostSortPopular, 1, 10).Return(posts, meta, nil)
+
+	c, w := newPostTestContext(http.MethodGet, "/api/tags/1/posts?sort=popular", gin.Params{{Key: "id", Value: "1"}})
+	handler.GetPostsByTag(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body models.PaginatedResponse
+	body.Data = &[]models.PostListResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, &posts, body.Data)
+	assert.Equal(t, 1, body.Pagination.Total)
+	assert.Equal(t, &models.PaginationLinks{
+		First: "/api/tags/1/posts?page=1&sort=popular",
+		Last:  "/api/tags/1/posts?page=1&sort=popular",
+	}, body.Pagination.Links)
+	assert.NotContains(t, w.Body.String(), "redirect_url")
+	postService.AssertExpectations(t)
+
+	c, w = newPostTestContext(http.MethodGet, "/api/tags/99/posts", gin.Params{{Key: "id", Value: "99"}})
+	handler.GetPostsByTag(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTagHandler_CreateTag_Location(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	handler := handlers.NewTagHandler(service.NewTagService(repository.NewTagRepository(db), 0), new(MockPostService))
+
+	c, w := newPostTestContext(http.MethodPost, "/api/v1/admin/tags", nil)
+	c.Request.Body = io.NopCloser(strings.NewReader(`{"name":"Located"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.CreateTag(c)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var body struct {
+		Data models.TagResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, fmt.Sprintf("/api/v1/tags/%d", body.Data.ID), w.Header().Get("Location"))
+}
+
+func TestTagHandler_GetTagBySlug_Renamed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	tagService := service.NewTagService(repository.NewTagRepository(db), 0)
+	handler := handlers.NewTagHandler(tagService, new(MockPostService))
+
+	tag, err := tagService.Create(&models.TagCreateRequest{Name: "Old name"})
+	require.NoError(t, err)
+	_, err = tagService.Update(tag.ID, &models.TagUpdateRequest{Name: "New name"})
+	require.NoError(t, err)
+
+	c, w := newPostTestContext(http.MethodGet, "/api/v1/tags/slug/old-name", gin.Params{{Key: "slug", Value: "old-name"}})
+	handler.GetTagBySlug(c)
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/api/v1/tags/slug/new-name", w.Header().Get("Location"))
+	var body struct {
+		Data models.TagResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, tag.ID, body.Data.ID)
+
+	c, w = newPostTestContext(http.MethodGet, "/api/tags/slug/new-name", gin.Params{{Key: "slug", Value: "new-name"}})
+	handler.GetTagBySlug(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	c, w = newPostTestContext(http.MethodGet, "/api/tags/slug/never-existed", gin.Params{{Key: "slug", Value: "never-existed"}})
+	handler.GetTagBySlug(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTagHandler_AttachDetachPosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "curator")
+	tag := &models.Tag{Name: "Curated", Slug: "curated"}
+	require.NoError(t, db.Create(tag).Error)
+	var posts []*models.Post
+	for i := 1; i <= 3; i++ {
+		post := &models.Post{Title: fmt.Sprintf("Curated post %d", i), Slug: fmt.Sprintf("curated-post-%d", i), Content: "Worth curating", Status: models.PostStatusDraft, AuthorID: author.ID}
+		require.NoError(t, db.Create(post).Error)
+		posts = append(posts, post)
+	}
+
+	postRepo := repository.NewPostRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	require.NoError(t, postRepo.AddTags(posts[0].ID, []uint{tag.ID}))
+	postService := service.NewPostService(postRepo, tagRepo, repository.NewCommentRepository(db), webhook.NewDispatcher(config.WebhookConfig{}), mailer.New(config.MailConfig{}), config.ContentConfig{}, config.JWTConfig{}, nil)
+	handler := handlers.NewTagHandler(service.NewTagService(tagRepo, 0), postService)
+
+	// send posts body to the attach or detach handler for tagID
+	send := func(serve gin.HandlerFunc, tagID uint, body string) (int, models.TagPostsResponse) {
+		c, w := newPostTestContext(http.MethodPost, fmt.Sprintf("/api/v1/admin/tags/%d/attach", tagID), gin.Params{{Key: "id", Value: fmt.Sprint(tagID)}})
+		c.Request.Body = io.NopCloser(strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		serve(c)
+
+		var response struct {
+			Data models.TagPostsResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response.Data
+	}
+	taggedPosts := func() []uint {
+		ids, err := postRepo.GetIDsWithTag(tag.ID, []uint{posts[0].ID, posts[1].ID, posts[2].ID})
+		require.NoError(t, err)
+		return ids
+	}
+
+	t.Run("attach", func(t *testing.T) {
+		code, result := send(handler.AttachTagToPosts, tag.ID, fmt.Sprintf(`{"post_ids":[%d,%d,%d,%d]}`, posts[0].ID, posts[1].ID, posts[2].ID, posts[1].ID))
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(3), result.PostsCount)
+		assert.Equal(t, []models.TagPostResult{
+			{PostID: posts[0].ID, Result: models.TagPostAlreadyAttached},
+			{PostID: posts[1].ID, Result: models.TagPostAttached},
+			{PostID: posts[2].ID, Result: models.TagPostAttached},
+		}, result.Results)
+		assert.ElementsMatch(t, []uint{posts[0].ID, posts[1].ID, posts[2].ID}, taggedPosts())
+	})
+
+	t.Run("detach", func(t *testing.T) {
+		code, result := send(handler.DetachTagFromPosts, tag.ID, fmt.Sprintf(`{"post_ids":[%d,%d]}`, posts[0].ID, posts[2].ID))
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(1), result.PostsCount)
+		assert.Equal(t, []models.TagPostResult{
+			{PostID: posts[0].ID, Result: models.TagPostDetached},
+			{PostID: posts[2].ID, Result: models.TagPostDetached},
+		}, result.Results)
+		assert.Equal(t, []uint{posts[1].ID}, taggedPosts())
+
+		_, result = send(handler.DetachTagFromPosts, tag.ID, fmt.Sprintf(`{"post_ids":[%d]}`, posts[0].ID))
+		assert.Equal(t, models.TagPostNotAttached, result.Results[0].Result)
+	})
+
+	t.Run("unknown posts change nothing", func(t *testing.T) {
+		code, _ := send(handler.AttachTagToPosts, tag.ID, fmt.Sprintf(`{"post_ids":[%d,999]}`, posts[0].ID))
+		assert.Equal(t, http.StatusUnprocessableEntity, code)
+		assert.Equal(t, []uint{posts[1].ID}, taggedPosts())
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		code, _ := send(handler.AttachTagToPosts, tag.ID, `{"post_ids":[]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, code)
+
+		code, _ = send(handler.AttachTagToPosts, 999, fmt.Sprintf(`{"post_ids":[%d]}`, posts[0].ID))
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+}
@@ -0,0 +1,69 @@
+// Package mailer sends outgoing email.
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+)
+
+// Message is a plain-text email to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns an SMTP mailer, or a mailer that only logs messages when no
+// SMTP host is configured
+func New(cfg config.MailConfig) Mailer {
+	if cfg.SMTPHost == "" {
+		return &logMailer{}
+	}
+	return &smtpMailer{config: cfg}
+}
+
+type smtpMailer struct {
+	config config.MailConfig
+}
+
+func (m *smtpMailer) Send(msg Message) error {
+	addr := m.config.SMTPHost + ":" + strconv.Itoa(m.config.SMTPPort)
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.SMTPHost)
+	}
+
+	headers := []string{
+		"From: " + m.config.From,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + msg.Body
+
+	if err := smtp.SendMail(addr, auth, m.config.From, []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("sending mail to %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// logMailer writes messages to the log, for development without an SMTP
+// server
+type logMailer struct{}
+
+func (m *logMailer) Send(msg Message) error {
+	log.Printf("📧 Mail to %s: %s", msg.To, msg.Subject)
+	return nil
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
+)
+
+// IdempotencyKeyHeader is the request header clients set to make a POST safe
+// to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the keys clients may send
+const maxIdempotencyKeyLength = 255
+
+// IdempotentResponse is a stored response, replayed for repeated requests
+type IdempotentResponse struct {
+	// Fingerprint identifies the request body the response was made for
+	Fingerprint string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+}
+
+// IdempotencyStore keeps responses by idempotency key. Implementations must
+// be safe for concurrent use.
+type IdempotencyStore interface {
+	// Reserve claims key for a request about to run. It returns the stored
+	// response when the key has already completed, and reserved is false
+	// when the key is taken, either completed or still in progress.
+	Reserve(key string) (response *IdempotentResponse, reserved bool)
+
+	// Save stores the response for a reserved key for ttl
+	Save(key string, response IdempotentResponse, ttl time.Duration)
+
+	// Release frees a reserved key without storing a response, so the
+	// request can be retried
+	Release(key string)
+}
+
+// idempotencyEntry is a key in MemoryIdempotencyStore. It has no response
+// while the request is in progress.
+type idempotencyEntry struct {
+	response  *IdempotentResponse
+	expiresAt time.Time
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore. Keys are kept per
+// process, so each instance only recognizes the requests it served.
+type MemoryIdempotencyStore struct {
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+// NewMemoryIdempotencyStore creates an empty in-memory store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		now:     time.Now,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key string) (*IdempotentResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if entry, exists := s.entries[key]; exists {
+		if entry.response == nil || now.Before(entry.expiresAt) {
+			return entry.response, false
+		}
+	}
+
+	s.sweep(now)
+	s.entries[key] = &idempotencyEntry{}
+	return nil, true
+}
+
+func (s *MemoryIdempotencyStore) Save(key string, response IdempotentResponse, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = &idempotencyEntry{response: &response, expiresAt: s.now().Add(ttl)}
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// sweep drops expired responses so the map doesn't grow unbounded
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if entry.response != nil && !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// Idempotency replays the stored response when a request is repeated with
+// the same Idempotency-Key, instead of running the handler again
+type Idempotency struct {
+	store IdempotencyStore
+	ttl   time.Duration
+}
+
+// NewIdempotency creates idempotency middleware keeping responses in store
+// for ttl
+func NewIdempotency(store IdempotencyStore, ttl time.Duration) *Idempotency {
+	return &Idempotency{store: store, ttl: ttl}
+}
+
+// Middleware handles the Idempotency-Key header. Keys are scoped to the user,
+// or the client IP for guests, and to the route. Successful responses are
+// stored and replayed with an Idempotent-Replayed header; any other response,
+// or a handler panic, frees the key so the request can be retried. Reusing a key with a
+// different body is rejected with 422, and a key whose first request is
+// still running with 409. It must run after the auth middleware.
+func (i *Idempotency) Middleware() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			respond.Abort(c, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			respond.Abort(c, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+
+		scope := "guest:" + c.ClientIP()
+		if userID, ok := GetUserID(c); ok {
+			scope = fmt.Sprintf("user:%d", userID)
+		}
+		storeKey := fmt.Sprintf("%s|%s %s|%s", scope, c.Request.Method, c.FullPath(), key)
+
+		stored, reserved := i.store.Reserve(storeKey)
+		if !reserved {
+			switch {
+			case stored == nil:
+				respond.Abort(c, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+			case stored.Fingerprint != fingerprint:
+				respond.Abort(c, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request body")
+			default:
+				replayHeader(c, stored.Header)
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(stored.StatusCode, stored.Header.Get("Content-Type"), stored.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		// A handler that panics leaves the default 200 status behind without
+		// writing a response, so only a completed, written response is kept
+		completed := false
+		defer func() {
+			if status := recorder.Status(); completed && recorder.Written() && status >= 200 && status < 300 {
+				i.store.Save(storeKey, IdempotentResponse{
+					Fingerprint: fingerprint,
+					StatusCode:  status,
+					Header:      recorder.Header().Clone(),
+					Body:        recorder.body.Bytes(),
+				}, i.ttl)
+			} else {
+				i.store.Release(storeKey)
+			}
+		}()
+
+		c.Next()
+		completed = true
+	})
+}
+
+// responseRecorder copies the response body while writing it through
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
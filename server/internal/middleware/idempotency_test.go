@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := NewMemoryIdempotencyStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	created := 0
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(io.Discard))
+	router.POST("/posts", func(c *gin.Context) {
+		if userID := c.GetHeader("X-User"); userID == "1" {
+			c.Set("user_id", uint(1))
+		} else if userID == "2" {
+			c.Set("user_id", uint(2))
+		}
+		c.Next()
+	}, NewIdempotency(store, time.Hour).Middleware(), func(c *gin.Context) {
+		if c.Query("panic") == "true" {
+			panic("handler bug")
+		}
+		if c.Query("fail") == "true" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+			return
+		}
+		created++
+		c.Header("Location", "/posts/"+strconv.Itoa(created))
+		c.JSON(http.StatusCreated, gin.H{"id": created})
+	})
+
+	send := func(user, key, body, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/posts"+query, strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("duplicate submission returns the original resource", func(t *testing.T) {
+		first := send("1", "abc", `{"title":"Hello"}`, "")
+		require.Equal(t, http.StatusCreated, first.Code)
+
+		second := send("1", "abc", `{"title":"Hello"}`, "")
+		require.Equal(t, http.StatusCreated, second.Code)
+		assert.JSONEq(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, first.Header().Get("Location"), second.Header().Get("Location"))
+		assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 1, created)
+	})
+
+	t.Run("keys are scoped per user", func(t *testing.T) {
+		w := send("2", "abc", `{"title":"Hello"}`, "")
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 2, created)
+	})
+
+	t.Run("different body is rejected", func(t *testing.T) {
+		w := send("1", "abc", `{"title":"Other"}`, "")
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("failures are not stored", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, send("1", "retry", `{}`, "?fail=true").Code)
+		require.Equal(t, http.StatusCreated, send("1", "retry", `{}`, "").Code)
+	})
+
+	t.Run("a panicking handler frees the key", func(t *testing.T) {
+		require.Equal(t, http.StatusInternalServerError, send("1", "crash", `{}`, "?panic=true").Code)
+		w := send("1", "crash", `{}`, "")
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("without a key every request runs", func(t *testing.T) {
+		before := created
+		send("1", "", `{}`, "")
+		send("1", "", `{}`, "")
+		assert.Equal(t, before+2, created)
+	})
+
+	t.Run("stored responses expire", func(t *testing.T) {
+		before := created
+		now = now.Add(2 * time.Hour)
+		require.Equal(t, http.StatusCreated, send("1", "abc", `{"title":"Hello"}`, "").Code)
+		assert.Equal(t, before+1, created)
+	})
+
+	t.Run("key still in progress", func(t *testing.T) {
+		_, reserved := store.Reserve("user:1|POST /posts|busy")
+		require.True(t, reserved)
+		assert.Equal(t, http.StatusConflict, send("1", "busy", `{}`, "").Code)
+	})
+}
@@ -8,17 +8,39 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
-	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
 )
 
-// CORS middleware
-func CORS() gin.HandlerFunc {
+// CORS middleware. Any origin may make requests without credentials; only
+// the configured origins are echoed back and allowed to send credentials,
+// such as the auth cookie.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	allowedOrigins := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		allowedOrigins[origin] = true
+	}
+
 	return gin.HandlerFunc(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		if origin := c.GetHeader("Origin"); allowedOrigins[origin] {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		} else {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		if len(allowedOrigins) > 0 {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		if exposeHeaders != "" {
+			c.Writer.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+		}
+		if cfg.MaxAge > 0 {
+			c.Writer.Header().Set("Access-Control-Max-Age", maxAge)
+		}
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -29,38 +51,56 @@ func CORS() gin.HandlerFunc {
 	})
 }
 
-// AuthMiddleware validates JWT token
-func AuthMiddleware(config *config.Config) gin.HandlerFunc {
+// replayHeader copies the headers of a stored response onto the current one,
+// apart from the CORS headers, which CORS set for this request's origin
+func replayHeader(c *gin.Context, stored http.Header) {
+	for name, values := range stored {
+		if strings.HasPrefix(name, "Access-Control-") {
+			continue
+		}
+		c.Writer.Header()[name] = values
+	}
+}
+
+// DeprecationMiddleware marks responses from a deprecated route group and
+// points clients at its successor prefix
+func DeprecationMiddleware(successor string) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, models.APIResponse{
-				Success: false,
-				Error:   "Authorization header is required",
-			})
-			c.Abort()
+		c.Writer.Header().Set("Deprecation", "true")
+		c.Writer.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		c.Next()
+	})
+}
+
+// RevocationChecker reports whether the token with a jti claim has been
+// revoked, as the token service does
+type RevocationChecker interface {
+	IsRevoked(jti string) (bool, error)
+}
+
+// AuthMiddleware validates JWT token, rejecting tokens revoked by logout.
+// A nil revocations skips the revocation check.
+func AuthMiddleware(config *config.Config, revocations RevocationChecker) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		token, errMsg := extractToken(c, config)
+		if errMsg != "" {
+			respond.Abort(c, http.StatusUnauthorized, errMsg)
 			return
 		}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, models.APIResponse{
-				Success: false,
-				Error:   "Authorization header format must be Bearer {token}",
-			})
-			c.Abort()
+		claims, err := utils.ValidateToken(token, config)
+		if err != nil || claims.TokenType == utils.TokenTypeRefresh {
+			respond.Abort(c, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
-		token := parts[1]
-		claims, err := utils.ValidateToken(token, config)
+		revoked, err := isRevoked(revocations, claims)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.APIResponse{
-				Success: false,
-				Error:   "Invalid or expired token",
-			})
-			c.Abort()
+			respond.Abort(c, http.StatusInternalServerError, "Failed to verify token")
+			return
+		}
+		if revoked {
+			respond.Abort(c, http.StatusUnauthorized, "Token has been revoked")
 			return
 		}
 
@@ -69,30 +109,51 @@ func AuthMiddleware(config *config.Config) gin.HandlerFunc {
 		c.Set("user_email", claims.Email)
 		c.Set("user_username", claims.Username)
 		c.Set("is_admin", claims.IsAdmin)
+		c.Set("must_change_password", claims.MustChangePassword)
+		c.Set("token_claims", claims)
 
 		c.Next()
 	})
 }
 
-// OptionalAuthMiddleware validates JWT token if present but doesn't require it
-func OptionalAuthMiddleware(config *config.Config) gin.HandlerFunc {
+// PasswordChangeMiddleware rejects requests from users who have to change
+// their password first. It runs after AuthMiddleware on every authenticated
+// route except the password change itself.
+func PasswordChangeMiddleware() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.Next()
+		if mustChange, _ := c.Get("must_change_password"); mustChange == true {
+			respond.Abort(c, http.StatusForbidden, "Password change required")
 			return
 		}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		c.Next()
+	})
+}
+
+// OptionalAuthMiddleware validates JWT token if present but doesn't require
+// it. Invalid and revoked tokens are treated as no token, and so are tokens
+// of users who have to change their password first, apart from keeping the
+// claims for logout to revoke.
+func OptionalAuthMiddleware(config *config.Config, revocations RevocationChecker) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		token, errMsg := extractToken(c, config)
+		if errMsg != "" {
 			c.Next()
 			return
 		}
 
-		token := parts[1]
 		claims, err := utils.ValidateToken(token, config)
-		if err != nil {
+		if err != nil || claims.TokenType == utils.TokenTypeRefresh {
+			c.Next()
+			return
+		}
+		if revoked, err := isRevoked(revocations, claims); err != nil || revoked {
+			c.Next()
+			return
+		}
+		if claims.MustChangePassword {
+			c.Set("must_change_password", true)
+			c.Set("token_claims", claims)
 			c.Next()
 			return
 		}
@@ -102,21 +163,49 @@ func OptionalAuthMiddleware(config *config.Config) gin.HandlerFunc {
 		c.Set("user_email", claims.Email)
 		c.Set("user_username", claims.Username)
 		c.Set("is_admin", claims.IsAdmin)
+		c.Set("token_claims", claims)
 
 		c.Next()
 	})
 }
 
+// isRevoked checks claims against revocations, when there are any
+func isRevoked(revocations RevocationChecker, claims *utils.JWTClaims) (bool, error) {
+	if revocations == nil {
+		return false, nil
+	}
+	return revocations.IsRevoked(claims.ID)
+}
+
+// extractToken reads the bearer token from the Authorization header, falling
+// back to the auth cookie when cookie mode is enabled and the header is
+// absent. It returns an error message when no usable token was sent.
+func extractToken(c *gin.Context, config *config.Config) (string, string) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		if config.Cookie.Enabled {
+			if token, err := c.Cookie(config.Cookie.Name); err == nil && token != "" {
+				return token, ""
+			}
+		}
+		return "", "Authorization header is required"
+	}
+
+	// Extract token from "Bearer <token>"
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", "Authorization header format must be Bearer {token}"
+	}
+
+	return parts[1], ""
+}
+
 // AdminMiddleware ensures user is an admin
 func AdminMiddleware() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		isAdmin, exists := c.Get("is_admin")
 		if !exists || !isAdmin.(bool) {
-			c.JSON(http.StatusForbidden, models.APIResponse{
-				Success: false,
-				Error:   "Admin access required",
-			})
-			c.Abort()
+			respond.Abort(c, http.StatusForbidden, "Admin access required")
 			return
 		}
 
@@ -124,21 +213,39 @@ func AdminMiddleware() gin.HandlerFunc {
 	})
 }
 
-// PaginationMiddleware extracts and validates pagination parameters
-func PaginationMiddleware() gin.HandlerFunc {
+// PaginationMiddleware extracts and validates pagination parameters. A
+// missing page is 1 and a missing per_page is the configured default. In
+// strict mode, a page or per_page that isn't a positive integer, or a
+// per_page above the configured maximum, is rejected with a 400. Otherwise
+// invalid values fall back to the defaults and per_page is clamped.
+func PaginationMiddleware(cfg config.PaginationConfig) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		page := 1
-		perPage := 10
+		perPage := cfg.DefaultPerPage
+
+		reject := func(message string) {
+			respond.Abort(c, http.StatusBadRequest, message)
+		}
 
 		if pageStr := c.Query("page"); pageStr != "" {
-			if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			p, err := strconv.Atoi(pageStr)
+			if cfg.Strict && (err != nil || p < 1) {
+				reject("page must be a positive integer")
+				return
+			}
+			if err == nil && p > 0 {
 				page = p
 			}
 		}
 
 		if perPageStr := c.Query("per_page"); perPageStr != "" {
-			if pp, err := strconv.Atoi(perPageStr); err == nil && pp > 0 && pp <= 100 {
-				perPage = pp
+			pp, err := strconv.Atoi(perPageStr)
+			if cfg.Strict && (err != nil || pp < 1 || pp > cfg.MaxPerPage) {
+				reject(fmt.Sprintf("per_page must be an integer between 1 and %d", cfg.MaxPerPage))
+				return
+			}
+			if err == nil && pp > 0 {
+				perPage = min(pp, cfg.MaxPerPage)
 			}
 		}
 
@@ -148,6 +255,41 @@ func PaginationMiddleware() gin.HandlerFunc {
 	})
 }
 
+// ExcerptLengthMiddleware reads the excerpt_length parameter, which shortens
+// the excerpts in post listings. It must be a positive integer and is
+// clamped to maxLength; without it excerpts are returned as stored.
+func ExcerptLengthMiddleware(maxLength int) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		raw := c.Query("excerpt_length")
+		if raw == "" {
+			c.Next()
+			return
+		}
+
+		length, err := strconv.Atoi(raw)
+		if err != nil || length < 1 {
+			respond.Abort(c, http.StatusBadRequest, "excerpt_length must be a positive integer")
+			return
+		}
+		if maxLength > 0 {
+			length = min(length, maxLength)
+		}
+
+		c.Set("excerpt_length", length)
+		c.Next()
+	})
+}
+
+// AuthorOnlyFieldsMiddleware marks whether post fields only a post's author
+// and admins may see, its status and view count, are hidden from everyone
+// else. Handlers read the setting with AuthorOnlyFields.
+func AuthorOnlyFieldsMiddleware(enabled bool) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		c.Set("author_only_fields", enabled)
+		c.Next()
+	})
+}
+
 // RequestLoggerMiddleware logs HTTP requests
 func RequestLoggerMiddleware() gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
@@ -196,6 +338,16 @@ func GetUserUsername(c *gin.Context) (string, bool) {
 	return username.(string), true
 }
 
+// GetTokenClaims returns the claims of the token the request was
+// authenticated with
+func GetTokenClaims(c *gin.Context) (*utils.JWTClaims, bool) {
+	claims, exists := c.Get("token_claims")
+	if !exists {
+		return nil, false
+	}
+	return claims.(*utils.JWTClaims), true
+}
+
 func IsAdmin(c *gin.Context) bool {
 	isAdmin, exists := c.Get("is_admin")
 	if !exists {
@@ -209,3 +361,15 @@ func GetPaginationParams(c *gin.Context) (int, int) {
 	perPage, _ := c.Get("per_page")
 	return page.(int), perPage.(int)
 }
+
+// GetExcerptLength returns the excerpt length requested with excerpt_length,
+// or 0 to keep excerpts as stored
+func GetExcerptLength(c *gin.Context) int {
+	return c.GetInt("excerpt_length")
+}
+
+// AuthorOnlyFields reports whether post status and view counts are hidden
+// from readers other than the post's author and admins
+func AuthorOnlyFields(c *gin.Context) bool {
+	return c.GetBool("author_only_fields")
+}
@@ -0,0 +1,326 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware_Cookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		JWT: config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour},
+		Cookie: config.CookieConfig{
+			Enabled: true,
+			Name:    "access_token",
+		},
+	}
+	token, err := utils.GenerateToken(&models.User{ID: 3, Email: "jane@example.com", Username: "jane"}, cfg)
+	require.NoError(t, err)
+
+	newRouter := func(cfg *config.Config) *gin.Engine {
+		router := gin.New()
+		router.GET("/me", AuthMiddleware(cfg, nil), func(c *gin.Context) {
+			userID, _ := GetUserID(c)
+			c.JSON(http.StatusOK, gin.H{"user_id": userID})
+		})
+		return router
+	}
+
+	t.Run("falls back to the cookie", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+		w := httptest.NewRecorder()
+		newRouter(cfg).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"user_id":3}`, w.Body.String())
+	})
+
+	t.Run("header still works", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		newRouter(cfg).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("cookie ignored when cookie mode is off", func(t *testing.T) {
+		headerOnly := *cfg
+		headerOnly.Cookie.Enabled = false
+
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+		w := httptest.NewRecorder()
+		newRouter(&headerOnly).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("refresh tokens are rejected", func(t *testing.T) {
+		refresh, err := utils.GenerateRefreshToken(&models.User{ID: 3}, cfg, time.Hour)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+refresh)
+		w := httptest.NewRecorder()
+		newRouter(cfg).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(CORS(config.CORSConfig{MaxAge: 2 * time.Hour, ExposeHeaders: []string{"Location", "ETag"}}))
+	router.GET("/posts", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, method := range []string{"OPTIONS", "GET"} {
+		t.Run(method, func(t *testing.T) {
+			req := httptest.NewRequest(method, "/posts", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, "7200", w.Header().Get("Access-Control-Max-Age"))
+			assert.Equal(t, "Location, ETag", w.Header().Get("Access-Control-Expose-Headers"))
+		})
+	}
+
+	t.Run("unset", func(t *testing.T) {
+		router := gin.New()
+		router.Use(CORS(config.CORSConfig{}))
+
+		req := httptest.NewRequest("OPTIONS", "/posts", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Max-Age"))
+		assert.Empty(t, w.Header().Get("Access-Control-Expose-Headers"))
+	})
+
+	t.Run("credentials only for allowed origins", func(t *testing.T) {
+		cache := NewResponseCache(NewMemoryResponseCacheStore(), time.Minute)
+		router := gin.New()
+		router.Use(CORS(config.CORSConfig{AllowedOrigins: []string{"https://blog.example.com", "https://admin.example.com"}}))
+		router.GET("/posts", cache.Middleware(nil), func(c *gin.Context) {
+			CacheResponse(c, "posts")
+			c.Status(http.StatusOK)
+		})
+
+		send := func(origin string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", "/posts", nil)
+			req.Header.Set("Origin", origin)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := send("https://blog.example.com")
+		assert.Equal(t, "https://blog.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+		// Cached responses keep the CORS headers of the request they answer
+		w = send("https://admin.example.com")
+		require.Equal(t, "HIT", w.Header().Get("X-Cache"))
+		assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+		w = send("https://evil.example.com")
+		require.Equal(t, "HIT", w.Header().Get("X-Cache"))
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+}
+
+func TestPasswordChangeMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour}}
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(cfg, nil), PasswordChangeMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		mustChange bool
+		want       int
+	}{
+		{"password change pending", true, http.StatusForbidden},
+		{"password already changed", false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := utils.GenerateToken(&models.User{ID: 3, MustChangePassword: tt.mustChange}, cfg)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest("GET", "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestPaginationMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(strict bool) *gin.Engine {
+		cfg := config.PaginationConfig{DefaultPerPage: 20, MaxPerPage: 50, Strict: strict}
+		router := gin.New()
+		router.GET("/items", PaginationMiddleware(cfg), func(c *gin.Context) {
+			page, perPage := GetPaginationParams(c)
+			c.JSON(http.StatusOK, gin.H{"page": page, "per_page": perPage})
+		})
+		return router
+	}
+
+	get := func(router *gin.Engine, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/items"+query, nil))
+		return w
+	}
+
+	t.Run("lenient mode normalizes", func(t *testing.T) {
+		router := newRouter(false)
+		tests := []struct {
+			query string
+			want  string
+		}{
+			{"", `{"page":1,"per_page":20}`},
+			{"?page=3&per_page=30", `{"page":3,"per_page":30}`},
+			{"?per_page=500", `{"page":1,"per_page":50}`},
+			{"?page=0", `{"page":1,"per_page":20}`},
+			{"?page=-2", `{"page":1,"per_page":20}`},
+			{"?page=abc&per_page=xyz", `{"page":1,"per_page":20}`},
+		}
+
+		for _, tt := range tests {
+			w := get(router, tt.query)
+			require.Equal(t, http.StatusOK, w.Code, tt.query)
+			assert.JSONEq(t, tt.want, w.Body.String(), tt.query)
+		}
+	})
+
+	t.Run("strict mode rejects", func(t *testing.T) {
+		router := newRouter(true)
+
+		w := get(router, "?page=3&per_page=50")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"page":3,"per_page":50}`, w.Body.String())
+
+		w = get(router, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"page":1,"per_page":20}`, w.Body.String())
+
+		tests := map[string]string{
+			"?page=abc":     "page must be a positive integer",
+			"?page=0":       "page must be a positive integer",
+			"?per_page=xyz": "per_page must be an integer between 1 and 50",
+			"?per_page=0":   "per_page must be an integer between 1 and 50",
+			"?per_page=51":  "per_page must be an integer between 1 and 50",
+		}
+		for query, message := range tests {
+			w := get(router, query)
+			require.Equal(t, http.StatusBadRequest, w.Code, query)
+			assert.Contains(t, w.Body.String(), message, query)
+		}
+	})
+}
+
+func TestExcerptLengthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/items", ExcerptLengthMiddleware(100), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"excerpt_length": GetExcerptLength(c)})
+	})
+
+	tests := []struct {
+		query string
+		code  int
+		want  int
+	}{
+		{"", http.StatusOK, 0},
+		{"?excerpt_length=40", http.StatusOK, 40},
+		{"?excerpt_length=5000", http.StatusOK, 100},
+		{"?excerpt_length=0", http.StatusBadRequest, 0},
+		{"?excerpt_length=short", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/items"+tt.query, nil))
+		require.Equal(t, tt.code, w.Code, tt.query)
+		if tt.code == http.StatusOK {
+			assert.JSONEq(t, fmt.Sprintf(`{"excerpt_length":%d}`, tt.want), w.Body.String(), tt.query)
+		}
+	}
+}
+
+// revokedIDs is a RevocationChecker over a fixed set of token IDs
+type revokedIDs map[string]bool
+
+func (r revokedIDs) IsRevoked(jti string) (bool, error) {
+	return r[jti], nil
+}
+
+func TestAuthMiddleware_Revoked(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour}}
+	user := &models.User{ID: 5, Email: "gone@example.com", Username: "gone"}
+	revokedToken, err := utils.GenerateToken(user, cfg)
+	require.NoError(t, err)
+	liveToken, err := utils.GenerateToken(user, cfg)
+	require.NoError(t, err)
+
+	claims, err := utils.ValidateToken(revokedToken, cfg)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.ID)
+	revoked := revokedIDs{claims.ID: true}
+
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(cfg, revoked), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/feed", OptionalAuthMiddleware(cfg, revoked), func(c *gin.Context) {
+		userID, _ := GetUserID(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": userID})
+	})
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("/me", revokedToken).Code)
+	assert.Equal(t, http.StatusOK, get("/me", liveToken).Code)
+
+	// Optional auth treats a revoked token as none
+	w := get("/feed", revokedToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":0}`, w.Body.String())
+	assert.JSONEq(t, `{"user_id":5}`, get("/feed", liveToken).Body.String())
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
+)
+
+// rateLimitWindow tracks how many requests a client made in the current window
+type rateLimitWindow struct {
+	start time.Time
+	count int
+}
+
+// RateLimiter is a fixed-window, in-memory rate limiter keyed by client IP.
+// Counts are kept per process, so each instance enforces its own limit.
+type RateLimiter struct {
+	limit   int
+	window  time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	clients map[string]*rateLimitWindow
+}
+
+// NewRateLimiter creates a limiter allowing limit requests per window
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		clients: make(map[string]*rateLimitWindow),
+	}
+}
+
+// Allow records a request for key and reports whether it is within the
+// limit, along with how long until the current window resets
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	entry, exists := l.clients[key]
+	if !exists || now.Sub(entry.start) >= l.window {
+		l.sweep(now)
+		entry = &rateLimitWindow{start: now}
+		l.clients[key] = entry
+	}
+
+	retryAfter := entry.start.Add(l.window).Sub(now)
+	if entry.count >= l.limit {
+		return false, retryAfter
+	}
+
+	entry.count++
+	return true, retryAfter
+}
+
+// sweep drops windows that have expired so the map doesn't grow unbounded
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, entry := range l.clients {
+		if now.Sub(entry.start) >= l.window {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// Middleware rejects requests over the limit with 429 Too Many Requests
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		l.throttle(c)
+	})
+}
+
+// GuestMiddleware is like Middleware but only counts unauthenticated
+// requests, so it must run after OptionalAuthMiddleware
+func (l *RateLimiter) GuestMiddleware() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if _, authenticated := GetUserID(c); authenticated {
+			c.Next()
+			return
+		}
+		l.throttle(c)
+	})
+}
+
+// throttle aborts with 429 when the client is over the limit
+func (l *RateLimiter) throttle(c *gin.Context) {
+	allowed, retryAfter := l.Allow(c.ClientIP())
+	if !allowed {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		respond.Abort(c, http.StatusTooManyRequests, "Too many requests, please try again later")
+		return
+	}
+
+	c.Next()
+}
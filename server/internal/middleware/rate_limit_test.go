@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	allowed, _ := limiter.Allow("1.2.3.4")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("1.2.3.4")
+	assert.True(t, allowed)
+
+	allowed, retryAfter := limiter.Allow("1.2.3.4")
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// Other clients have their own budget
+	allowed, _ = limiter.Allow("5.6.7.8")
+	assert.True(t, allowed)
+
+	// The window resets once it has elapsed
+	now = now.Add(time.Minute)
+	allowed, _ = limiter.Allow("1.2.3.4")
+	assert.True(t, allowed)
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(1, time.Minute)
+	router := gin.New()
+	router.GET("/check", limiter.Middleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/check", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/check", nil))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+func TestRateLimiter_GuestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(1, time.Minute)
+	router := gin.New()
+	router.POST("/comments", func(c *gin.Context) {
+		if c.GetHeader("X-Test-User") != "" {
+			c.Set("user_id", uint(1))
+		}
+	}, limiter.GuestMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	post := func(authenticated bool) int {
+		req := httptest.NewRequest("POST", "/comments", nil)
+		if authenticated {
+			req.Header.Set("X-Test-User", "1")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusCreated, post(false))
+	require.Equal(t, http.StatusTooManyRequests, post(false))
+
+	// Logged-in users are not limited
+	require.Equal(t, http.StatusCreated, post(true))
+	require.Equal(t, http.StatusCreated, post(true))
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxResponseCacheEntries bounds MemoryResponseCacheStore, so requests with
+// ever-changing query strings can't grow it without limit
+const maxResponseCacheEntries = 10000
+
+// cacheTagsKey is the context key under which handlers mark a response as
+// cacheable
+const cacheTagsKey = "response_cache_tags"
+
+// CachedResponse is a stored response, served again for the same request
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// Tags name what the response was built from, so it can be invalidated
+	// when that changes
+	Tags []string
+}
+
+// ResponseCacheStore keeps responses by request. Implementations must be
+// safe for concurrent use.
+type ResponseCacheStore interface {
+	// Get returns the unexpired response stored under key
+	Get(key string) (*CachedResponse, bool)
+
+	// Set stores response under key for ttl
+	Set(key string, response CachedResponse, ttl time.Duration)
+
+	// Invalidate drops every response carrying any of tags
+	Invalidate(tags ...string)
+}
+
+// responseCacheEntry is a key in MemoryResponseCacheStore
+type responseCacheEntry struct {
+	response  CachedResponse
+	expiresAt time.Time
+}
+
+// MemoryResponseCacheStore is an in-memory ResponseCacheStore. Responses are
+// kept per process, so with several instances each caches and invalidates
+// its own copies; use a shared store such as Redis there.
+type MemoryResponseCacheStore struct {
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]*responseCacheEntry
+	tags    map[string]map[string]struct{}
+}
+
+// NewMemoryResponseCacheStore creates an empty in-memory store
+func NewMemoryResponseCacheStore() *MemoryResponseCacheStore {
+	return &MemoryResponseCacheStore{
+		now:     time.Now,
+		entries: make(map[string]*responseCacheEntry),
+		tags:    make(map[string]map[string]struct{}),
+	}
+}
+
+func (s *MemoryResponseCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[key]
+	if !exists || !s.now().Before(entry.expiresAt) {
+		return nil, false
+	}
+	response := entry.response
+	return &response, true
+}
+
+func (s *MemoryResponseCacheStore) Set(key string, response CachedResponse, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.delete(key)
+	if len(s.entries) >= maxResponseCacheEntries {
+		s.sweep(now)
+		if len(s.entries) >= maxResponseCacheEntries {
+			return
+		}
+	}
+
+	s.entries[key] = &responseCacheEntry{response: response, expiresAt: now.Add(ttl)}
+	for _, tag := range response.Tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[string]struct{})
+		}
+		s.tags[tag][key] = struct{}{}
+	}
+}
+
+func (s *MemoryResponseCacheStore) Invalidate(tags ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			s.delete(key)
+		}
+	}
+}
+
+// delete drops key along with its place in the tag index
+func (s *MemoryResponseCacheStore) delete(key string) {
+	entry, exists := s.entries[key]
+	if !exists {
+		return
+	}
+	delete(s.entries, key)
+	for _, tag := range entry.response.Tags {
+		delete(s.tags[tag], key)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+// sweep drops expired responses
+func (s *MemoryResponseCacheStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			s.delete(key)
+		}
+	}
+}
+
+// ResponseCache serves repeated anonymous GET requests from stored responses
+// instead of running the handler again
+type ResponseCache struct {
+	store ResponseCacheStore
+	ttl   time.Duration
+}
+
+// NewResponseCache creates a response cache keeping responses in store for
+// ttl
+func NewResponseCache(store ResponseCacheStore, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{store: store, ttl: ttl}
+}
+
+// CacheResponse marks the response being handled as safe to cache, tagged
+// with what it was built from. Only marked responses are stored, so
+// handlers decide what may be shared, e.g. published posts but not drafts.
+func CacheResponse(c *gin.Context, tags ...string) {
+	c.Set(cacheTagsKey, tags)
+}
+
+// Middleware caches the responses handlers mark with CacheResponse, keyed by
+// the request path and query. Requests from logged-in users bypass the cache
+// in both directions, since their responses may depend on who they are.
+// onHit, when set, runs for every request answered from the cache, for the
+// side effects the skipped handler would have had. It must run after the
+// optional auth middleware.
+func (rc *ResponseCache) Middleware(onHit func(c *gin.Context, response *CachedResponse)) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if _, loggedIn := GetUserID(c); loggedIn || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		key := c.Request.URL.RequestURI()
+		if cached, ok := rc.store.Get(key); ok {
+			replayHeader(c, cached.Header)
+			c.Header("X-Cache", "HIT")
+			c.Abort()
+
+			// Revalidated requests get a 304, as from the handler
+			if etag := cached.Header.Get("ETag"); etag != "" && ETagMatches(c.GetHeader("If-None-Match"), etag) {
+				c.Status(http.StatusNotModified)
+				return
+			}
+			if onHit != nil {
+				onHit(c, cached)
+			}
+			c.Data(cached.StatusCode, cached.Header.Get("Content-Type"), cached.Body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		value, marked := c.Get(cacheTagsKey)
+		if !marked || recorder.Status() != http.StatusOK {
+			return
+		}
+		rc.store.Set(key, CachedResponse{
+			StatusCode: http.StatusOK,
+			Header:     recorder.Header().Clone(),
+			Body:       recorder.body.Bytes(),
+			Tags:       value.([]string),
+		}, rc.ttl)
+	})
+}
+
+// Invalidate drops the cached responses carrying the tags returned by tags
+// once the request succeeds. It goes on the routes that change what cached
+// responses were built from.
+func (rc *ResponseCache) Invalidate(tags func(c *gin.Context) []string) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		c.Next()
+
+		if status := c.Writer.Status(); status >= 200 && status < 300 {
+			rc.store.Invalidate(tags(c)...)
+		}
+	})
+}
+
+// ETagMatches reports whether an If-None-Match header value matches etag.
+// If-None-Match uses weak comparison, so the W/ prefix is ignored.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
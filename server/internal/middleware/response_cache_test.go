@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := NewMemoryResponseCacheStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	cache := NewResponseCache(store, time.Minute)
+
+	loads, hits := 0, 0
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("X-User") == "1" {
+			c.Set("user_id", uint(1))
+		}
+		c.Next()
+	})
+	router.GET("/posts/:id", cache.Middleware(func(c *gin.Context, response *CachedResponse) {
+		hits++
+	}), func(c *gin.Context) {
+		loads++
+		if c.Param("id") != "draft" {
+			CacheResponse(c, "post:"+c.Param("id"))
+		}
+		c.Header("ETag", `W/"`+strconv.Itoa(loads)+`"`)
+		c.JSON(http.StatusOK, gin.H{"loads": loads})
+	})
+	router.PUT("/posts/:id", cache.Invalidate(func(c *gin.Context) []string {
+		return []string{"post:" + c.Param("id")}
+	}), func(c *gin.Context) {
+		if c.Query("fail") == "true" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	send := func(method, target, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("repeated request is served from the cache", func(t *testing.T) {
+		first := send("GET", "/posts/1", "")
+		second := send("GET", "/posts/1", "")
+
+		require.Equal(t, http.StatusOK, second.Code)
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+		assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+		assert.Equal(t, 1, loads)
+		assert.Equal(t, 1, hits)
+	})
+
+	t.Run("query string is part of the key", func(t *testing.T) {
+		loads = 0
+		send("GET", "/posts/1?include=comments", "")
+		assert.Equal(t, 1, loads)
+	})
+
+	t.Run("matching If-None-Match gets a 304", func(t *testing.T) {
+		etag := send("GET", "/posts/1", "").Header().Get("ETag")
+
+		req := httptest.NewRequest("GET", "/posts/1", nil)
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("logged-in users bypass the cache", func(t *testing.T) {
+		loads = 0
+		w := send("GET", "/posts/1", "1")
+		assert.Empty(t, w.Header().Get("X-Cache"))
+		assert.Equal(t, 1, loads)
+	})
+
+	t.Run("unmarked responses aren't cached", func(t *testing.T) {
+		loads = 0
+		send("GET", "/posts/draft", "")
+		send("GET", "/posts/draft", "")
+		assert.Equal(t, 2, loads)
+	})
+
+	t.Run("successful writes invalidate the tagged responses", func(t *testing.T) {
+		send("GET", "/posts/1", "")
+		send("GET", "/posts/2", "")
+
+		send("PUT", "/posts/1?fail=true", "1")
+		assert.Equal(t, "HIT", send("GET", "/posts/1", "").Header().Get("X-Cache"), "failed writes leave the cache alone")
+
+		send("PUT", "/posts/1", "1")
+		assert.Empty(t, send("GET", "/posts/1", "").Header().Get("X-Cache"))
+		assert.Equal(t, "HIT", send("GET", "/posts/2", "").Header().Get("X-Cache"))
+	})
+
+	t.Run("responses expire", func(t *testing.T) {
+		send("GET", "/posts/3", "")
+		now = now.Add(time.Minute)
+		assert.Empty(t, send("GET", "/posts/3", "").Header().Get("X-Cache"))
+	})
+}
@@ -5,23 +5,17 @@ import (
 
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"gorm.io/gorm"
 )
 
-// RunMigrations runs all database migrations
-func RunMigrations() error {
+// RunMigrations runs all database migrations, then creates the default admin
+// from admin and the default tags
+func RunMigrations(admin config.AdminConfig) error {
 	db := config.GetDB()
 
 	log.Println("🔄 Running database migrations...")
 
-	// Auto-migrate all models
-	err := db.AutoMigrate(
-		&models.User{},
-		&models.Tag{},
-		&models.Post{},
-		&models.Comment{},
-	)
-
-	if err != nil {
+	if err := Migrate(db); err != nil {
 		log.Printf("❌ Migration failed: %v", err)
 		return err
 	}
@@ -29,7 +23,7 @@ func RunMigrations() error {
 	log.Println("✅ Database migrations completed successfully")
 
 	// Create default admin user if it doesn't exist
-	if err := createDefaultAdmin(); err != nil {
+	if err := createDefaultAdmin(db, admin); err != nil {
 		log.Printf("⚠️  Warning: Failed to create default admin user: %v", err)
 	}
 
@@ -41,34 +35,68 @@ func RunMigrations() error {
 	return nil
 }
 
-// createDefaultAdmin creates a default admin user
-func createDefaultAdmin() error {
-	db := config.GetDB()
+// Migrate brings the schema up to date: AutoMigrate creates the baseline
+// tables on a fresh database, then pending versioned migrations are applied
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&models.User{},
+		&models.Tag{},
+		&models.TagSlugHistory{},
+		&models.Series{},
+		&models.Post{},
+		&models.PostSlugHistory{},
+		&models.PostModerationLog{},
+		&models.Comment{},
+		&models.CommentReport{},
+		&models.RevokedToken{},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = NewMigrator(db, migrations).Up()
+	return err
+}
 
-	var user models.User
-	result := db.Where("email = ?", "admin@blog.com").First(&user)
+// NewDefaultMigrator returns a migrator for the application's migrations
+func NewDefaultMigrator(db *gorm.DB) *Migrator {
+	return NewMigrator(db, migrations)
+}
+
+// createDefaultAdmin creates the configured admin account unless an admin
+// already exists. The account must change its password on first login.
+func createDefaultAdmin(db *gorm.DB, admin config.AdminConfig) error {
+	var admins int64
+	if err := db.Model(&models.User{}).Where("is_admin = ?", true).Count(&admins).Error; err != nil {
+		return err
+	}
+	if admins > 0 {
+		log.Println("ℹ️  Admin user already exists")
+		return nil
+	}
 
-	if result.Error == nil {
-		log.Println("ℹ️  Default admin user already exists")
+	if admin.Email == "" || admin.Password == "" {
+		log.Println("⚠️  ADMIN_EMAIL and ADMIN_PASSWORD are not set, skipping default admin user")
 		return nil
 	}
 
 	adminUser := models.User{
-		FirstName: "Admin",
-		LastName:  "User",
-		Email:     "admin@blog.com",
-		Username:  "admin",
-		Password:  "admin123456", // This will be hashed by the BeforeCreate hook
-		Bio:       "Default administrator account",
-		IsActive:  true,
-		IsAdmin:   true,
+		FirstName:          "Admin",
+		LastName:           "User",
+		Email:              admin.Email,
+		Username:           "admin",
+		Password:           admin.Password, // This will be hashed by the BeforeCreate hook
+		Bio:                "Default administrator account",
+		IsActive:           true,
+		IsAdmin:            true,
+		MustChangePassword: true,
 	}
 
 	if err := db.Create(&adminUser).Error; err != nil {
 		return err
 	}
 
-	log.Println("✅ Default admin user created (admin@blog.com / admin123456)")
+	log.Printf("✅ Default admin user created (%s), change its password on first login", admin.Email)
 	return nil
 }
 
@@ -77,11 +105,11 @@ func createDefaultTags() error {
 	db := config.GetDB()
 
 	defaultTags := []models.Tag{
-		{Name: "Technology", Slug: "technology", Description: "Posts about technology and programming", Color: "#3B82F6"},
-		{Name: "Lifestyle", Slug: "lifestyle", Description: "Posts about lifestyle and personal experiences", Color: "#10B981"},
-		{Name: "Tutorial", Slug: "tutorial", Description: "Educational and how-to posts", Color: "#F59E0B"},
-		{Name: "News", Slug: "news", Description: "Latest news and updates", Color: "#EF4444"},
-		{Name: "Opinion", Slug: "opinion", Description: "Personal opinions and thoughts", Color: "#8B5CF6"},
+		{Name: "Technology", Slug: "technology", Description: "Posts about technology and programming", Color: "#3b82f6"},
+		{Name: "Lifestyle", Slug: "lifestyle", Description: "Posts about lifestyle and personal experiences", Color: "#10b981"},
+		{Name: "Tutorial", Slug: "tutorial", Description: "Educational and how-to posts", Color: "#f59e0b"},
+		{Name: "News", Slug: "news", Description: "Latest news and updates", Color: "#ef4444"},
+		{Name: "Opinion", Slug: "opinion", Description: "Personal opinions and thoughts", Color: "#8b5cf6"},
 	}
 
 	for _, tag := range defaultTags {
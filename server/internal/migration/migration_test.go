@@ -0,0 +1,89 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDefaultAdmin(t *testing.T) {
+	t.Run("created from config and must change password", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+
+		require.NoError(t, createDefaultAdmin(db, config.AdminConfig{Email: "ops@example.com", Password: "k3ep-it-s3cret"}))
+
+		var admin models.User
+		require.NoError(t, db.Where("email = ?", "ops@example.com").First(&admin).Error)
+		assert.True(t, admin.IsAdmin)
+		assert.True(t, admin.MustChangePassword)
+		assert.True(t, admin.CheckPassword("k3ep-it-s3cret"))
+	})
+
+	t.Run("skipped without credentials", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+
+		require.NoError(t, createDefaultAdmin(db, config.AdminConfig{}))
+
+		var users int64
+		db.Model(&models.User{}).Count(&users)
+		assert.Zero(t, users)
+	})
+
+	t.Run("skipped when an admin exists", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		existing := testutil.CreateUser(t, db, "root")
+		require.NoError(t, db.Model(existing).Update("is_admin", true).Error)
+
+		require.NoError(t, createDefaultAdmin(db, config.AdminConfig{Email: "ops@example.com", Password: "k3ep-it-s3cret"}))
+
+		var users int64
+		db.Model(&models.User{}).Count(&users)
+		assert.Equal(t, int64(1), users)
+	})
+}
+
+func TestFlagDefaultAdminPassword(t *testing.T) {
+	db := testutil.NewTestDB(t)
+
+	legacy := &models.User{FirstName: "Admin", LastName: "User", Email: "admin@blog.com", Username: "admin", Password: "admin123456", IsAdmin: true}
+	require.NoError(t, db.Create(legacy).Error)
+	rotated := &models.User{FirstName: "Other", LastName: "Admin", Email: "other@blog.com", Username: "other", Password: "n0t-the-default", IsAdmin: true}
+	require.NoError(t, db.Create(rotated).Error)
+
+	require.NoError(t, flagDefaultAdminPassword(db))
+
+	require.NoError(t, db.First(legacy, legacy.ID).Error)
+	assert.True(t, legacy.MustChangePassword)
+	require.NoError(t, db.First(rotated, rotated.ID).Error)
+	assert.False(t, rotated.MustChangePassword)
+}
+
+func TestBackfillCommentDepth(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "threader")
+	post := &models.Post{Title: "Threaded post", Slug: "threaded-post", Content: "Deep discussions", AuthorID: author.ID}
+	require.NoError(t, db.Create(post).Error)
+
+	// Comments stored before depth was, all at the default of 0
+	var parentID *uint
+	var thread []*models.Comment
+	for i := 0; i < 3; i++ {
+		comment := &models.Comment{Content: "In the thread", PostID: post.ID, AuthorID: &author.ID, ParentID: parentID}
+		require.NoError(t, db.Create(comment).Error)
+		thread = append(thread, comment)
+		parentID = &comment.ID
+	}
+	require.NoError(t, db.Delete(thread[1]).Error, "soft-deleted comments keep their place in the thread")
+
+	require.NoError(t, backfillCommentDepth(db))
+
+	for want, comment := range thread {
+		var got models.Comment
+		require.NoError(t, db.Unscoped().First(&got, comment.ID).Error)
+		assert.Equal(t, want, got.Depth)
+	}
+}
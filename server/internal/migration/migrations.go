@@ -0,0 +1,153 @@
+package migration
+
+import (
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"gorm.io/gorm"
+)
+
+// migrations is the ordered list of schema changes applied on top of the
+// AutoMigrate baseline. Append new migrations with the next version number;
+// never renumber or edit one that has shipped.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "add_hot_path_indexes",
+		Up: createIndexes(
+			"CREATE INDEX IF NOT EXISTS idx_posts_status_published_at ON posts (status, published_at)",
+			"CREATE INDEX IF NOT EXISTS idx_posts_author_id_status ON posts (author_id, status)",
+			"CREATE INDEX IF NOT EXISTS idx_comments_post_id_parent_id_status ON comments (post_id, parent_id, status)",
+			"CREATE INDEX IF NOT EXISTS idx_comments_status ON comments (status)",
+		),
+		Down: dropIndexes(
+			"idx_posts_status_published_at",
+			"idx_posts_author_id_status",
+			"idx_comments_post_id_parent_id_status",
+			"idx_comments_status",
+		),
+	},
+	{
+		Version: 2,
+		Name:    "normalize_tag_colors",
+		Up:      normalizeTagColors,
+		// Normalized colors are still valid, so there is nothing to undo
+		Down: func(tx *gorm.DB) error { return nil },
+	},
+	{
+		Version: 3,
+		Name:    "flag_default_admin_password",
+		Up:      flagDefaultAdminPassword,
+		// The flag is cleared by changing the password
+		Down: func(tx *gorm.DB) error { return nil },
+	},
+	{
+		Version: 4,
+		Name:    "backfill_comment_depth",
+		Up:      backfillCommentDepth,
+		// The depth column itself comes from AutoMigrate and stays
+		Down: func(tx *gorm.DB) error { return nil },
+	},
+	{
+		Version: 5,
+		Name:    "add_comment_duplicate_index",
+		// Serves the lookup of an author's recent comments on a post when
+		// rejecting duplicates
+		Up: createIndexes(
+			"CREATE INDEX IF NOT EXISTS idx_comments_post_id_author_id_created_at ON comments (post_id, author_id, created_at)",
+		),
+		Down: dropIndexes("idx_comments_post_id_author_id_created_at"),
+	},
+}
+
+// backfillCommentDepth sets the depth of replies posted before comments
+// stored it, a level at a time from the top-level comments down
+func backfillCommentDepth(tx *gorm.DB) error {
+	var parents []uint
+	if err := tx.Unscoped().Model(&models.Comment{}).Where("parent_id IS NULL").Pluck("id", &parents).Error; err != nil {
+		return err
+	}
+
+	for depth := 1; len(parents) > 0; depth++ {
+		var replies []uint
+		if err := tx.Unscoped().Model(&models.Comment{}).Where("parent_id IN ?", parents).Pluck("id", &replies).Error; err != nil {
+			return err
+		}
+		if len(replies) > 0 {
+			if err := tx.Unscoped().Model(&models.Comment{}).Where("id IN ?", replies).UpdateColumn("depth", depth).Error; err != nil {
+				return err
+			}
+		}
+		parents = replies
+	}
+	return nil
+}
+
+// formerDefaultAdminPassword is the password every install's admin used to be
+// created with
+const formerDefaultAdminPassword = "admin123456"
+
+// flagDefaultAdminPassword makes admins still using the former hard-coded
+// default password change it on their next login
+func flagDefaultAdminPassword(tx *gorm.DB) error {
+	var admins []models.User
+	if err := tx.Where("is_admin = ?", true).Find(&admins).Error; err != nil {
+		return err
+	}
+
+	for _, admin := range admins {
+		if !admin.CheckPassword(formerDefaultAdminPassword) {
+			continue
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", admin.ID).Update("must_change_password", true).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeTagColors rewrites stored tag colors in the lowercase #rrggbb form
+// the tag service now enforces. Values that aren't hex colors are left alone.
+func normalizeTagColors(tx *gorm.DB) error {
+	var tags []struct {
+		ID    uint
+		Color string
+	}
+	if err := tx.Table("tags").Select("id, color").Scan(&tags).Error; err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		color, ok := utils.NormalizeHexColor(tag.Color)
+		if !ok || color == tag.Color {
+			continue
+		}
+		if err := tx.Table("tags").Where("id = ?", tag.ID).Update("color", color).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createIndexes returns a migration step running each CREATE INDEX statement
+func createIndexes(statements ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// dropIndexes returns a migration step dropping each named index
+func dropIndexes(names ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, name := range names {
+			if err := tx.Exec("DROP INDEX IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,135 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestMigrator(t *testing.T) {
+	db := testutil.NewTestDB(t)
+
+	testMigrations := []Migration{
+		{
+			Version: 2,
+			Name:    "add_widget_name_index",
+			Up: func(tx *gorm.DB) error {
+				return tx.Exec("CREATE INDEX idx_widgets_name ON widgets (name)").Error
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Exec("DROP INDEX idx_widgets_name").Error
+			},
+		},
+		{
+			Version: 1,
+			Name:    "create_widgets",
+			Up: func(tx *gorm.DB) error {
+				return tx.Migrator().CreateTable(&widget{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&widget{})
+			},
+		},
+	}
+	migrator := NewMigrator(db, testMigrations)
+
+	ran, err := migrator.Up()
+	require.NoError(t, err)
+	require.Len(t, ran, 2)
+	assert.Equal(t, uint(1), ran[0].Version, "migrations run in version order")
+	assert.True(t, db.Migrator().HasIndex(&widget{}, "idx_widgets_name"))
+
+	// Applying again is a no-op
+	ran, err = migrator.Up()
+	require.NoError(t, err)
+	assert.Empty(t, ran)
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.True(t, statuses[1].Applied)
+
+	// Roll back the newest migration only
+	rolledBack, err := migrator.Down(1)
+	require.NoError(t, err)
+	require.Len(t, rolledBack, 1)
+	assert.Equal(t, uint(2), rolledBack[0].Version)
+	assert.False(t, db.Migrator().HasIndex(&widget{}, "idx_widgets_name"))
+	assert.True(t, db.Migrator().HasTable(&widget{}))
+
+	statuses, err = migrator.Status()
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+
+	// Roll back everything
+	rolledBack, err = migrator.Down(5)
+	require.NoError(t, err)
+	require.Len(t, rolledBack, 1)
+	assert.False(t, db.Migrator().HasTable(&widget{}))
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	db := testutil.NewTestDB(t)
+
+	migrator := NewMigrator(db, []Migration{{
+		Version: 1,
+		Name:    "broken",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE missing_table ADD COLUMN x INTEGER").Error
+		},
+	}})
+
+	_, err := migrator.Up()
+	require.Error(t, err)
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	assert.False(t, statuses[0].Applied)
+}
+
+func TestMigrations_ApplyAndRollBack(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	migrator := NewDefaultMigrator(db)
+
+	_, err := migrator.Up()
+	require.NoError(t, err)
+	assert.True(t, db.Migrator().HasIndex("posts", "idx_posts_status_published_at"))
+	assert.True(t, db.Migrator().HasIndex("comments", "idx_comments_status"))
+	assert.True(t, db.Migrator().HasIndex("comments", "idx_comments_post_id_author_id_created_at"))
+
+	_, err = migrator.Down(len(migrations))
+	require.NoError(t, err)
+	assert.False(t, db.Migrator().HasIndex("posts", "idx_posts_status_published_at"))
+	assert.False(t, db.Migrator().HasIndex("comments", "idx_comments_status"))
+	assert.False(t, db.Migrator().HasIndex("comments", "idx_comments_post_id_author_id_created_at"))
+}
+
+func TestNormalizeTagColors(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	for name, color := range map[string]string{"upper": "#3B82F6", "short": "#FFF", "lower": "#10b981", "broken": "nope"} {
+		require.NoError(t, db.Exec("INSERT INTO tags (name, slug, color) VALUES (?, ?, ?)", name, name, color).Error)
+	}
+
+	require.NoError(t, normalizeTagColors(db))
+
+	var tags []struct {
+		Name  string
+		Color string
+	}
+	require.NoError(t, db.Table("tags").Select("name, color").Scan(&tags).Error)
+	colors := map[string]string{}
+	for _, tag := range tags {
+		colors[tag.Name] = tag.Color
+	}
+	assert.Equal(t, map[string]string{"upper": "#3b82f6", "short": "#ffffff", "lower": "#10b981", "broken": "nope"}, colors)
+}
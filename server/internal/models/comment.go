@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type CommentStatus string
@@ -14,31 +16,60 @@ const (
 
 type Comment struct {
 	ID        uint          `json:"id" gorm:"primaryKey"`
-	Content   string        `json:"content" gorm:"type:text;not null" validate:"required,min=1,max=1000"`
+	Content   string        `json:"content" gorm:"type:text;not null" validate:"required,min=1"`
 	Status    CommentStatus `json:"status" gorm:"default:'pending'" validate:"oneof=pending approved rejected"`
-	AuthorID  uint          `json:"author_id" gorm:"not null" validate:"required"`
+	AuthorID  *uint         `json:"author_id" gorm:"index"` // Nil for guest comments
 	PostID    uint          `json:"post_id" gorm:"not null" validate:"required"`
 	ParentID  *uint         `json:"parent_id" gorm:"index"` // For nested comments/replies
 	CreatedAt time.Time     `json:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at"`
 
+	// EditedAt is when the content was last changed, nil if it never was.
+	// UpdatedAt also moves on moderation, so it can't tell edits apart.
+	EditedAt *time.Time `json:"edited_at"`
+
+	// Depth is 0 for top-level comments and the parent's depth + 1 for
+	// replies, so the nesting limit is checked without walking the thread
+	Depth int `json:"depth" gorm:"not null;default:0"`
+
+	// Guest details, set instead of AuthorID for unauthenticated comments
+	GuestName  string `json:"guest_name,omitempty" gorm:"size:50"`
+	GuestEmail string `json:"-" gorm:"size:100"`
+
+	// DeletedAt soft-deletes the comment so moderation history is kept
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
 	// Relationships
-	Author  User      `json:"author" gorm:"foreignKey:AuthorID"`
-	Post    Post      `json:"post" gorm:"foreignKey:PostID"`
-	Parent  *Comment  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
-	Replies []Comment `json:"replies,omitempty" gorm:"foreignKey:ParentID"`
+	Author  User            `json:"author" gorm:"foreignKey:AuthorID"`
+	Post    Post            `json:"post" gorm:"foreignKey:PostID"`
+	Parent  *Comment        `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
+	Replies []Comment       `json:"replies,omitempty" gorm:"foreignKey:ParentID"`
+	Reports []CommentReport `json:"-" gorm:"foreignKey:CommentID"`
+}
+
+// PendingCommentFilter narrows the moderation queue to the comments on
+// PostID, when set, created between From and To. Both bounds are inclusive;
+// a nil bound is open.
+type PendingCommentFilter struct {
+	PostID uint
+	From   *time.Time
+	To     *time.Time
 }
 
 // CommentCreateRequest represents the request for creating a new comment
 type CommentCreateRequest struct {
-	Content  string `json:"content" validate:"required,min=1,max=1000"`
+	Content  string `json:"content" validate:"required,min=1"`
 	PostID   uint   `json:"post_id" validate:"required"`
 	ParentID *uint  `json:"parent_id" validate:"omitempty"`
+
+	// Required when commenting without logging in, ignored otherwise
+	GuestName  string `json:"guest_name" validate:"omitempty,min=2,max=50"`
+	GuestEmail string `json:"guest_email" validate:"omitempty,email,max=100"`
 }
 
 // CommentUpdateRequest represents the request for updating a comment
 type CommentUpdateRequest struct {
-	Content string        `json:"content" validate:"omitempty,min=1,max=1000"`
+	Content string        `json:"content" validate:"omitempty,min=1"`
 	Status  CommentStatus `json:"status" validate:"omitempty,oneof=pending approved rejected"`
 }
 
@@ -47,13 +78,35 @@ type CommentResponse struct {
 	ID        uint              `json:"id"`
 	Content   string            `json:"content"`
 	Status    CommentStatus     `json:"status"`
-	AuthorID  uint              `json:"author_id"`
+	AuthorID  *uint             `json:"author_id"`
 	PostID    uint              `json:"post_id"`
+	PostSlug  string            `json:"post_slug,omitempty"`
+	PostTitle string            `json:"post_title,omitempty"`
 	ParentID  *uint             `json:"parent_id"`
+	Depth     int               `json:"depth"`
 	Author    UserResponse      `json:"author"`
+	IsGuest   bool              `json:"is_guest"`
 	Replies   []CommentResponse `json:"replies,omitempty"`
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
+	EditedAt  *time.Time        `json:"edited_at"`
+
+	// RepliesCount is the number of approved direct replies and HasMore
+	// tells whether Replies holds only the first of them. Both are only
+	// set on a single comment fetched by ID.
+	RepliesCount int  `json:"replies_count,omitempty"`
+	HasMore      bool `json:"has_more,omitempty"`
+}
+
+// IsGuest reports whether the comment was left without an account
+func (c *Comment) IsGuest() bool {
+	return c.AuthorID == nil
+}
+
+// IsAuthoredBy reports whether userID wrote the comment. Guest comments
+// belong to no user.
+func (c *Comment) IsAuthoredBy(userID uint) bool {
+	return c.AuthorID != nil && *c.AuthorID == userID
 }
 
 // ToResponse converts Comment to CommentResponse
@@ -65,9 +118,24 @@ func (c *Comment) ToResponse() CommentResponse {
 		AuthorID:  c.AuthorID,
 		PostID:    c.PostID,
 		ParentID:  c.ParentID,
+		Depth:     c.Depth,
 		Author:    c.Author.ToResponse(),
 		CreatedAt: c.CreatedAt,
 		UpdatedAt: c.UpdatedAt,
+		EditedAt:  c.EditedAt,
+	}
+
+	// Link back to the post when it was loaded with the comment
+	if c.Post.ID != 0 {
+		response.PostSlug = c.Post.Slug
+		response.PostTitle = c.Post.Title
+	}
+
+	// Guests have no account, so present their display name as the author
+	// and keep their email private
+	if c.IsGuest() {
+		response.Author = UserResponse{FirstName: c.GuestName}
+		response.IsGuest = true
 	}
 
 	// Convert replies if they exist
@@ -80,3 +148,73 @@ func (c *Comment) ToResponse() CommentResponse {
 
 	return response
 }
+
+// Commenter is a user with the number of approved comments they wrote on a
+// post
+type Commenter struct {
+	User          User
+	CommentsCount int64
+}
+
+// CommenterResponse is a post's commenter as shown to readers
+type CommenterResponse struct {
+	User          PublicUserResponse `json:"user"`
+	CommentsCount int64              `json:"comments_count"`
+}
+
+// ToResponse converts Commenter to CommenterResponse
+func (c *Commenter) ToResponse() CommenterResponse {
+	return CommenterResponse{
+		User:          c.User.ToPublicResponse(),
+		CommentsCount: c.CommentsCount,
+	}
+}
+
+// CommentReport is a reader's flag on a comment they consider abusive. Each
+// user can report a comment once. Reports are resolved when an admin
+// moderates the comment, so they no longer count towards the threshold.
+type CommentReport struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CommentID  uint      `json:"comment_id" gorm:"not null;uniqueIndex:idx_comment_reports_comment_reporter"`
+	ReporterID uint      `json:"reporter_id" gorm:"not null;uniqueIndex:idx_comment_reports_comment_reporter;index"`
+	Reason     string    `json:"reason" gorm:"size:500;not null"`
+	Resolved   bool      `json:"resolved" gorm:"not null;default:false;index"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Relationships
+	Comment  Comment `json:"-" gorm:"foreignKey:CommentID"`
+	Reporter User    `json:"reporter" gorm:"foreignKey:ReporterID"`
+}
+
+// CommentReportRequest represents the request for reporting a comment
+type CommentReportRequest struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+// CommentReportResponse is a single report on a comment
+type CommentReportResponse struct {
+	ID               uint      `json:"id"`
+	ReporterID       uint      `json:"reporter_id"`
+	ReporterUsername string    `json:"reporter_username"`
+	Reason           string    `json:"reason"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ReportedCommentResponse is a comment in the admin report queue, with its
+// unresolved reports
+type ReportedCommentResponse struct {
+	CommentResponse
+	ReportsCount int                     `json:"reports_count"`
+	Reports      []CommentReportResponse `json:"reports"`
+}
+
+// ToResponse converts CommentReport to CommentReportResponse
+func (r *CommentReport) ToResponse() CommentReportResponse {
+	return CommentReportResponse{
+		ID:               r.ID,
+		ReporterID:       r.ReporterID,
+		ReporterUsername: r.Reporter.Username,
+		Reason:           r.Reason,
+		CreatedAt:        r.CreatedAt,
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 )
 
@@ -12,6 +13,42 @@ const (
 	PostStatusArchived  PostStatus = "archived"
 )
 
+// PublishedRange limits a listing to posts published within it. Both bounds
+// are inclusive; a nil bound is open.
+type PublishedRange struct {
+	After  *time.Time
+	Before *time.Time
+}
+
+// PostFilter narrows a post listing. Published, when set, is shorthand for
+// the status: true matches published posts and false drafts and archived
+// ones.
+type PostFilter struct {
+	Status    PostStatus
+	Published *bool
+	AuthorID  uint
+	PublishedRange
+}
+
+// PostSort selects the order of a post listing
+type PostSort string
+
+const (
+	PostSortNewest   PostSort = "newest"
+	PostSortOldest   PostSort = "oldest"
+	PostSortPopular  PostSort = "popular"
+	PostSortComments PostSort = "comments"
+)
+
+// IsValid reports whether s is a supported sort; empty means the default
+func (s PostSort) IsValid() bool {
+	switch s {
+	case "", PostSortNewest, PostSortOldest, PostSortPopular, PostSortComments:
+		return true
+	}
+	return false
+}
+
 type Post struct {
 	ID          uint       `json:"id" gorm:"primaryKey"`
 	Title       string     `json:"title" gorm:"not null;size:200" validate:"required,min=5,max=200"`
@@ -26,10 +63,96 @@ type Post struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 
+	// Featured pins the post for editors' picks; FeaturedOrder ranks
+	// featured posts, lowest first
+	Featured      bool `json:"featured" gorm:"default:false;index"`
+	FeaturedOrder int  `json:"featured_order" gorm:"default:0"`
+
+	// SeriesID puts the post in a series, at SeriesOrder
+	SeriesID    *uint `json:"series_id" gorm:"index"`
+	SeriesOrder int   `json:"series_order" gorm:"default:0"`
+
 	// Relationships
 	Author   User      `json:"author" gorm:"foreignKey:AuthorID"`
 	Comments []Comment `json:"comments,omitempty" gorm:"foreignKey:PostID"`
 	Tags     []Tag     `json:"tags,omitempty" gorm:"many2many:post_tags;"`
+
+	// CoAuthors share edit rights with the author, who stays the post's
+	// owner in AuthorID
+	CoAuthors []User `json:"co_authors,omitempty" gorm:"many2many:post_authors;"`
+
+	Series *Series `json:"series,omitempty" gorm:"foreignKey:SeriesID"`
+}
+
+// PostSlugHistory records a slug a post used before its title changed, so
+// links to the old slug keep working. A slug is dropped from the history
+// once a post uses it again.
+type PostSlugHistory struct {
+	ID        uint   `gorm:"primaryKey"`
+	PostID    uint   `gorm:"not null;index"`
+	Slug      string `gorm:"uniqueIndex;not null;size:250"`
+	CreatedAt time.Time
+}
+
+// TableName keeps the history in a single post_slug_history table
+func (PostSlugHistory) TableName() string {
+	return "post_slug_history"
+}
+
+// PostModerationAction is what an admin did to another user's post
+type PostModerationAction string
+
+const (
+	PostModerationUnpublish PostModerationAction = "unpublish"
+	PostModerationArchive   PostModerationAction = "archive"
+)
+
+// PostModerationLog records an admin unpublishing or archiving another
+// user's post, with the reason they gave the author
+type PostModerationLog struct {
+	ID          uint                 `gorm:"primaryKey"`
+	PostID      uint                 `gorm:"not null;index"`
+	ModeratorID uint                 `gorm:"not null"`
+	Action      PostModerationAction `gorm:"size:20;not null"`
+	Reason      string               `gorm:"type:text"`
+	CreatedAt   time.Time
+}
+
+// PostFeatureRequest places a post among the featured posts. Order ranks
+// it, lowest first; posts with the same order go newest first.
+type PostFeatureRequest struct {
+	Order int `json:"order" validate:"min=0"`
+}
+
+// PostCoAuthorRequest names a user to add as a co-author of a post
+type PostCoAuthorRequest struct {
+	UserID uint `json:"user_id" validate:"required"`
+}
+
+// PostModerationRequest optionally tells the author why their post is
+// being unpublished or archived
+type PostModerationRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// PostModerationResponse is an entry of a post's moderation log
+type PostModerationResponse struct {
+	ID          uint                 `json:"id"`
+	ModeratorID uint                 `json:"moderator_id"`
+	Action      PostModerationAction `json:"action"`
+	Reason      string               `json:"reason,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+}
+
+// ToResponse converts PostModerationLog to PostModerationResponse
+func (l *PostModerationLog) ToResponse() PostModerationResponse {
+	return PostModerationResponse{
+		ID:          l.ID,
+		ModeratorID: l.ModeratorID,
+		Action:      l.Action,
+		Reason:      l.Reason,
+		CreatedAt:   l.CreatedAt,
+	}
 }
 
 // PostCreateRequest represents the request for creating a new post
@@ -42,44 +165,124 @@ type PostCreateRequest struct {
 	TagIDs      []uint     `json:"tag_ids" validate:"omitempty"`
 }
 
-// PostUpdateRequest represents the request for updating a post
+// PostFrontMatter is the YAML front matter of a Markdown document imported
+// as a post. Other keys, such as the dates static site generators add, are
+// ignored.
+type PostFrontMatter struct {
+	Title  string     `yaml:"title"`
+	Slug   string     `yaml:"slug"`
+	Status PostStatus `yaml:"status"`
+	Tags   []string   `yaml:"tags"`
+}
+
+// PostImportPreview is the post importing a document would create, as
+// reported by a dry run
+type PostImportPreview struct {
+	Title   string     `json:"title"`
+	Slug    string     `json:"slug"`
+	Status  PostStatus `json:"status"`
+	Excerpt string     `json:"excerpt"`
+	Tags    []string   `json:"tags"`
+	// NewTags are the tags that don't exist yet and would be created
+	NewTags []string `json:"new_tags"`
+}
+
+// PostUpdateRequest represents the request for updating a post. It's a
+// partial update: fields left out or null keep their current value, and
+// fields sent are applied as given, so an empty excerpt or featured_image
+// clears it and an empty tag_ids list removes every tag.
 type PostUpdateRequest struct {
-	Title       string     `json:"title" validate:"omitempty,min=5,max=200"`
-	Content     string     `json:"content" validate:"omitempty,min=10"`
-	Excerpt     string     `json:"excerpt" validate:"max=500"`
-	FeaturedImg string     `json:"featured_image" validate:"omitempty,url"`
-	Status      PostStatus `json:"status" validate:"omitempty,oneof=draft published archived"`
-	TagIDs      []uint     `json:"tag_ids" validate:"omitempty"`
+	Title       *string     `json:"title" validate:"omitempty,min=5,max=200"`
+	Content     *string     `json:"content" validate:"omitempty,min=10"`
+	Excerpt     *string     `json:"excerpt" validate:"omitempty,max=500"`
+	FeaturedImg *string     `json:"featured_image" validate:"omitempty,url|eq="`
+	Status      *PostStatus `json:"status" validate:"omitempty,oneof=draft published archived"`
+	TagIDs      []uint      `json:"tag_ids" validate:"omitempty"`
+}
+
+// IgnoreEmpty drops the empty fields of the request, so they leave the post
+// unchanged instead of clearing it. PUT requests use it to keep their
+// original semantics.
+func (r *PostUpdateRequest) IgnoreEmpty() {
+	if r.Title != nil && *r.Title == "" {
+		r.Title = nil
+	}
+	if r.Content != nil && *r.Content == "" {
+		r.Content = nil
+	}
+	if r.Excerpt != nil && *r.Excerpt == "" {
+		r.Excerpt = nil
+	}
+	if r.FeaturedImg != nil && *r.FeaturedImg == "" {
+		r.FeaturedImg = nil
+	}
+	if r.Status != nil && *r.Status == "" {
+		r.Status = nil
+	}
+	if len(r.TagIDs) == 0 {
+		r.TagIDs = nil
+	}
 }
 
 // PostResponse represents the post response
 type PostResponse struct {
-	ID            uint          `json:"id"`
-	Title         string        `json:"title"`
-	Slug          string        `json:"slug"`
-	Content       string        `json:"content"`
-	Excerpt       string        `json:"excerpt"`
-	FeaturedImg   string        `json:"featured_image"`
-	Status        PostStatus    `json:"status"`
-	ViewCount     int           `json:"view_count"`
-	AuthorID      uint          `json:"author_id"`
-	Author        UserResponse  `json:"author"`
-	PublishedAt   *time.Time    `json:"published_at"`
-	CreatedAt     time.Time     `json:"created_at"`
-	UpdatedAt     time.Time     `json:"updated_at"`
-	Tags          []TagResponse `json:"tags,omitempty"`
-	CommentsCount int           `json:"comments_count"`
+	ID          uint   `json:"id"`
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	Content     string `json:"content"`
+	Excerpt     string `json:"excerpt"`
+	FeaturedImg string `json:"featured_image"`
+	// Status and ViewCount are only omitted when HideAuthorFields cleared them
+	Status      PostStatus    `json:"status,omitempty"`
+	ViewCount   *int          `json:"view_count,omitempty"`
+	AuthorID    uint          `json:"author_id"`
+	Author      UserResponse  `json:"author"`
+	PublishedAt *time.Time    `json:"published_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Tags        []TagResponse `json:"tags,omitempty"`
+
+	// CoAuthors are the users who share edit rights with the author
+	CoAuthors []UserResponse `json:"co_authors,omitempty"`
+
+	// Series is the series the post belongs to, with the previous and next
+	// published posts in it
+	Series *PostSeriesResponse `json:"series,omitempty"`
+
+	Featured      bool `json:"featured"`
+	FeaturedOrder int  `json:"featured_order,omitempty"`
+
+	// ContentHTML is Content rendered as HTML and sanitized under the
+	// configured content policy
+	ContentHTML string `json:"content_html"`
+
+	// CommentsCount is the number of approved comments, replies included.
+	// RepliesCount is how many of them are replies, so the number of
+	// top-level threads is CommentsCount - RepliesCount.
+	CommentsCount int `json:"comments_count"`
+	RepliesCount  int `json:"replies_count"`
+
+	// Comments holds the approved comments, only when requested with
+	// ?include=comments. It is a flat list, replies included, oldest first;
+	// replies point at their thread with parent_id. Its length always
+	// equals CommentsCount.
+	Comments []CommentResponse `json:"comments,omitempty"`
+
+	// ModerationNote is the latest moderation action on a post that is still
+	// unpublished, only shown to its author and admins
+	ModerationNote *PostModerationResponse `json:"moderation_note,omitempty"`
 }
 
 // PostListResponse represents a simplified post response for listing
 type PostListResponse struct {
-	ID            uint          `json:"id"`
-	Title         string        `json:"title"`
-	Slug          string        `json:"slug"`
-	Excerpt       string        `json:"excerpt"`
-	FeaturedImg   string        `json:"featured_image"`
-	Status        PostStatus    `json:"status"`
-	ViewCount     int           `json:"view_count"`
+	ID          uint   `json:"id"`
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	Excerpt     string `json:"excerpt"`
+	FeaturedImg string `json:"featured_image"`
+	// Status and ViewCount are only omitted when HideAuthorFields cleared them
+	Status        PostStatus    `json:"status,omitempty"`
+	ViewCount     *int          `json:"view_count,omitempty"`
 	AuthorID      uint          `json:"author_id"`
 	Author        UserResponse  `json:"author"`
 	PublishedAt   *time.Time    `json:"published_at"`
@@ -87,11 +290,71 @@ type PostListResponse struct {
 	UpdatedAt     time.Time     `json:"updated_at"`
 	Tags          []TagResponse `json:"tags,omitempty"`
 	CommentsCount int           `json:"comments_count"`
+	Featured      bool          `json:"featured"`
+	FeaturedOrder int           `json:"featured_order,omitempty"`
+
+	// MatchExcerpt is the content around the first search match, with the
+	// match wrapped in <mark> tags. Only set by searches with ?highlight=true.
+	MatchExcerpt string `json:"match_excerpt,omitempty"`
+	// MatchPosition is the character offset of that match in the content,
+	// or -1 when only the title or excerpt matched
+	MatchPosition *int `json:"match_position,omitempty"`
+
+	// CoAuthorIDs are the post's co-authors, who see its author-only fields
+	CoAuthorIDs []uint `json:"-"`
+}
+
+// IsEditor reports whether userID is the author or a co-author of the post
+func (r *PostListResponse) IsEditor(userID uint) bool {
+	if userID == 0 {
+		return false
+	}
+	return r.AuthorID == userID || slices.Contains(r.CoAuthorIDs, userID)
+}
+
+// HideAuthorFields clears the fields only the post's author and admins may
+// see, leaving them out of the JSON
+func (r *PostListResponse) HideAuthorFields() {
+	r.Status = ""
+	r.ViewCount = nil
+}
+
+// PreviewTokenResponse is a token sharing a post before it is published.
+// Reading the post with ?preview=<token> works without logging in until
+// ExpiresAt.
+type PreviewTokenResponse struct {
+	PostID    uint      `json:"post_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ArchiveCount is the number of posts published in one month, for archive
+// navigation
+type ArchiveCount struct {
+	Year  int   `json:"year"`
+	Month int   `json:"month"`
+	Count int64 `json:"count"`
+}
+
+// SetStatus moves the post to status and reports whether that published
+// it. PublishedAt records when the post was first published: it's set the
+// first time the post is published and kept when the post is unpublished or
+// archived, so publishing it again restores its original place in the
+// timeline instead of bumping it to the top.
+func (p *Post) SetStatus(status PostStatus) bool {
+	published := status == PostStatusPublished && p.Status != PostStatusPublished
+	p.Status = status
+	if status == PostStatusPublished && p.PublishedAt == nil {
+		now := time.Now()
+		p.PublishedAt = &now
+	}
+	return published
 }
 
 // ToResponse converts Post to PostResponse
 func (p *Post) ToResponse() PostResponse {
-	return PostResponse{
+	viewCount := p.ViewCount
+	response := PostResponse{
 		ID:          p.ID,
 		Title:       p.Title,
 		Slug:        p.Slug,
@@ -99,29 +362,84 @@ func (p *Post) ToResponse() PostResponse {
 		Excerpt:     p.Excerpt,
 		FeaturedImg: p.FeaturedImg,
 		Status:      p.Status,
-		ViewCount:   p.ViewCount,
+		ViewCount:   &viewCount,
 		AuthorID:    p.AuthorID,
 		Author:      p.Author.ToResponse(),
 		PublishedAt: p.PublishedAt,
 		CreatedAt:   p.CreatedAt,
 		UpdatedAt:   p.UpdatedAt,
+
+		Featured:      p.Featured,
+		FeaturedOrder: p.FeaturedOrder,
+	}
+	for _, coAuthor := range p.CoAuthors {
+		response.CoAuthors = append(response.CoAuthors, coAuthor.ToResponse())
 	}
+	return response
+}
+
+// IsEditor reports whether userID is the post's author or one of its
+// co-authors, who may all edit it
+func (p *Post) IsEditor(userID uint) bool {
+	if userID == 0 {
+		return false
+	}
+	if p.AuthorID == userID {
+		return true
+	}
+	for _, coAuthor := range p.CoAuthors {
+		if coAuthor.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEditor reports whether userID is the author or a co-author of the post
+func (r *PostResponse) IsEditor(userID uint) bool {
+	if userID == 0 {
+		return false
+	}
+	if r.AuthorID == userID {
+		return true
+	}
+	for _, coAuthor := range r.CoAuthors {
+		if coAuthor.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// HideAuthorFields clears the fields only the post's author and admins may
+// see, leaving them out of the JSON
+func (r *PostResponse) HideAuthorFields() {
+	r.Status = ""
+	r.ViewCount = nil
 }
 
 // ToListResponse converts Post to PostListResponse
 func (p *Post) ToListResponse() PostListResponse {
-	return PostListResponse{
+	viewCount := p.ViewCount
+	response := PostListResponse{
 		ID:          p.ID,
 		Title:       p.Title,
 		Slug:        p.Slug,
 		Excerpt:     p.Excerpt,
 		FeaturedImg: p.FeaturedImg,
 		Status:      p.Status,
-		ViewCount:   p.ViewCount,
+		ViewCount:   &viewCount,
 		AuthorID:    p.AuthorID,
 		Author:      p.Author.ToResponse(),
 		PublishedAt: p.PublishedAt,
 		CreatedAt:   p.CreatedAt,
 		UpdatedAt:   p.UpdatedAt,
+
+		Featured:      p.Featured,
+		FeaturedOrder: p.FeaturedOrder,
+	}
+	for _, coAuthor := range p.CoAuthors {
+		response.CoAuthorIDs = append(response.CoAuthorIDs, coAuthor.ID)
 	}
+	return response
 }
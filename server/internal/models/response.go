@@ -10,10 +10,24 @@ type APIResponse struct {
 
 // PaginationMeta represents pagination metadata
 type PaginationMeta struct {
-	Page       int `json:"page"`
-	PerPage    int `json:"per_page"`
-	Total      int `json:"total"`
-	TotalPages int `json:"total_pages"`
+	Page       int  `json:"page"`
+	PerPage    int  `json:"per_page"`
+	Total      int  `json:"total"`
+	TotalPages int  `json:"total_pages"`
+	HasNext    bool `json:"has_next"`
+	HasPrev    bool `json:"has_prev"`
+
+	// Links navigates between pages of the same listing
+	Links *PaginationLinks `json:"links,omitempty"`
+}
+
+// PaginationLinks are the request path and query of neighbouring pages.
+// Next and Prev are omitted on the last and first page.
+type PaginationLinks struct {
+	First string `json:"first"`
+	Last  string `json:"last"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
 }
 
 // PaginatedResponse represents a paginated API response
@@ -25,13 +39,35 @@ type PaginatedResponse struct {
 	Error      interface{}    `json:"error,omitempty"`
 }
 
+// CursorPaginationMeta represents cursor pagination metadata
+type CursorPaginationMeta struct {
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// CursorPaginatedResponse represents a cursor-paginated API response
+type CursorPaginatedResponse struct {
+	Success    bool                 `json:"success"`
+	Message    string               `json:"message,omitempty"`
+	Data       interface{}          `json:"data"`
+	Pagination CursorPaginationMeta `json:"pagination"`
+	Error      interface{}          `json:"error,omitempty"`
+}
+
 // AuthResponse represents authentication response
 type AuthResponse struct {
-	User         UserResponse `json:"user"`
-	Token        string       `json:"token"`
-	TokenType    string       `json:"token_type"`
-	ExpiresIn    int          `json:"expires_in"`
-	RefreshToken string       `json:"refresh_token,omitempty"`
+	User             UserResponse `json:"user"`
+	Token            string       `json:"token"`
+	TokenType        string       `json:"token_type"`
+	ExpiresIn        int          `json:"expires_in"`
+	RefreshToken     string       `json:"refresh_token,omitempty"`
+	RefreshExpiresIn int          `json:"refresh_expires_in,omitempty"`
+}
+
+// AvailabilityResponse reports whether a username or email is still free
+type AvailabilityResponse struct {
+	Available bool `json:"available"`
 }
 
 // ErrorResponse represents an error response
@@ -48,3 +84,79 @@ type ValidationError struct {
 	Value   string `json:"value"`
 	Message string `json:"message"`
 }
+
+// ValidationErrorResponse lists the fields a request was rejected for
+type ValidationErrorResponse struct {
+	Fields []ValidationError `json:"fields"`
+}
+
+// NewsletterDigestResult summarizes a newsletter digest run
+type NewsletterDigestResult struct {
+	Posts      int `json:"posts"`
+	Recipients int `json:"recipients"`
+	Failed     int `json:"failed"`
+}
+
+// NewsletterSubscriptionResponse reports a user's newsletter subscription
+type NewsletterSubscriptionResponse struct {
+	Subscribed bool `json:"subscribed"`
+}
+
+// AuthorStatsResponse summarizes an author's posts and comments
+type AuthorStatsResponse struct {
+	Posts                    AuthorPostCounts `json:"posts"`
+	TotalViews               int64            `json:"total_views"`
+	ApprovedCommentsReceived int64            `json:"approved_comments_received"`
+	CommentsAuthored         int64            `json:"comments_authored"`
+}
+
+// AuthorPostCounts counts an author's posts by status
+type AuthorPostCounts struct {
+	Total     int64 `json:"total"`
+	Draft     int64 `json:"draft"`
+	Published int64 `json:"published"`
+	Archived  int64 `json:"archived"`
+}
+
+// SearchType names a section of the global search results
+type SearchType string
+
+const (
+	SearchTypePosts SearchType = "posts"
+	SearchTypeTags  SearchType = "tags"
+	SearchTypeUsers SearchType = "users"
+)
+
+// SearchResponse groups global search results by type. Sections that
+// weren't requested are omitted.
+type SearchResponse struct {
+	Posts *PostSearchResults `json:"posts,omitempty"`
+	Tags  *TagSearchResults  `json:"tags,omitempty"`
+	Users *UserSearchResults `json:"users,omitempty"`
+}
+
+// PostSearchResults is a page of matching published posts
+type PostSearchResults struct {
+	Items []PostListResponse `json:"items"`
+	Total int64              `json:"total"`
+}
+
+// TagSearchResults is a page of matching tags
+type TagSearchResults struct {
+	Items []TagResponse `json:"items"`
+	Total int64         `json:"total"`
+}
+
+// UserSearchResults is a page of matching active users
+type UserSearchResults struct {
+	Items []UserResponse `json:"items"`
+	Total int64          `json:"total"`
+}
+
+// SiteStatsResponse holds the public totals shown on the home page
+type SiteStatsResponse struct {
+	PublishedPosts   int64 `json:"published_posts"`
+	ApprovedComments int64 `json:"approved_comments"`
+	Tags             int64 `json:"tags"`
+	ActiveAuthors    int64 `json:"active_authors"`
+}
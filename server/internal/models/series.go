@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+)
+
+// Series groups related posts by one author into a reading order. Posts
+// join it through their SeriesID and are ordered by SeriesOrder, then ID.
+type Series struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Title       string    `json:"title" gorm:"not null;size:200" validate:"required,min=3,max=200"`
+	Slug        string    `json:"slug" gorm:"uniqueIndex;not null;size:250"`
+	Description string    `json:"description" gorm:"size:500" validate:"max=500"`
+	AuthorID    uint      `json:"author_id" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	Author User `json:"author" gorm:"foreignKey:AuthorID"`
+}
+
+// SeriesCreateRequest represents the request for creating a series. The
+// slug is derived from the title.
+type SeriesCreateRequest struct {
+	Title       string `json:"title" validate:"required,min=3,max=200"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+// SeriesUpdateRequest represents the request for updating a series. Fields
+// left out keep their current value; a new title also changes the slug.
+type SeriesUpdateRequest struct {
+	Title       *string `json:"title" validate:"omitempty,min=3,max=200"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+}
+
+// SeriesPostRequest places a post in a series at Order. Without an order
+// the post goes at the end.
+type SeriesPostRequest struct {
+	Order int `json:"order" validate:"min=0"`
+}
+
+// SeriesResponse represents the series response
+type SeriesResponse struct {
+	ID          uint         `json:"id"`
+	Title       string       `json:"title"`
+	Slug        string       `json:"slug"`
+	Description string       `json:"description"`
+	AuthorID    uint         `json:"author_id"`
+	Author      UserResponse `json:"author"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+
+	// PostsCount is the number of published posts in the series
+	PostsCount int `json:"posts_count"`
+
+	// Posts are the series' posts in reading order, only when getting a
+	// single series. Readers get the published ones; the responses to
+	// adding and removing posts list every post, drafts included.
+	Posts []PostListResponse `json:"posts,omitempty"`
+}
+
+// ToResponse converts Series to SeriesResponse
+func (s *Series) ToResponse() SeriesResponse {
+	return SeriesResponse{
+		ID:          s.ID,
+		Title:       s.Title,
+		Slug:        s.Slug,
+		Description: s.Description,
+		AuthorID:    s.AuthorID,
+		Author:      s.Author.ToResponse(),
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
+
+// PostSeriesResponse places a post in its series, linking the published
+// posts before and after it for navigation
+type PostSeriesResponse struct {
+	ID       uint            `json:"id"`
+	Title    string          `json:"title"`
+	Slug     string          `json:"slug"`
+	Previous *SeriesPostLink `json:"previous,omitempty"`
+	Next     *SeriesPostLink `json:"next,omitempty"`
+}
+
+// SeriesPostLink is a neighbouring post in a series
+type SeriesPostLink struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
@@ -9,7 +9,7 @@ type Tag struct {
 	Name        string    `json:"name" gorm:"uniqueIndex;not null;size:50" validate:"required,min=2,max=50"`
 	Slug        string    `json:"slug" gorm:"uniqueIndex;not null;size:60" validate:"required,min=2,max=60"`
 	Description string    `json:"description" gorm:"size:200" validate:"max=200"`
-	Color       string    `json:"color" gorm:"size:7;default:'#3B82F6'" validate:"omitempty,hexcolor"`
+	Color       string    `json:"color" gorm:"size:7;default:'#3b82f6'" validate:"omitempty,hexcolor"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
@@ -17,18 +17,36 @@ type Tag struct {
 	Posts []Post `json:"posts,omitempty" gorm:"many2many:post_tags;"`
 }
 
-// TagCreateRequest represents the request for creating a new tag
+// TagSlugHistory records a slug a tag used before it was renamed, so links
+// to the old slug can be redirected. A slug is dropped from the history once
+// a tag uses it again.
+type TagSlugHistory struct {
+	ID        uint   `gorm:"primaryKey"`
+	TagID     uint   `gorm:"not null;index"`
+	Slug      string `gorm:"uniqueIndex;not null;size:60"`
+	CreatedAt time.Time
+}
+
+// TableName keeps the history in a single tag_slug_history table
+func (TagSlugHistory) TableName() string {
+	return "tag_slug_history"
+}
+
+// TagCreateRequest represents the request for creating a new tag. Slug is
+// derived from Name unless given; either way a -N suffix is added if another
+// tag already uses it.
 type TagCreateRequest struct {
 	Name        string `json:"name" validate:"required,min=2,max=50"`
+	Slug        string `json:"slug" validate:"omitempty,min=2,max=55"`
 	Description string `json:"description" validate:"max=200"`
-	Color       string `json:"color" validate:"omitempty,hexcolor"`
+	Color       string `json:"color"` // normalized by the tag service
 }
 
 // TagUpdateRequest represents the request for updating a tag
 type TagUpdateRequest struct {
 	Name        string `json:"name" validate:"omitempty,min=2,max=50"`
 	Description string `json:"description" validate:"max=200"`
-	Color       string `json:"color" validate:"omitempty,hexcolor"`
+	Color       string `json:"color"` // normalized by the tag service
 }
 
 // TagResponse represents the tag response
@@ -43,6 +61,11 @@ type TagResponse struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// TagInUseResponse reports how many posts use a tag that couldn't be deleted
+type TagInUseResponse struct {
+	PostsCount int64 `json:"posts_count"`
+}
+
 // ToResponse converts Tag to TagResponse
 func (t *Tag) ToResponse() TagResponse {
 	return TagResponse{
@@ -54,4 +77,33 @@ func (t *Tag) ToResponse() TagResponse {
 		CreatedAt:   t.CreatedAt,
 		UpdatedAt:   t.UpdatedAt,
 	}
-}
\ No newline at end of file
+}
+
+// TagPostsRequest is the body of the bulk tag attach and detach endpoints
+type TagPostsRequest struct {
+	PostIDs []uint `json:"post_ids" validate:"required,min=1,max=100"`
+}
+
+// TagPostOutcome is what a bulk attach or detach did to a post
+type TagPostOutcome string
+
+const (
+	TagPostAttached        TagPostOutcome = "attached"
+	TagPostAlreadyAttached TagPostOutcome = "already_attached"
+	TagPostDetached        TagPostOutcome = "detached"
+	TagPostNotAttached     TagPostOutcome = "not_attached"
+)
+
+// TagPostsResponse reports a bulk attach or detach per post, in request
+// order, with the number of posts carrying the tag afterwards
+type TagPostsResponse struct {
+	TagID      uint            `json:"tag_id"`
+	PostsCount int64           `json:"posts_count"`
+	Results    []TagPostResult `json:"results"`
+}
+
+// TagPostResult is the result for one post of a bulk attach or detach
+type TagPostResult struct {
+	PostID uint           `json:"post_id"`
+	Result TagPostOutcome `json:"result"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// RevokedToken blocks a JWT before it expires, by its ID (the jti claim).
+// It only needs keeping until the token would have expired anyway.
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	JTI       string    `json:"jti" gorm:"uniqueIndex;not null;size:64"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LogoutRequest optionally names a refresh token to revoke along with the
+// access token used to log out
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
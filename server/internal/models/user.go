@@ -7,6 +7,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// DeletedUserUsername is the username of the placeholder account that keeps
+// the posts and comments of users deleted with reassign
+const DeletedUserUsername = "deleteduser"
+
 type User struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	FirstName string    `json:"first_name" gorm:"not null;size:50" validate:"required,min=2,max=50"`
@@ -21,6 +25,26 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
+	// NewsletterSubscribed opts the user in to the periodic digest email
+	NewsletterSubscribed bool `json:"newsletter_subscribed" gorm:"default:false"`
+
+	// MustChangePassword restricts the user to changing their password
+	// until they have done so, e.g. for the default admin account
+	MustChangePassword bool `json:"must_change_password" gorm:"default:false"`
+
+	// LastLoginAt is when the user last logged in with their password, nil
+	// if they never have
+	LastLoginAt *time.Time `json:"last_login_at"`
+
+	// PendingEmail is the address the user asked to change to, which
+	// replaces Email once confirmed from the link sent to it
+	PendingEmail string `json:"-" gorm:"size:100"`
+
+	// DeletedAt soft-deletes a user whose deletion an admin scheduled,
+	// hiding them until they are restored or purged at PurgeAt
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	PurgeAt   *time.Time     `json:"-"`
+
 	// Relationships
 	Posts    []Post    `json:"posts,omitempty" gorm:"foreignKey:AuthorID"`
 	Comments []Comment `json:"comments,omitempty" gorm:"foreignKey:AuthorID"`
@@ -32,7 +56,7 @@ type UserCreateRequest struct {
 	LastName  string `json:"last_name" validate:"required,min=2,max=50"`
 	Email     string `json:"email" validate:"required,email,max=100"`
 	Username  string `json:"username" validate:"required,min=3,max=30,alphanum"`
-	Password  string `json:"password" validate:"required,min=8"`
+	Password  string `json:"password" validate:"required"` // checked against the password policy
 	Bio       string `json:"bio" validate:"max=500"`
 	Avatar    string `json:"avatar" validate:"omitempty,url"`
 }
@@ -47,10 +71,75 @@ type UserUpdateRequest struct {
 	Avatar    string `json:"avatar" validate:"omitempty,url"`
 }
 
+// UserHasContentResponse reports what a user who couldn't be deleted still has
+type UserHasContentResponse struct {
+	PostsCount    int64 `json:"posts_count"`
+	CommentsCount int64 `json:"comments_count"`
+}
+
+// UserFilter narrows the admin user list. Query matches a substring of the
+// username, first or last name or email, ignoring case; nil flags match
+// every user.
+type UserFilter struct {
+	Query    string
+	IsActive *bool
+	IsAdmin  *bool
+
+	// Deleted lists the users scheduled for deletion instead of the others
+	Deleted bool
+}
+
+// UserSort selects the order of the admin user list
+type UserSort string
+
+const (
+	// UserSortCreatedAt lists the newest accounts first
+	UserSortCreatedAt UserSort = "created_at"
+	// UserSortLastLogin lists the most recent logins first and users who
+	// never logged in last
+	UserSortLastLogin UserSort = "last_login_at"
+	// UserSortUsername lists users alphabetically
+	UserSortUsername UserSort = "username"
+)
+
+// IsValid reports whether s is a supported sort; empty means ID order
+func (s UserSort) IsValid() bool {
+	switch s {
+	case "", UserSortCreatedAt, UserSortLastLogin, UserSortUsername:
+		return true
+	}
+	return false
+}
+
+// UserWithCounts is a user with the number of posts and comments they have
+// written, as loaded for the admin user list
+type UserWithCounts struct {
+	User          `gorm:"embedded"`
+	PostsCount    int64
+	CommentsCount int64
+}
+
+// AdminUserResponse is a user as listed for admins, with activity details
+type AdminUserResponse struct {
+	UserResponse
+	LastLoginAt   *time.Time `json:"last_login_at"`
+	PostsCount    int64      `json:"posts_count"`
+	CommentsCount int64      `json:"comments_count"`
+	PurgeAt       *time.Time `json:"purge_at,omitempty"`
+}
+
+// UserDeactivateRequest optionally schedules the deactivated user for
+// deletion. They are hidden straight away and purged after DeleteAfterDays
+// unless restored in the meantime.
+type UserDeactivateRequest struct {
+	DeleteAfterDays int `json:"delete_after_days" validate:"min=0,max=365"`
+}
+
 // UserLoginRequest represents the login request
 type UserLoginRequest struct {
 	EmailOrUsername string `json:"email_or_username" validate:"required"`
 	Password        string `json:"password" validate:"required"`
+	RememberMe      bool   `json:"remember_me"`
 }
 
 // UserResponse represents the user response (without sensitive data)
@@ -66,17 +155,45 @@ type UserResponse struct {
 	IsAdmin   bool      `json:"is_admin"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	MustChangePassword bool   `json:"must_change_password"`
+	PendingEmail       string `json:"pending_email,omitempty"`
+}
+
+// EmailChangeRequest asks to change the user's email address. The current
+// password is required so a stolen session can't take over the account.
+type EmailChangeRequest struct {
+	Email    string `json:"email" validate:"required,email,max=100"`
+	Password string `json:"password" validate:"required"`
+}
+
+// PublicUserResponse is the part of a user's profile anyone may see
+type PublicUserResponse struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Bio       string `json:"bio"`
+	Avatar    string `json:"avatar"`
 }
 
 // BeforeCreate is a GORM hook that runs before creating a user
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-		u.Password = string(hashedPassword)
+		return u.SetPassword(u.Password)
+	}
+	return nil
+}
+
+// SetPassword replaces the password with the bcrypt hash of password. Use
+// it when changing the password of a saved user, since the hook only
+// hashes on create.
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
 	}
+	u.Password = string(hashedPassword)
 	return nil
 }
 
@@ -86,6 +203,18 @@ func (u *User) CheckPassword(password string) bool {
 	return err == nil
 }
 
+// ToPublicResponse converts User to PublicUserResponse
+func (u *User) ToPublicResponse() PublicUserResponse {
+	return PublicUserResponse{
+		ID:        u.ID,
+		Username:  u.Username,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Bio:       u.Bio,
+		Avatar:    u.Avatar,
+	}
+}
+
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
@@ -100,5 +229,8 @@ func (u *User) ToResponse() UserResponse {
 		IsAdmin:   u.IsAdmin,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
+
+		MustChangePassword: u.MustChangePassword,
+		PendingEmail:       u.PendingEmail,
 	}
 }
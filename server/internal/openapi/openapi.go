@@ -0,0 +1,447 @@
+// Package openapi builds an OpenAPI 3 document from the swag-style
+// annotations on the HTTP handlers, so the spec can be generated without
+// external tooling.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Options locates the annotated sources
+type Options struct {
+	// GeneralInfoFile holds the API-wide annotations (@title, @version,
+	// @BasePath, @securityDefinitions...)
+	GeneralInfoFile string
+	// HandlersDir holds the handlers annotated with @Router
+	HandlersDir string
+	// ModelsDir holds the request and response types the annotations
+	// reference as models.X
+	ModelsDir string
+	// TrimPrefix is removed from @Router paths, which are then relative to
+	// @BasePath
+	TrimPrefix string
+}
+
+type object = map[string]interface{}
+
+// GenerateJSON returns the indented OpenAPI document
+func GenerateJSON(opts Options) ([]byte, error) {
+	doc, err := Generate(opts)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// Generate builds the OpenAPI document
+func Generate(opts Options) (map[string]interface{}, error) {
+	models, err := loadModels(opts.ModelsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := object{"openapi": "3.0.3"}
+	if err := parseGeneralInfo(opts.GeneralInfoFile, doc); err != nil {
+		return nil, err
+	}
+
+	paths := object{}
+	operationIDs := map[string]bool{}
+	files, err := parseDir(opts.HandlersDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
+				continue
+			}
+			op, path, method, err := parseOperation(annotations(fn.Doc), models)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", fn.Name.Name, err)
+			}
+			if op == nil {
+				continue
+			}
+
+			id := lowerFirst(fn.Name.Name)
+			if operationIDs[id] {
+				id = lowerFirst(strings.TrimSuffix(receiverName(fn), "Handler")) + fn.Name.Name
+			}
+			operationIDs[id] = true
+			op["operationId"] = id
+
+			path = strings.TrimPrefix(path, opts.TrimPrefix)
+			item, _ := paths[path].(object)
+			if item == nil {
+				item = object{}
+				paths[path] = item
+			}
+			if _, exists := item[method]; exists {
+				return nil, fmt.Errorf("%s: duplicate route %s %s", fn.Name.Name, strings.ToUpper(method), path)
+			}
+			item[method] = op
+		}
+	}
+	doc["paths"] = paths
+
+	components, _ := doc["components"].(object)
+	if components == nil {
+		components = object{}
+		doc["components"] = components
+	}
+	components["schemas"] = models.schemas
+
+	return doc, nil
+}
+
+// parseDir parses the non-test Go files in dir, in name order
+func parseDir(dir string) ([]*ast.File, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// annotations returns the "@Name value" lines of a comment group
+func annotations(group *ast.CommentGroup) [][2]string {
+	var lines [][2]string
+	for _, comment := range group.List {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if !strings.HasPrefix(text, "@") {
+			continue
+		}
+		name, value, _ := strings.Cut(text, " ")
+		lines = append(lines, [2]string{name, strings.TrimSpace(value)})
+	}
+	return lines
+}
+
+// parseGeneralInfo reads the API-wide annotations into doc
+func parseGeneralInfo(path string, doc object) error {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	info := object{}
+	schemes := object{}
+	var scheme object
+	for _, group := range file.Comments {
+		for _, line := range annotations(group) {
+			name, value := line[0], line[1]
+			switch {
+			case name == "@title":
+				info["title"] = value
+			case name == "@version":
+				info["version"] = value
+			case name == "@description" && scheme == nil:
+				info["description"] = value
+			case name == "@BasePath":
+				doc["servers"] = []object{{"url": value}}
+			case name == "@securityDefinitions.apikey":
+				scheme = object{"type": "apiKey"}
+				schemes[value] = scheme
+			case name == "@in" && scheme != nil:
+				scheme["in"] = value
+			case name == "@name" && scheme != nil:
+				scheme["name"] = value
+			case name == "@description" && scheme != nil:
+				scheme["description"] = value
+			}
+		}
+	}
+
+	if info["title"] == nil || info["version"] == nil {
+		return fmt.Errorf("%s: @title and @version are required", path)
+	}
+	doc["info"] = info
+	if len(schemes) > 0 {
+		doc["components"] = object{"securitySchemes": schemes}
+	}
+	return nil
+}
+
+// parseOperation builds an operation from a handler's annotations. It
+// returns a nil operation for functions without @Router.
+func parseOperation(lines [][2]string, models *modelSet) (object, string, string, error) {
+	op := object{}
+	var path, method string
+	var params []object
+	responses := object{}
+	consumes := "application/json"
+	produces := "application/json"
+
+	// @Accept and @Produce may follow the lines they apply to
+	for _, line := range lines {
+		switch line[0] {
+		case "@Accept":
+			consumes = mimeType(line[1])
+		case "@Produce":
+			produces = mimeType(line[1])
+		}
+	}
+
+	for _, line := range lines {
+		name, value := line[0], line[1]
+		switch name {
+		case "@Summary":
+			op["summary"] = value
+		case "@Description":
+			// Long descriptions continue over several @Description lines
+			if description, ok := op["description"].(string); ok {
+				value = description + " " + value
+			}
+			op["description"] = value
+		case "@Tags":
+			var tags []string
+			for _, tag := range strings.Split(value, ",") {
+				tags = append(tags, strings.TrimSpace(tag))
+			}
+			op["tags"] = tags
+		case "@Security":
+			security, _ := op["security"].([]object)
+			op["security"] = append(security, object{value: []string{}})
+		case "@Param":
+			param, body, err := parseParam(value, models)
+			if err != nil {
+				return nil, "", "", fmt.Errorf("@Param %s: %w", value, err)
+			}
+			if body != nil {
+				body["content"] = object{consumes: object{"schema": body["schema"]}}
+				delete(body, "schema")
+				op["requestBody"] = body
+				continue
+			}
+			params = append(params, param)
+		case "@Success", "@Failure":
+			code, response, err := parseResponse(value, produces, models)
+			if err != nil {
+				return nil, "", "", fmt.Errorf("%s %s: %w", name, value, err)
+			}
+			responses[code] = response
+		case "@Router":
+			fields := strings.Fields(value)
+			if len(fields) != 2 {
+				return nil, "", "", fmt.Errorf("@Router %q must be a path and a [method]", value)
+			}
+			path = fields[0]
+			method = strings.ToLower(strings.Trim(fields[1], "[]"))
+		}
+	}
+
+	if path == "" {
+		return nil, "", "", nil
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if len(responses) == 0 {
+		return nil, "", "", fmt.Errorf("%s %s documents no responses", method, path)
+	}
+	op["responses"] = responses
+	return op, path, method, nil
+}
+
+// parseParam parses `name in type required "description" attrs...`. Body
+// parameters are returned as a request body instead.
+func parseParam(value string, models *modelSet) (object, object, error) {
+	fields := tokenize(value)
+	if len(fields) < 4 {
+		return nil, nil, fmt.Errorf("expected name, location, type and required")
+	}
+	name, in, typ := fields[0], fields[1], fields[2]
+	required, err := strconv.ParseBool(fields[3])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid required flag %q", fields[3])
+	}
+
+	schema, err := models.typeSchema(typ)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var description string
+	for _, field := range fields[4:] {
+		switch {
+		case strings.HasPrefix(field, `"`):
+			description = strings.Trim(field, `"`)
+		case strings.HasPrefix(field, "default(") && strings.HasSuffix(field, ")"):
+			schema["default"] = literal(strings.TrimSuffix(strings.TrimPrefix(field, "default("), ")"), schema["type"])
+		case strings.HasPrefix(field, "Enums(") && strings.HasSuffix(field, ")"):
+			var values []interface{}
+			for _, v := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(field, "Enums("), ")"), ",") {
+				values = append(values, literal(strings.TrimSpace(v), schema["type"]))
+			}
+			schema["enum"] = values
+		default:
+			return nil, nil, fmt.Errorf("unknown attribute %q", field)
+		}
+	}
+
+	if in == "body" {
+		body := object{"required": required, "schema": schema}
+		if description != "" {
+			body["description"] = description
+		}
+		return nil, body, nil
+	}
+
+	switch in {
+	case "query", "path", "header":
+	default:
+		return nil, nil, fmt.Errorf("unknown parameter location %q", in)
+	}
+	param := object{"name": name, "in": in, "required": required || in == "path", "schema": schema}
+	if description != "" {
+		param["description"] = description
+	}
+	return param, nil, nil
+}
+
+// parseResponse parses `code {object} type "description"` or
+// `code "description"`
+func parseResponse(value, produces string, models *modelSet) (string, object, error) {
+	fields := tokenize(value)
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("missing status code")
+	}
+	code := fields[0]
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid status code %q", code)
+	}
+
+	response := object{"description": http.StatusText(status)}
+	rest := fields[1:]
+	if len(rest) >= 2 && strings.HasPrefix(rest[0], "{") {
+		schema, err := models.typeSchema(rest[1])
+		if err != nil {
+			return "", nil, err
+		}
+		response["content"] = object{produces: object{"schema": schema}}
+		rest = rest[2:]
+	}
+	if len(rest) > 0 {
+		response["description"] = strings.Trim(rest[0], `"`)
+	}
+	return code, response, nil
+}
+
+// tokenize splits on spaces outside quotes, parentheses and braces
+func tokenize(value string) []string {
+	var fields []string
+	var current strings.Builder
+	depth := 0
+	quoted := false
+	for _, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == '(' || r == '{'):
+			depth++
+		case !quoted && (r == ')' || r == '}'):
+			depth--
+		case !quoted && depth == 0 && unicode.IsSpace(r):
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// literal converts an annotation value to the JSON type of the schema
+func literal(value string, schemaType interface{}) interface{} {
+	switch schemaType {
+	case "integer":
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// mimeType expands swag's short MIME type names
+func mimeType(value string) string {
+	switch value {
+	case "json":
+		return "application/json"
+	case "xml":
+		return "application/xml"
+	case "plain":
+		return "text/plain"
+	case "html":
+		return "text/html"
+	case "mpfd":
+		return "multipart/form-data"
+	case "x-www-form-urlencoded":
+		return "application/x-www-form-urlencoded"
+	}
+	return value
+}
+
+func receiverName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,109 @@
+package openapi
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// repoOptions points the generator at the real sources, as go generate does
+var repoOptions = Options{
+	GeneralInfoFile: "../../cmd/server/main.go",
+	HandlersDir:     "../handlers",
+	ModelsDir:       "../models",
+	TrimPrefix:      "/api",
+}
+
+func TestGenerate_CommittedSpecIsUpToDate(t *testing.T) {
+	generated, err := GenerateJSON(repoOptions)
+	require.NoError(t, err)
+
+	committed, err := os.ReadFile("../docs/openapi.json")
+	require.NoError(t, err)
+	assert.Equal(t, string(generated), string(committed), "run `go generate ./internal/docs` to refresh the spec")
+}
+
+func TestGenerate_Document(t *testing.T) {
+	doc, err := Generate(repoOptions)
+	require.NoError(t, err)
+
+	components := doc["components"].(object)
+	bearer, ok := components["securitySchemes"].(object)["BearerAuth"].(object)
+	require.True(t, ok, "BearerAuth security scheme")
+	assert.Equal(t, "header", bearer["in"])
+	assert.Equal(t, "Authorization", bearer["name"])
+
+	// Every @Router annotation becomes an operation
+	routerLine := regexp.MustCompile(`(?m)^// @Router (\S+) \[(\w+)\]`)
+	files, err := os.ReadDir("../handlers")
+	require.NoError(t, err)
+	paths := doc["paths"].(object)
+	documented := 0
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile("../handlers/" + file.Name())
+		require.NoError(t, err)
+		for _, match := range routerLine.FindAllStringSubmatch(string(src), -1) {
+			documented++
+			item, ok := paths[strings.TrimPrefix(match[1], "/api")].(object)
+			require.True(t, ok, "path %s", match[1])
+			op, ok := item[match[2]].(object)
+			require.True(t, ok, "%s %s", match[2], match[1])
+			assert.NotEmpty(t, op["operationId"])
+			assert.NotEmpty(t, op["responses"])
+		}
+	}
+	assert.Greater(t, documented, 0)
+
+	// Every schema reference resolves
+	encoded, err := json.Marshal(doc)
+	require.NoError(t, err)
+	schemas := components["schemas"].(object)
+	for _, match := range regexp.MustCompile(`#/components/schemas/(\w+)`).FindAllStringSubmatch(string(encoded), -1) {
+		assert.Contains(t, schemas, match[1])
+	}
+}
+
+func TestTypeSchema(t *testing.T) {
+	models, err := loadModels("../models")
+	require.NoError(t, err)
+
+	schema, err := models.typeSchema("models.APIResponse{data=object{count=int}}")
+	require.NoError(t, err)
+	allOf := schema["allOf"].([]object)
+	require.Len(t, allOf, 2)
+	assert.Equal(t, "#/components/schemas/APIResponse", allOf[0]["$ref"])
+	data := allOf[1]["properties"].(object)["data"].(object)
+	assert.Equal(t, object{"type": "integer"}, data["properties"].(object)["count"])
+
+	schema, err = models.typeSchema("[]models.TagResponse")
+	require.NoError(t, err)
+	assert.Equal(t, "array", schema["type"])
+
+	// Named string types carry their constants as an enum
+	post := models.schemas["PostResponse"]
+	if post == nil {
+		_, err = models.typeSchema("models.PostResponse")
+		require.NoError(t, err)
+		post = models.schemas["PostResponse"]
+	}
+	status := post.(object)["properties"].(object)["status"].(object)
+	assert.ElementsMatch(t, []interface{}{"draft", "published", "archived"}, status["enum"])
+
+	for _, bad := range []string{"models.Missing", "widget", "object{count}", "object{count=int"} {
+		_, err := models.typeSchema(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	fields := tokenize(`status query string false "Filter (optional) by status" Enums(all, pending) default(all)`)
+	assert.Equal(t, []string{"status", "query", "string", "false", `"Filter (optional) by status"`, "Enums(all, pending)", "default(all)"}, fields)
+}
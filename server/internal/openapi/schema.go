@@ -0,0 +1,377 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// modelsPrefix is how annotations refer to types in the models package
+const modelsPrefix = "models."
+
+// modelSet turns the types of the models package into component schemas,
+// adding each one the first time an annotation references it
+type modelSet struct {
+	types   map[string]*ast.TypeSpec
+	enums   map[string][]interface{}
+	schemas object
+}
+
+// loadModels parses the type and constant declarations in dir
+func loadModels(dir string) (*modelSet, error) {
+	files, err := parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &modelSet{
+		types:   map[string]*ast.TypeSpec{},
+		enums:   map[string][]interface{}{},
+		schemas: object{},
+	}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Doc == nil && len(gen.Specs) == 1 {
+						spec.Doc = gen.Doc
+					}
+					m.types[spec.Name.Name] = spec
+				case *ast.ValueSpec:
+					m.collectEnum(gen.Tok, spec)
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// collectEnum records typed string constants, such as PostStatusDraft, as
+// the allowed values of their type
+func (m *modelSet) collectEnum(tok token.Token, spec *ast.ValueSpec) {
+	ident, ok := spec.Type.(*ast.Ident)
+	if tok != token.CONST || !ok {
+		return
+	}
+	for _, value := range spec.Values {
+		lit, ok := value.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			continue
+		}
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			m.enums[ident.Name] = append(m.enums[ident.Name], s)
+		}
+	}
+}
+
+// typeSchema parses a swag type expression such as string, []models.Tag,
+// object{count=int} or models.APIResponse{data=models.PostResponse}
+func (m *modelSet) typeSchema(expr string) (object, error) {
+	schema, rest, err := m.parseType(expr)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q in type %q", rest, expr)
+	}
+	return schema, nil
+}
+
+func (m *modelSet) parseType(expr string) (object, string, error) {
+	if strings.HasPrefix(expr, "[]") {
+		items, rest, err := m.parseType(expr[2:])
+		if err != nil {
+			return nil, "", err
+		}
+		return object{"type": "array", "items": items}, rest, nil
+	}
+
+	end := strings.IndexAny(expr, "{},=")
+	if end < 0 {
+		end = len(expr)
+	}
+	name, rest := expr[:end], expr[end:]
+
+	var schema object
+	switch {
+	case name == "object":
+		schema = object{"type": "object"}
+	case strings.HasPrefix(name, modelsPrefix):
+		ref, err := m.ref(strings.TrimPrefix(name, modelsPrefix))
+		if err != nil {
+			return nil, "", err
+		}
+		schema = ref
+	default:
+		primitive, ok := primitiveSchema(name)
+		if !ok {
+			return nil, "", fmt.Errorf("unknown type %q", name)
+		}
+		schema = primitive
+	}
+
+	if !strings.HasPrefix(rest, "{") {
+		return schema, rest, nil
+	}
+
+	// Field overrides: object{a=string} or models.X{data=models.Y}
+	properties := object{}
+	rest = rest[1:]
+	for {
+		field, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			return nil, "", fmt.Errorf("expected field=type in %q", expr)
+		}
+		fieldSchema, remaining, err := m.parseType(value)
+		if err != nil {
+			return nil, "", err
+		}
+		properties[field] = fieldSchema
+		rest = remaining
+		if strings.HasPrefix(rest, ",") {
+			rest = rest[1:]
+			continue
+		}
+		if !strings.HasPrefix(rest, "}") {
+			return nil, "", fmt.Errorf("unterminated field list in %q", expr)
+		}
+		rest = rest[1:]
+		break
+	}
+
+	overrides := object{"type": "object", "properties": properties}
+	if name == "object" {
+		return overrides, rest, nil
+	}
+	return object{"allOf": []object{schema, overrides}}, rest, nil
+}
+
+// primitiveSchema maps swag's primitive type names
+func primitiveSchema(name string) (object, bool) {
+	switch name {
+	case "string":
+		return object{"type": "string"}, true
+	case "int", "integer", "uint":
+		return object{"type": "integer"}, true
+	case "number", "float", "float64":
+		return object{"type": "number"}, true
+	case "bool", "boolean":
+		return object{"type": "boolean"}, true
+	}
+	return nil, false
+}
+
+// ref returns a reference to the named model, adding its schema to the
+// components first if needed
+func (m *modelSet) ref(name string) (object, error) {
+	ref := object{"$ref": "#/components/schemas/" + name}
+	if _, done := m.schemas[name]; done {
+		return ref, nil
+	}
+
+	spec, ok := m.types[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown model %q", name)
+	}
+
+	// Reserve the name first so self-referencing types terminate
+	m.schemas[name] = object{}
+	schema, err := m.exprSchema(spec.Type)
+	if err != nil {
+		return nil, fmt.Errorf("model %s: %w", name, err)
+	}
+	if spec.Doc != nil {
+		if doc := strings.TrimSpace(spec.Doc.Text()); doc != "" {
+			schema["description"] = doc
+		}
+	}
+	m.schemas[name] = schema
+	return ref, nil
+}
+
+// exprSchema converts a Go type expression from the models package
+func (m *modelSet) exprSchema(expr ast.Expr) (object, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if primitive, ok := goPrimitiveSchema(t.Name); ok {
+			return primitive, nil
+		}
+		spec, ok := m.types[t.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %q", t.Name)
+		}
+		if _, isStruct := spec.Type.(*ast.StructType); isStruct {
+			return m.ref(t.Name)
+		}
+		// Named basic types such as PostStatus are inlined with their values
+		schema, err := m.exprSchema(spec.Type)
+		if err != nil {
+			return nil, err
+		}
+		if values := m.enums[t.Name]; len(values) > 0 {
+			schema["enum"] = values
+		}
+		return schema, nil
+	case *ast.StarExpr:
+		schema, err := m.exprSchema(t.X)
+		if err != nil {
+			return nil, err
+		}
+		if _, isRef := schema["$ref"]; isRef {
+			return schema, nil
+		}
+		schema["nullable"] = true
+		return schema, nil
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return object{"type": "string", "format": "byte"}, nil
+		}
+		items, err := m.exprSchema(t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return object{"type": "array", "items": items}, nil
+	case *ast.MapType:
+		values, err := m.exprSchema(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return object{"type": "object", "additionalProperties": values}, nil
+	case *ast.InterfaceType:
+		return object{}, nil
+	case *ast.SelectorExpr:
+		return selectorSchema(t), nil
+	case *ast.StructType:
+		return m.structSchema(t)
+	}
+	return nil, fmt.Errorf("unsupported type %T", expr)
+}
+
+// structSchema describes the JSON encoding of a struct
+func (m *modelSet) structSchema(st *ast.StructType) (object, error) {
+	properties := object{}
+	var required []string
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			if unquoted, err := strconv.Unquote(field.Tag.Value); err == nil {
+				tag = reflect.StructTag(unquoted)
+			}
+		}
+		jsonName, _, _ := strings.Cut(tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+
+		// Embedded structs from this package contribute their fields
+		if len(field.Names) == 0 {
+			ident, ok := field.Type.(*ast.Ident)
+			if !ok || m.types[ident.Name] == nil {
+				continue
+			}
+			embedded, ok := m.types[ident.Name].Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			schema, err := m.structSchema(embedded)
+			if err != nil {
+				return nil, err
+			}
+			for name, prop := range schema["properties"].(object) {
+				properties[name] = prop
+			}
+			continue
+		}
+
+		schema, err := m.exprSchema(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		if field.Doc != nil {
+			if doc := strings.TrimSpace(field.Doc.Text()); doc != "" {
+				schema = describe(schema, doc)
+			}
+		}
+		isRequired := hasRule(tag.Get("validate"), "required")
+
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			key := jsonName
+			if key == "" {
+				key = name.Name
+			}
+			properties[key] = schema
+			if isRequired {
+				required = append(required, key)
+			}
+		}
+	}
+
+	schema := object{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+// describe adds a description to a schema. References can't carry siblings
+// in OpenAPI 3.0, so they are wrapped in allOf.
+func describe(schema object, description string) object {
+	if _, isRef := schema["$ref"]; isRef {
+		return object{"allOf": []object{schema}, "description": description}
+	}
+	schema["description"] = description
+	return schema
+}
+
+// hasRule reports whether a validate tag contains rule
+func hasRule(validate, rule string) bool {
+	for _, r := range strings.Split(validate, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// goPrimitiveSchema maps Go's builtin types
+func goPrimitiveSchema(name string) (object, bool) {
+	switch name {
+	case "string":
+		return object{"type": "string"}, true
+	case "bool":
+		return object{"type": "boolean"}, true
+	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32":
+		return object{"type": "integer"}, true
+	case "int64", "uint64":
+		return object{"type": "integer", "format": "int64"}, true
+	case "float32", "float64":
+		return object{"type": "number"}, true
+	}
+	return nil, false
+}
+
+// selectorSchema maps types from other packages
+func selectorSchema(sel *ast.SelectorExpr) object {
+	pkg, _ := sel.X.(*ast.Ident)
+	if pkg == nil {
+		return object{}
+	}
+	switch pkg.Name + "." + sel.Sel.Name {
+	case "time.Time", "gorm.DeletedAt":
+		return object{"type": "string", "format": "date-time"}
+	case "time.Duration":
+		return object{"type": "integer", "format": "int64"}
+	}
+	return object{}
+}
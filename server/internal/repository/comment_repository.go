@@ -1,24 +1,41 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"time"
 
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
 	"gorm.io/gorm"
 )
 
 type CommentRepository interface {
+	WithContext(ctx context.Context) CommentRepository
 	Create(comment *models.Comment) error
 	GetByID(id uint) (*models.Comment, error)
 	Update(comment *models.Comment) error
 	Delete(id uint) error
 	GetByPost(postID uint, offset, limit int) ([]models.Comment, int64, error)
-	GetByAuthor(authorID uint, offset, limit int) ([]models.Comment, int64, error)
-	GetPending(offset, limit int) ([]models.Comment, int64, error)
-	GetReplies(parentID uint) ([]models.Comment, error)
+	GetApprovedByPost(postID uint) ([]models.Comment, error)
+	DistinctCommentersByPost(postID uint) ([]models.Commenter, error)
+	GetByAuthor(authorID uint, status models.CommentStatus, offset, limit int) ([]models.Comment, int64, error)
+	GetRecentContent(postID uint, authorID *uint, guestEmail string, since time.Time) ([]string, error)
+	GetPending(filter models.PendingCommentFilter, offset, limit int) ([]models.Comment, int64, error)
+	GetReplies(parentID uint, offset, limit int) ([]models.Comment, int64, error)
 	CountByPost(postID uint) (int64, error)
+	CountRepliesByPost(postID uint) (int64, error)
+	CountByPosts(postIDs []uint) (map[uint]int64, error)
 	CountPending() (int64, error)
+	CountApprovedOnAuthorPosts(authorID uint) (int64, error)
+	CountByAuthor(authorID uint) (int64, error)
+	CountApprovedOnPublished() (int64, error)
 	UpdateStatus(id uint, status models.CommentStatus) error
+	ApproveAllForPost(postID uint) ([]models.Comment, error)
+	CreateReport(report *models.CommentReport) error
+	CountUnresolvedReports(commentID uint) (int64, error)
+	GetReported(offset, limit int) ([]models.Comment, int64, error)
+	ResolveReports(commentID uint) error
 }
 
 type commentRepository struct {
@@ -29,19 +46,27 @@ func NewCommentRepository(db *gorm.DB) CommentRepository {
 	return &commentRepository{db: db}
 }
 
+// ErrAlreadyReported is returned by CreateReport when the user has already
+// reported the comment
+var ErrAlreadyReported = apperrors.Conflict("you have already reported this comment")
+
+// WithContext returns a repository whose calls run with ctx, so they are
+// cancelled when it is done
+func (r *commentRepository) WithContext(ctx context.Context) CommentRepository {
+	return &commentRepository{db: r.db.WithContext(ctx)}
+}
+
 func (r *commentRepository) Create(comment *models.Comment) error {
 	return r.db.Create(comment).Error
 }
 
 func (r *commentRepository) GetByID(id uint) (*models.Comment, error) {
 	var comment models.Comment
-	err := r.db.Preload("Author").Preload("Post").Preload("Replies", func(db *gorm.DB) *gorm.DB {
-		return db.Preload("Author").Where("status = ?", models.CommentStatusApproved)
-	}).First(&comment, id).Error
+	err := r.db.Preload("Author", withDeletedUsers).Preload("Post").First(&comment, id).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("comment not found")
+			return nil, apperrors.NotFound("comment not found")
 		}
 		return nil, err
 	}
@@ -52,22 +77,30 @@ func (r *commentRepository) Update(comment *models.Comment) error {
 	return r.db.Save(comment).Error
 }
 
+// Delete soft-deletes a comment together with every reply beneath it, at
+// any depth, so no reply is left pointing at a deleted parent
 func (r *commentRepository) Delete(id uint) error {
-	// Delete all replies first
-	if err := r.db.Where("parent_id = ?", id).Delete(&models.Comment{}).Error; err != nil {
-		return err
-	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		ids := []uint{id}
+		for parents := ids; len(parents) > 0; {
+			var replies []uint
+			if err := tx.Model(&models.Comment{}).Where("parent_id IN ?", parents).Pluck("id", &replies).Error; err != nil {
+				return err
+			}
+			ids = append(ids, replies...)
+			parents = replies
+		}
 
-	// Delete the comment itself
-	return r.db.Delete(&models.Comment{}, id).Error
+		return tx.Where("id IN ?", ids).Delete(&models.Comment{}).Error
+	})
 }
 
 func (r *commentRepository) GetByPost(postID uint, offset, limit int) ([]models.Comment, int64, error) {
 	var comments []models.Comment
 	var total int64
 
-	query := r.db.Model(&models.Comment{}).Preload("Author").Preload("Replies", func(db *gorm.DB) *gorm.DB {
-		return db.Preload("Author").Where("status = ?", models.CommentStatusApproved).Order("created_at ASC")
+	query := r.db.Model(&models.Comment{}).Preload("Author", withDeletedUsers).Preload("Replies", func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Author", withDeletedUsers).Where("status = ?", models.CommentStatusApproved).Order("created_at ASC")
 	}).Where("post_id = ? AND parent_id IS NULL AND status = ?", postID, models.CommentStatusApproved)
 
 	// Count total records
@@ -80,13 +113,73 @@ func (r *commentRepository) GetByPost(postID uint, offset, limit int) ([]models.
 	return comments, total, err
 }
 
-func (r *commentRepository) GetByAuthor(authorID uint, offset, limit int) ([]models.Comment, int64, error) {
+// GetApprovedByPost returns every approved comment on a post, replies
+// included, oldest first. Comments created at the same instant are ordered
+// by ID so the order is stable.
+func (r *commentRepository) GetApprovedByPost(postID uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.Preload("Author", withDeletedUsers).
+		Where("post_id = ? AND status = ?", postID, models.CommentStatusApproved).
+		Order("created_at ASC").
+		Order("id ASC").
+		Find(&comments).Error
+	return comments, err
+}
+
+// DistinctCommentersByPost returns the users with approved comments on a
+// post, replies included, with how many each wrote, most active first.
+// Guests have no account and are left out.
+func (r *commentRepository) DistinctCommentersByPost(postID uint) ([]models.Commenter, error) {
+	var rows []struct {
+		AuthorID      uint
+		CommentsCount int64
+	}
+	err := r.db.Model(&models.Comment{}).
+		Select("author_id, COUNT(*) AS comments_count").
+		Where("post_id = ? AND status = ? AND author_id IS NOT NULL", postID, models.CommentStatusApproved).
+		Group("author_id").
+		Order("comments_count DESC").
+		Order("author_id ASC").
+		Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	authorIDs := make([]uint, len(rows))
+	for i, row := range rows {
+		authorIDs[i] = row.AuthorID
+	}
+	var users []models.User
+	if err := r.db.Where("id IN ?", authorIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	usersByID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	commenters := make([]models.Commenter, 0, len(rows))
+	for _, row := range rows {
+		if user, ok := usersByID[row.AuthorID]; ok {
+			commenters = append(commenters, models.Commenter{User: user, CommentsCount: row.CommentsCount})
+		}
+	}
+	return commenters, nil
+}
+
+// GetByAuthor returns an author's comments, limited to one status unless
+// status is empty
+func (r *commentRepository) GetByAuthor(authorID uint, status models.CommentStatus, offset, limit int) ([]models.Comment, int64, error) {
 	var comments []models.Comment
 	var total int64
 
-	query := r.db.Model(&models.Comment{}).Preload("Author").Preload("Post").
+	query := r.db.Model(&models.Comment{}).Preload("Author", withDeletedUsers).Preload("Post").
 		Where("author_id = ?", authorID)
 
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
 	// Count total records
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -97,12 +190,37 @@ func (r *commentRepository) GetByAuthor(authorID uint, offset, limit int) ([]mod
 	return comments, total, err
 }
 
-func (r *commentRepository) GetPending(offset, limit int) ([]models.Comment, int64, error) {
+// GetRecentContent returns the content of the comments left on postID since
+// the given time by authorID, or by the guest with guestEmail when authorID
+// is nil, whatever their status
+func (r *commentRepository) GetRecentContent(postID uint, authorID *uint, guestEmail string, since time.Time) ([]string, error) {
+	query := r.db.Model(&models.Comment{}).Where("post_id = ? AND created_at >= ?", postID, since)
+	if authorID != nil {
+		query = query.Where("author_id = ?", *authorID)
+	} else {
+		query = query.Where("author_id IS NULL AND guest_email = ?", guestEmail)
+	}
+
+	var contents []string
+	err := query.Pluck("content", &contents).Error
+	return contents, err
+}
+
+func (r *commentRepository) GetPending(filter models.PendingCommentFilter, offset, limit int) ([]models.Comment, int64, error) {
 	var comments []models.Comment
 	var total int64
 
-	query := r.db.Model(&models.Comment{}).Preload("Author").Preload("Post").
+	query := r.db.Model(&models.Comment{}).Preload("Author", withDeletedUsers).Preload("Post").
 		Where("status = ?", models.CommentStatusPending)
+	if filter.PostID != 0 {
+		query = query.Where("post_id = ?", filter.PostID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
 
 	// Count total records
 	if err := query.Count(&total).Error; err != nil {
@@ -114,11 +232,19 @@ func (r *commentRepository) GetPending(offset, limit int) ([]models.Comment, int
 	return comments, total, err
 }
 
-func (r *commentRepository) GetReplies(parentID uint) ([]models.Comment, error) {
+// GetReplies returns a page of the approved direct replies to a comment,
+// oldest first, with their total
+func (r *commentRepository) GetReplies(parentID uint, offset, limit int) ([]models.Comment, int64, error) {
 	var replies []models.Comment
-	err := r.db.Preload("Author").Where("parent_id = ? AND status = ?", parentID, models.CommentStatusApproved).
-		Order("created_at ASC").Find(&replies).Error
-	return replies, err
+	var total int64
+
+	query := r.db.Model(&models.Comment{}).Where("parent_id = ? AND status = ?", parentID, models.CommentStatusApproved)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Author", withDeletedUsers).Order("created_at ASC, id ASC").Offset(offset).Limit(limit).Find(&replies).Error
+	return replies, total, err
 }
 
 func (r *commentRepository) CountByPost(postID uint) (int64, error) {
@@ -127,12 +253,165 @@ func (r *commentRepository) CountByPost(postID uint) (int64, error) {
 	return count, err
 }
 
+// CountRepliesByPost counts the approved comments on a post that reply to
+// another comment
+func (r *commentRepository) CountRepliesByPost(postID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Comment{}).
+		Where("post_id = ? AND status = ? AND parent_id IS NOT NULL", postID, models.CommentStatusApproved).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByPosts returns approved comment counts for several posts in one
+// grouped query. Posts without comments are absent from the map.
+func (r *commentRepository) CountByPosts(postIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(postIDs))
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		PostID uint
+		Count  int64
+	}
+	err := r.db.Model(&models.Comment{}).
+		Select("post_id, COUNT(*) AS count").
+		Where("post_id IN ? AND status = ?", postIDs, models.CommentStatusApproved).
+		Group("post_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.PostID] = row.Count
+	}
+	return counts, nil
+}
+
 func (r *commentRepository) CountPending() (int64, error) {
 	var count int64
 	err := r.db.Model(&models.Comment{}).Where("status = ?", models.CommentStatusPending).Count(&count).Error
 	return count, err
 }
 
+// CountApprovedOnAuthorPosts counts the approved comments left on the
+// author's posts
+func (r *commentRepository) CountApprovedOnAuthorPosts(authorID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Comment{}).
+		Joins("JOIN posts ON posts.id = comments.post_id").
+		Where("posts.author_id = ? AND comments.status = ?", authorID, models.CommentStatusApproved).
+		Count(&count).Error
+	return count, err
+}
+
+// CountApprovedOnPublished counts the approved comments on published posts,
+// the ones visitors can see
+func (r *commentRepository) CountApprovedOnPublished() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Comment{}).
+		Joins("JOIN posts ON posts.id = comments.post_id").
+		Where("comments.status = ? AND posts.status = ? AND posts.published_at <= ?",
+			models.CommentStatusApproved, models.PostStatusPublished, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByAuthor counts the comments the user has written, in any status
+func (r *commentRepository) CountByAuthor(authorID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Comment{}).Where("author_id = ?", authorID).Count(&count).Error
+	return count, err
+}
+
 func (r *commentRepository) UpdateStatus(id uint, status models.CommentStatus) error {
 	return r.db.Model(&models.Comment{}).Where("id = ?", id).Update("status", status).Error
 }
+
+// ApproveAllForPost approves every pending comment on a post in one
+// transaction, resolving any reports on them, and returns the comments it
+// approved
+func (r *commentRepository) ApproveAllForPost(postID uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Author", withDeletedUsers).Preload("Post").
+			Where("post_id = ? AND status = ?", postID, models.CommentStatusPending).
+			Order("created_at ASC").Find(&comments).Error; err != nil {
+			return err
+		}
+		if len(comments) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(comments))
+		for i := range comments {
+			ids[i] = comments[i].ID
+			comments[i].Status = models.CommentStatusApproved
+		}
+		if err := tx.Model(&models.Comment{}).Where("id IN ?", ids).Update("status", models.CommentStatusApproved).Error; err != nil {
+			return err
+		}
+		// Approving a comment moderates the reports that sent it back to pending
+		return tx.Model(&models.CommentReport{}).
+			Where("comment_id IN ? AND resolved = ?", ids, false).
+			Update("resolved", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *commentRepository) CreateReport(report *models.CommentReport) error {
+	err := r.db.Create(report).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrAlreadyReported
+	}
+	return err
+}
+
+// CountUnresolvedReports counts the reports on a comment that no admin has
+// acted on yet
+func (r *commentRepository) CountUnresolvedReports(commentID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.CommentReport{}).
+		Where("comment_id = ? AND resolved = ?", commentID, false).
+		Count(&count).Error
+	return count, err
+}
+
+// GetReported returns the comments with unresolved reports, most reported
+// first, with those reports and their reporters loaded
+func (r *commentRepository) GetReported(offset, limit int) ([]models.Comment, int64, error) {
+	var comments []models.Comment
+	var total int64
+
+	reports := r.db.Model(&models.CommentReport{}).
+		Select("comment_id, COUNT(*) AS reports_count").
+		Where("resolved = ?", false).
+		Group("comment_id")
+
+	query := r.db.Model(&models.Comment{}).
+		Joins("JOIN (?) AS reports ON reports.comment_id = comments.id", reports)
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	err := query.Preload("Author", withDeletedUsers).Preload("Post").Preload("Reports", func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Reporter").Where("resolved = ?", false).Order("created_at ASC")
+	}).Order("reports.reports_count DESC").Order("comments.id ASC").Offset(offset).Limit(limit).Find(&comments).Error
+	return comments, total, err
+}
+
+// ResolveReports marks every report on a comment resolved, taking it out of
+// the report queue
+func (r *commentRepository) ResolveReports(commentID uint) error {
+	return r.db.Model(&models.CommentReport{}).
+		Where("comment_id = ? AND resolved = ?", commentID, false).
+		Update("resolved", true).Error
+}
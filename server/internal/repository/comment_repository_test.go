@@ -0,0 +1,139 @@
+package repository_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentRepository_CountByPosts(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewCommentRepository(db)
+	author := testutil.CreateUser(t, db, "counter")
+
+	base := time.Now().UTC().Add(-time.Hour)
+	var postIDs []uint
+	for i := 0; i < 4; i++ {
+		post := createPublishedPost(t, db, author.ID, fmt.Sprintf("Counted %d", i), base.Add(time.Duration(i)*time.Minute))
+		postIDs = append(postIDs, post.ID)
+
+		// Post i gets i approved comments plus one pending comment that
+		// must not be counted
+		for j := 0; j < i; j++ {
+			require.NoError(t, db.Create(&models.Comment{
+				Content:  "Approved",
+				Status:   models.CommentStatusApproved,
+				AuthorID: &author.ID,
+				PostID:   post.ID,
+			}).Error)
+		}
+		require.NoError(t, db.Create(&models.Comment{
+			Content:  "Pending",
+			Status:   models.CommentStatusPending,
+			AuthorID: &author.ID,
+			PostID:   post.ID,
+		}).Error)
+	}
+
+	counts, err := repo.CountByPosts(postIDs)
+	require.NoError(t, err)
+
+	for _, postID := range postIDs {
+		expected, err := repo.CountByPost(postID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, counts[postID], "post %d", postID)
+	}
+
+	empty, err := repo.CountByPosts(nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestCommentRepository_GetApprovedByPost(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewCommentRepository(db)
+	author := testutil.CreateUser(t, db, "approvedreader")
+	post := createPublishedPost(t, db, author.ID, "Embedded comments", time.Now().UTC())
+
+	for _, status := range []models.CommentStatus{models.CommentStatusApproved, models.CommentStatusPending, models.CommentStatusApproved} {
+		require.NoError(t, db.Create(&models.Comment{
+			Content:  string(status),
+			Status:   status,
+			AuthorID: &author.ID,
+			PostID:   post.ID,
+		}).Error)
+	}
+
+	comments, err := repo.GetApprovedByPost(post.ID)
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	for _, comment := range comments {
+		assert.Equal(t, models.CommentStatusApproved, comment.Status)
+		assert.Equal(t, author.ID, comment.Author.ID)
+	}
+}
+
+func TestCommentRepository_Delete_SoftDeletesReplyTree(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewCommentRepository(db)
+	author := testutil.CreateUser(t, db, "threader")
+	post := createPublishedPost(t, db, author.ID, "Threads", time.Now().UTC())
+
+	create := func(parentID *uint) *models.Comment {
+		comment := &models.Comment{
+			Content:  "Reply",
+			Status:   models.CommentStatusApproved,
+			AuthorID: &author.ID,
+			PostID:   post.ID,
+			ParentID: parentID,
+		}
+		require.NoError(t, db.Create(comment).Error)
+		return comment
+	}
+
+	// root -> child -> grandchild -> great-grandchild, plus an unrelated
+	// sibling thread that must survive
+	root := create(nil)
+	child := create(&root.ID)
+	grandchild := create(&child.ID)
+	greatGrandchild := create(&grandchild.ID)
+	sibling := create(nil)
+	create(&sibling.ID)
+
+	require.NoError(t, repo.Delete(root.ID))
+
+	for _, id := range []uint{root.ID, child.ID, grandchild.ID, greatGrandchild.ID} {
+		_, err := repo.GetByID(id)
+		assert.Error(t, err, "comment %d should be deleted", id)
+
+		// The row is kept for moderation history
+		var deleted models.Comment
+		require.NoError(t, db.Unscoped().First(&deleted, id).Error)
+		assert.True(t, deleted.DeletedAt.Valid)
+	}
+
+	count, err := repo.CountByPost(post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	counts, err := repo.CountByPosts([]uint{post.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counts[post.ID])
+
+	approved, err := repo.GetApprovedByPost(post.ID)
+	require.NoError(t, err)
+	assert.Len(t, approved, 2)
+
+	threads, total, err := repo.GetByPost(post.ID, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, threads, 1)
+	assert.Equal(t, sibling.ID, threads[0].ID)
+	assert.Len(t, threads[0].Replies, 1)
+}
@@ -1,30 +1,54 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
 
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
 	"gorm.io/gorm"
 )
 
 type PostRepository interface {
+	WithContext(ctx context.Context) PostRepository
+	Transaction(fn func(repo PostRepository) error) error
+	Tags() TagRepository
 	Create(post *models.Post) error
 	GetByID(id uint) (*models.Post, error)
+	GetAccess(id uint) (*models.Post, error)
+	GetTags(postID uint) ([]models.Tag, error)
 	GetBySlug(slug string) (*models.Post, error)
+	GetByFormerSlug(slug string) (*models.Post, error)
 	Update(post *models.Post) error
+	RecordSlugChange(postID uint, oldSlug, newSlug string) error
+	AddModerationLog(entry *models.PostModerationLog) error
+	GetLatestModerationLog(postID uint) (*models.PostModerationLog, error)
+	GetModerationLogs(postID uint) ([]models.PostModerationLog, error)
 	Delete(id uint) error
-	List(offset, limit int, status models.PostStatus, authorID uint) ([]models.Post, int64, error)
-	GetPublished(offset, limit int) ([]models.Post, int64, error)
+	List(filter models.PostFilter, offset, limit int) ([]models.Post, int64, error)
+	GetPublished(sort models.PostSort, published models.PublishedRange, featuredFirst bool, offset, limit int) ([]models.Post, int64, error)
+	GetFeatured(offset, limit int) ([]models.Post, int64, error)
+	GetPublishedAfterCursor(published models.PublishedRange, publishedAt *time.Time, id uint, limit int) ([]models.Post, error)
 	GetByAuthor(authorID uint, offset, limit int) ([]models.Post, int64, error)
-	GetByTag(tagID uint, offset, limit int) ([]models.Post, int64, error)
+	GetByTag(tagID uint, sort models.PostSort, offset, limit int) ([]models.Post, int64, error)
 	Search(query string, offset, limit int) ([]models.Post, int64, error)
 	IncrementViewCount(id uint) error
 	IsSlugTaken(slug string, excludeID uint) bool
+	GetExistingIDs(ids []uint) ([]uint, error)
+	GetIDsWithTag(tagID uint, postIDs []uint) ([]uint, error)
 	AddTags(postID uint, tagIDs []uint) error
 	RemoveTags(postID uint, tagIDs []uint) error
 	UpdateTags(postID uint, tagIDs []uint) error
+	AddCoAuthor(postID, userID uint) error
+	GetSeriesNeighbours(post *models.Post) (previous, next *models.Post, err error)
+	RemoveCoAuthor(postID, userID uint) error
+	GetTopPublishedSince(since time.Time, limit int) ([]models.Post, error)
+	CountByAuthorAndStatus(authorID uint) (map[models.PostStatus]int64, error)
+	SumViewsByAuthor(authorID uint) (int64, error)
+	ArchiveCounts() ([]models.ArchiveCount, error)
+	CountPublished() (int64, error)
 }
 
 type postRepository struct {
@@ -35,34 +59,101 @@ func NewPostRepository(db *gorm.DB) PostRepository {
 	return &postRepository{db: db}
 }
 
+// WithContext returns a repository whose calls run with ctx, so they are
+// cancelled when it is done
+func (r *postRepository) WithContext(ctx context.Context) PostRepository {
+	return &postRepository{db: r.db.WithContext(ctx)}
+}
+
+// Transaction runs fn with a repository whose every call goes through one
+// database transaction, committed if fn returns nil and rolled back
+// otherwise. Calling Transaction on that repository nests a savepoint, so a
+// failed statement can be retried without aborting the outer transaction.
+func (r *postRepository) Transaction(fn func(repo PostRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&postRepository{db: tx})
+	})
+}
+
+// Tags returns a tag repository on the same connection, so within
+// Transaction its calls are part of the transaction
+func (r *postRepository) Tags() TagRepository {
+	return &tagRepository{db: r.db}
+}
+
+// ErrSlugTaken is returned by Create when another post already uses the slug
+var ErrSlugTaken = apperrors.Conflict("slug already exists")
+
 func (r *postRepository) Create(post *models.Post) error {
-	return r.db.Create(post).Error
+	err := r.db.Create(post).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrSlugTaken
+	}
+	if err != nil {
+		return err
+	}
+	// A slug in use no longer leads to the post that used to have it
+	return r.db.Where("slug = ?", post.Slug).Delete(&models.PostSlugHistory{}).Error
 }
 
 func (r *postRepository) GetByID(id uint) (*models.Post, error) {
 	var post models.Post
-	err := r.db.Preload("Author").Preload("Tags").Preload("Comments", func(db *gorm.DB) *gorm.DB {
-		return db.Where("status = ?", models.CommentStatusApproved)
-	}).First(&post, id).Error
+	err := r.db.Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).Preload("Series").First(&post, id).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("post not found")
+			return nil, apperrors.NotFound("post not found")
 		}
 		return nil, err
 	}
 	return &post, nil
 }
 
+// GetAccess loads only the columns that decide who may see a post: its ID,
+// status and author
+func (r *postRepository) GetAccess(id uint) (*models.Post, error) {
+	var post models.Post
+	err := r.db.Select("id", "status", "author_id").First(&post, id).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("post not found")
+		}
+		return nil, err
+	}
+	return &post, nil
+}
+
+// GetTags returns a post's tags by name, loading only the association
+func (r *postRepository) GetTags(postID uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.Model(&models.Post{ID: postID}).Order("tags.name ASC").Association("Tags").Find(&tags)
+	return tags, err
+}
+
 func (r *postRepository) GetBySlug(slug string) (*models.Post, error) {
 	var post models.Post
-	err := r.db.Preload("Author").Preload("Tags").Preload("Comments", func(db *gorm.DB) *gorm.DB {
-		return db.Where("status = ?", models.CommentStatusApproved)
-	}).Where("slug = ?", slug).First(&post).Error
+	err := r.db.Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).Preload("Series").Where("slug = ?", slug).First(&post).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("post not found")
+			return nil, apperrors.NotFound("post not found")
+		}
+		return nil, err
+	}
+	return &post, nil
+}
+
+// GetByFormerSlug returns the post that used slug before its title changed
+func (r *postRepository) GetByFormerSlug(slug string) (*models.Post, error) {
+	var post models.Post
+	err := r.db.Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).Preload("Series").
+		Joins("JOIN post_slug_history ON post_slug_history.post_id = posts.id").
+		Where("post_slug_history.slug = ?", slug).First(&post).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("post not found")
 		}
 		return nil, err
 	}
@@ -70,27 +161,83 @@ func (r *postRepository) GetBySlug(slug string) (*models.Post, error) {
 }
 
 func (r *postRepository) Update(post *models.Post) error {
-	return r.db.Save(post).Error
+	// The loaded series would otherwise overwrite SeriesID; membership is
+	// changed through the series repository
+	return r.db.Omit("Series").Save(post).Error
+}
+
+// RecordSlugChange adds oldSlug to the slug history of a post now at
+// newSlug. Call it in the transaction saving the post.
+func (r *postRepository) RecordSlugChange(postID uint, oldSlug, newSlug string) error {
+	// Either slug may be in another post's history; the post now using it
+	// (or the one that just gave it up) takes precedence
+	if err := r.db.Where("slug IN ?", []string{oldSlug, newSlug}).Delete(&models.PostSlugHistory{}).Error; err != nil {
+		return err
+	}
+	return r.db.Create(&models.PostSlugHistory{PostID: postID, Slug: oldSlug}).Error
+}
+
+// AddModerationLog records a moderation action on a post
+func (r *postRepository) AddModerationLog(entry *models.PostModerationLog) error {
+	return r.db.Create(entry).Error
+}
+
+// GetLatestModerationLog returns the most recent moderation action on a post
+func (r *postRepository) GetLatestModerationLog(postID uint) (*models.PostModerationLog, error) {
+	var entry models.PostModerationLog
+	err := r.db.Where("post_id = ?", postID).Order("created_at DESC, id DESC").First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("moderation log entry not found")
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetModerationLogs returns every moderation action on a post, newest first
+func (r *postRepository) GetModerationLogs(postID uint) ([]models.PostModerationLog, error) {
+	var entries []models.PostModerationLog
+	err := r.db.Where("post_id = ?", postID).Order("created_at DESC, id DESC").Find(&entries).Error
+	return entries, err
 }
 
 func (r *postRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Post{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id = ?", id).Delete(&models.PostSlugHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_authors WHERE post_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Post{}, id).Error
+	})
 }
 
-func (r *postRepository) List(offset, limit int, status models.PostStatus, authorID uint) ([]models.Post, int64, error) {
+func (r *postRepository) List(filter models.PostFilter, offset, limit int) ([]models.Post, int64, error) {
 	var posts []models.Post
 	var total int64
 
-	query := r.db.Model(&models.Post{}).Preload("Author").Preload("Tags")
+	query := r.db.Model(&models.Post{}).Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors)
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
 
-	if status != "" {
-		query = query.Where("status = ?", status)
+	if filter.Published != nil {
+		if *filter.Published {
+			query = query.Where("status = ?", models.PostStatusPublished)
+		} else {
+			query = query.Where("status <> ?", models.PostStatusPublished)
+		}
 	}
 
-	if authorID > 0 {
-		query = query.Where("author_id = ?", authorID)
+	if filter.AuthorID > 0 {
+		query = query.Where("author_id = ?", filter.AuthorID)
 	}
 
+	query = withinPublishedRange(query, filter.PublishedRange)
+
 	// Count total records
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -101,28 +248,85 @@ func (r *postRepository) List(offset, limit int, status models.PostStatus, autho
 	return posts, total, err
 }
 
-func (r *postRepository) GetPublished(offset, limit int) ([]models.Post, int64, error) {
+// GetPublished returns the published posts in the given order. With
+// featuredFirst, featured posts come first in their featured order.
+func (r *postRepository) GetPublished(sort models.PostSort, published models.PublishedRange, featuredFirst bool, offset, limit int) ([]models.Post, int64, error) {
 	var posts []models.Post
 	var total int64
 
-	query := r.db.Model(&models.Post{}).Preload("Author").Preload("Tags").
+	query := r.db.Model(&models.Post{}).Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).
 		Where("status = ? AND published_at <= ?", models.PostStatusPublished, time.Now())
+	query = withinPublishedRange(query, published)
 
 	// Count total records
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
+	if featuredFirst {
+		query = query.Order("posts.featured DESC, posts.featured_order ASC")
+	}
+
 	// Get paginated results
-	err := query.Order("published_at DESC").Offset(offset).Limit(limit).Find(&posts).Error
+	err := r.orderPublished(query, sort).Offset(offset).Limit(limit).Find(&posts).Error
 	return posts, total, err
 }
 
+// GetFeatured returns the published featured posts by featured order, then
+// newest first
+func (r *postRepository) GetFeatured(offset, limit int) ([]models.Post, int64, error) {
+	var posts []models.Post
+	var total int64
+
+	query := r.db.Model(&models.Post{}).Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).
+		Where("featured = ? AND status = ? AND published_at <= ?", true, models.PostStatusPublished, time.Now())
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	err := query.Order("featured_order ASC, published_at DESC, id DESC").Offset(offset).Limit(limit).Find(&posts).Error
+	return posts, total, err
+}
+
+// GetPublishedAfterCursor returns published posts ordered newest first that
+// come strictly after the given (published_at, id) position. A nil
+// publishedAt starts from the newest post. Rows inserted while a client is
+// iterating never shift the remaining pages, unlike offset pagination.
+func (r *postRepository) GetPublishedAfterCursor(published models.PublishedRange, publishedAt *time.Time, id uint, limit int) ([]models.Post, error) {
+	var posts []models.Post
+
+	query := r.db.Model(&models.Post{}).Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).
+		Where("status = ? AND published_at <= ?", models.PostStatusPublished, time.Now())
+	query = withinPublishedRange(query, published)
+
+	if publishedAt != nil {
+		query = query.Where("(published_at < ? OR (published_at = ? AND id < ?))", *publishedAt, *publishedAt, id)
+	}
+
+	err := query.Order("published_at DESC").Order("id DESC").Limit(limit).Find(&posts).Error
+	return posts, err
+}
+
+// withinPublishedRange limits query to posts published within the range,
+// bounds included
+func withinPublishedRange(query *gorm.DB, published models.PublishedRange) *gorm.DB {
+	if published.After != nil {
+		query = query.Where("published_at >= ?", *published.After)
+	}
+	if published.Before != nil {
+		query = query.Where("published_at <= ?", *published.Before)
+	}
+	return query
+}
+
 func (r *postRepository) GetByAuthor(authorID uint, offset, limit int) ([]models.Post, int64, error) {
 	var posts []models.Post
 	var total int64
 
-	query := r.db.Model(&models.Post{}).Preload("Author").Preload("Tags").
+	query := r.db.Model(&models.Post{}).Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).
 		Where("author_id = ? AND status = ?", authorID, models.PostStatusPublished)
 
 	// Count total records
@@ -135,12 +339,12 @@ func (r *postRepository) GetByAuthor(authorID uint, offset, limit int) ([]models
 	return posts, total, err
 }
 
-func (r *postRepository) GetByTag(tagID uint, offset, limit int) ([]models.Post, int64, error) {
+func (r *postRepository) GetByTag(tagID uint, sort models.PostSort, offset, limit int) ([]models.Post, int64, error) {
 	var posts []models.Post
 	var total int64
 
 	subQuery := r.db.Table("post_tags").Select("post_id").Where("tag_id = ?", tagID)
-	query := r.db.Model(&models.Post{}).Preload("Author").Preload("Tags").
+	query := r.db.Model(&models.Post{}).Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).
 		Where("id IN (?) AND status = ?", subQuery, models.PostStatusPublished)
 
 	// Count total records
@@ -149,16 +353,39 @@ func (r *postRepository) GetByTag(tagID uint, offset, limit int) ([]models.Post,
 	}
 
 	// Get paginated results
-	err := query.Order("published_at DESC").Offset(offset).Limit(limit).Find(&posts).Error
+	err := r.orderPublished(query, sort).Offset(offset).Limit(limit).Find(&posts).Error
 	return posts, total, err
 }
 
+// orderPublished orders a listing of published posts; ties fall back to
+// the newest post first. Ordering by comments joins each post's approved
+// comment count, grouped per post so the join never duplicates a post.
+// Count the listing before ordering it.
+func (r *postRepository) orderPublished(query *gorm.DB, sort models.PostSort) *gorm.DB {
+	switch sort {
+	case models.PostSortOldest:
+		return query.Order("posts.published_at ASC, posts.id ASC")
+	case models.PostSortPopular:
+		return query.Order("posts.view_count DESC, posts.published_at DESC, posts.id DESC")
+	case models.PostSortComments:
+		counts := r.db.Model(&models.Comment{}).
+			Select("post_id, COUNT(*) AS approved_count").
+			Where("status = ?", models.CommentStatusApproved).
+			Group("post_id")
+		return query.Select("posts.*").
+			Joins("LEFT JOIN (?) AS comment_counts ON comment_counts.post_id = posts.id", counts).
+			Order("COALESCE(comment_counts.approved_count, 0) DESC, posts.published_at DESC, posts.id DESC")
+	default:
+		return query.Order("posts.published_at DESC, posts.id DESC")
+	}
+}
+
 func (r *postRepository) Search(query string, offset, limit int) ([]models.Post, int64, error) {
 	var posts []models.Post
 	var total int64
 
 	searchQuery := "%" + strings.ToLower(query) + "%"
-	dbQuery := r.db.Model(&models.Post{}).Preload("Author").Preload("Tags").
+	dbQuery := r.db.Model(&models.Post{}).Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).
 		Where("status = ? AND (LOWER(title) LIKE ? OR LOWER(content) LIKE ? OR LOWER(excerpt) LIKE ?)",
 			models.PostStatusPublished, searchQuery, searchQuery, searchQuery)
 
@@ -186,6 +413,26 @@ func (r *postRepository) IsSlugTaken(slug string, excludeID uint) bool {
 	return count > 0
 }
 
+// GetExistingIDs returns which of the given post IDs exist
+func (r *postRepository) GetExistingIDs(ids []uint) ([]uint, error) {
+	existing := []uint{}
+	if len(ids) == 0 {
+		return existing, nil
+	}
+	err := r.db.Model(&models.Post{}).Where("id IN ?", ids).Pluck("id", &existing).Error
+	return existing, err
+}
+
+// GetIDsWithTag returns which of the given posts carry the tag
+func (r *postRepository) GetIDsWithTag(tagID uint, postIDs []uint) ([]uint, error) {
+	tagged := []uint{}
+	if len(postIDs) == 0 {
+		return tagged, nil
+	}
+	err := r.db.Table("post_tags").Where("tag_id = ? AND post_id IN ?", tagID, postIDs).Pluck("post_id", &tagged).Error
+	return tagged, err
+}
+
 func (r *postRepository) AddTags(postID uint, tagIDs []uint) error {
 	var post models.Post
 	if err := r.db.First(&post, postID).Error; err != nil {
@@ -220,6 +467,10 @@ func (r *postRepository) UpdateTags(postID uint, tagIDs []uint) error {
 		return err
 	}
 
+	if len(tagIDs) == 0 {
+		return r.db.Model(&post).Association("Tags").Clear()
+	}
+
 	var tags []models.Tag
 	if err := r.db.Find(&tags, tagIDs).Error; err != nil {
 		return err
@@ -227,3 +478,136 @@ func (r *postRepository) UpdateTags(postID uint, tagIDs []uint) error {
 
 	return r.db.Model(&post).Association("Tags").Replace(&tags)
 }
+
+// orderedCoAuthors is the preload condition for a post's co-authors: by
+// username, keeping users scheduled for deletion like authors
+func orderedCoAuthors(db *gorm.DB) *gorm.DB {
+	return withDeletedUsers(db).Order("users.username ASC")
+}
+
+// AddCoAuthor gives userID edit rights on a post. Adding an existing
+// co-author again changes nothing.
+func (r *postRepository) AddCoAuthor(postID, userID uint) error {
+	var user models.User
+	if err := r.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.NotFound("user not found")
+		}
+		return err
+	}
+	return r.db.Model(&models.Post{ID: postID}).Association("CoAuthors").Append(&user)
+}
+
+// RemoveCoAuthor takes userID off a post's co-authors
+func (r *postRepository) RemoveCoAuthor(postID, userID uint) error {
+	result := r.db.Exec("DELETE FROM post_authors WHERE post_id = ? AND user_id = ?", postID, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("co-author not found")
+	}
+	return nil
+}
+
+// GetSeriesNeighbours returns the published posts right before and after
+// post in its series, or nil where there are none
+func (r *postRepository) GetSeriesNeighbours(post *models.Post) (previous, next *models.Post, err error) {
+	if post.SeriesID == nil {
+		return nil, nil, nil
+	}
+
+	neighbour := func(cmp, order string) (*models.Post, error) {
+		var found []models.Post
+		err := r.db.Select("id", "title", "slug").
+			Where("series_id = ? AND status = ? AND id <> ?", *post.SeriesID, models.PostStatusPublished, post.ID).
+			Where("series_order "+cmp+" ? OR (series_order = ? AND id "+cmp+" ?)", post.SeriesOrder, post.SeriesOrder, post.ID).
+			Order("series_order " + order + ", id " + order).
+			Limit(1).Find(&found).Error
+		if err != nil || len(found) == 0 {
+			return nil, err
+		}
+		return &found[0], nil
+	}
+
+	if previous, err = neighbour("<", "DESC"); err != nil {
+		return nil, nil, err
+	}
+	if next, err = neighbour(">", "ASC"); err != nil {
+		return nil, nil, err
+	}
+	return previous, next, nil
+}
+
+// GetTopPublishedSince returns the most viewed posts published since the
+// given time
+func (r *postRepository) GetTopPublishedSince(since time.Time, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.Preload("Author", withDeletedUsers).
+		Where("status = ? AND published_at >= ? AND published_at <= ?", models.PostStatusPublished, since, time.Now()).
+		Order("view_count DESC, published_at DESC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// CountByAuthorAndStatus returns the author's post counts per status in one
+// grouped query. Statuses without posts are absent from the map.
+func (r *postRepository) CountByAuthorAndStatus(authorID uint) (map[models.PostStatus]int64, error) {
+	var rows []struct {
+		Status models.PostStatus
+		Count  int64
+	}
+	err := r.db.Model(&models.Post{}).
+		Select("status, COUNT(*) AS count").
+		Where("author_id = ?", authorID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[models.PostStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// CountPublished counts the posts that are published and visible
+func (r *postRepository) CountPublished() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Post{}).
+		Where("status = ? AND published_at <= ?", models.PostStatusPublished, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// SumViewsByAuthor totals the view counts of the author's published posts
+func (r *postRepository) SumViewsByAuthor(authorID uint) (int64, error) {
+	var total int64
+	err := r.db.Model(&models.Post{}).
+		Select("COALESCE(SUM(view_count), 0)").
+		Where("author_id = ? AND status = ?", authorID, models.PostStatusPublished).
+		Scan(&total).Error
+	return total, err
+}
+
+// ArchiveCounts returns how many posts were published in each month, newest
+// month first, in one grouped query. Months without posts are absent.
+func (r *postRepository) ArchiveCounts() ([]models.ArchiveCount, error) {
+	// Postgres and SQLite disagree on how to take a date apart
+	year, month := "CAST(EXTRACT(YEAR FROM published_at) AS INTEGER)", "CAST(EXTRACT(MONTH FROM published_at) AS INTEGER)"
+	if r.db.Dialector.Name() == "sqlite" {
+		year, month = "CAST(strftime('%Y', published_at) AS INTEGER)", "CAST(strftime('%m', published_at) AS INTEGER)"
+	}
+
+	var counts []models.ArchiveCount
+	err := r.db.Model(&models.Post{}).
+		Select(year+" AS year, "+month+" AS month, COUNT(*) AS count").
+		Where("status = ? AND published_at <= ?", models.PostStatusPublished, time.Now()).
+		Group(year + ", " + month).
+		Order("year DESC, month DESC").
+		Scan(&counts).Error
+	return counts, err
+}
@@ -0,0 +1,228 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// createPublishedPost inserts a published post with the given publish time
+func createPublishedPost(t *testing.T, db *gorm.DB, authorID uint, title string, publishedAt time.Time) *models.Post {
+	t.Helper()
+
+	post := &models.Post{
+		Title:       title,
+		Slug:        fmt.Sprintf("%s-%d", "post", publishedAt.UnixNano()),
+		Content:     "Content for " + title,
+		Status:      models.PostStatusPublished,
+		AuthorID:    authorID,
+		PublishedAt: &publishedAt,
+	}
+	require.NoError(t, db.Create(post).Error)
+	return post
+}
+
+func TestPostRepository_GetPublishedAfterCursor(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewPostRepository(db)
+	author := testutil.CreateUser(t, db, "cursorauthor")
+
+	base := time.Now().UTC().Add(-time.Hour)
+	var expected []uint
+	for i := 0; i < 5; i++ {
+		post := createPublishedPost(t, db, author.ID, fmt.Sprintf("Post %d", i), base.Add(time.Duration(i)*time.Minute))
+		expected = append([]uint{post.ID}, expected...) // newest first
+	}
+
+	t.Run("iterates all posts newest first", func(t *testing.T) {
+		var seen []uint
+		var publishedAt *time.Time
+		var lastID uint
+
+		for {
+			posts, err := repo.GetPublishedAfterCursor(models.PublishedRange{}, publishedAt, lastID, 2)
+			require.NoError(t, err)
+			if len(posts) == 0 {
+				break
+			}
+			for _, post := range posts {
+				seen = append(seen, post.ID)
+			}
+			last := posts[len(posts)-1]
+			publishedAt = last.PublishedAt
+			lastID = last.ID
+		}
+
+		assert.Equal(t, expected, seen)
+	})
+
+	t.Run("stable across concurrent inserts", func(t *testing.T) {
+		firstPage, err := repo.GetPublishedAfterCursor(models.PublishedRange{}, nil, 0, 2)
+		require.NoError(t, err)
+		require.Len(t, firstPage, 2)
+
+		// A newer post lands while the client is paging
+		createPublishedPost(t, db, author.ID, "Breaking news", base.Add(30*time.Minute))
+
+		seen := []uint{firstPage[0].ID, firstPage[1].ID}
+		last := firstPage[len(firstPage)-1]
+		publishedAt, lastID := last.PublishedAt, last.ID
+		for {
+			posts, err := repo.GetPublishedAfterCursor(models.PublishedRange{}, publishedAt, lastID, 2)
+			require.NoError(t, err)
+			if len(posts) == 0 {
+				break
+			}
+			for _, post := range posts {
+				seen = append(seen, post.ID)
+			}
+			last := posts[len(posts)-1]
+			publishedAt, lastID = last.PublishedAt, last.ID
+		}
+
+		// No duplicates and nothing skipped from the original set
+		assert.Equal(t, expected, seen)
+	})
+
+	t.Run("ties on published_at are broken by id", func(t *testing.T) {
+		tieDB := testutil.NewTestDB(t)
+		tieRepo := repository.NewPostRepository(tieDB)
+		tieAuthor := testutil.CreateUser(t, tieDB, "tieauthor")
+
+		at := time.Now().UTC().Add(-time.Hour)
+		var ids []uint
+		for i := 0; i < 3; i++ {
+			post := &models.Post{
+				Title:       fmt.Sprintf("Tie %d", i),
+				Slug:        fmt.Sprintf("tie-%d", i),
+				Content:     "Same publish time",
+				Status:      models.PostStatusPublished,
+				AuthorID:    tieAuthor.ID,
+				PublishedAt: &at,
+			}
+			require.NoError(t, tieDB.Create(post).Error)
+			ids = append([]uint{post.ID}, ids...)
+		}
+
+		first, err := tieRepo.GetPublishedAfterCursor(models.PublishedRange{}, nil, 0, 1)
+		require.NoError(t, err)
+		require.Len(t, first, 1)
+
+		rest, err := tieRepo.GetPublishedAfterCursor(models.PublishedRange{}, first[0].PublishedAt, first[0].ID, 10)
+		require.NoError(t, err)
+		require.Len(t, rest, 2)
+		assert.Equal(t, ids, []uint{first[0].ID, rest[0].ID, rest[1].ID})
+	})
+}
+
+func TestPostRepository_PublishedRange(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewPostRepository(db)
+	author := testutil.CreateUser(t, db, "rangeauthor")
+
+	// One post on the first of each month, January to April
+	var ids []uint
+	for month := time.January; month <= time.April; month++ {
+		post := createPublishedPost(t, db, author.ID, month.String(), time.Date(2024, month, 1, 12, 0, 0, 0, time.UTC))
+		ids = append(ids, post.ID)
+	}
+
+	at := func(month time.Month, day int) *time.Time {
+		d := time.Date(2024, month, day, 12, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	tests := []struct {
+		name string
+		rng  models.PublishedRange
+		want []uint
+	}{
+		{"no bounds", models.PublishedRange{}, []uint{ids[3], ids[2], ids[1], ids[0]}},
+		{"bounds are inclusive", models.PublishedRange{After: at(time.February, 1), Before: at(time.March, 1)}, []uint{ids[2], ids[1]}},
+		{"after only", models.PublishedRange{After: at(time.March, 15)}, []uint{ids[3]}},
+		{"before only", models.PublishedRange{Before: at(time.January, 31)}, []uint{ids[0]}},
+		{"empty month", models.PublishedRange{After: at(time.February, 2), Before: at(time.February, 28)}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			published, total, err := repo.GetPublished(models.PostSortNewest, tt.rng, false, 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.want)), total)
+			assert.Equal(t, tt.want, postIDs(published))
+
+			listed, _, err := repo.List(models.PostFilter{PublishedRange: tt.rng}, 0, 10)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, postIDs(listed))
+
+			paged, err := repo.GetPublishedAfterCursor(tt.rng, nil, 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, postIDs(paged))
+		})
+	}
+}
+
+func postIDs(posts []models.Post) []uint {
+	var ids []uint
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
+
+func TestPostRepository_ArchiveCounts(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewPostRepository(db)
+	author := testutil.CreateUser(t, db, "archiveauthor")
+
+	for i, publishedAt := range []time.Time{
+		time.Date(2023, time.December, 31, 23, 0, 0, 0, time.UTC),
+		time.Date(2024, time.January, 5, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 31, 23, 59, 0, 0, time.UTC),
+	} {
+		createPublishedPost(t, db, author.ID, fmt.Sprintf("Archived %d", i), publishedAt)
+	}
+
+	// Drafts and scheduled posts are left out
+	draft := createPublishedPost(t, db, author.ID, "Draft", time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, db.Model(draft).Update("status", models.PostStatusDraft).Error)
+	createPublishedPost(t, db, author.ID, "Scheduled", time.Now().Add(24*time.Hour))
+
+	counts, err := repo.ArchiveCounts()
+	require.NoError(t, err)
+	assert.Equal(t, []models.ArchiveCount{
+		{Year: 2024, Month: 3, Count: 3},
+		{Year: 2024, Month: 1, Count: 1},
+		{Year: 2023, Month: 12, Count: 1},
+	}, counts)
+}
+
+func TestPostRepository_WithContext_Cancelled(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewPostRepository(db)
+	author := testutil.CreateUser(t, db, "cancelledauthor")
+	post := createPublishedPost(t, db, author.ID, "Cancelled", time.Now().Add(-time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.WithContext(ctx).GetByID(post.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, _, err = repo.WithContext(ctx).GetPublished(models.PostSortNewest, models.PublishedRange{}, false, 0, 10)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The repository it was derived from is unaffected
+	found, err := repo.GetByID(post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, post.ID, found.ID)
+}
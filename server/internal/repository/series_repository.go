@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"gorm.io/gorm"
+)
+
+type SeriesRepository interface {
+	WithContext(ctx context.Context) SeriesRepository
+	Create(series *models.Series) error
+	GetByID(id uint) (*models.Series, error)
+	GetBySlug(slug string) (*models.Series, error)
+	Update(series *models.Series) error
+	Delete(id uint) error
+	List(offset, limit int) ([]models.Series, int64, error)
+	IsSlugTaken(slug string, excludeID uint) bool
+	CountPublishedPosts(seriesIDs []uint) (map[uint]int64, error)
+	GetPosts(seriesID uint, publishedOnly bool) ([]models.Post, error)
+	AddPost(seriesID, postID uint, order int) error
+	RemovePost(seriesID, postID uint) error
+}
+
+type seriesRepository struct {
+	db *gorm.DB
+}
+
+func NewSeriesRepository(db *gorm.DB) SeriesRepository {
+	return &seriesRepository{db: db}
+}
+
+// WithContext returns a repository whose calls run with ctx, so they are
+// cancelled when it is done
+func (r *seriesRepository) WithContext(ctx context.Context) SeriesRepository {
+	return &seriesRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *seriesRepository) Create(series *models.Series) error {
+	return r.db.Create(series).Error
+}
+
+func (r *seriesRepository) GetByID(id uint) (*models.Series, error) {
+	var series models.Series
+	err := r.db.Preload("Author", withDeletedUsers).First(&series, id).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("series not found")
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (r *seriesRepository) GetBySlug(slug string) (*models.Series, error) {
+	var series models.Series
+	err := r.db.Preload("Author", withDeletedUsers).Where("slug = ?", slug).First(&series).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("series not found")
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (r *seriesRepository) Update(series *models.Series) error {
+	return r.db.Omit("Author").Save(series).Error
+}
+
+// Delete removes a series. Its posts are kept and leave the series.
+func (r *seriesRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Post{}).Where("series_id = ?", id).
+			UpdateColumns(map[string]interface{}{"series_id": nil, "series_order": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Series{}, id).Error
+	})
+}
+
+// List returns series newest first
+func (r *seriesRepository) List(offset, limit int) ([]models.Series, int64, error) {
+	var series []models.Series
+	var total int64
+
+	query := r.db.Model(&models.Series{})
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	err := query.Preload("Author", withDeletedUsers).Order("created_at DESC, id DESC").
+		Offset(offset).Limit(limit).Find(&series).Error
+	return series, total, err
+}
+
+func (r *seriesRepository) IsSlugTaken(slug string, excludeID uint) bool {
+	var count int64
+	query := r.db.Model(&models.Series{}).Where("slug = ?", slug)
+	if excludeID > 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+	query.Count(&count)
+	return count > 0
+}
+
+// CountPublishedPosts returns the number of published posts in each of the
+// given series. Series without published posts are left out of the map.
+func (r *seriesRepository) CountPublishedPosts(seriesIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(seriesIDs))
+	if len(seriesIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		SeriesID uint
+		Count    int64
+	}
+	err := r.db.Model(&models.Post{}).
+		Select("series_id, COUNT(*) AS count").
+		Where("series_id IN ? AND status = ?", seriesIDs, models.PostStatusPublished).
+		Group("series_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.SeriesID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetPosts returns the posts of a series in reading order: by SeriesOrder,
+// then ID, so posts given the same order keep the order they were written
+// in
+func (r *seriesRepository) GetPosts(seriesID uint, publishedOnly bool) ([]models.Post, error) {
+	var posts []models.Post
+	query := r.db.Preload("Author", withDeletedUsers).Preload("Tags").Preload("CoAuthors", orderedCoAuthors).Where("series_id = ?", seriesID)
+	if publishedOnly {
+		query = query.Where("status = ?", models.PostStatusPublished)
+	}
+	err := query.Order("series_order ASC, id ASC").Find(&posts).Error
+	return posts, err
+}
+
+// AddPost puts a post in a series at order, moving it out of any series it
+// was in. An order of 0 puts it after the series' last post.
+func (r *seriesRepository) AddPost(seriesID, postID uint, order int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if order == 0 {
+			var last int
+			if err := tx.Model(&models.Post{}).Where("series_id = ? AND id <> ?", seriesID, postID).
+				Select("COALESCE(MAX(series_order), 0)").Scan(&last).Error; err != nil {
+				return err
+			}
+			order = last + 1
+		}
+
+		result := tx.Model(&models.Post{}).Where("id = ?", postID).
+			UpdateColumns(map[string]interface{}{"series_id": seriesID, "series_order": order})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperrors.NotFound("post not found")
+		}
+		return nil
+	})
+}
+
+// RemovePost takes a post out of a series
+func (r *seriesRepository) RemovePost(seriesID, postID uint) error {
+	result := r.db.Model(&models.Post{}).Where("id = ? AND series_id = ?", postID, seriesID).
+		UpdateColumns(map[string]interface{}{"series_id": nil, "series_order": 0})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("post is not in the series")
+	}
+	return nil
+}
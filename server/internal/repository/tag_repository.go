@@ -1,23 +1,35 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"strings"
 
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
 	"gorm.io/gorm"
 )
 
 type TagRepository interface {
+	WithContext(ctx context.Context) TagRepository
 	Create(tag *models.Tag) error
 	GetByID(id uint) (*models.Tag, error)
 	GetBySlug(slug string) (*models.Tag, error)
+	GetByFormerSlug(slug string) (*models.Tag, error)
 	Update(tag *models.Tag) error
+	Rename(tag *models.Tag, oldSlug string) error
 	Delete(id uint) error
 	List(offset, limit int) ([]models.Tag, int64, error)
 	GetAll() ([]models.Tag, error)
 	IsNameTaken(name string, excludeID uint) bool
+	GetByNames(names []string) ([]models.Tag, error)
 	IsSlugTaken(slug string, excludeID uint) bool
 	GetPopular(limit int) ([]models.Tag, error)
+	Count() (int64, error)
+	CountPosts(tagID uint) (int64, error)
+	CountPublishedPosts(tagIDs []uint) (map[uint]int64, error)
+	GetExistingIDs(ids []uint) ([]uint, error)
+	Search(query string, offset, limit int) ([]models.Tag, int64, error)
 }
 
 type tagRepository struct {
@@ -28,8 +40,20 @@ func NewTagRepository(db *gorm.DB) TagRepository {
 	return &tagRepository{db: db}
 }
 
+// WithContext returns a repository whose calls run with ctx, so they are
+// cancelled when it is done
+func (r *tagRepository) WithContext(ctx context.Context) TagRepository {
+	return &tagRepository{db: r.db.WithContext(ctx)}
+}
+
 func (r *tagRepository) Create(tag *models.Tag) error {
-	return r.db.Create(tag).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// A slug in use no longer redirects to the tag that used to have it
+		if err := tx.Where("slug = ?", tag.Slug).Delete(&models.TagSlugHistory{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(tag).Error
+	})
 }
 
 func (r *tagRepository) GetByID(id uint) (*models.Tag, error) {
@@ -40,7 +64,7 @@ func (r *tagRepository) GetByID(id uint) (*models.Tag, error) {
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("tag not found")
+			return nil, apperrors.NotFound("tag not found")
 		}
 		return nil, err
 	}
@@ -55,7 +79,24 @@ func (r *tagRepository) GetBySlug(slug string) (*models.Tag, error) {
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("tag not found")
+			return nil, apperrors.NotFound("tag not found")
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetByFormerSlug returns the tag that used slug before being renamed
+func (r *tagRepository) GetByFormerSlug(slug string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Preload("Posts", func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ?", models.PostStatusPublished)
+	}).Joins("JOIN tag_slug_history ON tag_slug_history.tag_id = tags.id").
+		Where("tag_slug_history.slug = ?", slug).First(&tag).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("tag not found")
 		}
 		return nil, err
 	}
@@ -66,6 +107,22 @@ func (r *tagRepository) Update(tag *models.Tag) error {
 	return r.db.Save(tag).Error
 }
 
+// Rename saves a tag whose slug changed from oldSlug, recording oldSlug in
+// the slug history in the same transaction
+func (r *tagRepository) Rename(tag *models.Tag, oldSlug string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// A slug in use no longer redirects to the tag that used to have
+		// it, which includes the tag taking back one of its own
+		if err := tx.Where("slug = ?", tag.Slug).Delete(&models.TagSlugHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.TagSlugHistory{TagID: tag.ID, Slug: oldSlug}).Error; err != nil {
+			return err
+		}
+		return tx.Save(tag).Error
+	})
+}
+
 func (r *tagRepository) Delete(id uint) error {
 	// Remove associations with posts first
 	var tag models.Tag
@@ -77,10 +134,66 @@ func (r *tagRepository) Delete(id uint) error {
 		return err
 	}
 
+	if err := r.db.Where("tag_id = ?", id).Delete(&models.TagSlugHistory{}).Error; err != nil {
+		return err
+	}
+
 	// Delete the tag
 	return r.db.Delete(&models.Tag{}, id).Error
 }
 
+// Count counts all tags
+func (r *tagRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Tag{}).Count(&count).Error
+	return count, err
+}
+
+// CountPosts counts the posts the tag is attached to, in any status
+func (r *tagRepository) CountPosts(tagID uint) (int64, error) {
+	var count int64
+	err := r.db.Table("post_tags").Where("tag_id = ?", tagID).Count(&count).Error
+	return count, err
+}
+
+// CountPublishedPosts returns how many published posts use each of the
+// tags, in one grouped query. Unused tags are absent from the map.
+func (r *tagRepository) CountPublishedPosts(tagIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(tagIDs))
+	if len(tagIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		TagID uint
+		Count int64
+	}
+	err := r.db.Table("post_tags").
+		Select("post_tags.tag_id, COUNT(*) AS count").
+		Joins("JOIN posts ON posts.id = post_tags.post_id").
+		Where("post_tags.tag_id IN ? AND posts.status = ?", tagIDs, models.PostStatusPublished).
+		Group("post_tags.tag_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetExistingIDs returns which of the given tag IDs exist
+func (r *tagRepository) GetExistingIDs(ids []uint) ([]uint, error) {
+	existing := []uint{}
+	if len(ids) == 0 {
+		return existing, nil
+	}
+	err := r.db.Model(&models.Tag{}).Where("id IN ?", ids).Pluck("id", &existing).Error
+	return existing, err
+}
+
 func (r *tagRepository) List(offset, limit int) ([]models.Tag, int64, error) {
 	var tags []models.Tag
 	var total int64
@@ -102,6 +215,25 @@ func (r *tagRepository) List(offset, limit int) ([]models.Tag, int64, error) {
 	return tags, total, err
 }
 
+// Search finds tags whose name, slug or description contains query
+func (r *tagRepository) Search(query string, offset, limit int) ([]models.Tag, int64, error) {
+	var tags []models.Tag
+	var total int64
+
+	searchQuery := "%" + strings.ToLower(query) + "%"
+	dbQuery := r.db.Model(&models.Tag{}).
+		Where("LOWER(name) LIKE ? OR LOWER(slug) LIKE ? OR LOWER(description) LIKE ?", searchQuery, searchQuery, searchQuery)
+
+	// Count total records
+	if err := dbQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	err := dbQuery.Order("name ASC").Offset(offset).Limit(limit).Find(&tags).Error
+	return tags, total, err
+}
+
 func (r *tagRepository) GetAll() ([]models.Tag, error) {
 	var tags []models.Tag
 	err := r.db.Order("name ASC").Find(&tags).Error
@@ -118,6 +250,22 @@ func (r *tagRepository) IsNameTaken(name string, excludeID uint) bool {
 	return count > 0
 }
 
+// GetByNames returns the tags with any of the given names, matched
+// case-insensitively like IsNameTaken
+func (r *tagRepository) GetByNames(names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+	err := r.db.Where("LOWER(name) IN ?", lowered).Find(&tags).Error
+	return tags, err
+}
+
 func (r *tagRepository) IsSlugTaken(slug string, excludeID uint) bool {
 	var count int64
 	query := r.db.Model(&models.Tag{}).Where("slug = ?", slug)
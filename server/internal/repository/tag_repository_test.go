@@ -0,0 +1,38 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagRepository_CountPosts(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewTagRepository(db)
+	postRepo := repository.NewPostRepository(db)
+	author := testutil.CreateUser(t, db, "tagcounter")
+
+	used := &models.Tag{Name: "Used", Slug: "used"}
+	unused := &models.Tag{Name: "Unused", Slug: "unused"}
+	require.NoError(t, db.Create(used).Error)
+	require.NoError(t, db.Create(unused).Error)
+
+	published := createPublishedPost(t, db, author.ID, "Tagged published", time.Now().UTC())
+	draft := &models.Post{Title: "Tagged draft", Slug: "tagged-draft", Content: "Not out yet", Status: models.PostStatusDraft, AuthorID: author.ID}
+	require.NoError(t, db.Create(draft).Error)
+	require.NoError(t, postRepo.AddTags(published.ID, []uint{used.ID}))
+	require.NoError(t, postRepo.AddTags(draft.ID, []uint{used.ID}))
+
+	count, err := repo.CountPosts(used.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count, "drafts count too")
+
+	count, err = repo.CountPosts(unused.ID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"gorm.io/gorm"
+)
+
+type TokenRepository interface {
+	WithContext(ctx context.Context) TokenRepository
+	Revoke(token *models.RevokedToken) error
+	IsRevoked(jti string) (bool, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+// WithContext returns a repository whose calls run with ctx, so they are
+// cancelled when it is done
+func (r *tokenRepository) WithContext(ctx context.Context) TokenRepository {
+	return &tokenRepository{db: r.db.WithContext(ctx)}
+}
+
+// Revoke records token's ID as revoked. Revoking it again is a no-op.
+func (r *tokenRepository) Revoke(token *models.RevokedToken) error {
+	err := r.db.Create(token).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil
+	}
+	return err
+}
+
+func (r *tokenRepository) IsRevoked(jti string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteExpired removes the revocations of tokens that expired before the
+// given time, which are rejected for their expiry anyway
+func (r *tokenRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&models.RevokedToken{})
+	return result.RowsAffected, result.Error
+}
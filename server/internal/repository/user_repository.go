@@ -2,8 +2,12 @@ package repository
 
 import (
 	"errors"
+	"strings"
+	"time"
 
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -15,9 +19,22 @@ type UserRepository interface {
 	GetByEmailOrUsername(emailOrUsername string) (*models.User, error)
 	Update(user *models.User) error
 	Delete(id uint) error
-	List(offset, limit int) ([]models.User, int64, error)
+	CountContent(id uint) (posts, comments int64, err error)
+	CountAdmins() (int64, error)
+	CountActiveAuthors() (int64, error)
+	DeleteWithContent(id uint) error
+	ReassignAndDelete(id uint, placeholder *models.User) error
+	List(filter models.UserFilter, offset, limit int) ([]models.User, int64, error)
+	ListWithCounts(filter models.UserFilter, sort models.UserSort, offset, limit int) ([]models.UserWithCounts, int64, error)
+	RecordLogin(id uint, at time.Time) error
+	Search(query string, offset, limit int) ([]models.User, int64, error)
 	IsEmailTaken(email string, excludeID uint) bool
 	IsUsernameTaken(username string, excludeID uint) bool
+	SetNewsletterSubscribed(id uint, subscribed bool) error
+	GetNewsletterSubscribers() ([]models.User, error)
+	ScheduleDeletion(id uint, purgeAt time.Time) error
+	Restore(id uint) error
+	GetPurgeable(now time.Time) ([]models.User, error)
 }
 
 type userRepository struct {
@@ -28,8 +45,23 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// withDeletedUsers is a preload condition that keeps users scheduled for
+// deletion, whose posts and comments stay up until they are purged
+func withDeletedUsers(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// ErrUserTaken is returned by Create and Update when the email or username
+// is already used by another account. The services check availability
+// first, but only the unique indexes rule out concurrent requests.
+var ErrUserTaken = apperrors.Conflict("email or username is already taken")
+
 func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+	err := r.db.Create(user).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrUserTaken
+	}
+	return err
 }
 
 func (r *userRepository) GetByID(id uint) (*models.User, error) {
@@ -37,7 +69,7 @@ func (r *userRepository) GetByID(id uint) (*models.User, error) {
 	err := r.db.First(&user, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, apperrors.NotFound("user not found")
 		}
 		return nil, err
 	}
@@ -46,10 +78,10 @@ func (r *userRepository) GetByID(id uint) (*models.User, error) {
 
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.Where("email = ?", utils.NormalizeEmail(email)).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, apperrors.NotFound("user not found")
 		}
 		return nil, err
 	}
@@ -61,7 +93,7 @@ func (r *userRepository) GetByUsername(username string) (*models.User, error) {
 	err := r.db.Where("username = ?", username).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, apperrors.NotFound("user not found")
 		}
 		return nil, err
 	}
@@ -70,10 +102,11 @@ func (r *userRepository) GetByUsername(username string) (*models.User, error) {
 
 func (r *userRepository) GetByEmailOrUsername(emailOrUsername string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ? OR username = ?", emailOrUsername, emailOrUsername).First(&user).Error
+	identifier := strings.TrimSpace(emailOrUsername)
+	err := r.db.Where("email = ? OR username = ?", utils.NormalizeEmail(identifier), identifier).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, apperrors.NotFound("user not found")
 		}
 		return nil, err
 	}
@@ -81,30 +114,268 @@ func (r *userRepository) GetByEmailOrUsername(emailOrUsername string) (*models.U
 }
 
 func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+	err := r.db.Save(user).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrUserTaken
+	}
+	return err
 }
 
+// Delete deletes a user along with the comment reports they filed and the
+// series they created
 func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("reporter_id = ?", id).Delete(&models.CommentReport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_authors WHERE user_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := deleteSeriesBy(tx, id); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.User{}, id).Error
+	})
+}
+
+// deleteSeriesBy deletes the series a user created. Posts of other users
+// in them are kept, outside any series.
+func deleteSeriesBy(tx *gorm.DB, userID uint) error {
+	userSeries := tx.Model(&models.Series{}).Select("id").Where("author_id = ?", userID)
+	if err := tx.Model(&models.Post{}).Where("series_id IN (?)", userSeries).
+		UpdateColumns(map[string]interface{}{"series_id": nil, "series_order": 0}).Error; err != nil {
+		return err
+	}
+	return tx.Where("author_id = ?", userID).Delete(&models.Series{}).Error
+}
+
+// CountContent returns how many posts and comments a user has written.
+// Soft-deleted comments count too, since they still reference the user.
+func (r *userRepository) CountContent(id uint) (int64, int64, error) {
+	var posts, comments int64
+	if err := r.db.Model(&models.Post{}).Where("author_id = ?", id).Count(&posts).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := r.db.Unscoped().Model(&models.Comment{}).Where("author_id = ?", id).Count(&comments).Error; err != nil {
+		return 0, 0, err
+	}
+	return posts, comments, nil
+}
+
+// CountAdmins returns how many admin accounts exist
+func (r *userRepository) CountAdmins() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("is_admin = ?", true).Count(&count).Error
+	return count, err
+}
+
+// CountActiveAuthors counts the active users with at least one published
+// post
+func (r *userRepository) CountActiveAuthors() (int64, error) {
+	var count int64
+	published := r.db.Model(&models.Post{}).Select("author_id").
+		Where("status = ? AND published_at <= ?", models.PostStatusPublished, time.Now())
+	err := r.db.Model(&models.User{}).
+		Where("is_active = ? AND id IN (?)", true, published).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteWithContent deletes a user together with their posts, their
+// comments and every comment on their posts. Replies to deleted comments go
+// too, so none is left pointing at a missing parent, as do the reports on
+// deleted comments and the reports the user filed. Their series go as well.
+func (r *userRepository) DeleteWithContent(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		userPosts := tx.Model(&models.Post{}).Select("id").Where("author_id = ?", id)
+
+		var commentIDs []uint
+		if err := tx.Unscoped().Model(&models.Comment{}).
+			Where("author_id = ? OR post_id IN (?)", id, userPosts).
+			Pluck("id", &commentIDs).Error; err != nil {
+			return err
+		}
+		for parents := commentIDs; len(parents) > 0; {
+			var replies []uint
+			if err := tx.Unscoped().Model(&models.Comment{}).Where("parent_id IN ?", parents).Pluck("id", &replies).Error; err != nil {
+				return err
+			}
+			commentIDs = append(commentIDs, replies...)
+			parents = replies
+		}
+
+		if err := tx.Where("reporter_id = ? OR comment_id IN ?", id, commentIDs).Delete(&models.CommentReport{}).Error; err != nil {
+			return err
+		}
+		if len(commentIDs) > 0 {
+			if err := tx.Unscoped().Where("id IN ?", commentIDs).Delete(&models.Comment{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM post_tags WHERE post_id IN (?)", userPosts).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_authors WHERE user_id = ? OR post_id IN (?)", id, userPosts).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_id IN (?)", userPosts).Delete(&models.PostSlugHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("author_id = ?", id).Delete(&models.Post{}).Error; err != nil {
+			return err
+		}
+		if err := deleteSeriesBy(tx, id); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.User{}, id).Error
+	})
+}
+
+// ReassignAndDelete hands a user's posts, series and comments over to the
+// placeholder account, creating it on first use, then deletes the user, the
+// comment reports they filed and their place among other posts' co-authors
+func (r *userRepository) ReassignAndDelete(id uint, placeholder *models.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("username = ?", placeholder.Username).FirstOrCreate(placeholder).Error; err != nil {
+			return err
+		}
+		// is_active defaults to true on create, so switch it off explicitly
+		if err := tx.Model(placeholder).UpdateColumn("is_active", false).Error; err != nil {
+			return err
+		}
+		if placeholder.ID == id {
+			return apperrors.Validation("the placeholder account cannot be reassigned to itself")
+		}
+
+		if err := tx.Model(&models.Post{}).Where("author_id = ?", id).UpdateColumn("author_id", placeholder.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Series{}).Where("author_id = ?", id).UpdateColumn("author_id", placeholder.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Comment{}).Where("author_id = ?", id).UpdateColumn("author_id", placeholder.ID).Error; err != nil {
+			return err
+		}
+		// Reports are the reporter's own judgement, so they aren't handed over,
+		// and neither is co-authoring other users' posts
+		if err := tx.Where("reporter_id = ?", id).Delete(&models.CommentReport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_authors WHERE user_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.User{}, id).Error
+	})
+}
+
+// List returns the users matching filter, in ID order
+func (r *userRepository) List(filter models.UserFilter, offset, limit int) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	query := r.filtered(filter)
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	err := query.Order("id ASC").Offset(offset).Limit(limit).Find(&users).Error
+	return users, total, err
 }
 
-func (r *userRepository) List(offset, limit int) ([]models.User, int64, error) {
+// ListWithCounts returns the users matching filter in the given order, each
+// with their post and comment counts. The counts come from grouped
+// subqueries joined onto the page, so the listing takes a single query.
+func (r *userRepository) ListWithCounts(filter models.UserFilter, sort models.UserSort, offset, limit int) ([]models.UserWithCounts, int64, error) {
+	var users []models.UserWithCounts
+	var total int64
+
+	// Count total records
+	if err := r.filtered(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	postCounts := r.db.Model(&models.Post{}).
+		Select("author_id, COUNT(*) AS posts_count").
+		Group("author_id")
+	commentCounts := r.db.Model(&models.Comment{}).
+		Select("author_id, COUNT(*) AS comments_count").
+		Where("author_id IS NOT NULL").
+		Group("author_id")
+
+	query := r.filtered(filter).
+		Select("users.*, COALESCE(post_counts.posts_count, 0) AS posts_count, COALESCE(comment_counts.comments_count, 0) AS comments_count").
+		Joins("LEFT JOIN (?) AS post_counts ON post_counts.author_id = users.id", postCounts).
+		Joins("LEFT JOIN (?) AS comment_counts ON comment_counts.author_id = users.id", commentCounts)
+
+	switch sort {
+	case models.UserSortCreatedAt:
+		query = query.Order("users.created_at DESC, users.id DESC")
+	case models.UserSortLastLogin:
+		query = query.Order("users.last_login_at IS NULL, users.last_login_at DESC, users.id ASC")
+	case models.UserSortUsername:
+		query = query.Order("users.username ASC")
+	default:
+		query = query.Order("users.id ASC")
+	}
+
+	// Get paginated results
+	err := query.Offset(offset).Limit(limit).Scan(&users).Error
+	return users, total, err
+}
+
+// filtered starts a user query narrowed by filter
+func (r *userRepository) filtered(filter models.UserFilter) *gorm.DB {
+	query := r.db.Model(&models.User{})
+	if filter.Deleted {
+		query = r.db.Unscoped().Model(&models.User{}).Where("users.deleted_at IS NOT NULL")
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		searchQuery := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(users.username) LIKE ? OR LOWER(users.first_name) LIKE ? OR LOWER(users.last_name) LIKE ? OR LOWER(users.email) LIKE ?",
+			searchQuery, searchQuery, searchQuery, searchQuery)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("users.is_active = ?", *filter.IsActive)
+	}
+	if filter.IsAdmin != nil {
+		query = query.Where("users.is_admin = ?", *filter.IsAdmin)
+	}
+	return query
+}
+
+// RecordLogin stores when the user last logged in, leaving updated_at alone
+func (r *userRepository) RecordLogin(id uint, at time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).UpdateColumn("last_login_at", at).Error
+}
+
+// Search finds active users whose username or name contains query
+func (r *userRepository) Search(query string, offset, limit int) ([]models.User, int64, error) {
 	var users []models.User
 	var total int64
 
+	searchQuery := "%" + strings.ToLower(query) + "%"
+	dbQuery := r.db.Model(&models.User{}).
+		Where("is_active = ? AND (LOWER(username) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?)",
+			true, searchQuery, searchQuery, searchQuery)
+
 	// Count total records
-	if err := r.db.Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := dbQuery.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results
-	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
+	err := dbQuery.Order("username ASC").Offset(offset).Limit(limit).Find(&users).Error
 	return users, total, err
 }
 
+// IsEmailTaken reports whether another account uses email. Users scheduled
+// for deletion keep their email and username until they are purged.
 func (r *userRepository) IsEmailTaken(email string, excludeID uint) bool {
 	var count int64
-	query := r.db.Model(&models.User{}).Where("email = ?", email)
+	query := r.db.Unscoped().Model(&models.User{}).Where("email = ?", utils.NormalizeEmail(email))
 	if excludeID > 0 {
 		query = query.Where("id != ?", excludeID)
 	}
@@ -114,10 +385,70 @@ func (r *userRepository) IsEmailTaken(email string, excludeID uint) bool {
 
 func (r *userRepository) IsUsernameTaken(username string, excludeID uint) bool {
 	var count int64
-	query := r.db.Model(&models.User{}).Where("username = ?", username)
+	query := r.db.Unscoped().Model(&models.User{}).Where("username = ?", username)
 	if excludeID > 0 {
 		query = query.Where("id != ?", excludeID)
 	}
 	query.Count(&count)
 	return count > 0
 }
+
+// SetNewsletterSubscribed opts a user in to or out of the newsletter
+func (r *userRepository) SetNewsletterSubscribed(id uint, subscribed bool) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("newsletter_subscribed", subscribed)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("user not found")
+	}
+	return nil
+}
+
+// GetNewsletterSubscribers returns the active users who want the newsletter
+func (r *userRepository) GetNewsletterSubscribers() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("newsletter_subscribed = ? AND is_active = ?", true, true).Order("id").Find(&users).Error
+	return users, err
+}
+
+// ScheduleDeletion deactivates and soft-deletes a user, to be purged at
+// purgeAt
+func (r *userRepository) ScheduleDeletion(id uint, purgeAt time.Time) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"is_active":  false,
+		"purge_at":   purgeAt,
+		"deleted_at": time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("user not found")
+	}
+	return nil
+}
+
+// Restore cancels the scheduled deletion of a user. They stay deactivated.
+func (r *userRepository) Restore(id uint) error {
+	result := r.db.Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "purge_at": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("user not found or not scheduled for deletion")
+	}
+	return nil
+}
+
+// GetPurgeable returns the soft-deleted users whose grace period ended by
+// now
+func (r *userRepository) GetPurgeable(now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND purge_at <= ?", now).
+		Order("id").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,144 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository_List_Filters(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewUserRepository(db)
+
+	alice := testutil.CreateUser(t, db, "alice")
+	bob := testutil.CreateUser(t, db, "bob")
+	carol := testutil.CreateUser(t, db, "carol")
+	dave := testutil.CreateUser(t, db, "dave")
+
+	// bob is a deactivated admin, carol an active admin, dave is deactivated
+	// and only findable by email
+	require.NoError(t, db.Model(bob).Updates(map[string]interface{}{"is_admin": true, "is_active": false}).Error)
+	require.NoError(t, db.Model(carol).Update("is_admin", true).Error)
+	require.NoError(t, db.Model(dave).Updates(map[string]interface{}{"is_active": false, "email": "d.smith@Corp.example"}).Error)
+
+	yes, no := true, false
+	tests := []struct {
+		name   string
+		filter models.UserFilter
+		want   []uint
+	}{
+		{"no filter", models.UserFilter{}, []uint{alice.ID, bob.ID, carol.ID, dave.ID}},
+		{"query matches username", models.UserFilter{Query: "CAR"}, []uint{carol.ID}},
+		{"query matches email", models.UserFilter{Query: "corp.example"}, []uint{dave.ID}},
+		{"query matches name", models.UserFilter{Query: "TEST"}, []uint{alice.ID, bob.ID, carol.ID, dave.ID}},
+		{"active", models.UserFilter{IsActive: &yes}, []uint{alice.ID, carol.ID}},
+		{"inactive", models.UserFilter{IsActive: &no}, []uint{bob.ID, dave.ID}},
+		{"admins", models.UserFilter{IsAdmin: &yes}, []uint{bob.ID, carol.ID}},
+		{"non-admins", models.UserFilter{IsAdmin: &no}, []uint{alice.ID, dave.ID}},
+		{"inactive admins", models.UserFilter{IsActive: &no, IsAdmin: &yes}, []uint{bob.ID}},
+		{"query and active", models.UserFilter{Query: "example.com", IsActive: &yes}, []uint{alice.ID, carol.ID}},
+		{"query, active and admin", models.UserFilter{Query: "a", IsActive: &yes, IsAdmin: &yes}, []uint{carol.ID}},
+		{"no match", models.UserFilter{Query: "nobody", IsAdmin: &yes}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, total, err := repo.List(tt.filter, 0, 10)
+			require.NoError(t, err)
+
+			var ids []uint
+			for _, user := range users {
+				ids = append(ids, user.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+			assert.Equal(t, int64(len(tt.want)), total)
+		})
+	}
+
+	t.Run("pagination applies after filtering", func(t *testing.T) {
+		users, total, err := repo.List(models.UserFilter{IsActive: &no}, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		require.Len(t, users, 1)
+		assert.Equal(t, dave.ID, users[0].ID)
+	})
+}
+
+func TestUserRepository_ListWithCounts(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	repo := repository.NewUserRepository(db)
+
+	now := time.Now().UTC()
+	prolific := testutil.CreateUser(t, db, "zed")
+	dormant := testutil.CreateUser(t, db, "amy")
+	lurker := testutil.CreateUser(t, db, "max")
+
+	require.NoError(t, db.Model(prolific).UpdateColumns(map[string]interface{}{"created_at": now.Add(-72 * time.Hour), "last_login_at": now.Add(-time.Hour)}).Error)
+	require.NoError(t, db.Model(dormant).UpdateColumns(map[string]interface{}{"created_at": now.Add(-48 * time.Hour)}).Error)
+	require.NoError(t, db.Model(lurker).UpdateColumns(map[string]interface{}{"created_at": now.Add(-24 * time.Hour), "last_login_at": now.Add(-30 * 24 * time.Hour)}).Error)
+
+	first := createPublishedPost(t, db, prolific.ID, "First", now)
+	createPublishedPost(t, db, prolific.ID, "Second", now.Add(-time.Minute))
+	draft := &models.Post{Title: "Draft", Slug: "draft", Content: "Unfinished", Status: models.PostStatusDraft, AuthorID: prolific.ID}
+	require.NoError(t, db.Create(draft).Error)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, db.Create(&models.Comment{Content: "Nice", Status: models.CommentStatusApproved, AuthorID: &prolific.ID, PostID: first.ID}).Error)
+	}
+	require.NoError(t, db.Create(&models.Comment{Content: "Hmm", Status: models.CommentStatusPending, AuthorID: &lurker.ID, PostID: first.ID}).Error)
+	deleted := &models.Comment{Content: "Removed", Status: models.CommentStatusApproved, AuthorID: &lurker.ID, PostID: first.ID}
+	require.NoError(t, db.Create(deleted).Error)
+	require.NoError(t, db.Delete(deleted).Error)
+	require.NoError(t, db.Create(&models.Comment{Content: "Guest", Status: models.CommentStatusApproved, GuestName: "Visitor", PostID: first.ID}).Error)
+
+	t.Run("counts", func(t *testing.T) {
+		users, total, err := repo.ListWithCounts(models.UserFilter{}, "", 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		require.Len(t, users, 3)
+
+		counts := make(map[string][2]int64)
+		for _, user := range users {
+			counts[user.Username] = [2]int64{user.PostsCount, user.CommentsCount}
+		}
+		assert.Equal(t, [2]int64{3, 2}, counts["zed"], "drafts count as posts")
+		assert.Equal(t, [2]int64{0, 0}, counts["amy"])
+		assert.Equal(t, [2]int64{0, 1}, counts["max"], "deleted comments don't count")
+	})
+
+	tests := []struct {
+		sort models.UserSort
+		want []string
+	}{
+		{"", []string{"zed", "amy", "max"}},
+		{models.UserSortCreatedAt, []string{"max", "amy", "zed"}},
+		{models.UserSortLastLogin, []string{"zed", "max", "amy"}},
+		{models.UserSortUsername, []string{"amy", "max", "zed"}},
+	}
+	for _, tt := range tests {
+		t.Run("sort "+string(tt.sort), func(t *testing.T) {
+			users, _, err := repo.ListWithCounts(models.UserFilter{}, tt.sort, 0, 10)
+			require.NoError(t, err)
+
+			var usernames []string
+			for _, user := range users {
+				usernames = append(usernames, user.Username)
+			}
+			assert.Equal(t, tt.want, usernames)
+		})
+	}
+
+	t.Run("filters and pages", func(t *testing.T) {
+		users, total, err := repo.ListWithCounts(models.UserFilter{Query: "@example.com"}, models.UserSortUsername, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		require.Len(t, users, 1)
+		assert.Equal(t, "max", users[0].Username)
+		assert.Equal(t, int64(1), users[0].CommentsCount)
+	})
+}
@@ -0,0 +1,77 @@
+// Package respond renders error responses in the format the client asked
+// for. JSON routes answer with a models.APIResponse; routes serving XML,
+// such as the RSS feeds, answer with an equivalent XML document. A client
+// can ask for the other format by naming it first in the Accept header.
+package respond
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+)
+
+// xmlRouteKey marks a request whose route serves XML
+const xmlRouteKey = "respond_xml_route"
+
+// XMLError is the XML form of an error response
+type XMLError struct {
+	XMLName xml.Name `xml:"error"`
+	Success bool     `xml:"success"`
+	Message string   `xml:"message"`
+}
+
+// XMLRoute marks the route as serving XML, so its errors are rendered as XML
+// unless the client asks for JSON
+func XMLRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(xmlRouteKey, true)
+		c.Next()
+	}
+}
+
+// Error writes an error response with the given status and message
+func Error(c *gin.Context, status int, message string) {
+	ErrorWithData(c, status, message, nil)
+}
+
+// ErrorWithData is like Error but adds details to the JSON response's data.
+// The XML form carries only the message.
+func ErrorWithData(c *gin.Context, status int, message string, data interface{}) {
+	if wantsXML(c) {
+		c.XML(status, XMLError{Success: false, Message: message})
+		return
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Error:   message,
+		Data:    data,
+	})
+}
+
+// Abort writes an error response like Error and stops the handler chain,
+// for use in middleware
+func Abort(c *gin.Context, status int, message string) {
+	Error(c, status, message)
+	c.Abort()
+}
+
+// wantsXML picks the error format from the client's preferred media type,
+// the first one in Accept, falling back to the route's own format when that
+// is neither JSON nor XML. Only the first type counts, so browsers, which
+// list HTML first and XML later, still get JSON from JSON routes.
+func wantsXML(c *gin.Context) bool {
+	preferred, _, _ := strings.Cut(c.GetHeader("Accept"), ",")
+	mediaType, _, _ := strings.Cut(preferred, ";")
+
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case binding.MIMEXML, binding.MIMEXML2:
+		return true
+	case binding.MIMEJSON:
+		return false
+	}
+	return c.GetBool(xmlRouteKey)
+}
@@ -0,0 +1,73 @@
+package respond
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError_Negotiation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	fail := func(c *gin.Context) { Error(c, http.StatusNotFound, "Post not found") }
+	router.GET("/posts", fail)
+	router.GET("/feed", XMLRoute(), fail)
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		xml    bool
+	}{
+		{"JSON route", "/posts", "", false},
+		{"JSON route, any type", "/posts", "*/*", false},
+		{"JSON route, XML requested", "/posts", "application/xml", true},
+		{"JSON route, text/xml with parameters", "/posts", "text/xml; charset=utf-8, application/json", true},
+		{"JSON route, browser", "/posts", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", false},
+		{"XML route", "/feed", "", true},
+		{"XML route, RSS reader", "/feed", "application/rss+xml", true},
+		{"XML route, JSON requested", "/feed", "application/json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusNotFound, w.Code)
+			if !tt.xml {
+				assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+				assert.JSONEq(t, `{"success":false,"error":"Post not found"}`, w.Body.String())
+				return
+			}
+
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/xml")
+			var body XMLError
+			require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &body))
+			assert.False(t, body.Success)
+			assert.Equal(t, "Post not found", body.Message)
+		})
+	}
+}
+
+func TestErrorWithData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/tags/1", nil)
+
+	ErrorWithData(c, http.StatusConflict, "tag is in use", map[string]int{"posts_count": 2})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"success":false,"error":"tag is in use","data":{"posts_count":2}}`, w.Body.String())
+}
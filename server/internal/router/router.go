@@ -1,23 +1,62 @@
 package router
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/breach"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/docs"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/handlers"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/middleware"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/respond"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/version"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
+	"gorm.io/gorm"
 )
 
 type Router struct {
 	config         *config.Config
+	db             *gorm.DB
 	authHandler    *handlers.AuthHandler
 	postHandler    *handlers.PostHandler
 	tagHandler     *handlers.TagHandler
 	commentHandler *handlers.CommentHandler
 	adminHandler   *handlers.AdminHandler
+	searchHandler  *handlers.SearchHandler
+	feedHandler    *handlers.FeedHandler
+	statsHandler   *handlers.StatsHandler
+	seriesHandler  *handlers.SeriesHandler
+
+	newsletterHandler *handlers.NewsletterHandler
+	newsletterService service.NewsletterService
+	userService       service.UserService
+	tokenService      service.TokenService
+	webhooks          webhook.Dispatcher
+
+	// availabilityLimiter throttles the username/email availability checks
+	// to make account enumeration expensive. It's shared across API
+	// prefixes so the legacy alias doesn't double the budget.
+	availabilityLimiter *middleware.RateLimiter
+
+	// guestCommentLimiter throttles comments from visitors who aren't
+	// logged in, who are far more likely to be spam
+	guestCommentLimiter *middleware.RateLimiter
+
+	// idempotency replays creation requests retried with the same
+	// Idempotency-Key instead of creating duplicates
+	idempotency *middleware.Idempotency
+
+	// responseCache serves published posts to anonymous readers without
+	// going back to the database
+	responseCache *middleware.ResponseCache
 }
 
 func NewRouter(cfg *config.Config) *Router {
@@ -29,28 +68,74 @@ func NewRouter(cfg *config.Config) *Router {
 	postRepo := repository.NewPostRepository(db)
 	tagRepo := repository.NewTagRepository(db)
 	commentRepo := repository.NewCommentRepository(db)
+	seriesRepo := repository.NewSeriesRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, cfg)
-	postService := service.NewPostService(postRepo, tagRepo, commentRepo)
-	tagService := service.NewTagService(tagRepo)
-	commentService := service.NewCommentService(commentRepo, postRepo)
+	mail := mailer.New(cfg.Mail)
+	userService := service.NewUserService(userRepo, breach.NewChecker(cfg.Password), mail, cfg)
+	tokenService := service.NewTokenService(tokenRepo, cfg)
+	webhooks := webhook.NewDispatcher(cfg.Webhooks)
+	tagService := service.NewTagService(tagRepo, cfg.Cache.TagListTTL)
+	postService := service.NewPostService(postRepo, tagRepo, commentRepo, webhooks, mail, cfg.Content, cfg.JWT, tagService)
+	commentService := service.NewCommentService(commentRepo, postRepo, webhooks, cfg.Comments)
+	searchService := service.NewSearchService(postService, tagRepo, userRepo)
+	seriesService := service.NewSeriesService(seriesRepo, postRepo, commentRepo)
+	statsService := service.NewStatsService(postRepo, commentRepo, tagRepo, userRepo, time.Minute)
+	newsletterService := service.NewNewsletterService(userRepo, postRepo, mail, cfg.Newsletter, cfg.App.BaseURL)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(userService)
+	authHandler := handlers.NewAuthHandler(userService, tokenService, cfg)
 	postHandler := handlers.NewPostHandler(postService)
-	tagHandler := handlers.NewTagHandler(tagService)
+	tagHandler := handlers.NewTagHandler(tagService, postService)
 	commentHandler := handlers.NewCommentHandler(commentService)
 	adminHandler := handlers.NewAdminHandler(userService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	feedHandler := handlers.NewFeedHandler(postService, tagService, cfg.App)
+	newsletterHandler := handlers.NewNewsletterHandler(newsletterService)
+	statsHandler := handlers.NewStatsHandler(statsService)
+	seriesHandler := handlers.NewSeriesHandler(seriesService)
 
 	return &Router{
 		config:         cfg,
+		db:             db,
 		authHandler:    authHandler,
 		postHandler:    postHandler,
 		tagHandler:     tagHandler,
 		commentHandler: commentHandler,
 		adminHandler:   adminHandler,
+		searchHandler:  searchHandler,
+		feedHandler:    feedHandler,
+		statsHandler:   statsHandler,
+		seriesHandler:  seriesHandler,
+
+		newsletterHandler: newsletterHandler,
+		newsletterService: newsletterService,
+		userService:       userService,
+		tokenService:      tokenService,
+		webhooks:          webhooks,
+
+		availabilityLimiter: middleware.NewRateLimiter(20, time.Minute),
+		guestCommentLimiter: middleware.NewRateLimiter(5, 10*time.Minute),
+		idempotency:         middleware.NewIdempotency(middleware.NewMemoryIdempotencyStore(), 24*time.Hour),
+		responseCache:       middleware.NewResponseCache(middleware.NewMemoryResponseCacheStore(), cfg.Cache.ResponseTTL),
+	}
+}
+
+// StartJobs starts the background jobs enabled in the configuration. They
+// stop when ctx is cancelled.
+func (r *Router) StartJobs(ctx context.Context) {
+	if r.config.Newsletter.Enabled {
+		go service.RunNewsletterScheduler(ctx, r.newsletterService, r.config.Newsletter.Interval)
 	}
+	go service.RunUserPurgeScheduler(ctx, r.userService, r.config.Users.PurgeInterval)
+	go service.RunTokenPurgeScheduler(ctx, r.tokenService, r.config.JWT.RevokedPurgeInterval)
+}
+
+// Shutdown waits for the webhook deliveries queued by requests to finish, or
+// for ctx to be done. Call it once the server has stopped taking requests.
+func (r *Router) Shutdown(ctx context.Context) error {
+	return r.webhooks.Close(ctx)
 }
 
 func (r *Router) SetupRoutes() *gin.Engine {
@@ -60,8 +145,15 @@ func (r *Router) SetupRoutes() *gin.Engine {
 	// Create router
 	router := gin.New()
 
+	// Only trust forwarding headers from the configured proxies, so the
+	// client IP used for rate limiting and logs can't be spoofed. The
+	// entries were checked by Config.Validate.
+	if err := router.SetTrustedProxies(r.config.App.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Add middlewares
-	router.Use(middleware.CORS())
+	router.Use(middleware.CORS(r.config.CORS))
 	router.Use(middleware.RequestLoggerMiddleware())
 	router.Use(middleware.ErrorHandlerMiddleware())
 	router.Use(gin.Recovery())
@@ -74,135 +166,283 @@ func (r *Router) SetupRoutes() *gin.Engine {
 		})
 	})
 
-	// API routes
-	api := router.Group("/api")
+	// Readiness check: the server is only ready to take traffic while the
+	// database is reachable
+	router.GET("/health/ready", func(c *gin.Context) {
+		if err := r.pingDB(c.Request.Context()); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"success": false,
+				"error":   "Database is unavailable",
+				"version": version.Get(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Server is ready",
+			"version": version.Get(),
+		})
+	})
+
+	// Build information, for finding out what's deployed
+	router.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, version.Get())
+	})
+
+	// API documentation. The spec is always available for client
+	// generators; the interactive UI can be turned off.
+	router.GET("/api/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", docs.OpenAPI)
+	})
+	if r.config.App.DocsUIEnabled {
+		router.GET("/api/docs", func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", docs.SwaggerUI)
+		})
+	}
+
+	// Versioned API routes
+	r.registerAPIRoutes(router.Group("/api/v1"))
+
+	// Deprecated: the unversioned /api prefix is kept as an alias of /api/v1
+	// for one release so existing clients keep working. Responses carry a
+	// Deprecation header pointing clients at the versioned prefix.
+	legacy := router.Group("/api")
+	legacy.Use(middleware.DeprecationMiddleware("/api/v1"))
+	r.registerAPIRoutes(legacy)
+
+	return router
+}
+
+// pingDB checks that the database connection is alive
+func (r *Router) pingDB(ctx context.Context) error {
+	if r.db == nil {
+		return errors.New("database is not connected")
+	}
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// registerAPIRoutes registers every API route on the given group. Each API
+// version gets its own group, so adding /api/v2 later only needs a new call
+// (or a new registration function for the routes that change).
+func (r *Router) registerAPIRoutes(api *gin.RouterGroup) {
+	// Public routes (no authentication required)
+	public := api.Group("")
+	public.Use(middleware.PaginationMiddleware(r.config.Pagination))
+	public.Use(middleware.ExcerptLengthMiddleware(r.config.Content.MaxExcerptLength))
+	public.Use(middleware.AuthorOnlyFieldsMiddleware(r.config.Content.AuthorOnlyFields))
+	{
+		// Authentication routes
+		auth := public.Group("/auth")
+		{
+			auth.POST("/register", r.authHandler.Register)
+			auth.POST("/login", r.authHandler.Login)
+			auth.POST("/refresh", r.authHandler.RefreshToken)
+			auth.POST("/logout", middleware.OptionalAuthMiddleware(r.config, r.tokenService), r.authHandler.Logout)
+			auth.GET("/check-username", r.availabilityLimiter.Middleware(), r.authHandler.CheckUsername)
+			auth.GET("/check-email", r.availabilityLimiter.Middleware(), r.authHandler.CheckEmail)
+			auth.GET("/confirm-email-change", r.authHandler.ConfirmEmailChange)
+		}
+
+		// Public post routes
+		posts := public.Group("/posts")
+		posts.Use(middleware.OptionalAuthMiddleware(r.config, r.tokenService))
+		{
+			posts.GET("", r.postHandler.GetPosts)
+			posts.GET("/published", r.responseCache.Middleware(nil), r.postHandler.GetPublishedPosts)
+			posts.GET("/featured", r.postHandler.GetFeaturedPosts)
+			posts.GET("/archive", r.postHandler.GetArchive)
+			posts.GET("/search", r.postHandler.SearchPosts)
+			posts.GET("/:id", r.responseCache.Middleware(r.postHandler.CountCachedView), r.postHandler.GetPost)
+			posts.GET("/:id/tags", r.postHandler.GetPostTags)
+			posts.GET("/:id/commenters", r.postHandler.GetPostCommenters)
+			posts.GET("/slug/:slug", r.responseCache.Middleware(r.postHandler.CountCachedView), r.postHandler.GetPostBySlug)
+		}
+
+		// Public tag routes
+		tags := public.Group("/tags")
+		tags.Use(middleware.OptionalAuthMiddleware(r.config, r.tokenService))
+		{
+			tags.GET("", r.tagHandler.GetTags)
+			tags.GET("/all", r.tagHandler.GetAllTags)
+			tags.GET("/popular", r.tagHandler.GetPopularTags)
+			tags.GET("/:id", r.tagHandler.GetTag)
+			tags.GET("/slug/:slug", r.tagHandler.GetTagBySlug)
+			tags.GET("/:id/posts", r.tagHandler.GetPostsByTag)
+		}
+
+		// Public series routes
+		series := public.Group("/series")
+		series.Use(middleware.OptionalAuthMiddleware(r.config, r.tokenService))
+		{
+			series.GET("", r.seriesHandler.GetSeries)
+			series.GET("/:slug", r.seriesHandler.GetSeriesBySlug)
+		}
+
+		// Global search across posts, tags and users
+		public.GET("/search", r.searchHandler.Search)
+
+		// Site-wide totals for the home page
+		public.GET("/stats", r.statsHandler.GetSiteStats)
+
+		// Public comment routes (separate from posts to avoid conflicts)
+
+		comments := public.Group("/comments")
+		comments.Use(middleware.OptionalAuthMiddleware(r.config, r.tokenService))
+		invalidateComments := r.responseCache.Invalidate(handlers.CommentCacheTags)
+		{
+			comments.GET("/post/:post_id", r.commentHandler.GetCommentsByPost)
+			comments.POST("", r.idempotency.Middleware(), r.guestCommentLimiter.GuestMiddleware(), invalidateComments, r.commentHandler.CreateComment)
+		}
+	}
+
+	// Feeds of published posts. They take no pagination, and the RSS feeds
+	// report errors as XML.
+	feeds := api.Group("/feed")
+	{
+		rss := feeds.Group("/rss", respond.XMLRoute())
+		rss.GET("", r.feedHandler.GetRSSFeed)
+		rss.GET("/tag/:slug", r.feedHandler.GetTagRSSFeed)
+		feeds.GET("/json", r.feedHandler.GetJSONFeed)
+	}
+
+	// Changing the password is the one route open to users who must change
+	// it before doing anything else
+	api.POST("/auth/change-password", middleware.AuthMiddleware(r.config, r.tokenService), r.authHandler.ChangePassword)
+
+	// Protected routes (authentication required)
+	protected := api.Group("")
+	protected.Use(middleware.AuthMiddleware(r.config, r.tokenService))
+	protected.Use(middleware.PasswordChangeMiddleware())
+	protected.Use(middleware.PaginationMiddleware(r.config.Pagination))
+	protected.Use(middleware.ExcerptLengthMiddleware(r.config.Content.MaxExcerptLength))
+	protected.Use(middleware.AuthorOnlyFieldsMiddleware(r.config.Content.AuthorOnlyFields))
 	{
-		// Public routes (no authentication required)
-		public := api.Group("")
-		public.Use(middleware.PaginationMiddleware())
+		// Protected auth routes
+		auth := protected.Group("/auth")
 		{
-			// Authentication routes
-			auth := public.Group("/auth")
-			{
-				auth.POST("/register", r.authHandler.Register)
-				auth.POST("/login", r.authHandler.Login)
-				auth.POST("/refresh", r.authHandler.RefreshToken)
-			}
-
-			// Public post routes
-			posts := public.Group("/posts")
-			posts.Use(middleware.OptionalAuthMiddleware(r.config))
-			{
-				posts.GET("", r.postHandler.GetPosts)
-				posts.GET("/published", r.postHandler.GetPublishedPosts)
-				posts.GET("/search", r.postHandler.SearchPosts)
-				posts.GET("/:id", r.postHandler.GetPost)
-				posts.GET("/slug/:slug", r.postHandler.GetPostBySlug)
-			}
-
-			// Public tag routes
-			tags := public.Group("/tags")
-			tags.Use(middleware.OptionalAuthMiddleware(r.config))
-			{
-				tags.GET("", r.tagHandler.GetTags)
-				tags.GET("/all", r.tagHandler.GetAllTags)
-				tags.GET("/popular", r.tagHandler.GetPopularTags)
-				tags.GET("/:id", r.tagHandler.GetTag)
-				tags.GET("/slug/:slug", r.tagHandler.GetTagBySlug)
-				tags.GET("/:id/posts", r.tagHandler.GetPostsByTag)
-			}
-
-			// Public comment routes (separate from posts to avoid conflicts)
-
-			comments := public.Group("/comments")
-			comments.Use(middleware.OptionalAuthMiddleware(r.config))
-			{
-				comments.GET("/post/:post_id", r.commentHandler.GetCommentsByPost)
-			}
+			auth.GET("/profile", r.authHandler.GetProfile)
+			auth.PUT("/profile", r.responseCache.Invalidate(handlers.UserCacheTags), r.authHandler.UpdateProfile)
+			auth.POST("/change-email", r.authHandler.ChangeEmail)
+			auth.GET("/stats", r.postHandler.GetMyStats)
+			auth.GET("/posts", r.postHandler.GetMyPosts)
+			auth.POST("/newsletter/subscribe", r.newsletterHandler.Subscribe)
+			auth.POST("/newsletter/unsubscribe", r.newsletterHandler.Unsubscribe)
 		}
 
-		// Protected routes (authentication required)
-		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware(r.config))
-		protected.Use(middleware.PaginationMiddleware())
+		// Protected post routes. Writes drop the cached responses of the post
+		// and the published listings.
+		posts := protected.Group("/posts")
+		invalidate := r.responseCache.Invalidate(handlers.PostCacheTags)
 		{
-			// Protected auth routes
-			auth := protected.Group("/auth")
-			{
-				auth.GET("/profile", r.authHandler.GetProfile)
-				auth.PUT("/profile", r.authHandler.UpdateProfile)
-				auth.POST("/change-password", r.authHandler.ChangePassword)
-			}
-
-			// Protected post routes
-			posts := protected.Group("/posts")
-			{
-				posts.POST("", r.postHandler.CreatePost)
-				posts.PUT("/:id", r.postHandler.UpdatePost)
-				posts.DELETE("/:id", r.postHandler.DeletePost)
-				posts.POST("/:id/publish", r.postHandler.PublishPost)
-				posts.POST("/:id/unpublish", r.postHandler.UnpublishPost)
-			}
-
-			// Protected comment routes
-			comments := protected.Group("/comments")
-			{
-				comments.POST("", r.commentHandler.CreateComment)
-				comments.PUT("/:id", r.commentHandler.UpdateComment)
-				comments.DELETE("/:id", r.commentHandler.DeleteComment)
-				comments.GET("/my-comments", r.commentHandler.GetCommentsByAuthor)
-			}
+			posts.POST("", r.idempotency.Middleware(), invalidate, r.postHandler.CreatePost)
+			posts.POST("/import", invalidate, r.postHandler.ImportPost)
+			posts.PUT("/:id", invalidate, r.postHandler.UpdatePost)
+			posts.PATCH("/:id", invalidate, r.postHandler.PatchPost)
+			posts.DELETE("/:id", invalidate, r.postHandler.DeletePost)
+			posts.POST("/:id/publish", invalidate, r.postHandler.PublishPost)
+			posts.POST("/:id/preview-token", r.postHandler.CreatePreviewToken)
+			posts.POST("/:id/co-authors", invalidate, r.postHandler.AddCoAuthor)
+			posts.DELETE("/:id/co-authors/:user_id", invalidate, r.postHandler.RemoveCoAuthor)
+			posts.POST("/:id/unpublish", invalidate, r.postHandler.UnpublishPost)
+			posts.POST("/:id/archive", invalidate, r.postHandler.ArchivePost)
+			posts.GET("/archived", r.postHandler.GetArchivedPosts)
 		}
 
-		// Admin routes (admin access required)
-		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(r.config))
-		admin.Use(middleware.AdminMiddleware())
-		admin.Use(middleware.PaginationMiddleware())
+		// Protected series routes
+		series := protected.Group("/series")
+		invalidateSeries := r.responseCache.Invalidate(handlers.SeriesCacheTags)
 		{
-			// Admin user management
-			adminUsers := admin.Group("/users")
-			{
-				adminUsers.GET("", r.adminHandler.GetUsers)
-				adminUsers.GET("/:id", r.adminHandler.GetUser)
-				adminUsers.POST("/:id/deactivate", r.adminHandler.DeactivateUser)
-				adminUsers.POST("/:id/activate", r.adminHandler.ActivateUser)
-				adminUsers.GET("/stats", r.adminHandler.GetUserStats)
-			}
-
-			// Admin post management
-			adminPosts := admin.Group("/posts")
-			{
-				adminPosts.GET("", r.postHandler.GetPosts)
-				adminPosts.GET("/:id", r.postHandler.GetPost)
-				adminPosts.PUT("/:id", r.postHandler.UpdatePost)
-				adminPosts.DELETE("/:id", r.postHandler.DeletePost)
-				adminPosts.POST("/:id/publish", r.postHandler.PublishPost)
-				adminPosts.POST("/:id/unpublish", r.postHandler.UnpublishPost)
-			}
-
-			// Admin comment management
-			adminComments := admin.Group("/comments")
-			{
-				adminComments.GET("/pending", r.commentHandler.GetPendingComments)
-				adminComments.POST("/:id/approve", r.commentHandler.ApproveComment)
-				adminComments.POST("/:id/reject", r.commentHandler.RejectComment)
-				adminComments.GET("/pending/count", r.commentHandler.GetPendingCount)
-			}
-
-			// Admin tag management
-			adminTags := admin.Group("/tags")
-			{
-				adminTags.POST("", r.tagHandler.CreateTag)
-				adminTags.PUT("/:id", r.tagHandler.UpdateTag)
-				adminTags.DELETE("/:id", r.tagHandler.DeleteTag)
-				adminTags.GET("/stats", r.tagHandler.GetTagStats)
-			}
-
-			// Admin dashboard
-			admin.GET("/dashboard/stats", r.adminHandler.GetDashboardStats)
+			series.POST("", r.seriesHandler.CreateSeries)
+			series.PUT("/:id", invalidateSeries, r.seriesHandler.UpdateSeries)
+			series.DELETE("/:id", invalidateSeries, r.seriesHandler.DeleteSeries)
+			series.PUT("/:id/posts/:post_id", invalidateSeries, r.seriesHandler.AddSeriesPost)
+			series.DELETE("/:id/posts/:post_id", invalidateSeries, r.seriesHandler.RemoveSeriesPost)
+		}
+
+		// Protected comment routes
+		comments := protected.Group("/comments")
+		invalidateComments := r.responseCache.Invalidate(handlers.CommentCacheTags)
+		{
+			comments.PUT("/:id", invalidateComments, r.commentHandler.UpdateComment)
+			comments.DELETE("/:id", invalidateComments, r.commentHandler.DeleteComment)
+			comments.POST("/:id/report", invalidateComments, r.commentHandler.ReportComment)
+			comments.GET("/my-comments", r.commentHandler.GetCommentsByAuthor)
 		}
 	}
 
-	return router
+	// Admin routes (admin access required)
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(r.config, r.tokenService))
+	admin.Use(middleware.PasswordChangeMiddleware())
+	admin.Use(middleware.AdminMiddleware())
+	admin.Use(middleware.PaginationMiddleware(r.config.Pagination))
+	admin.Use(middleware.ExcerptLengthMiddleware(r.config.Content.MaxExcerptLength))
+	admin.Use(middleware.AuthorOnlyFieldsMiddleware(r.config.Content.AuthorOnlyFields))
+	{
+		// Admin user management
+		adminUsers := admin.Group("/users")
+		invalidateUsers := r.responseCache.Invalidate(handlers.UserCacheTags)
+		{
+			adminUsers.GET("", r.adminHandler.GetUsers)
+			adminUsers.GET("/:id", r.adminHandler.GetUser)
+			adminUsers.POST("/:id/deactivate", invalidateUsers, r.adminHandler.DeactivateUser)
+			adminUsers.POST("/:id/activate", invalidateUsers, r.adminHandler.ActivateUser)
+			adminUsers.POST("/:id/restore", invalidateUsers, r.adminHandler.RestoreUser)
+			adminUsers.DELETE("/:id", invalidateUsers, r.adminHandler.DeleteUser)
+			adminUsers.GET("/stats", r.adminHandler.GetUserStats)
+			adminUsers.GET("/:id/comments", r.commentHandler.GetUserComments)
+		}
+
+		// Admin post management
+		adminPosts := admin.Group("/posts")
+		invalidate := r.responseCache.Invalidate(handlers.PostCacheTags)
+		{
+			adminPosts.GET("", r.postHandler.GetPosts)
+			adminPosts.GET("/:id", r.postHandler.GetPost)
+			adminPosts.PUT("/:id", invalidate, r.postHandler.UpdatePost)
+			adminPosts.PATCH("/:id", invalidate, r.postHandler.PatchPost)
+			adminPosts.DELETE("/:id", invalidate, r.postHandler.DeletePost)
+			adminPosts.POST("/:id/publish", invalidate, r.postHandler.PublishPost)
+			adminPosts.POST("/:id/unpublish", invalidate, r.postHandler.UnpublishPost)
+			adminPosts.POST("/:id/feature", invalidate, r.postHandler.FeaturePost)
+			adminPosts.POST("/:id/unfeature", invalidate, r.postHandler.UnfeaturePost)
+			adminPosts.GET("/:id/moderation-log", r.postHandler.GetPostModerationLog)
+			adminPosts.POST("/:id/comments/approve-all", invalidate, r.commentHandler.ApproveAllForPost)
+		}
+
+		// Admin comment management
+		adminComments := admin.Group("/comments")
+		invalidateComments := r.responseCache.Invalidate(handlers.CommentCacheTags)
+		{
+			adminComments.GET("/pending", r.commentHandler.GetPendingComments)
+			adminComments.GET("/reported", r.commentHandler.GetReportedComments)
+			adminComments.GET("/:id", r.commentHandler.GetComment)
+			adminComments.GET("/:id/replies", r.commentHandler.GetCommentReplies)
+			adminComments.POST("/:id/approve", invalidateComments, r.commentHandler.ApproveComment)
+			adminComments.POST("/:id/reject", invalidateComments, r.commentHandler.RejectComment)
+			adminComments.GET("/pending/count", r.commentHandler.GetPendingCount)
+		}
+
+		// Admin tag management
+		adminTags := admin.Group("/tags")
+		invalidateTags := r.responseCache.Invalidate(handlers.TagCacheTags)
+		{
+			adminTags.POST("", r.tagHandler.CreateTag)
+			adminTags.PUT("/:id", invalidateTags, r.tagHandler.UpdateTag)
+			adminTags.DELETE("/:id", invalidateTags, r.tagHandler.DeleteTag)
+			adminTags.POST("/:id/attach", invalidateTags, r.tagHandler.AttachTagToPosts)
+			adminTags.POST("/:id/detach", invalidateTags, r.tagHandler.DetachTagFromPosts)
+			adminTags.GET("/stats", r.tagHandler.GetTagStats)
+		}
+
+		// Admin newsletter
+		admin.POST("/newsletter/send-now", r.newsletterHandler.SendNow)
+
+		// Admin dashboard
+		admin.GET("/dashboard/stats", r.adminHandler.GetDashboardStats)
+	}
 }
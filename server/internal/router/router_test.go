@@ -0,0 +1,123 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/docs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(docsUI bool) *config.Config {
+	return &config.Config{
+		GinMode: "test",
+		JWT:     config.JWTConfig{Algorithm: config.JWTAlgorithmHS256, Secret: "test-secret"},
+
+		Pagination: config.PaginationConfig{DefaultPerPage: 10, MaxPerPage: 100},
+		App:        config.AppConfig{DocsUIEnabled: docsUI},
+	}
+}
+
+func TestSetupRoutes_ServesDocumentedRoutes(t *testing.T) {
+	engine := NewRouter(testConfig(true)).SetupRoutes()
+
+	served := map[string]bool{}
+	for _, route := range engine.Routes() {
+		served[route.Method+" "+route.Path] = true
+	}
+
+	var spec struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(docs.OpenAPI, &spec))
+	require.Len(t, spec.Servers, 1)
+
+	// OpenAPI's {param} is gin's :param
+	param := regexp.MustCompile(`\{(\w+)\}`)
+	for path, methods := range spec.Paths {
+		ginPath := spec.Servers[0].URL + param.ReplaceAllString(path, ":$1")
+		for method := range methods {
+			key := strings.ToUpper(method) + " " + ginPath
+			assert.True(t, served[key], "documented route %s is not served", key)
+		}
+	}
+}
+
+func TestSetupRoutes_Docs(t *testing.T) {
+	engine := NewRouter(testConfig(true)).SetupRoutes()
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.True(t, json.Valid(w.Body.Bytes()))
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/api/openapi.json")
+
+	// The UI can be disabled, but the spec stays available
+	engine = NewRouter(testConfig(false)).SetupRoutes()
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestSetupRoutes_Version(t *testing.T) {
+	engine := NewRouter(testConfig(false)).SetupRoutes()
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"version":"dev","commit":"dev","build_time":"dev"}`, w.Body.String())
+
+	// Readiness reports the version even when the database is down
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"version":{"version":"dev"`)
+}
+
+func TestSetupRoutes_TrustedProxies(t *testing.T) {
+	cfg := testConfig(false)
+	cfg.App.TrustedProxies = []string{"10.0.0.0/8"}
+	engine := NewRouter(cfg).SetupRoutes()
+	engine.GET("/client-ip", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+
+	clientIP := func(remoteAddr string) string {
+		req := httptest.NewRequest(http.MethodGet, "/client-ip", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "203.0.113.7", clientIP("10.1.2.3:4321"), "forwarded header from a trusted proxy")
+	assert.Equal(t, "198.51.100.9", clientIP("198.51.100.9:4321"), "forwarded header from an untrusted address")
+
+	// Without trusted proxies the header is always ignored
+	engine = NewRouter(testConfig(false)).SetupRoutes()
+	engine.GET("/client-ip", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+	assert.Equal(t, "10.1.2.3", clientIP("10.1.2.3:4321"))
+}
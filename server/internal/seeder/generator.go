@@ -0,0 +1,304 @@
+package seeder
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// generatedBatchSize is the number of rows inserted per statement when
+// generating large datasets
+const generatedBatchSize = 500
+
+// generatedPassword is the password of every generated user
+const generatedPassword = "password123"
+
+// Options controls how much data RunSeeder creates. When both Users and
+// Posts are zero the curated sample dataset is seeded instead.
+type Options struct {
+	Users           int   `json:"users"`
+	Posts           int   `json:"posts"`
+	CommentsPerPost int   `json:"comments_per_post"`
+	Seed            int64 `json:"seed"`
+}
+
+// Generated reports whether the options ask for a generated dataset
+func (o Options) Generated() bool {
+	return o.Users > 0 || o.Posts > 0
+}
+
+// LoadOptions reads seeding options from a JSON file such as
+// {"users": 100, "posts": 5000, "comments_per_post": 5, "seed": 42}
+func LoadOptions(path string) (Options, error) {
+	var opts Options
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return opts, err
+	}
+
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return opts, fmt.Errorf("invalid seed file %s: %w", path, err)
+	}
+
+	return opts, nil
+}
+
+var (
+	generatedFirstNames = []string{"Aung", "Bella", "Chris", "Dana", "Elena", "Farid", "Grace", "Hiro", "Isla", "Jonas", "Kyaw", "Lena", "Mateo", "Nina", "Omar", "Priya"}
+	generatedLastNames  = []string{"Anderson", "Brown", "Chen", "Diaz", "Evans", "Garcia", "Htun", "Kim", "Lopez", "Martin", "Nguyen", "Patel", "Rossi", "Silva", "Tanaka", "Walker"}
+	generatedTopics     = []string{"Go", "Databases", "Testing", "Kubernetes", "Frontend", "APIs", "Security", "Performance", "Caching", "Observability", "Pagination", "Search"}
+	generatedPhrases    = []string{"A Practical Guide to", "Lessons Learned from", "Getting Started with", "Deep Dive into", "Common Mistakes in", "Scaling", "Rethinking", "Notes on"}
+	generatedWords      = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua server client query index cache latency request response handler middleware deploy release version schema migration")
+)
+
+// generator builds deterministic fake data from a seeded RNG, so the same
+// options always produce the same dataset
+type generator struct {
+	rng  *rand.Rand
+	seed int64
+	now  time.Time
+}
+
+func newGenerator(seed int64, now time.Time) *generator {
+	return &generator{
+		rng:  rand.New(rand.NewSource(seed)),
+		seed: seed,
+		now:  now,
+	}
+}
+
+// user returns the i-th generated user. passwordHash is set directly since
+// hooks are skipped when inserting in bulk.
+func (g *generator) user(i int, passwordHash string) models.User {
+	username := fmt.Sprintf("seed%duser%d", g.seed, i)
+	return models.User{
+		FirstName: generatedFirstNames[g.rng.Intn(len(generatedFirstNames))],
+		LastName:  generatedLastNames[g.rng.Intn(len(generatedLastNames))],
+		Email:     username + "@example.com",
+		Username:  username,
+		Password:  passwordHash,
+		Bio:       g.sentence(12),
+		IsActive:  true,
+	}
+}
+
+// post returns the i-th generated post, authored by one of authors and
+// tagged with up to three of tags
+func (g *generator) post(i int, authors []models.User, tags []models.Tag) models.Post {
+	title := fmt.Sprintf("%s %s #%d",
+		generatedPhrases[g.rng.Intn(len(generatedPhrases))],
+		generatedTopics[g.rng.Intn(len(generatedTopics))],
+		i,
+	)
+
+	paragraphs := make([]string, 3+g.rng.Intn(5))
+	for p := range paragraphs {
+		paragraphs[p] = g.sentence(40 + g.rng.Intn(80))
+	}
+	content := strings.Join(paragraphs, "\n\n")
+
+	post := models.Post{
+		Title:    title,
+		Slug:     fmt.Sprintf("%s-s%d", utils.GenerateSlug(title), g.seed),
+		Content:  content,
+		Excerpt:  utils.ExtractExcerpt(content, 200),
+		Status:   models.PostStatusDraft,
+		AuthorID: authors[g.rng.Intn(len(authors))].ID,
+	}
+
+	// Most posts are published, spread over the past two years
+	if g.rng.Intn(10) < 8 {
+		publishedAt := g.now.Add(-time.Duration(g.rng.Int63n(int64(2 * 365 * 24 * time.Hour))))
+		post.Status = models.PostStatusPublished
+		post.PublishedAt = &publishedAt
+		post.ViewCount = g.rng.Intn(5000)
+	}
+
+	if len(tags) > 0 {
+		for _, idx := range g.rng.Perm(len(tags))[:1+g.rng.Intn(min(3, len(tags)))] {
+			post.Tags = append(post.Tags, tags[idx])
+		}
+	}
+
+	return post
+}
+
+// comments returns up to max approved comments on a post
+func (g *generator) comments(postID uint, max int, authors []models.User) []models.Comment {
+	if max <= 0 {
+		return nil
+	}
+
+	comments := make([]models.Comment, g.rng.Intn(max+1))
+	for i := range comments {
+		comments[i] = models.Comment{
+			Content:  g.sentence(5 + g.rng.Intn(30)),
+			Status:   models.CommentStatusApproved,
+			PostID:   postID,
+			AuthorID: &authors[g.rng.Intn(len(authors))].ID,
+		}
+	}
+	return comments
+}
+
+// sentence returns n random words with a leading capital and a full stop
+func (g *generator) sentence(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = generatedWords[g.rng.Intn(len(generatedWords))]
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ") + "."
+}
+
+// seedGenerated creates a generated dataset of the requested size
+func (s *Seeder) seedGenerated(opts Options) error {
+	g := newGenerator(opts.Seed, time.Now())
+
+	authors, err := s.seedGeneratedUsers(g, opts.Users)
+	if err != nil {
+		return fmt.Errorf("failed to seed users: %w", err)
+	}
+
+	if opts.Posts == 0 {
+		return nil
+	}
+
+	// Fall back to existing authors when only posts were requested
+	if len(authors) == 0 {
+		s.db.Where("is_admin = ?", false).Find(&authors)
+	}
+	if len(authors) == 0 {
+		return fmt.Errorf("no users available to author generated posts")
+	}
+
+	var tags []models.Tag
+	s.db.Find(&tags)
+
+	if err := s.seedGeneratedPosts(g, opts.Posts, opts.CommentsPerPost, authors, tags); err != nil {
+		return fmt.Errorf("failed to seed posts: %w", err)
+	}
+
+	return nil
+}
+
+// seedGeneratedUsers creates count users, skipping any that already exist
+// from an earlier run with the same seed
+func (s *Seeder) seedGeneratedUsers(g *generator, count int) ([]models.User, error) {
+	if count == 0 {
+		return nil, nil
+	}
+
+	log.Printf("👥 Generating %d users...", count)
+
+	// Hash once; bcrypt per user would dominate the run time
+	hash, err := bcrypt.GenerateFromPassword([]byte(generatedPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]models.User, count)
+	usernames := make([]string, count)
+	for i := range users {
+		users[i] = g.user(i+1, string(hash))
+		usernames[i] = users[i].Username
+	}
+
+	var existing []string
+	if err := s.db.Unscoped().Model(&models.User{}).Where("username IN ?", usernames).Pluck("username", &existing).Error; err != nil {
+		return nil, err
+	}
+	skip := make(map[string]bool, len(existing))
+	for _, username := range existing {
+		skip[username] = true
+	}
+
+	var missing []models.User
+	for _, user := range users {
+		if !skip[user.Username] {
+			missing = append(missing, user)
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := s.db.Session(&gorm.Session{SkipHooks: true}).CreateInBatches(&missing, generatedBatchSize).Error; err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("✅ Created %d users (%d already existed)", len(missing), len(existing))
+
+	var authors []models.User
+	if err := s.db.Where("username IN ?", usernames).Order("id").Find(&authors).Error; err != nil {
+		return nil, err
+	}
+	return authors, nil
+}
+
+// seedGeneratedPosts creates count posts with tags and comments in batches,
+// skipping any that already exist from an earlier run with the same seed
+func (s *Seeder) seedGeneratedPosts(g *generator, count, commentsPerPost int, authors []models.User, tags []models.Tag) error {
+	log.Printf("📝 Generating %d posts...", count)
+
+	created, skipped := 0, 0
+	for start := 0; start < count; start += generatedBatchSize {
+		end := min(start+generatedBatchSize, count)
+
+		posts := make([]models.Post, 0, end-start)
+		slugs := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			post := g.post(i+1, authors, tags)
+			posts = append(posts, post)
+			slugs = append(slugs, post.Slug)
+		}
+
+		var existing []string
+		if err := s.db.Model(&models.Post{}).Where("slug IN ?", slugs).Pluck("slug", &existing).Error; err != nil {
+			return err
+		}
+		skip := make(map[string]bool, len(existing))
+		for _, slug := range existing {
+			skip[slug] = true
+		}
+
+		batch := make([]models.Post, 0, len(posts))
+		for _, post := range posts {
+			if !skip[post.Slug] {
+				batch = append(batch, post)
+			}
+		}
+		skipped += len(posts) - len(batch)
+		if len(batch) == 0 {
+			continue
+		}
+
+		if err := s.db.Create(&batch).Error; err != nil {
+			return err
+		}
+		created += len(batch)
+
+		var comments []models.Comment
+		for _, post := range batch {
+			if post.Status == models.PostStatusPublished {
+				comments = append(comments, g.comments(post.ID, commentsPerPost, authors)...)
+			}
+		}
+		if len(comments) > 0 {
+			if err := s.db.CreateInBatches(&comments, generatedBatchSize).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Printf("✅ Created %d posts (%d already existed)", created, skipped)
+	return nil
+}
@@ -0,0 +1,58 @@
+package seeder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Deterministic(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	authors := []models.User{{ID: 1}, {ID: 2}, {ID: 3}}
+	tags := []models.Tag{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+
+	generate := func(seed int64) []models.Post {
+		g := newGenerator(seed, now)
+		posts := make([]models.Post, 20)
+		for i := range posts {
+			posts[i] = g.post(i+1, authors, tags)
+		}
+		return posts
+	}
+
+	first, second := generate(42), generate(42)
+	assert.Equal(t, first, second)
+
+	other := generate(7)
+	assert.NotEqual(t, first[0].Title+first[0].Content, other[0].Title+other[0].Content)
+}
+
+func TestSeeder_RunSeeder_Generated(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	s := &Seeder{db: db}
+
+	opts := Options{Users: 12, Posts: 40, CommentsPerPost: 2, Seed: 3}
+	require.NoError(t, s.RunSeeder(true, opts))
+
+	var users, posts int64
+	db.Model(&models.User{}).Count(&users)
+	db.Model(&models.Post{}).Count(&posts)
+	assert.Equal(t, int64(12), users)
+	assert.Equal(t, int64(40), posts)
+
+	// Generated users can log in with the shared password
+	var user models.User
+	require.NoError(t, db.Where("username = ?", "seed3user1").First(&user).Error)
+	assert.True(t, user.CheckPassword(generatedPassword))
+
+	// Re-running with the same seed doesn't duplicate anything
+	require.NoError(t, s.RunSeeder(true, opts))
+	db.Model(&models.User{}).Count(&users)
+	db.Model(&models.Post{}).Count(&posts)
+	assert.Equal(t, int64(12), users)
+	assert.Equal(t, int64(40), posts)
+}
@@ -21,8 +21,10 @@ func NewSeeder() *Seeder {
 	}
 }
 
-// RunSeeder runs all seeders
-func (s *Seeder) RunSeeder(force bool) error {
+// RunSeeder runs all seeders. With zero counts in opts the curated sample
+// dataset is seeded; otherwise a generated dataset of the requested size is
+// created on top of the curated tags.
+func (s *Seeder) RunSeeder(force bool, opts Options) error {
 	log.Println("🌱 Starting database seeding...")
 
 	// Check if data already exists
@@ -38,6 +40,19 @@ func (s *Seeder) RunSeeder(force bool) error {
 		}
 	}
 
+	if opts.Generated() {
+		if err := s.seedTags(); err != nil {
+			return fmt.Errorf("failed to seed tags: %w", err)
+		}
+
+		if err := s.seedGenerated(opts); err != nil {
+			return err
+		}
+
+		log.Println("✅ Database seeding completed successfully!")
+		return nil
+	}
+
 	// Seed in order due to dependencies
 	if err := s.seedUsers(); err != nil {
 		return fmt.Errorf("failed to seed users: %w", err)
@@ -140,16 +155,16 @@ func (s *Seeder) seedTags() error {
 	log.Println("🏷️  Seeding additional tags...")
 
 	additionalTags := []models.Tag{
-		{Name: "JavaScript", Slug: "javascript", Description: "JavaScript programming and frameworks", Color: "#F7DF1E"},
-		{Name: "Go", Slug: "go", Description: "Go programming language", Color: "#00ADD8"},
-		{Name: "Python", Slug: "python", Description: "Python programming and data science", Color: "#3776AB"},
-		{Name: "Web Development", Slug: "web-development", Description: "Web development tips and tutorials", Color: "#61DAFB"},
-		{Name: "DevOps", Slug: "devops", Description: "DevOps practices and tools", Color: "#FF6B35"},
-		{Name: "Machine Learning", Slug: "machine-learning", Description: "ML and AI related content", Color: "#FF6F00"},
-		{Name: "Mobile", Slug: "mobile", Description: "Mobile app development", Color: "#A4C639"},
+		{Name: "JavaScript", Slug: "javascript", Description: "JavaScript programming and frameworks", Color: "#f7df1e"},
+		{Name: "Go", Slug: "go", Description: "Go programming language", Color: "#00add8"},
+		{Name: "Python", Slug: "python", Description: "Python programming and data science", Color: "#3776ab"},
+		{Name: "Web Development", Slug: "web-development", Description: "Web development tips and tutorials", Color: "#61dafb"},
+		{Name: "DevOps", Slug: "devops", Description: "DevOps practices and tools", Color: "#ff6b35"},
+		{Name: "Machine Learning", Slug: "machine-learning", Description: "ML and AI related content", Color: "#ff6f00"},
+		{Name: "Mobile", Slug: "mobile", Description: "Mobile app development", Color: "#a4c639"},
 		{Name: "Database", Slug: "database", Description: "Database design and optimization", Color: "#336791"},
-		{Name: "Security", Slug: "security", Description: "Cybersecurity and best practices", Color: "#DC143C"},
-		{Name: "Open Source", Slug: "open-source", Description: "Open source projects and contributions", Color: "#28A745"},
+		{Name: "Security", Slug: "security", Description: "Cybersecurity and best practices", Color: "#dc143c"},
+		{Name: "Open Source", Slug: "open-source", Description: "Open source projects and contributions", Color: "#28a745"},
 	}
 
 	for _, tag := range additionalTags {
@@ -172,7 +187,7 @@ func (s *Seeder) seedPosts() error {
 
 	// Get users and tags for associations
 	var users []models.User
-	s.db.Where("email != ?", "admin@blog.com").Find(&users)
+	s.db.Where("is_admin = ?", false).Find(&users)
 
 	var tags []models.Tag
 	s.db.Find(&tags)
@@ -442,7 +457,7 @@ func (s *Seeder) seedComments() error {
 	s.db.Where("status = ?", models.PostStatusPublished).Find(&posts)
 
 	var users []models.User
-	s.db.Where("email != ?", "admin@blog.com").Find(&users)
+	s.db.Where("is_admin = ?", false).Find(&users)
 
 	if len(posts) == 0 || len(users) == 0 {
 		return fmt.Errorf("posts or users not found for seeding comments")
@@ -522,7 +537,7 @@ func (s *Seeder) seedComments() error {
 				Content:  commentData.Content,
 				Status:   commentData.Status,
 				PostID:   posts[commentData.PostIndex].ID,
-				AuthorID: users[commentData.AuthorIndex].ID,
+				AuthorID: &users[commentData.AuthorIndex].ID,
 			}
 
 			if err := s.db.Create(&comment).Error; err != nil {
@@ -560,7 +575,7 @@ func (s *Seeder) seedComments() error {
 				Content:  replyData.Content,
 				Status:   models.CommentStatusApproved,
 				PostID:   parentComments[replyData.ParentIndex].PostID,
-				AuthorID: users[replyData.AuthorIndex].ID,
+				AuthorID: &users[replyData.AuthorIndex].ID,
 				ParentID: &parentComments[replyData.ParentIndex].ID,
 			}
 
@@ -575,31 +590,89 @@ func (s *Seeder) seedComments() error {
 	return nil
 }
 
-// CleanDatabase removes all seeded data (useful for testing)
+// CleanDatabase removes all seeded data (useful for testing). Only the
+// default admin, their posts and comments, and the default tags remain.
 func (s *Seeder) CleanDatabase() error {
 	log.Println("🧹 Cleaning database...")
 
+	var adminID uint
+	if err := s.db.Model(&models.User{}).Where("is_admin = ?", true).Order("id").Limit(1).Pluck("id", &adminID).Error; err != nil {
+		return err
+	}
+
+	seededPosts := s.db.Model(&models.Post{}).Select("id").Where("author_id <> ?", adminID)
+
+	// Comments by other users, or on other users' posts, plus any replies
+	// to them so no reply is left pointing at a deleted parent. Soft-deleted
+	// comments are included so they don't block deleting their posts.
+	var commentIDs []uint
+	if err := s.db.Unscoped().Model(&models.Comment{}).
+		Where("author_id <> ? OR post_id IN (?)", adminID, seededPosts).
+		Pluck("id", &commentIDs).Error; err != nil {
+		return err
+	}
+	for parents := commentIDs; len(parents) > 0; {
+		var replies []uint
+		if err := s.db.Unscoped().Model(&models.Comment{}).Where("parent_id IN ?", parents).Pluck("id", &replies).Error; err != nil {
+			return err
+		}
+		commentIDs = append(commentIDs, replies...)
+		parents = replies
+	}
+
 	// Delete in reverse order of dependencies
-	if err := s.db.Exec("DELETE FROM post_tags").Error; err != nil {
+	if err := s.db.Where("reporter_id <> ? OR comment_id IN ?", adminID, commentIDs).Delete(&models.CommentReport{}).Error; err != nil {
 		return err
 	}
+	if len(commentIDs) > 0 {
+		if err := s.db.Unscoped().Where("id IN ?", commentIDs).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+	}
 
-	if err := s.db.Where("email != ?", "admin@blog.com").Delete(&models.Comment{}).Error; err != nil {
+	if err := s.db.Exec("DELETE FROM post_tags WHERE post_id IN (?) OR tag_id IN (?)",
+		seededPosts,
+		s.db.Model(&models.Tag{}).Select("id").Where("slug NOT IN ?", defaultTagSlugs),
+	).Error; err != nil {
 		return err
 	}
 
-	if err := s.db.Where("author_id != (SELECT id FROM users WHERE email = 'admin@blog.com' LIMIT 1)").Delete(&models.Post{}).Error; err != nil {
+	if err := s.db.Exec("DELETE FROM post_authors WHERE post_id IN (?) OR user_id <> ?", seededPosts, adminID).Error; err != nil {
 		return err
 	}
 
-	if err := s.db.Where("slug NOT IN ('technology', 'lifestyle', 'tutorial', 'news', 'opinion')").Delete(&models.Tag{}).Error; err != nil {
+	if err := s.db.Where("post_id IN (?)", seededPosts).Delete(&models.PostSlugHistory{}).Error; err != nil {
 		return err
 	}
 
-	if err := s.db.Where("email != ?", "admin@blog.com").Delete(&models.User{}).Error; err != nil {
+	if err := s.db.Where("author_id <> ?", adminID).Delete(&models.Post{}).Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Model(&models.Post{}).Where("series_id IN (?)", s.db.Model(&models.Series{}).Select("id").Where("author_id <> ?", adminID)).
+		UpdateColumns(map[string]interface{}{"series_id": nil, "series_order": 0}).Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Where("author_id <> ?", adminID).Delete(&models.Series{}).Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Where("tag_id IN (?)", s.db.Model(&models.Tag{}).Select("id").Where("slug NOT IN ?", defaultTagSlugs)).Delete(&models.TagSlugHistory{}).Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Where("slug NOT IN ?", defaultTagSlugs).Delete(&models.Tag{}).Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Where("id <> ?", adminID).Delete(&models.User{}).Error; err != nil {
 		return err
 	}
 
 	log.Println("✅ Database cleaned successfully")
 	return nil
 }
+
+// defaultTagSlugs are the tags created by migrations, which cleaning keeps
+var defaultTagSlugs = []string{"technology", "lifestyle", "tutorial", "news", "opinion"}
@@ -0,0 +1,77 @@
+package seeder
+
+import (
+	"testing"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeeder_CleanDatabase(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	s := &Seeder{db: db}
+
+	// Default admin and tags, as created by migrations
+	admin := &models.User{
+		FirstName: "Admin",
+		LastName:  "User",
+		Email:     "admin@blog.com",
+		Username:  "admin",
+		Password:  "admin123456",
+		IsActive:  true,
+		IsAdmin:   true,
+	}
+	require.NoError(t, db.Create(admin).Error)
+	for _, slug := range defaultTagSlugs {
+		require.NoError(t, db.Create(&models.Tag{Name: slug, Slug: slug}).Error)
+	}
+
+	require.NoError(t, s.RunSeeder(true, Options{}))
+
+	// Admin-owned content mixed in with the seeded data
+	var tech models.Tag
+	require.NoError(t, db.Where("slug = ?", "technology").First(&tech).Error)
+	adminPost := &models.Post{Title: "Admin announcement", Slug: "admin-announcement", Content: "Welcome to the blog", Status: models.PostStatusPublished, AuthorID: admin.ID, Tags: []models.Tag{tech}}
+	require.NoError(t, db.Create(adminPost).Error)
+
+	var seededUser models.User
+	require.NoError(t, db.Where("email = ?", "john.doe@example.com").First(&seededUser).Error)
+	var seededPost models.Post
+	require.NoError(t, db.Where("author_id = ?", seededUser.ID).First(&seededPost).Error)
+
+	adminComment := &models.Comment{Content: "Thanks for reading", AuthorID: &admin.ID, PostID: adminPost.ID, Status: models.CommentStatusApproved}
+	require.NoError(t, db.Create(adminComment).Error)
+	require.NoError(t, db.Create(&models.Comment{Content: "Nice post", AuthorID: &seededUser.ID, PostID: adminPost.ID, ParentID: &adminComment.ID}).Error)
+	userComment := &models.Comment{Content: "First!", AuthorID: &seededUser.ID, PostID: adminPost.ID}
+	require.NoError(t, db.Create(userComment).Error)
+	require.NoError(t, db.Create(&models.Comment{Content: "Welcome", AuthorID: &admin.ID, PostID: adminPost.ID, ParentID: &userComment.ID}).Error)
+	require.NoError(t, db.Create(&models.Comment{Content: "Admin on a seeded post", AuthorID: &admin.ID, PostID: seededPost.ID}).Error)
+
+	var comments int64
+	db.Model(&models.Comment{}).Count(&comments)
+	require.Greater(t, comments, int64(5))
+
+	require.NoError(t, s.CleanDatabase())
+
+	var users []models.User
+	db.Find(&users)
+	require.Len(t, users, 1)
+	assert.Equal(t, admin.ID, users[0].ID)
+
+	var posts []models.Post
+	db.Preload("Tags").Find(&posts)
+	require.Len(t, posts, 1)
+	assert.Equal(t, adminPost.ID, posts[0].ID)
+	require.Len(t, posts[0].Tags, 1)
+
+	var remaining []models.Comment
+	db.Find(&remaining)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, adminComment.ID, remaining[0].ID)
+
+	var tags int64
+	db.Model(&models.Tag{}).Count(&tags)
+	assert.Equal(t, int64(len(defaultTagSlugs)), tags)
+}
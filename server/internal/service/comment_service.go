@@ -1,66 +1,126 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
 )
 
+// maxGuestLinks is the most links a guest comment may contain
+const maxGuestLinks = 1
+
+// inlineRepliesLimit is how many replies a comment fetched by ID carries
+const inlineRepliesLimit = 10
+
+// linkPattern matches URLs and bare www. hostnames
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://|\bwww\.`)
+
 type CommentService interface {
 	Create(authorID uint, req *models.CommentCreateRequest) (*models.CommentResponse, error)
-	GetByID(id uint) (*models.CommentResponse, error)
+	CreateGuest(req *models.CommentCreateRequest) (*models.CommentResponse, error)
+	GetByID(ctx context.Context, id uint) (*models.CommentResponse, error)
+	GetReplies(ctx context.Context, id uint, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error)
 	Update(commentID, authorID uint, req *models.CommentUpdateRequest, isAdmin bool) (*models.CommentResponse, error)
-	Delete(commentID, authorID uint, isAdmin bool) error
-	GetByPost(postID uint, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error)
-	GetByAuthor(authorID uint, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error)
-	GetPending(page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error)
+	Delete(commentID, authorID uint, isAdmin bool) (postID uint, err error)
+	GetByPost(ctx context.Context, postID uint, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error)
+	GetByAuthor(ctx context.Context, authorID uint, status models.CommentStatus, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error)
+	GetPending(filter models.PendingCommentFilter, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error)
 	ApproveComment(commentID uint) (*models.CommentResponse, error)
 	RejectComment(commentID uint) (*models.CommentResponse, error)
+	ApproveAllForPost(postID uint) (int64, error)
 	GetPendingCount() (int64, error)
+	ReportComment(commentID, reporterID uint, req *models.CommentReportRequest) (postID uint, err error)
+	GetReported(page, perPage int) ([]models.ReportedCommentResponse, models.PaginationMeta, error)
 }
 
 type commentService struct {
 	commentRepo repository.CommentRepository
 	postRepo    repository.PostRepository
+	webhooks    webhook.Dispatcher
+	config      config.CommentConfig
 }
 
-func NewCommentService(commentRepo repository.CommentRepository, postRepo repository.PostRepository) CommentService {
+func NewCommentService(commentRepo repository.CommentRepository, postRepo repository.PostRepository, webhooks webhook.Dispatcher, cfg config.CommentConfig) CommentService {
 	return &commentService{
 		commentRepo: commentRepo,
 		postRepo:    postRepo,
+		webhooks:    webhooks,
+		config:      cfg,
 	}
 }
 
 func (s *commentService) Create(authorID uint, req *models.CommentCreateRequest) (*models.CommentResponse, error) {
+	return s.create(req, &models.Comment{AuthorID: &authorID})
+}
+
+// CreateGuest creates a comment for a visitor without an account. Guests must
+// leave a name and email and face stricter spam checks.
+func (s *commentService) CreateGuest(req *models.CommentCreateRequest) (*models.CommentResponse, error) {
+	guestName := utils.SanitizeText(req.GuestName)
+	guestEmail := utils.NormalizeEmail(req.GuestEmail)
+	if guestName == "" || guestEmail == "" {
+		return nil, apperrors.Validation("guest_name and guest_email are required to comment without logging in")
+	}
+
+	if countLinks(guestName) > 0 || countLinks(req.Content) > maxGuestLinks {
+		return nil, apperrors.Validation("comment looks like spam: too many links")
+	}
+
+	return s.create(req, &models.Comment{GuestName: guestName, GuestEmail: guestEmail})
+}
+
+// create validates req and stores it as comment, which carries the author
+// or guest details
+func (s *commentService) create(req *models.CommentCreateRequest, comment *models.Comment) (*models.CommentResponse, error) {
 	// Validate request
-	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
-		return nil, fmt.Errorf("validation failed: %v", validationErrors)
+	validationErrors := utils.ValidateStruct(req)
+	validationErrors = append(validationErrors, utils.ValidateMaxLength("Content", req.Content, s.config.MaxLength)...)
+	if len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
 	}
 
 	// Verify that the post exists
 	_, err := s.postRepo.GetByID(req.PostID)
 	if err != nil {
-		return nil, errors.New("post not found")
+		return nil, err
 	}
 
 	// Verify parent comment exists if this is a reply
 	if req.ParentID != nil {
-		_, err := s.commentRepo.GetByID(*req.ParentID)
+		parent, err := s.commentRepo.GetByID(*req.ParentID)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, apperrors.NotFound("parent comment not found")
+		}
 		if err != nil {
-			return nil, errors.New("parent comment not found")
+			return nil, err
 		}
+		if s.config.MaxDepth > 0 && parent.Depth >= s.config.MaxDepth {
+			return nil, apperrors.Validation(fmt.Sprintf("replies can be nested at most %d levels deep", s.config.MaxDepth))
+		}
+		comment.Depth = parent.Depth + 1
 	}
 
-	// Create comment
-	comment := &models.Comment{
-		Content:  utils.SanitizeText(req.Content),
-		AuthorID: authorID,
-		PostID:   req.PostID,
-		ParentID: req.ParentID,
-		Status:   models.CommentStatusPending, // Comments need approval by default
+	comment.Content = utils.SanitizeText(req.Content)
+	comment.PostID = req.PostID
+	if s.config.RejectDuplicates {
+		if err := s.checkDuplicate(comment); err != nil {
+			return nil, err
+		}
+	}
+	comment.ParentID = req.ParentID
+	comment.Status = models.CommentStatusApproved
+	if s.config.RequireApproval {
+		comment.Status = models.CommentStatusPending
 	}
 
 	if err := s.commentRepo.Create(comment); err != nil {
@@ -74,23 +134,103 @@ func (s *commentService) Create(authorID uint, req *models.CommentCreateRequest)
 	}
 
 	response := createdComment.ToResponse()
+	s.webhooks.Dispatch(webhook.EventCommentCreated, response)
+	if response.Status == models.CommentStatusApproved {
+		s.webhooks.Dispatch(webhook.EventCommentApproved, response)
+	}
 	return &response, nil
 }
 
-func (s *commentService) GetByID(id uint) (*models.CommentResponse, error) {
+// checkDuplicate refuses comment when its author, or guest, left the same
+// content on the post within the duplicate window. Content is compared
+// ignoring case and whitespace, so trivially varied copies are caught too.
+func (s *commentService) checkDuplicate(comment *models.Comment) error {
+	since := time.Now().Add(-s.config.DuplicateWindow)
+	recent, err := s.commentRepo.GetRecentContent(comment.PostID, comment.AuthorID, comment.GuestEmail, since)
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicate comments: %w", err)
+	}
+
+	content := normalizeCommentContent(comment.Content)
+	for _, previous := range recent {
+		if normalizeCommentContent(previous) == content {
+			return apperrors.Conflict("duplicate comment: you already posted this on this post")
+		}
+	}
+	return nil
+}
+
+// normalizeCommentContent lower-cases content and collapses its whitespace
+func normalizeCommentContent(content string) string {
+	return strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
+
+// countLinks counts the URLs in text, the main signal of comment spam
+func countLinks(text string) int {
+	return len(linkPattern.FindAllString(text, -1))
+}
+
+// withContext returns a copy of the service whose queries run with ctx, so
+// they are cancelled along with the request they serve
+func (s *commentService) withContext(ctx context.Context) *commentService {
+	scoped := *s
+	scoped.commentRepo = s.commentRepo.WithContext(ctx)
+	scoped.postRepo = s.postRepo.WithContext(ctx)
+	return &scoped
+}
+
+// GetByID returns a comment with the first of its approved replies. The rest
+// are read a page at a time with GetReplies.
+func (s *commentService) GetByID(ctx context.Context, id uint) (*models.CommentResponse, error) {
+	s = s.withContext(ctx)
+
 	comment, err := s.commentRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
+	replies, total, err := s.commentRepo.GetReplies(comment.ID, 0, inlineRepliesLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replies: %w", err)
+	}
+	comment.Replies = replies
+
 	response := comment.ToResponse()
+	response.RepliesCount = int(total)
+	response.HasMore = total > int64(len(replies))
 	return &response, nil
 }
 
+// GetReplies returns a page of the approved direct replies to a comment,
+// oldest first
+func (s *commentService) GetReplies(ctx context.Context, id uint, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
+	if _, err := s.commentRepo.GetByID(id); err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+
+	offset := (page - 1) * perPage
+	replies, total, err := s.commentRepo.GetReplies(id, offset, perPage)
+	if err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+
+	responses := make([]models.CommentResponse, 0, len(replies))
+	for _, reply := range replies {
+		responses = append(responses, reply.ToResponse())
+	}
+
+	pagination := utils.CalculatePagination(page, perPage, total)
+	return responses, pagination, nil
+}
+
 func (s *commentService) Update(commentID, authorID uint, req *models.CommentUpdateRequest, isAdmin bool) (*models.CommentResponse, error) {
 	// Validate request
-	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
-		return nil, fmt.Errorf("validation failed: %v", validationErrors)
+	validationErrors := utils.ValidateStruct(req)
+	validationErrors = append(validationErrors, utils.ValidateMaxLength("Content", req.Content, s.config.MaxLength)...)
+	if len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
 	}
 
 	// Get existing comment
@@ -100,16 +240,27 @@ func (s *commentService) Update(commentID, authorID uint, req *models.CommentUpd
 	}
 
 	// Check ownership (only author or admin can update)
-	if !isAdmin && comment.AuthorID != authorID {
-		return nil, errors.New("unauthorized: you can only update your own comments")
+	if !isAdmin && !comment.IsAuthoredBy(authorID) {
+		return nil, apperrors.Forbidden("unauthorized: you can only update your own comments")
+	}
+
+	// Authors can only edit for a while after posting; admins always can
+	if !isAdmin && s.config.EditWindow > 0 && time.Since(comment.CreatedAt) > s.config.EditWindow {
+		return nil, apperrors.Forbidden(fmt.Sprintf("comments can only be edited within %s of posting", s.config.EditWindow))
 	}
 
 	// Update fields
 	if req.Content != "" {
-		comment.Content = utils.SanitizeText(req.Content)
-		// Reset status to pending if content is changed (except by admin)
-		if !isAdmin {
-			comment.Status = models.CommentStatusPending
+		content := utils.SanitizeText(req.Content)
+		if content != comment.Content {
+			now := time.Now()
+			comment.Content = content
+			comment.EditedAt = &now
+
+			// Changed content goes back for re-approval (except by admin)
+			if !isAdmin && s.config.RequireApproval {
+				comment.Status = models.CommentStatusPending
+			}
 		}
 	}
 
@@ -122,30 +273,43 @@ func (s *commentService) Update(commentID, authorID uint, req *models.CommentUpd
 		return nil, fmt.Errorf("failed to update comment: %w", err)
 	}
 
+	// An admin setting the status has moderated any reports on the comment
+	if isAdmin && req.Status != "" {
+		if err := s.commentRepo.ResolveReports(commentID); err != nil {
+			return nil, fmt.Errorf("failed to resolve comment reports: %w", err)
+		}
+	}
+
 	response := comment.ToResponse()
 	return &response, nil
 }
 
-func (s *commentService) Delete(commentID, authorID uint, isAdmin bool) error {
+// Delete removes a comment and returns the ID of the post it was on
+func (s *commentService) Delete(commentID, authorID uint, isAdmin bool) (uint, error) {
 	// Get existing comment
 	comment, err := s.commentRepo.GetByID(commentID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Check ownership (only author or admin can delete)
-	if !isAdmin && comment.AuthorID != authorID {
-		return errors.New("unauthorized: you can only delete your own comments")
+	if !isAdmin && !comment.IsAuthoredBy(authorID) {
+		return 0, apperrors.Forbidden("unauthorized: you can only delete your own comments")
 	}
 
-	return s.commentRepo.Delete(commentID)
+	if err := s.commentRepo.Delete(commentID); err != nil {
+		return 0, err
+	}
+	return comment.PostID, nil
 }
 
-func (s *commentService) GetByPost(postID uint, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error) {
+func (s *commentService) GetByPost(ctx context.Context, postID uint, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
 	// Verify that the post exists
 	_, err := s.postRepo.GetByID(postID)
 	if err != nil {
-		return nil, models.PaginationMeta{}, errors.New("post not found")
+		return nil, models.PaginationMeta{}, err
 	}
 
 	offset := (page - 1) * perPage
@@ -154,7 +318,7 @@ func (s *commentService) GetByPost(postID uint, page, perPage int) ([]models.Com
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.CommentResponse
+	responses := make([]models.CommentResponse, 0, len(comments))
 	for _, comment := range comments {
 		responses = append(responses, comment.ToResponse())
 	}
@@ -163,14 +327,16 @@ func (s *commentService) GetByPost(postID uint, page, perPage int) ([]models.Com
 	return responses, pagination, nil
 }
 
-func (s *commentService) GetByAuthor(authorID uint, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error) {
+func (s *commentService) GetByAuthor(ctx context.Context, authorID uint, status models.CommentStatus, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
 	offset := (page - 1) * perPage
-	comments, total, err := s.commentRepo.GetByAuthor(authorID, offset, perPage)
+	comments, total, err := s.commentRepo.GetByAuthor(authorID, status, offset, perPage)
 	if err != nil {
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.CommentResponse
+	responses := make([]models.CommentResponse, 0, len(comments))
 	for _, comment := range comments {
 		responses = append(responses, comment.ToResponse())
 	}
@@ -179,14 +345,20 @@ func (s *commentService) GetByAuthor(authorID uint, page, perPage int) ([]models
 	return responses, pagination, nil
 }
 
-func (s *commentService) GetPending(page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error) {
+// GetPending returns a page of the comments awaiting moderation that match
+// filter, oldest first
+func (s *commentService) GetPending(filter models.PendingCommentFilter, page, perPage int) ([]models.CommentResponse, models.PaginationMeta, error) {
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, models.PaginationMeta{}, apperrors.BadRequest("from must not be later than to")
+	}
+
 	offset := (page - 1) * perPage
-	comments, total, err := s.commentRepo.GetPending(offset, perPage)
+	comments, total, err := s.commentRepo.GetPending(filter, offset, perPage)
 	if err != nil {
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.CommentResponse
+	responses := make([]models.CommentResponse, 0, len(comments))
 	for _, comment := range comments {
 		responses = append(responses, comment.ToResponse())
 	}
@@ -196,14 +368,18 @@ func (s *commentService) GetPending(page, perPage int) ([]models.CommentResponse
 }
 
 func (s *commentService) ApproveComment(commentID uint) (*models.CommentResponse, error) {
-	_, err := s.commentRepo.GetByID(commentID)
+	comment, err := s.commentRepo.GetByID(commentID)
 	if err != nil {
 		return nil, err
 	}
+	wasApproved := comment.Status == models.CommentStatusApproved
 
 	if err := s.commentRepo.UpdateStatus(commentID, models.CommentStatusApproved); err != nil {
 		return nil, fmt.Errorf("failed to approve comment: %w", err)
 	}
+	if err := s.commentRepo.ResolveReports(commentID); err != nil {
+		return nil, fmt.Errorf("failed to resolve comment reports: %w", err)
+	}
 
 	// Get updated comment
 	updatedComment, err := s.commentRepo.GetByID(commentID)
@@ -212,6 +388,9 @@ func (s *commentService) ApproveComment(commentID uint) (*models.CommentResponse
 	}
 
 	response := updatedComment.ToResponse()
+	if !wasApproved {
+		s.webhooks.Dispatch(webhook.EventCommentApproved, response)
+	}
 	return &response, nil
 }
 
@@ -224,6 +403,9 @@ func (s *commentService) RejectComment(commentID uint) (*models.CommentResponse,
 	if err := s.commentRepo.UpdateStatus(commentID, models.CommentStatusRejected); err != nil {
 		return nil, fmt.Errorf("failed to reject comment: %w", err)
 	}
+	if err := s.commentRepo.ResolveReports(commentID); err != nil {
+		return nil, fmt.Errorf("failed to resolve comment reports: %w", err)
+	}
 
 	// Get updated comment
 	updatedComment, err := s.commentRepo.GetByID(commentID)
@@ -235,6 +417,101 @@ func (s *commentService) RejectComment(commentID uint) (*models.CommentResponse,
 	return &response, nil
 }
 
+// ApproveAllForPost approves every pending comment on a post and returns
+// how many were approved. Each approval is announced like ApproveComment's.
+func (s *commentService) ApproveAllForPost(postID uint) (int64, error) {
+	// Verify that the post exists
+	if _, err := s.postRepo.GetByID(postID); err != nil {
+		return 0, err
+	}
+
+	approved, err := s.commentRepo.ApproveAllForPost(postID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to approve comments: %w", err)
+	}
+	for _, comment := range approved {
+		s.webhooks.Dispatch(webhook.EventCommentApproved, comment.ToResponse())
+	}
+	return int64(len(approved)), nil
+}
+
 func (s *commentService) GetPendingCount() (int64, error) {
 	return s.commentRepo.CountPending()
 }
+
+// ReportComment flags a visible comment as abusive. Once the comment has
+// as many unresolved reports as the configured threshold it goes back to
+// pending, so it is hidden until an admin moderates it again. It returns
+// the ID of the post the comment is on.
+func (s *commentService) ReportComment(commentID, reporterID uint, req *models.CommentReportRequest) (uint, error) {
+	// Validate request
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return 0, &ValidationError{Fields: validationErrors}
+	}
+	reason := utils.SanitizeText(req.Reason)
+	if reason == "" {
+		return 0, apperrors.Validation("reason is required")
+	}
+
+	comment, err := s.commentRepo.GetByID(commentID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Readers can only see, and so only report, approved comments
+	if comment.Status != models.CommentStatusApproved {
+		return 0, apperrors.NotFound("comment not found")
+	}
+	if comment.IsAuthoredBy(reporterID) {
+		return 0, apperrors.Validation("you cannot report your own comment")
+	}
+
+	report := &models.CommentReport{
+		CommentID:  commentID,
+		ReporterID: reporterID,
+		Reason:     reason,
+	}
+	if err := s.commentRepo.CreateReport(report); err != nil {
+		if errors.Is(err, apperrors.ErrConflict) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("failed to report comment: %w", err)
+	}
+
+	reports, err := s.commentRepo.CountUnresolvedReports(commentID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count comment reports: %w", err)
+	}
+	if reports >= int64(s.config.ReportThreshold) {
+		if err := s.commentRepo.UpdateStatus(commentID, models.CommentStatusPending); err != nil {
+			return 0, fmt.Errorf("failed to queue reported comment: %w", err)
+		}
+	}
+	return comment.PostID, nil
+}
+
+// GetReported returns the comments with unresolved reports, most reported
+// first
+func (s *commentService) GetReported(page, perPage int) ([]models.ReportedCommentResponse, models.PaginationMeta, error) {
+	offset := (page - 1) * perPage
+	comments, total, err := s.commentRepo.GetReported(offset, perPage)
+	if err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+
+	responses := make([]models.ReportedCommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		response := models.ReportedCommentResponse{
+			CommentResponse: comment.ToResponse(),
+			ReportsCount:    len(comment.Reports),
+			Reports:         make([]models.CommentReportResponse, len(comment.Reports)),
+		}
+		for i, report := range comment.Reports {
+			response.Reports[i] = report.ToResponse()
+		}
+		responses = append(responses, response)
+	}
+
+	pagination := utils.CalculatePagination(page, perPage, total)
+	return responses, pagination, nil
+}
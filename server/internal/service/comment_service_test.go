@@ -0,0 +1,670 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// moderatedComments is the default comment config, where every new comment
+// waits for approval, two reports send a comment back to the queue, authors
+// can edit for 15 minutes and replies nest up to 3 levels deep
+var moderatedComments = config.CommentConfig{RequireApproval: true, ReportThreshold: 2, EditWindow: 15 * time.Minute, MaxDepth: 3}
+
+// newTestCommentService wires a comment service against an in-memory
+// database and returns a published post to comment on
+func newTestCommentService(t *testing.T) (service.CommentService, *gorm.DB, *models.Post) {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "postauthor")
+	now := time.Now()
+	post := &models.Post{
+		Title:       "Open for comments",
+		Slug:        "open-for-comments",
+		Content:     "Say something",
+		Status:      models.PostStatusPublished,
+		AuthorID:    author.ID,
+		PublishedAt: &now,
+	}
+	require.NoError(t, db.Create(post).Error)
+
+	svc := service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), &fakeWebhooks{}, moderatedComments)
+	return svc, db, post
+}
+
+func TestCommentService_CreateGuest(t *testing.T) {
+	svc, _, post := newTestCommentService(t)
+
+	t.Run("stores a pending guest comment", func(t *testing.T) {
+		comment, err := svc.CreateGuest(&models.CommentCreateRequest{
+			Content:    "Great write-up, thanks!",
+			PostID:     post.ID,
+			GuestName:  "  Jane   Visitor ",
+			GuestEmail: "Jane@Example.com",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, models.CommentStatusPending, comment.Status)
+		assert.True(t, comment.IsGuest)
+		assert.Nil(t, comment.AuthorID)
+		assert.Equal(t, "Jane Visitor", comment.Author.FirstName)
+		assert.Empty(t, comment.Author.Email)
+	})
+
+	t.Run("requires a name and email", func(t *testing.T) {
+		_, err := svc.CreateGuest(&models.CommentCreateRequest{
+			Content: "Anonymous drive-by",
+			PostID:  post.ID,
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("rejects link-heavy comments", func(t *testing.T) {
+		_, err := svc.CreateGuest(&models.CommentCreateRequest{
+			Content:    "Cheap pills at https://spam.example and www.more-spam.example",
+			PostID:     post.ID,
+			GuestName:  "Spammer",
+			GuestEmail: "spam@example.com",
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("guests can't edit or delete their comments", func(t *testing.T) {
+		comment, err := svc.CreateGuest(&models.CommentCreateRequest{
+			Content:    "Posted and forgotten",
+			PostID:     post.ID,
+			GuestName:  "Passerby",
+			GuestEmail: "passerby@example.com",
+		})
+		require.NoError(t, err)
+
+		_, err = svc.Delete(comment.ID, 0, false)
+		assert.Error(t, err)
+	})
+}
+
+func TestCommentService_Create_Authenticated(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+	user := testutil.CreateUser(t, db, "regular")
+
+	comment, err := svc.Create(user.ID, &models.CommentCreateRequest{
+		Content: "Logged-in comment",
+		PostID:  post.ID,
+	})
+	require.NoError(t, err)
+
+	assert.False(t, comment.IsGuest)
+	require.NotNil(t, comment.AuthorID)
+	assert.Equal(t, user.ID, *comment.AuthorID)
+	assert.Equal(t, "regular", comment.Author.Username)
+}
+
+func TestCommentService_MaxLength(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+	user := testutil.CreateUser(t, db, "rambler")
+
+	limited := moderatedComments
+	limited.MaxLength = 10
+	svc = service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), &fakeWebhooks{}, limited)
+
+	comment, err := svc.Create(user.ID, &models.CommentCreateRequest{Content: "ten chars!", PostID: post.ID})
+	require.NoError(t, err)
+
+	_, err = svc.Create(user.ID, &models.CommentCreateRequest{Content: "eleven chars", PostID: post.ID})
+	var invalid *service.ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "Content", invalid.Fields[0].Field)
+	assert.Equal(t, "max", invalid.Fields[0].Tag)
+
+	_, err = svc.Update(comment.ID, user.ID, &models.CommentUpdateRequest{Content: "still eleven"}, false)
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+}
+
+func TestCommentService_GetByID_CapsReplies(t *testing.T) {
+	_, db, post := newTestCommentService(t)
+	user := testutil.CreateUser(t, db, "chatty")
+	svc := service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), &fakeWebhooks{}, config.CommentConfig{})
+
+	parent, err := svc.Create(user.ID, &models.CommentCreateRequest{Content: "Start of a long thread", PostID: post.ID})
+	require.NoError(t, err)
+	for i := 1; i <= 25; i++ {
+		_, err := svc.Create(user.ID, &models.CommentCreateRequest{Content: fmt.Sprintf("Reply %d", i), PostID: post.ID, ParentID: &parent.ID})
+		require.NoError(t, err)
+	}
+
+	comment, err := svc.GetByID(context.Background(), parent.ID)
+	require.NoError(t, err)
+	require.Len(t, comment.Replies, 10)
+	assert.Equal(t, "Reply 1", comment.Replies[0].Content)
+	assert.Equal(t, 25, comment.RepliesCount)
+	assert.True(t, comment.HasMore)
+
+	t.Run("rest are paginated", func(t *testing.T) {
+		replies, pagination, err := svc.GetReplies(context.Background(), parent.ID, 3, 10)
+		require.NoError(t, err)
+		require.Len(t, replies, 5)
+		assert.Equal(t, "Reply 21", replies[0].Content)
+		assert.Equal(t, 25, pagination.Total)
+	})
+
+	t.Run("no more when all fit", func(t *testing.T) {
+		reply, err := svc.GetByID(context.Background(), comment.Replies[0].ID)
+		require.NoError(t, err)
+		assert.Empty(t, reply.Replies)
+		assert.Zero(t, reply.RepliesCount)
+		assert.False(t, reply.HasMore)
+	})
+
+	t.Run("unknown comment", func(t *testing.T) {
+		_, _, err := svc.GetReplies(context.Background(), 9999, 1, 10)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestCommentService_GetByPost_DatabaseFailure(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+
+	_, _, err := svc.GetByPost(context.Background(), post.ID+100, 1, 10)
+	require.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	// A broken connection must not be reported as a missing post
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	_, _, err = svc.GetByPost(context.Background(), post.ID, 1, 10)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestCommentService_GetByAuthor_StatusFilter(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+	user := testutil.CreateUser(t, db, "prolific")
+	other := testutil.CreateUser(t, db, "bystander")
+
+	statuses := map[models.CommentStatus]int{
+		models.CommentStatusPending:  1,
+		models.CommentStatusApproved: 3,
+		models.CommentStatusRejected: 2,
+	}
+	for status, n := range statuses {
+		for i := 0; i < n; i++ {
+			require.NoError(t, db.Create(&models.Comment{Content: string(status), Status: status, AuthorID: &user.ID, PostID: post.ID}).Error)
+		}
+	}
+	require.NoError(t, db.Create(&models.Comment{Content: "Not mine", Status: models.CommentStatusApproved, AuthorID: &other.ID, PostID: post.ID}).Error)
+
+	tests := []struct {
+		status models.CommentStatus
+		want   int
+	}{
+		{"", 6},
+		{models.CommentStatusPending, 1},
+		{models.CommentStatusApproved, 3},
+		{models.CommentStatusRejected, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run("status="+string(tt.status), func(t *testing.T) {
+			comments, pagination, err := svc.GetByAuthor(context.Background(), user.ID, tt.status, 1, 2)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, pagination.Total)
+			assert.LessOrEqual(t, len(comments), 2)
+			for _, comment := range comments {
+				if tt.status != "" {
+					assert.Equal(t, tt.status, comment.Status)
+				}
+				assert.Equal(t, user.ID, *comment.AuthorID)
+			}
+		})
+	}
+}
+
+func TestCommentService_DispatchesWebhooks(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+	webhooks := &fakeWebhooks{}
+	svc = service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), webhooks, moderatedComments)
+	user := testutil.CreateUser(t, db, "hooked")
+
+	comment, err := svc.Create(user.ID, &models.CommentCreateRequest{
+		Content: "Worth a notification",
+		PostID:  post.ID,
+	})
+	require.NoError(t, err)
+
+	_, err = svc.ApproveComment(comment.ID)
+	require.NoError(t, err)
+	_, err = svc.ApproveComment(comment.ID)
+	require.NoError(t, err)
+
+	require.Len(t, webhooks.events, 2)
+	assert.Equal(t, webhook.EventCommentCreated, webhooks.events[0].Event)
+	assert.Equal(t, webhook.EventCommentApproved, webhooks.events[1].Event)
+	approved, ok := webhooks.events[1].Data.(models.CommentResponse)
+	require.True(t, ok)
+	assert.Equal(t, models.CommentStatusApproved, approved.Status)
+}
+
+func TestCommentService_RequireApproval(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireApproval bool
+		want            models.CommentStatus
+	}{
+		{"moderated", true, models.CommentStatusPending},
+		{"open", false, models.CommentStatusApproved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, db, post := newTestCommentService(t)
+			webhooks := &fakeWebhooks{}
+			svc := service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), webhooks, config.CommentConfig{RequireApproval: tt.requireApproval})
+			user := testutil.CreateUser(t, db, "commenter")
+
+			comment, err := svc.Create(user.ID, &models.CommentCreateRequest{Content: "Member comment", PostID: post.ID})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, comment.Status)
+
+			guest, err := svc.CreateGuest(&models.CommentCreateRequest{
+				Content:    "Guest comment",
+				PostID:     post.ID,
+				GuestName:  "Visitor",
+				GuestEmail: "visitor@example.com",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, guest.Status)
+
+			// Spam checks apply either way
+			_, err = svc.CreateGuest(&models.CommentCreateRequest{
+				Content:    "Visit http://spam.example and http://more.example",
+				PostID:     post.ID,
+				GuestName:  "Spammer",
+				GuestEmail: "spam@example.com",
+			})
+			assert.ErrorIs(t, err, apperrors.ErrValidation)
+
+			approvedEvents := 0
+			for _, event := range webhooks.events {
+				if event.Event == webhook.EventCommentApproved {
+					approvedEvents++
+				}
+			}
+			if tt.requireApproval {
+				assert.Zero(t, approvedEvents)
+			} else {
+				assert.Equal(t, 2, approvedEvents)
+			}
+		})
+	}
+}
+
+func TestCommentService_GetPending_LinksToPost(t *testing.T) {
+	svc, _, post := newTestCommentService(t)
+
+	_, err := svc.CreateGuest(&models.CommentCreateRequest{
+		Content:    "Awaiting moderation",
+		PostID:     post.ID,
+		GuestName:  "Visitor",
+		GuestEmail: "visitor@example.com",
+	})
+	require.NoError(t, err)
+
+	pending, _, err := svc.GetPending(models.PendingCommentFilter{}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, post.Slug, pending[0].PostSlug)
+	assert.Equal(t, post.Title, pending[0].PostTitle)
+}
+
+func TestCommentService_GetPending_Filters(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+	user := testutil.CreateUser(t, db, "backlogged")
+	other := &models.Post{Title: "Another post", Slug: "another-post", Content: "More to say", Status: models.PostStatusPublished, AuthorID: post.AuthorID}
+	require.NoError(t, db.Create(other).Error)
+
+	// create leaves a pending comment on postID, backdated to createdAt
+	day := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	create := func(postID uint, createdAt time.Time) uint {
+		comment, err := svc.Create(user.ID, &models.CommentCreateRequest{Content: "Queued for review", PostID: postID})
+		require.NoError(t, err)
+		require.NoError(t, db.Model(&models.Comment{}).Where("id = ?", comment.ID).UpdateColumn("created_at", createdAt).Error)
+		return comment.ID
+	}
+	early := create(post.ID, day.Add(-48*time.Hour))
+	onTheDay := create(post.ID, day)
+	onOther := create(other.ID, day.Add(time.Hour))
+	late := create(post.ID, day.Add(72*time.Hour))
+
+	ids := func(filter models.PendingCommentFilter) []uint {
+		pending, pagination, err := svc.GetPending(filter, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, len(pending), pagination.Total)
+
+		var ids []uint
+		for _, comment := range pending {
+			ids = append(ids, comment.ID)
+		}
+		return ids
+	}
+
+	from, to := day, day.Add(24*time.Hour)
+	assert.Equal(t, []uint{early, onTheDay, onOther, late}, ids(models.PendingCommentFilter{}))
+	assert.Equal(t, []uint{onOther}, ids(models.PendingCommentFilter{PostID: other.ID}))
+	assert.Equal(t, []uint{onTheDay, onOther}, ids(models.PendingCommentFilter{From: &from, To: &to}), "bounds are inclusive")
+	assert.Equal(t, []uint{onTheDay, late}, ids(models.PendingCommentFilter{PostID: post.ID, From: &from}))
+	assert.Equal(t, []uint{early, onTheDay}, ids(models.PendingCommentFilter{PostID: post.ID, To: &to}))
+
+	_, _, err := svc.GetPending(models.PendingCommentFilter{From: &to, To: &from}, 1, 10)
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+}
+
+func TestCommentService_ApproveAllForPost(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+	webhooks := &fakeWebhooks{}
+	svc = service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), webhooks, moderatedComments)
+	user := testutil.CreateUser(t, db, "queued")
+
+	other := &models.Post{Title: "Elsewhere", Slug: "elsewhere", Content: "Another post", Status: models.PostStatusPublished, AuthorID: post.AuthorID}
+	require.NoError(t, db.Create(other).Error)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, db.Create(&models.Comment{Content: "Pending", Status: models.CommentStatusPending, AuthorID: &user.ID, PostID: post.ID}).Error)
+	}
+	rejected := &models.Comment{Content: "Rejected", Status: models.CommentStatusRejected, AuthorID: &user.ID, PostID: post.ID}
+	require.NoError(t, db.Create(rejected).Error)
+	elsewhere := &models.Comment{Content: "Pending elsewhere", Status: models.CommentStatusPending, AuthorID: &user.ID, PostID: other.ID}
+	require.NoError(t, db.Create(elsewhere).Error)
+
+	// A comment reported back to pending, here and on the other post
+	reporter := testutil.CreateUser(t, db, "reporter")
+	reported := &models.Comment{Content: "Reported", Status: models.CommentStatusPending, AuthorID: &user.ID, PostID: post.ID}
+	require.NoError(t, db.Create(reported).Error)
+	for _, commentID := range []uint{reported.ID, elsewhere.ID} {
+		require.NoError(t, db.Create(&models.CommentReport{CommentID: commentID, ReporterID: reporter.ID, Reason: "Spam"}).Error)
+	}
+
+	approved, err := svc.ApproveAllForPost(post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), approved)
+
+	var pending int64
+	require.NoError(t, db.Model(&models.Comment{}).Where("post_id = ? AND status = ?", post.ID, models.CommentStatusPending).Count(&pending).Error)
+	assert.Zero(t, pending)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Comment{}).Where("post_id = ? AND status = ?", post.ID, models.CommentStatusApproved).Count(&count).Error)
+	assert.Equal(t, int64(4), count)
+
+	var unresolved []models.CommentReport
+	require.NoError(t, db.Where("resolved = ?", false).Find(&unresolved).Error)
+	require.Len(t, unresolved, 1, "approval resolves the reports on approved comments")
+	assert.Equal(t, elsewhere.ID, unresolved[0].CommentID)
+
+	require.NoError(t, db.First(rejected, rejected.ID).Error)
+	assert.Equal(t, models.CommentStatusRejected, rejected.Status, "rejected comments must stay rejected")
+	require.NoError(t, db.First(elsewhere, elsewhere.ID).Error)
+	assert.Equal(t, models.CommentStatusPending, elsewhere.Status, "other posts must be untouched")
+
+	require.Len(t, webhooks.events, 4, "one event per approved comment")
+	for _, event := range webhooks.events {
+		assert.Equal(t, webhook.EventCommentApproved, event.Event)
+		comment, ok := event.Data.(models.CommentResponse)
+		require.True(t, ok)
+		assert.Equal(t, models.CommentStatusApproved, comment.Status)
+		assert.Equal(t, post.ID, comment.PostID)
+	}
+
+	again, err := svc.ApproveAllForPost(post.ID)
+	require.NoError(t, err)
+	assert.Zero(t, again)
+	assert.Len(t, webhooks.events, 4)
+
+	_, err = svc.ApproveAllForPost(9999)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestCommentService_ReportComment(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+	author := testutil.CreateUser(t, db, "commenter")
+	first := testutil.CreateUser(t, db, "reporter1")
+	second := testutil.CreateUser(t, db, "reporter2")
+
+	comment := &models.Comment{Content: "Rude remark", Status: models.CommentStatusApproved, AuthorID: &author.ID, PostID: post.ID}
+	require.NoError(t, db.Create(comment).Error)
+	reason := &models.CommentReportRequest{Reason: "Harassment"}
+
+	statusOf := func() models.CommentStatus {
+		var stored models.Comment
+		require.NoError(t, db.First(&stored, comment.ID).Error)
+		return stored.Status
+	}
+
+	t.Run("below the threshold the comment stays approved", func(t *testing.T) {
+		postID, err := svc.ReportComment(comment.ID, first.ID, reason)
+		require.NoError(t, err)
+		assert.Equal(t, post.ID, postID)
+		assert.Equal(t, models.CommentStatusApproved, statusOf())
+	})
+
+	t.Run("a user cannot report twice", func(t *testing.T) {
+		_, err := svc.ReportComment(comment.ID, first.ID, reason)
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.Equal(t, models.CommentStatusApproved, statusOf())
+	})
+
+	t.Run("authors cannot report their own comments", func(t *testing.T) {
+		_, err := svc.ReportComment(comment.ID, author.ID, reason)
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+	})
+
+	t.Run("reaching the threshold moves the comment to pending", func(t *testing.T) {
+		_, err := svc.ReportComment(comment.ID, second.ID, &models.CommentReportRequest{Reason: "Spam"})
+		require.NoError(t, err)
+		assert.Equal(t, models.CommentStatusPending, statusOf())
+
+		reported, pagination, err := svc.GetReported(1, 10)
+		require.NoError(t, err)
+		require.Len(t, reported, 1)
+		assert.Equal(t, 1, pagination.Total)
+		assert.Equal(t, comment.ID, reported[0].ID)
+		assert.Equal(t, 2, reported[0].ReportsCount)
+		assert.Equal(t, "Harassment", reported[0].Reports[0].Reason)
+		assert.Equal(t, "reporter1", reported[0].Reports[0].ReporterUsername)
+		assert.Equal(t, "Spam", reported[0].Reports[1].Reason)
+	})
+
+	t.Run("hidden comments cannot be reported", func(t *testing.T) {
+		third := testutil.CreateUser(t, db, "reporter3")
+		_, err := svc.ReportComment(comment.ID, third.ID, reason)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("approving resolves the reports", func(t *testing.T) {
+		_, err := svc.ApproveComment(comment.ID)
+		require.NoError(t, err)
+
+		reported, _, err := svc.GetReported(1, 10)
+		require.NoError(t, err)
+		assert.Empty(t, reported)
+
+		// Resolved reports no longer count towards the threshold
+		third := testutil.CreateUser(t, db, "reporter4")
+		_, err = svc.ReportComment(comment.ID, third.ID, reason)
+		require.NoError(t, err)
+		assert.Equal(t, models.CommentStatusApproved, statusOf())
+	})
+}
+
+func TestCommentService_Update_EditWindow(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+	author := testutil.CreateUser(t, db, "editor")
+	admin := testutil.CreateUser(t, db, "editadmin")
+
+	newComment := func(age time.Duration) *models.CommentResponse {
+		t.Helper()
+		comment, err := svc.Create(author.ID, &models.CommentCreateRequest{Content: "First thoughts", PostID: post.ID})
+		require.NoError(t, err)
+		require.NoError(t, db.Model(&models.Comment{}).Where("id = ?", comment.ID).
+			UpdateColumn("created_at", time.Now().Add(-age)).Error)
+		return comment
+	}
+
+	t.Run("author edits within the window", func(t *testing.T) {
+		comment := newComment(5 * time.Minute)
+		assert.Nil(t, comment.EditedAt)
+
+		updated, err := svc.Update(comment.ID, author.ID, &models.CommentUpdateRequest{Content: "Second thoughts"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, "Second thoughts", updated.Content)
+		require.NotNil(t, updated.EditedAt)
+		assert.WithinDuration(t, time.Now(), *updated.EditedAt, time.Minute)
+
+		stored, err := svc.GetByID(context.Background(), comment.ID)
+		require.NoError(t, err)
+		assert.Equal(t, updated.EditedAt.Unix(), stored.EditedAt.Unix())
+	})
+
+	t.Run("author can't edit after the window", func(t *testing.T) {
+		comment := newComment(20 * time.Minute)
+
+		_, err := svc.Update(comment.ID, author.ID, &models.CommentUpdateRequest{Content: "Too late"}, false)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	t.Run("admins can edit after the window", func(t *testing.T) {
+		comment := newComment(20 * time.Minute)
+
+		updated, err := svc.Update(comment.ID, admin.ID, &models.CommentUpdateRequest{Content: "Moderated wording"}, true)
+		require.NoError(t, err)
+		assert.NotNil(t, updated.EditedAt)
+	})
+
+	t.Run("without a window authors can always edit", func(t *testing.T) {
+		comment := newComment(30 * 24 * time.Hour)
+		cfg := moderatedComments
+		cfg.EditWindow = 0
+		unlimited := service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), &fakeWebhooks{}, cfg)
+
+		updated, err := unlimited.Update(comment.ID, author.ID, &models.CommentUpdateRequest{Content: "A month later"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, "A month later", updated.Content)
+	})
+
+	t.Run("moderation alone is not an edit", func(t *testing.T) {
+		comment := newComment(time.Minute)
+
+		updated, err := svc.Update(comment.ID, admin.ID, &models.CommentUpdateRequest{Status: models.CommentStatusApproved}, true)
+		require.NoError(t, err)
+		assert.Nil(t, updated.EditedAt)
+	})
+}
+
+func TestCommentService_Update_ApprovalStatus(t *testing.T) {
+	_, db, post := newTestCommentService(t)
+	author := testutil.CreateUser(t, db, "reviser")
+
+	edit := func(requireApproval bool) models.CommentStatus {
+		t.Helper()
+		cfg := moderatedComments
+		cfg.RequireApproval = requireApproval
+		svc := service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), &fakeWebhooks{}, cfg)
+
+		comment := &models.Comment{Content: "Approved take", Status: models.CommentStatusApproved, AuthorID: &author.ID, PostID: post.ID}
+		require.NoError(t, db.Create(comment).Error)
+		updated, err := svc.Update(comment.ID, author.ID, &models.CommentUpdateRequest{Content: "Revised take"}, false)
+		require.NoError(t, err)
+		return updated.Status
+	}
+
+	assert.Equal(t, models.CommentStatusPending, edit(true), "edits go back for approval")
+	assert.Equal(t, models.CommentStatusApproved, edit(false), "without moderation edits stay approved")
+}
+
+func TestCommentService_Create_MaxDepth(t *testing.T) {
+	svc, db, post := newTestCommentService(t)
+	user := testutil.CreateUser(t, db, "nester")
+
+	comment, err := svc.Create(user.ID, &models.CommentCreateRequest{Content: "Top level", PostID: post.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 0, comment.Depth)
+
+	for depth := 1; depth <= moderatedComments.MaxDepth; depth++ {
+		comment, err = svc.Create(user.ID, &models.CommentCreateRequest{Content: fmt.Sprintf("Reply at depth %d", depth), PostID: post.ID, ParentID: &comment.ID})
+		require.NoError(t, err, "depth %d", depth)
+		assert.Equal(t, depth, comment.Depth)
+	}
+
+	_, err = svc.Create(user.ID, &models.CommentCreateRequest{Content: "One level too deep", PostID: post.ID, ParentID: &comment.ID})
+	require.ErrorIs(t, err, apperrors.ErrValidation)
+	assert.Contains(t, err.Error(), "at most 3 levels deep")
+
+	t.Run("zero allows any depth", func(t *testing.T) {
+		unlimited := moderatedComments
+		unlimited.MaxDepth = 0
+		svc := service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), &fakeWebhooks{}, unlimited)
+
+		reply, err := svc.Create(user.ID, &models.CommentCreateRequest{Content: "Deep but allowed", PostID: post.ID, ParentID: &comment.ID})
+		require.NoError(t, err)
+		assert.Equal(t, moderatedComments.MaxDepth+1, reply.Depth)
+	})
+}
+
+func TestCommentService_Create_RejectsDuplicates(t *testing.T) {
+	_, db, post := newTestCommentService(t)
+	cfg := moderatedComments
+	cfg.RejectDuplicates, cfg.DuplicateWindow = true, 10*time.Minute
+	svc := service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), &fakeWebhooks{}, cfg)
+
+	spammer := testutil.CreateUser(t, db, "repeater")
+	other := testutil.CreateUser(t, db, "bystander")
+
+	first, err := svc.Create(spammer.ID, &models.CommentCreateRequest{Content: "Great post, visit my site", PostID: post.ID})
+	require.NoError(t, err)
+
+	t.Run("the same content within the window is refused", func(t *testing.T) {
+		_, err := svc.Create(spammer.ID, &models.CommentCreateRequest{Content: "  great POST,\nvisit my   site ", PostID: post.ID})
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+
+		_, err = svc.CreateGuest(&models.CommentCreateRequest{Content: "Lovely read", PostID: post.ID, GuestName: "Guest", GuestEmail: "guest@example.com"})
+		require.NoError(t, err)
+		_, err = svc.CreateGuest(&models.CommentCreateRequest{Content: "Lovely read", PostID: post.ID, GuestName: "Guest", GuestEmail: "Guest@Example.com"})
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("other authors and different content are allowed", func(t *testing.T) {
+		_, err := svc.Create(other.ID, &models.CommentCreateRequest{Content: "Great post, visit my site", PostID: post.ID})
+		assert.NoError(t, err)
+		_, err = svc.Create(spammer.ID, &models.CommentCreateRequest{Content: "Great post, visit my blog", PostID: post.ID})
+		assert.NoError(t, err)
+	})
+
+	t.Run("the same content after the window is allowed", func(t *testing.T) {
+		require.NoError(t, db.Model(&models.Comment{}).Where("id = ?", first.ID).
+			UpdateColumn("created_at", time.Now().Add(-15*time.Minute)).Error)
+
+		_, err := svc.Create(spammer.ID, &models.CommentCreateRequest{Content: "Great post, visit my site", PostID: post.ID})
+		assert.NoError(t, err)
+	})
+
+	t.Run("the check can be turned off", func(t *testing.T) {
+		cfg.RejectDuplicates = false
+		svc := service.NewCommentService(repository.NewCommentRepository(db), repository.NewPostRepository(db), &fakeWebhooks{}, cfg)
+		_, err := svc.Create(spammer.ID, &models.CommentCreateRequest{Content: "Great post, visit my site", PostID: post.ID})
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+)
+
+// ValidationError is returned when a well-formed request has fields that
+// fail validation. Fields lists them, so clients can point at each one.
+type ValidationError struct {
+	Fields []models.ValidationError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Unwrap makes the error match apperrors.ErrValidation
+func (e *ValidationError) Unwrap() error {
+	return apperrors.ErrValidation
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+)
+
+type NewsletterService interface {
+	Subscribe(userID uint) error
+	Unsubscribe(userID uint) error
+	SendDigest() (*models.NewsletterDigestResult, error)
+}
+
+type newsletterService struct {
+	userRepo repository.UserRepository
+	postRepo repository.PostRepository
+	mailer   mailer.Mailer
+	config   config.NewsletterConfig
+	baseURL  string
+}
+
+func NewNewsletterService(userRepo repository.UserRepository, postRepo repository.PostRepository, m mailer.Mailer, cfg config.NewsletterConfig, baseURL string) NewsletterService {
+	return &newsletterService{
+		userRepo: userRepo,
+		postRepo: postRepo,
+		mailer:   m,
+		config:   cfg,
+		baseURL:  baseURL,
+	}
+}
+
+func (s *newsletterService) Subscribe(userID uint) error {
+	return s.userRepo.SetNewsletterSubscribed(userID, true)
+}
+
+func (s *newsletterService) Unsubscribe(userID uint) error {
+	return s.userRepo.SetNewsletterSubscribed(userID, false)
+}
+
+// SendDigest mails the most viewed posts of the last interval to every
+// subscriber. Nothing is sent when no posts were published. A failed
+// delivery is logged and counted but doesn't stop the others.
+func (s *newsletterService) SendDigest() (*models.NewsletterDigestResult, error) {
+	since := time.Now().Add(-s.config.Interval)
+	posts, err := s.postRepo.GetTopPublishedSince(since, s.config.PostLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load top posts: %w", err)
+	}
+
+	result := &models.NewsletterDigestResult{Posts: len(posts)}
+	if len(posts) == 0 {
+		return result, nil
+	}
+
+	subscribers, err := s.userRepo.GetNewsletterSubscribers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscribers: %w", err)
+	}
+
+	subject := fmt.Sprintf("Top posts since %s", since.Format("January 2"))
+	body := s.digestBody(posts)
+	for _, user := range subscribers {
+		msg := mailer.Message{
+			To:      user.Email,
+			Subject: subject,
+			Body:    fmt.Sprintf("Hi %s,\n\n%s", user.FirstName, body),
+		}
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("Warning: failed to send newsletter to user %d: %v", user.ID, err)
+			result.Failed++
+			continue
+		}
+		result.Recipients++
+	}
+
+	return result, nil
+}
+
+// digestBody lists the posts with links, most viewed first
+func (s *newsletterService) digestBody(posts []models.Post) string {
+	var b strings.Builder
+	b.WriteString("Here are the most read posts on the blog lately:\n\n")
+	for i, post := range posts {
+		fmt.Fprintf(&b, "%d. %s by %s %s\n", i+1, post.Title, post.Author.FirstName, post.Author.LastName)
+		if post.Excerpt != "" {
+			fmt.Fprintf(&b, "   %s\n", post.Excerpt)
+		}
+		fmt.Fprintf(&b, "   %s/posts/%d\n\n", s.baseURL, post.ID)
+	}
+	b.WriteString("You are receiving this because you subscribed to the newsletter. You can unsubscribe from your profile.\n")
+	return b.String()
+}
+
+// RunNewsletterScheduler sends a digest every interval until ctx is done
+func RunNewsletterScheduler(ctx context.Context, newsletter NewsletterService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := newsletter.SendDigest()
+			if err != nil {
+				log.Printf("Newsletter digest failed: %v", err)
+				continue
+			}
+			log.Printf("📰 Newsletter digest: %d posts sent to %d subscribers (%d failed)", result.Posts, result.Recipients, result.Failed)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeMailer records sent messages and fails for the addresses in failFor
+type fakeMailer struct {
+	sent    []mailer.Message
+	failFor map[string]bool
+}
+
+func (m *fakeMailer) Send(msg mailer.Message) error {
+	if m.failFor[msg.To] {
+		return errors.New("mailbox unavailable")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newTestNewsletterService(t *testing.T) (service.NewsletterService, *gorm.DB, *fakeMailer) {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	m := &fakeMailer{failFor: map[string]bool{}}
+	svc := service.NewNewsletterService(
+		repository.NewUserRepository(db),
+		repository.NewPostRepository(db),
+		m,
+		config.NewsletterConfig{Interval: 7 * 24 * time.Hour, PostLimit: 2},
+		"https://blog.example.com",
+	)
+	return svc, db, m
+}
+
+func TestNewsletterService_SendDigest(t *testing.T) {
+	svc, db, m := newTestNewsletterService(t)
+
+	author := testutil.CreateUser(t, db, "digestauthor")
+	subscriber := testutil.CreateUser(t, db, "subscriber")
+	inactive := testutil.CreateUser(t, db, "inactivesub")
+	testutil.CreateUser(t, db, "notsubscribed")
+
+	require.NoError(t, svc.Subscribe(subscriber.ID))
+	require.NoError(t, svc.Subscribe(inactive.ID))
+	require.NoError(t, db.Model(inactive).Update("is_active", false).Error)
+
+	now := time.Now()
+	createPost := func(title string, status models.PostStatus, publishedAt time.Time, views int) {
+		post := &models.Post{
+			Title:     title,
+			Slug:      strings.ToLower(strings.ReplaceAll(title, " ", "-")),
+			Content:   "Digest content",
+			Status:    status,
+			AuthorID:  author.ID,
+			ViewCount: views,
+		}
+		if status == models.PostStatusPublished {
+			post.PublishedAt = &publishedAt
+		}
+		require.NoError(t, db.Create(post).Error)
+	}
+	createPost("Quiet Week", models.PostStatusPublished, now.Add(-time.Hour), 5)
+	createPost("Most Read", models.PostStatusPublished, now.Add(-2*time.Hour), 50)
+	createPost("Runner Up", models.PostStatusPublished, now.Add(-3*time.Hour), 20)
+	createPost("Old News", models.PostStatusPublished, now.Add(-30*24*time.Hour), 500)
+	createPost("Unfinished", models.PostStatusDraft, now, 900)
+
+	result, err := svc.SendDigest()
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Posts)
+	assert.Equal(t, 1, result.Recipients)
+	assert.Equal(t, 0, result.Failed)
+
+	require.Len(t, m.sent, 1)
+	msg := m.sent[0]
+	assert.Equal(t, subscriber.Email, msg.To)
+	assert.Contains(t, msg.Body, "1. Most Read")
+	assert.Contains(t, msg.Body, "2. Runner Up")
+	assert.Contains(t, msg.Body, "https://blog.example.com/posts/")
+	for _, excluded := range []string{"Quiet Week", "Old News", "Unfinished"} {
+		assert.NotContains(t, msg.Body, excluded)
+	}
+
+	// Unsubscribed users and failed deliveries are left out
+	require.NoError(t, svc.Unsubscribe(subscriber.ID))
+	other := testutil.CreateUser(t, db, "othersub")
+	bounced := testutil.CreateUser(t, db, "bouncedsub")
+	require.NoError(t, svc.Subscribe(other.ID))
+	require.NoError(t, svc.Subscribe(bounced.ID))
+	m.sent = nil
+	m.failFor[bounced.Email] = true
+
+	result, err = svc.SendDigest()
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Recipients)
+	assert.Equal(t, 1, result.Failed)
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, other.Email, m.sent[0].To)
+}
+
+func TestNewsletterService_SendDigest_NoPosts(t *testing.T) {
+	svc, db, m := newTestNewsletterService(t)
+
+	subscriber := testutil.CreateUser(t, db, "lonelysub")
+	require.NoError(t, svc.Subscribe(subscriber.ID))
+
+	result, err := svc.SendDigest()
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Posts)
+	assert.Empty(t, m.sent)
+}
+
+func TestNewsletterService_Subscribe_UnknownUser(t *testing.T) {
+	svc, _, _ := newTestNewsletterService(t)
+
+	assert.Error(t, svc.Subscribe(9999))
+}
@@ -1,95 +1,158 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
 	"time"
 
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
+	"gopkg.in/yaml.v3"
 )
 
+// searchHighlightRadius is how many characters of context surround a
+// highlighted search match
+const searchHighlightRadius = 80
+
+// maxSlugAttempts bounds how many slugs Create tries before giving up
+const maxSlugAttempts = 5
+
 type PostService interface {
 	Create(authorID uint, req *models.PostCreateRequest) (*models.PostResponse, error)
-	GetByID(id uint) (*models.PostResponse, error)
-	GetBySlug(slug string) (*models.PostResponse, error)
+	Import(authorID uint, document string) (*models.PostResponse, error)
+	PreviewImport(document string) (*models.PostImportPreview, error)
+	GetByID(ctx context.Context, id, viewerID uint, isAdmin bool) (*models.PostResponse, error)
+	GetBySlug(ctx context.Context, slug string, viewerID uint, isAdmin bool) (*models.PostResponse, error)
+	GetTags(ctx context.Context, postID, viewerID uint, isAdmin bool) ([]models.TagResponse, error)
+	GetCommenters(ctx context.Context, postID, viewerID uint, isAdmin bool) ([]models.CommenterResponse, error)
 	Update(postID, authorID uint, req *models.PostUpdateRequest, isAdmin bool) (*models.PostResponse, error)
 	Delete(postID, authorID uint, isAdmin bool) error
-	GetPosts(page, perPage int, status models.PostStatus, authorID uint) ([]models.PostListResponse, models.PaginationMeta, error)
-	GetPublishedPosts(page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
-	GetPostsByAuthor(authorID uint, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
-	GetPostsByTag(tagID uint, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
-	SearchPosts(query string, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
+	GetPosts(ctx context.Context, filter models.PostFilter, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
+	GetPublishedPosts(ctx context.Context, sort models.PostSort, published models.PublishedRange, featuredFirst bool, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
+	GetFeaturedPosts(ctx context.Context, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
+	GetPublishedPostsByCursor(ctx context.Context, cursor string, published models.PublishedRange, perPage int) ([]models.PostListResponse, models.CursorPaginationMeta, error)
+	GetLatestPublished(ctx context.Context, limit int) ([]models.PostResponse, error)
+	GetPostsByAuthor(ctx context.Context, authorID uint, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
+	GetPostsByTag(ctx context.Context, tagID uint, sort models.PostSort, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
+	SearchPosts(ctx context.Context, query string, highlight bool, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error)
 	IncrementViewCount(id uint) error
+	AttachComments(ctx context.Context, post *models.PostResponse) error
 	Publish(postID, authorID uint, isAdmin bool) (*models.PostResponse, error)
-	Unpublish(postID, authorID uint, isAdmin bool) (*models.PostResponse, error)
+	Feature(postID uint, req *models.PostFeatureRequest) (*models.PostResponse, error)
+	Unfeature(postID uint) (*models.PostResponse, error)
+	AddCoAuthor(postID, userID uint, isAdmin bool, req *models.PostCoAuthorRequest) (*models.PostResponse, error)
+	RemoveCoAuthor(postID, userID uint, isAdmin bool, coAuthorID uint) (*models.PostResponse, error)
+	Unpublish(postID, authorID uint, isAdmin bool, req *models.PostModerationRequest) (*models.PostResponse, error)
+	Archive(postID, authorID uint, isAdmin bool, req *models.PostModerationRequest) (*models.PostResponse, error)
+	GetModerationLog(postID uint) ([]models.PostModerationResponse, error)
+	GetAuthorStats(authorID uint) (*models.AuthorStatsResponse, error)
+	GetArchive() ([]models.ArchiveCount, error)
+	CreatePreviewToken(postID, userID uint, isAdmin bool) (*models.PreviewTokenResponse, error)
+	GetByPreviewToken(token string) (*models.PostResponse, error)
+	AttachTag(tagID uint, req *models.TagPostsRequest) (*models.TagPostsResponse, error)
+	DetachTag(tagID uint, req *models.TagPostsRequest) (*models.TagPostsResponse, error)
 }
 
 type postService struct {
 	postRepo    repository.PostRepository
 	tagRepo     repository.TagRepository
 	commentRepo repository.CommentRepository
+	webhooks    webhook.Dispatcher
+	mailer      mailer.Mailer
+	content     config.ContentConfig
+	jwt         config.JWTConfig
+	tagCache    TagListCache
 }
 
-func NewPostService(postRepo repository.PostRepository, tagRepo repository.TagRepository, commentRepo repository.CommentRepository) PostService {
+// NewPostService returns a post service. Tags created by imports invalidate
+// tagCache; a nil tagCache is ignored.
+func NewPostService(postRepo repository.PostRepository, tagRepo repository.TagRepository, commentRepo repository.CommentRepository, webhooks webhook.Dispatcher, m mailer.Mailer, content config.ContentConfig, jwt config.JWTConfig, tagCache TagListCache) PostService {
 	return &postService{
 		postRepo:    postRepo,
 		tagRepo:     tagRepo,
 		commentRepo: commentRepo,
+		webhooks:    webhooks,
+		mailer:      m,
+		content:     content,
+		jwt:         jwt,
+		tagCache:    tagCache,
 	}
 }
 
 func (s *postService) Create(authorID uint, req *models.PostCreateRequest) (*models.PostResponse, error) {
+	return s.create(authorID, req, "", nil)
+}
+
+// create stores a new post under slug, or a slug derived from its title
+// when slug is empty. A given slug must be free; a derived one gets a
+// random suffix when taken. newTags are created along with the post and
+// added to it.
+func (s *postService) create(authorID uint, req *models.PostCreateRequest, slug string, newTags []*models.Tag) (*models.PostResponse, error) {
 	// Validate request
-	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
-		return nil, fmt.Errorf("validation failed: %v", validationErrors)
+	validationErrors := utils.ValidateStruct(req)
+	validationErrors = append(validationErrors, utils.ValidateMaxLength("Content", req.Content, s.content.MaxPostLength)...)
+	if len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
 	}
 
-	// Generate slug from title
-	slug := utils.GenerateSlug(req.Title)
-	originalSlug := slug
-
-	// Ensure slug is unique
-	counter := 1
-	for s.postRepo.IsSlugTaken(slug, 0) {
-		slug = fmt.Sprintf("%s-%d", originalSlug, counter)
-		counter++
+	tagIDs, err := s.validateTagIDs(req.TagIDs)
+	if err != nil {
+		return nil, err
 	}
 
+	content := s.sanitizeContent(req.Content)
+
 	// Extract excerpt if not provided
 	excerpt := req.Excerpt
 	if excerpt == "" {
-		excerpt = utils.ExtractExcerpt(req.Content, 200)
+		excerpt = utils.ExtractExcerpt(content, 200)
 	}
 
 	// Create post
 	post := &models.Post{
 		Title:       utils.SanitizeText(req.Title),
-		Slug:        slug,
-		Content:     req.Content,
+		Content:     content,
 		Excerpt:     utils.SanitizeText(excerpt),
 		FeaturedImg: req.FeaturedImg,
-		Status:      req.Status,
 		AuthorID:    authorID,
 	}
+	post.SetStatus(req.Status)
+
+	// The post and its tags are stored together or not at all
+	err = s.postRepo.Transaction(func(repo repository.PostRepository) error {
+		for _, tag := range newTags {
+			if err := repo.Tags().Create(tag); err != nil {
+				return fmt.Errorf("failed to create tag %q: %w", tag.Name, err)
+			}
+			tagIDs = append(tagIDs, tag.ID)
+		}
 
-	// Set published date if status is published
-	if req.Status == models.PostStatusPublished {
-		now := time.Now()
-		post.PublishedAt = &now
-	}
-
-	if err := s.postRepo.Create(post); err != nil {
-		return nil, fmt.Errorf("failed to create post: %w", err)
-	}
-
-	// Add tags if provided
-	if len(req.TagIDs) > 0 {
-		if err := s.postRepo.UpdateTags(post.ID, req.TagIDs); err != nil {
-			// Log error but don't fail the post creation
-			fmt.Printf("Warning: Failed to add tags to post: %v\n", err)
+		if slug != "" {
+			post.Slug = slug
+			if err := repo.Create(post); err != nil {
+				return err
+			}
+		} else if err := createWithUniqueSlug(repo, post, utils.GenerateSlug(req.Title)); err != nil {
+			return err
 		}
+		if len(tagIDs) > 0 {
+			if err := repo.UpdateTags(post.ID, tagIDs); err != nil {
+				return fmt.Errorf("failed to add tags: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create post: %w", err)
 	}
 
 	// Get the created post with relationships
@@ -99,21 +162,279 @@ func (s *postService) Create(authorID uint, req *models.PostCreateRequest) (*mod
 	}
 
 	response := s.enrichPostResponse(createdPost)
+	if createdPost.Status == models.PostStatusPublished {
+		s.webhooks.Dispatch(webhook.EventPostPublished, response)
+	}
 	return &response, nil
 }
 
-func (s *postService) GetByID(id uint) (*models.PostResponse, error) {
+// postImport is a Markdown document parsed for import
+type postImport struct {
+	req  models.PostCreateRequest
+	slug string
+
+	// tags are the existing tags named in the front matter, newTags the
+	// names that aren't tags yet
+	tags    []models.Tag
+	newTags []string
+}
+
+// Import creates a post owned by authorID from a Markdown document with
+// YAML front matter, as written for static site generators. The front
+// matter gives the title, slug, status (draft by default) and tag names;
+// tags that don't exist yet are created. The rest of the document is the
+// content.
+func (s *postService) Import(authorID uint, document string) (*models.PostResponse, error) {
+	parsed, err := s.parseImport(document)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range parsed.tags {
+		parsed.req.TagIDs = append(parsed.req.TagIDs, tag.ID)
+	}
+	newTags := make([]*models.Tag, 0, len(parsed.newTags))
+	for _, name := range parsed.newTags {
+		newTags = append(newTags, &models.Tag{Name: name, Slug: s.uniqueTagSlug(utils.GenerateSlug(name)), Color: defaultTagColor})
+	}
+
+	post, err := s.create(authorID, &parsed.req, parsed.slug, newTags)
+	if err != nil {
+		return nil, err
+	}
+	if len(newTags) > 0 && s.tagCache != nil {
+		s.tagCache.InvalidateCache()
+	}
+	return post, nil
+}
+
+// PreviewImport validates a document for Import without storing anything,
+// and reports the post it would create
+func (s *postService) PreviewImport(document string) (*models.PostImportPreview, error) {
+	parsed, err := s.parseImport(document)
+	if err != nil {
+		return nil, err
+	}
+
+	content := s.sanitizeContent(parsed.req.Content)
+	preview := &models.PostImportPreview{
+		Title:   utils.SanitizeText(parsed.req.Title),
+		Slug:    parsed.slug,
+		Status:  parsed.req.Status,
+		Excerpt: utils.SanitizeText(utils.ExtractExcerpt(content, 200)),
+		Tags:    []string{},
+		NewTags: parsed.newTags,
+	}
+	if preview.Slug == "" {
+		preview.Slug = utils.GenerateSlug(parsed.req.Title)
+	}
+	for _, tag := range parsed.tags {
+		preview.Tags = append(preview.Tags, tag.Name)
+	}
+	preview.Tags = append(preview.Tags, parsed.newTags...)
+	return preview, nil
+}
+
+// parseImport parses and validates a document for import, checking
+// everything Create would so a valid preview imports cleanly
+func (s *postService) parseImport(document string) (*postImport, error) {
+	frontMatter, body, ok := utils.SplitFrontMatter(document)
+	if !ok {
+		return nil, apperrors.BadRequest("the document must start with YAML front matter between --- lines")
+	}
+
+	var meta models.PostFrontMatter
+	if err := yaml.Unmarshal([]byte(frontMatter), &meta); err != nil {
+		return nil, apperrors.BadRequest(fmt.Sprintf("invalid front matter: %v", err))
+	}
+	if meta.Status == "" {
+		meta.Status = models.PostStatusDraft
+	}
+
+	parsed := &postImport{
+		req: models.PostCreateRequest{
+			Title:   strings.TrimSpace(meta.Title),
+			Content: strings.TrimSpace(body),
+			Status:  meta.Status,
+		},
+		slug:    strings.TrimSpace(meta.Slug),
+		newTags: []string{},
+	}
+
+	validationErrors := utils.ValidateStruct(&parsed.req)
+	validationErrors = append(validationErrors, utils.ValidateMaxLength("Content", parsed.req.Content, s.content.MaxPostLength)...)
+	if parsed.slug != "" && (!utils.IsValidSlug(parsed.slug) || len(parsed.slug) > 250) {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Field:   "Slug",
+			Tag:     "slug",
+			Value:   parsed.slug,
+			Message: "Slug may only contain lowercase letters, digits and single hyphens, can't start or end with a hyphen, and is at most 250 characters",
+		})
+	}
+
+	// Tag names repeat case-insensitively, like tags themselves
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range meta.Tags {
+		name = utils.SanitizeText(strings.TrimSpace(name))
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		if length := len([]rune(name)); length < 2 || length > 50 {
+			validationErrors = append(validationErrors, models.ValidationError{
+				Field:   "Tags",
+				Tag:     "tag",
+				Value:   name,
+				Message: "Tag names must be between 2 and 50 characters",
+			})
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
+	if parsed.slug != "" && s.postRepo.IsSlugTaken(parsed.slug, 0) {
+		return nil, repository.ErrSlugTaken
+	}
+
+	tags, err := s.tagRepo.GetByNames(names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up tags: %w", err)
+	}
+	existing := make(map[string]models.Tag, len(tags))
+	for _, tag := range tags {
+		existing[strings.ToLower(tag.Name)] = tag
+	}
+	for _, name := range names {
+		if tag, ok := existing[strings.ToLower(name)]; ok {
+			parsed.tags = append(parsed.tags, tag)
+		} else {
+			parsed.newTags = append(parsed.newTags, name)
+		}
+	}
+	return parsed, nil
+}
+
+// uniqueTagSlug returns base, or base with the first free -N suffix if a
+// tag uses it, as the tag service names new tags
+func (s *postService) uniqueTagSlug(base string) string {
+	slug := base
+	for counter := 1; s.tagRepo.IsSlugTaken(slug, 0); counter++ {
+		slug = fmt.Sprintf("%s-%d", base, counter)
+	}
+	return slug
+}
+
+// createWithUniqueSlug inserts the post under baseSlug, relying on the unique
+// index rather than checking first so concurrent creates can't race. When the
+// slug is taken it retries with a random suffix a bounded number of times.
+// Each attempt runs in its own savepoint so a conflict doesn't abort a
+// surrounding transaction.
+func createWithUniqueSlug(repo repository.PostRepository, post *models.Post, baseSlug string) error {
+	post.Slug = baseSlug
+	for attempt := 1; ; attempt++ {
+		err := repo.Transaction(func(repo repository.PostRepository) error {
+			return repo.Create(post)
+		})
+		if !errors.Is(err, repository.ErrSlugTaken) {
+			return err
+		}
+		if attempt == maxSlugAttempts {
+			return apperrors.Conflict(fmt.Sprintf("could not find a free slug for %q after %d attempts", baseSlug, attempt))
+		}
+		post.Slug = fmt.Sprintf("%s-%s", baseSlug, randomSlugSuffix())
+	}
+}
+
+// withContext returns a copy of the service whose queries run with ctx, so
+// they are cancelled along with the request they serve
+func (s *postService) withContext(ctx context.Context) *postService {
+	scoped := *s
+	scoped.postRepo = s.postRepo.WithContext(ctx)
+	scoped.tagRepo = s.tagRepo.WithContext(ctx)
+	scoped.commentRepo = s.commentRepo.WithContext(ctx)
+	return &scoped
+}
+
+// randomSlugSuffix returns a short random suffix for disambiguating slugs
+func randomSlugSuffix() string {
+	return fmt.Sprintf("%06x", rand.Uint32()&0xffffff)
+}
+
+// GetByID returns a post as seen by viewerID, which is 0 for anonymous
+// requests. Unpublished posts are only visible to their author and admins.
+func (s *postService) GetByID(ctx context.Context, id, viewerID uint, isAdmin bool) (*models.PostResponse, error) {
+	s = s.withContext(ctx)
+
 	post, err := s.postRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
+	if !canView(post, viewerID, isAdmin) {
+		return nil, apperrors.NotFound("post not found")
+	}
 
 	response := s.enrichPostResponse(post)
+	if err := s.attachModerationNote(&response, post, viewerID, isAdmin); err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
-func (s *postService) GetBySlug(slug string) (*models.PostResponse, error) {
+// GetBySlug is like GetByID but looks the post up by slug. A slug the post
+// had before its title changed finds it too, and the response carries the
+// current slug.
+func (s *postService) GetBySlug(ctx context.Context, slug string, viewerID uint, isAdmin bool) (*models.PostResponse, error) {
+	s = s.withContext(ctx)
+
 	post, err := s.postRepo.GetBySlug(slug)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		post, err = s.postRepo.GetByFormerSlug(slug)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !canView(post, viewerID, isAdmin) {
+		return nil, apperrors.NotFound("post not found")
+	}
+
+	response := s.enrichPostResponse(post)
+	if err := s.attachModerationNote(&response, post, viewerID, isAdmin); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreatePreviewToken mints a token that lets anyone holding it read the post,
+// whatever its status, until the token expires. Only the post's author and
+// admins can share a preview.
+func (s *postService) CreatePreviewToken(postID, userID uint, isAdmin bool) (*models.PreviewTokenResponse, error) {
+	post, err := s.postRepo.GetAccess(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && post.AuthorID != userID {
+		return nil, apperrors.Forbidden("unauthorized: you can only share previews of your own posts")
+	}
+
+	token, expiresAt, err := utils.GeneratePreviewToken(post.ID, &s.jwt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create preview token: %w", err)
+	}
+	return &models.PreviewTokenResponse{PostID: post.ID, Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// GetByPreviewToken returns the post a preview token was minted for
+func (s *postService) GetByPreviewToken(token string) (*models.PostResponse, error) {
+	claims, err := utils.ValidatePreviewToken(token, &s.jwt)
+	if err != nil {
+		return nil, apperrors.Unauthorized("preview token is invalid or has expired")
+	}
+
+	post, err := s.postRepo.GetByID(claims.PostID)
 	if err != nil {
 		return nil, err
 	}
@@ -122,10 +443,82 @@ func (s *postService) GetBySlug(slug string) (*models.PostResponse, error) {
 	return &response, nil
 }
 
+// GetTags returns the tags of a post visible to viewerID, each with the
+// number of published posts using it, without loading the post itself
+func (s *postService) GetTags(ctx context.Context, postID, viewerID uint, isAdmin bool) ([]models.TagResponse, error) {
+	s = s.withContext(ctx)
+
+	post, err := s.postRepo.GetAccess(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView(post, viewerID, isAdmin) {
+		return nil, apperrors.NotFound("post not found")
+	}
+
+	tags, err := s.postRepo.GetTags(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	tagIDs := make([]uint, len(tags))
+	for i, tag := range tags {
+		tagIDs[i] = tag.ID
+	}
+	counts, err := s.tagRepo.CountPublishedPosts(tagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.TagResponse, len(tags))
+	for i, tag := range tags {
+		responses[i] = tag.ToResponse()
+		responses[i].PostsCount = int(counts[tag.ID])
+	}
+	return responses, nil
+}
+
+// GetCommenters returns the users who have approved comments on a post
+// visible to viewerID, each with their number of comments, most active first
+func (s *postService) GetCommenters(ctx context.Context, postID, viewerID uint, isAdmin bool) ([]models.CommenterResponse, error) {
+	s = s.withContext(ctx)
+
+	post, err := s.postRepo.GetAccess(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView(post, viewerID, isAdmin) {
+		return nil, apperrors.NotFound("post not found")
+	}
+
+	commenters, err := s.commentRepo.DistinctCommentersByPost(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.CommenterResponse, len(commenters))
+	for i, commenter := range commenters {
+		responses[i] = commenter.ToResponse()
+	}
+	return responses, nil
+}
+
+// canView reports whether viewerID may read post. Drafts and archived posts
+// are hidden from everyone but their authors and admins, and reported as not
+// found so their existence isn't leaked. Co-authors only count when post
+// was loaded with them.
+func canView(post *models.Post, viewerID uint, isAdmin bool) bool {
+	return post.Status == models.PostStatusPublished || isAdmin || post.IsEditor(viewerID)
+}
+
 func (s *postService) Update(postID, authorID uint, req *models.PostUpdateRequest, isAdmin bool) (*models.PostResponse, error) {
 	// Validate request
-	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
-		return nil, fmt.Errorf("validation failed: %v", validationErrors)
+	validationErrors := utils.ValidateStruct(req)
+	if req.Content != nil {
+		validationErrors = append(validationErrors, utils.ValidateMaxLength("Content", *req.Content, s.content.MaxPostLength)...)
+	}
+	if len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
 	}
 
 	// Get existing post
@@ -133,58 +526,71 @@ func (s *postService) Update(postID, authorID uint, req *models.PostUpdateReques
 	if err != nil {
 		return nil, err
 	}
+	oldSlug := post.Slug
 
-	// Check ownership (only author or admin can update)
-	if !isAdmin && post.AuthorID != authorID {
-		return nil, errors.New("unauthorized: you can only update your own posts")
+	// Check ownership (only authors, co-authors included, or admins can update)
+	if !isAdmin && !post.IsEditor(authorID) {
+		return nil, apperrors.Forbidden("unauthorized: you can only update your own posts")
+	}
+
+	tagIDs, err := s.validateTagIDs(req.TagIDs)
+	if err != nil {
+		return nil, err
 	}
 
 	// Update fields
-	if req.Title != "" {
-		post.Title = utils.SanitizeText(req.Title)
+	if req.Title != nil {
+		post.Title = utils.SanitizeText(*req.Title)
 
 		// Regenerate slug if title changed
-		newSlug := utils.GenerateSlug(req.Title)
+		newSlug := utils.GenerateSlug(*req.Title)
 		if newSlug != post.Slug && !s.postRepo.IsSlugTaken(newSlug, postID) {
 			post.Slug = newSlug
 		}
 	}
 
-	if req.Content != "" {
-		post.Content = req.Content
+	if req.Content != nil {
+		post.Content = s.sanitizeContent(*req.Content)
 	}
 
-	if req.Excerpt != "" {
-		post.Excerpt = utils.SanitizeText(req.Excerpt)
-	} else if req.Content != "" {
+	if req.Excerpt != nil {
+		post.Excerpt = utils.SanitizeText(*req.Excerpt)
+	} else if req.Content != nil {
 		// Auto-generate excerpt from content
-		post.Excerpt = utils.ExtractExcerpt(req.Content, 200)
+		post.Excerpt = utils.ExtractExcerpt(post.Content, 200)
 	}
 
-	if req.FeaturedImg != "" {
-		post.FeaturedImg = req.FeaturedImg
+	if req.FeaturedImg != nil {
+		post.FeaturedImg = *req.FeaturedImg
 	}
 
 	// Handle status change
-	if req.Status != "" && req.Status != post.Status {
-		post.Status = req.Status
-
-		// Set published date when publishing
-		if req.Status == models.PostStatusPublished && post.PublishedAt == nil {
-			now := time.Now()
-			post.PublishedAt = &now
-		}
+	published := false
+	if req.Status != nil {
+		published = post.SetStatus(*req.Status)
 	}
 
-	if err := s.postRepo.Update(post); err != nil {
-		return nil, fmt.Errorf("failed to update post: %w", err)
-	}
-
-	// Update tags if provided
-	if len(req.TagIDs) > 0 {
-		if err := s.postRepo.UpdateTags(post.ID, req.TagIDs); err != nil {
-			fmt.Printf("Warning: Failed to update tags for post: %v\n", err)
+	// The fields and tags change together or not at all
+	err = s.postRepo.Transaction(func(repo repository.PostRepository) error {
+		if err := repo.Update(post); err != nil {
+			return err
 		}
+		// Keep links to the old slug working
+		if post.Slug != oldSlug {
+			if err := repo.RecordSlugChange(post.ID, oldSlug, post.Slug); err != nil {
+				return fmt.Errorf("failed to record slug history: %w", err)
+			}
+		}
+		// Update tags if provided; an empty list removes them all
+		if req.TagIDs != nil {
+			if err := repo.UpdateTags(post.ID, tagIDs); err != nil {
+				return fmt.Errorf("failed to update tags: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
 	}
 
 	// Get updated post with relationships
@@ -194,6 +600,9 @@ func (s *postService) Update(postID, authorID uint, req *models.PostUpdateReques
 	}
 
 	response := s.enrichPostResponse(updatedPost)
+	if published {
+		s.webhooks.Dispatch(webhook.EventPostPublished, response)
+	}
 	return &response, nil
 }
 
@@ -204,93 +613,184 @@ func (s *postService) Delete(postID, authorID uint, isAdmin bool) error {
 		return err
 	}
 
-	// Check ownership (only author or admin can delete)
-	if !isAdmin && post.AuthorID != authorID {
-		return errors.New("unauthorized: you can only delete your own posts")
+	// Check ownership (only authors, co-authors included, or admins can delete)
+	if !isAdmin && !post.IsEditor(authorID) {
+		return apperrors.Forbidden("unauthorized: you can only delete your own posts")
 	}
 
 	return s.postRepo.Delete(postID)
 }
 
-func (s *postService) GetPosts(page, perPage int, status models.PostStatus, authorID uint) ([]models.PostListResponse, models.PaginationMeta, error) {
+func (s *postService) GetPosts(ctx context.Context, filter models.PostFilter, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
+	if err := validatePublishedRange(filter.PublishedRange); err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+
 	offset := (page - 1) * perPage
-	posts, total, err := s.postRepo.List(offset, perPage, status, authorID)
+	posts, total, err := s.postRepo.List(filter, offset, perPage)
 	if err != nil {
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.PostListResponse
-	for _, post := range posts {
-		response := s.enrichPostListResponse(&post)
-		responses = append(responses, response)
-	}
+	responses := s.enrichPostListResponses(posts)
 
 	pagination := utils.CalculatePagination(page, perPage, total)
 	return responses, pagination, nil
 }
 
-func (s *postService) GetPublishedPosts(page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+func (s *postService) GetPublishedPosts(ctx context.Context, sort models.PostSort, published models.PublishedRange, featuredFirst bool, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
+	if err := validatePostSort(sort); err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+	if err := validatePublishedRange(published); err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+
 	offset := (page - 1) * perPage
-	posts, total, err := s.postRepo.GetPublished(offset, perPage)
+	posts, total, err := s.postRepo.GetPublished(sort, published, featuredFirst, offset, perPage)
 	if err != nil {
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.PostListResponse
-	for _, post := range posts {
-		response := s.enrichPostListResponse(&post)
-		responses = append(responses, response)
-	}
+	responses := s.enrichPostListResponses(posts)
 
 	pagination := utils.CalculatePagination(page, perPage, total)
 	return responses, pagination, nil
 }
 
-func (s *postService) GetPostsByAuthor(authorID uint, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+// GetFeaturedPosts returns the published featured posts in their featured
+// order
+func (s *postService) GetFeaturedPosts(ctx context.Context, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
 	offset := (page - 1) * perPage
-	posts, total, err := s.postRepo.GetByAuthor(authorID, offset, perPage)
+	posts, total, err := s.postRepo.GetFeatured(offset, perPage)
 	if err != nil {
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.PostListResponse
+	responses := s.enrichPostListResponses(posts)
+
+	pagination := utils.CalculatePagination(page, perPage, total)
+	return responses, pagination, nil
+}
+
+// GetLatestPublished returns the most recently published posts with their
+// full content, for feeds
+func (s *postService) GetLatestPublished(ctx context.Context, limit int) ([]models.PostResponse, error) {
+	s = s.withContext(ctx)
+
+	posts, _, err := s.postRepo.GetPublished(models.PostSortNewest, models.PublishedRange{}, false, 0, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.PostResponse, 0, len(posts))
 	for _, post := range posts {
-		response := s.enrichPostListResponse(&post)
+		response := post.ToResponse()
+		response.ContentHTML = s.renderContent(&post)
+		for _, tag := range post.Tags {
+			response.Tags = append(response.Tags, tag.ToResponse())
+		}
 		responses = append(responses, response)
 	}
+	return responses, nil
+}
+
+func (s *postService) GetPublishedPostsByCursor(ctx context.Context, cursor string, published models.PublishedRange, perPage int) ([]models.PostListResponse, models.CursorPaginationMeta, error) {
+	s = s.withContext(ctx)
+
+	if err := validatePublishedRange(published); err != nil {
+		return nil, models.CursorPaginationMeta{}, err
+	}
+
+	var publishedAt *time.Time
+	var lastID uint
+	if cursor != "" {
+		at, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return nil, models.CursorPaginationMeta{}, err
+		}
+		publishedAt = &at
+		lastID = id
+	}
+
+	// Fetch one extra row to know whether another page exists
+	posts, err := s.postRepo.GetPublishedAfterCursor(published, publishedAt, lastID, perPage+1)
+	if err != nil {
+		return nil, models.CursorPaginationMeta{}, err
+	}
+
+	meta := models.CursorPaginationMeta{PerPage: perPage}
+	if len(posts) > perPage {
+		posts = posts[:perPage]
+		meta.HasMore = true
+	}
+
+	responses := s.enrichPostListResponses(posts)
+
+	if meta.HasMore {
+		last := posts[len(posts)-1]
+		meta.NextCursor = utils.EncodeCursor(*last.PublishedAt, last.ID)
+	}
+
+	return responses, meta, nil
+}
+
+func (s *postService) GetPostsByAuthor(ctx context.Context, authorID uint, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
+	offset := (page - 1) * perPage
+	posts, total, err := s.postRepo.GetByAuthor(authorID, offset, perPage)
+	if err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+
+	responses := s.enrichPostListResponses(posts)
 
 	pagination := utils.CalculatePagination(page, perPage, total)
 	return responses, pagination, nil
 }
 
-func (s *postService) GetPostsByTag(tagID uint, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+func (s *postService) GetPostsByTag(ctx context.Context, tagID uint, sort models.PostSort, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
+	if err := validatePostSort(sort); err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+
 	offset := (page - 1) * perPage
-	posts, total, err := s.postRepo.GetByTag(tagID, offset, perPage)
+	posts, total, err := s.postRepo.GetByTag(tagID, sort, offset, perPage)
 	if err != nil {
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.PostListResponse
-	for _, post := range posts {
-		response := s.enrichPostListResponse(&post)
-		responses = append(responses, response)
-	}
+	responses := s.enrichPostListResponses(posts)
 
 	pagination := utils.CalculatePagination(page, perPage, total)
 	return responses, pagination, nil
 }
 
-func (s *postService) SearchPosts(query string, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+func (s *postService) SearchPosts(ctx context.Context, query string, highlight bool, page, perPage int) ([]models.PostListResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
 	offset := (page - 1) * perPage
 	posts, total, err := s.postRepo.Search(query, offset, perPage)
 	if err != nil {
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.PostListResponse
-	for _, post := range posts {
-		response := s.enrichPostListResponse(&post)
-		responses = append(responses, response)
+	responses := s.enrichPostListResponses(posts)
+	if highlight {
+		for i, post := range posts {
+			snippet, position := utils.HighlightSnippet(post.Content, query, searchHighlightRadius)
+			responses[i].MatchExcerpt = snippet
+			responses[i].MatchPosition = &position
+		}
 	}
 
 	pagination := utils.CalculatePagination(page, perPage, total)
@@ -301,6 +801,80 @@ func (s *postService) IncrementViewCount(id uint) error {
 	return s.postRepo.IncrementViewCount(id)
 }
 
+// AttachComments loads a post's approved comments into its response
+func (s *postService) AttachComments(ctx context.Context, post *models.PostResponse) error {
+	s = s.withContext(ctx)
+
+	comments, err := s.commentRepo.GetApprovedByPost(post.ID)
+	if err != nil {
+		return err
+	}
+
+	// Count from the list itself so the counts always describe exactly the
+	// comments returned, even if one was approved since they were counted
+	post.Comments = make([]models.CommentResponse, len(comments))
+	post.CommentsCount = len(comments)
+	post.RepliesCount = 0
+	for i, comment := range comments {
+		post.Comments[i] = comment.ToResponse()
+		if comment.ParentID != nil {
+			post.RepliesCount++
+		}
+	}
+	return nil
+}
+
+// GetArchive returns the number of published posts in each month, newest
+// first
+func (s *postService) GetArchive() ([]models.ArchiveCount, error) {
+	counts, err := s.postRepo.ArchiveCounts()
+	if err != nil {
+		return nil, err
+	}
+	if counts == nil {
+		counts = []models.ArchiveCount{}
+	}
+	return counts, nil
+}
+
+// GetAuthorStats aggregates an author's post counts, views and comments
+func (s *postService) GetAuthorStats(authorID uint) (*models.AuthorStatsResponse, error) {
+	counts, err := s.postRepo.CountByAuthorAndStatus(authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count posts: %w", err)
+	}
+
+	views, err := s.postRepo.SumViewsByAuthor(authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum views: %w", err)
+	}
+
+	received, err := s.commentRepo.CountApprovedOnAuthorPosts(authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count comments received: %w", err)
+	}
+
+	authored, err := s.commentRepo.CountByAuthor(authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count comments authored: %w", err)
+	}
+
+	stats := &models.AuthorStatsResponse{
+		Posts: models.AuthorPostCounts{
+			Draft:     counts[models.PostStatusDraft],
+			Published: counts[models.PostStatusPublished],
+			Archived:  counts[models.PostStatusArchived],
+		},
+		TotalViews:               views,
+		ApprovedCommentsReceived: received,
+		CommentsAuthored:         authored,
+	}
+	for _, count := range counts {
+		stats.Posts.Total += count
+	}
+	return stats, nil
+}
+
 func (s *postService) Publish(postID, authorID uint, isAdmin bool) (*models.PostResponse, error) {
 	post, err := s.postRepo.GetByID(postID)
 	if err != nil {
@@ -308,49 +882,283 @@ func (s *postService) Publish(postID, authorID uint, isAdmin bool) (*models.Post
 	}
 
 	// Check ownership
-	if !isAdmin && post.AuthorID != authorID {
-		return nil, errors.New("unauthorized: you can only publish your own posts")
+	if !isAdmin && !post.IsEditor(authorID) {
+		return nil, apperrors.Forbidden("unauthorized: you can only publish your own posts")
 	}
 
-	post.Status = models.PostStatusPublished
-	if post.PublishedAt == nil {
-		now := time.Now()
-		post.PublishedAt = &now
-	}
+	published := post.SetStatus(models.PostStatusPublished)
 
 	if err := s.postRepo.Update(post); err != nil {
 		return nil, fmt.Errorf("failed to publish post: %w", err)
 	}
 
 	response := s.enrichPostResponse(post)
+	if published {
+		s.webhooks.Dispatch(webhook.EventPostPublished, response)
+	}
 	return &response, nil
 }
 
-func (s *postService) Unpublish(postID, authorID uint, isAdmin bool) (*models.PostResponse, error) {
+// Feature pins a post among the featured posts, or moves it there if it
+// already is. A draft can be featured ahead of time; it is only listed once
+// published.
+func (s *postService) Feature(postID uint, req *models.PostFeatureRequest) (*models.PostResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
 	post, err := s.postRepo.GetByID(postID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check ownership
-	if !isAdmin && post.AuthorID != authorID {
-		return nil, errors.New("unauthorized: you can only unpublish your own posts")
+	post.Featured = true
+	post.FeaturedOrder = req.Order
+	if err := s.postRepo.Update(post); err != nil {
+		return nil, fmt.Errorf("failed to feature post: %w", err)
 	}
 
-	post.Status = models.PostStatusDraft
+	response := s.enrichPostResponse(post)
+	return &response, nil
+}
+
+// Unfeature takes a post off the featured posts
+func (s *postService) Unfeature(postID uint) (*models.PostResponse, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
 
+	post.Featured = false
+	post.FeaturedOrder = 0
 	if err := s.postRepo.Update(post); err != nil {
+		return nil, fmt.Errorf("failed to unfeature post: %w", err)
+	}
+
+	response := s.enrichPostResponse(post)
+	return &response, nil
+}
+
+// AddCoAuthor gives another user edit rights on a post. Only the post's
+// author and admins can add co-authors.
+func (s *postService) AddCoAuthor(postID, userID uint, isAdmin bool, req *models.PostCoAuthorRequest) (*models.PostResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && post.AuthorID != userID {
+		return nil, apperrors.Forbidden("unauthorized: only the post's author can add co-authors")
+	}
+	if req.UserID == post.AuthorID {
+		return nil, apperrors.Validation("the post's author can't also be a co-author")
+	}
+	if post.IsEditor(req.UserID) {
+		return nil, apperrors.Conflict("user is already a co-author")
+	}
+
+	if err := s.postRepo.AddCoAuthor(post.ID, req.UserID); err != nil {
+		return nil, err
+	}
+	return s.reloadPost(post.ID)
+}
+
+// RemoveCoAuthor takes away a co-author's edit rights on a post. Only the
+// post's author and admins can remove co-authors.
+func (s *postService) RemoveCoAuthor(postID, userID uint, isAdmin bool, coAuthorID uint) (*models.PostResponse, error) {
+	post, err := s.postRepo.GetAccess(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && post.AuthorID != userID {
+		return nil, apperrors.Forbidden("unauthorized: only the post's author can remove co-authors")
+	}
+
+	if err := s.postRepo.RemoveCoAuthor(post.ID, coAuthorID); err != nil {
+		return nil, err
+	}
+	return s.reloadPost(post.ID)
+}
+
+// reloadPost returns a post after its associations changed
+func (s *postService) reloadPost(postID uint) (*models.PostResponse, error) {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve updated post: %w", err)
+	}
+	response := s.enrichPostResponse(post)
+	return &response, nil
+}
+
+// Unpublish moves a post back to draft. Like Archive, it keeps the post's
+// PublishedAt, so publishing it again restores its original date. An admin
+// unpublishing someone else's post may give the author a reason.
+func (s *postService) Unpublish(postID, authorID uint, isAdmin bool, req *models.PostModerationRequest) (*models.PostResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check ownership
+	if !isAdmin && !post.IsEditor(authorID) {
+		return nil, apperrors.Forbidden("unauthorized: you can only unpublish your own posts")
+	}
+
+	post.SetStatus(models.PostStatusDraft)
+
+	entry, err := s.saveModerated(post, authorID, models.PostModerationUnpublish, req.Reason)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unpublish post: %w", err)
 	}
 
 	response := s.enrichPostResponse(post)
+	response.ModerationNote = entry
+	return &response, nil
+}
+
+// Archive takes a post out of every public listing without deleting it. The
+// post keeps its PublishedAt so publishing it again restores its original
+// place in the timeline. An admin archiving someone else's post may give the
+// author a reason.
+func (s *postService) Archive(postID, authorID uint, isAdmin bool, req *models.PostModerationRequest) (*models.PostResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check ownership
+	if !isAdmin && !post.IsEditor(authorID) {
+		return nil, apperrors.Forbidden("unauthorized: you can only archive your own posts")
+	}
+
+	post.SetStatus(models.PostStatusArchived)
+
+	entry, err := s.saveModerated(post, authorID, models.PostModerationArchive, req.Reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive post: %w", err)
+	}
+
+	response := s.enrichPostResponse(post)
+	response.ModerationNote = entry
 	return &response, nil
 }
 
+// saveModerated saves a post whose status userID changed. When that is an
+// admin acting on someone else's post, the action goes into the post's
+// moderation log in the same transaction and the author is emailed the
+// reason; the log entry is returned. Authors and co-authors changing their
+// own posts aren't moderated and get nil.
+func (s *postService) saveModerated(post *models.Post, userID uint, action models.PostModerationAction, reason string) (*models.PostModerationResponse, error) {
+	if post.IsEditor(userID) {
+		return nil, s.postRepo.Update(post)
+	}
+
+	entry := &models.PostModerationLog{
+		PostID:      post.ID,
+		ModeratorID: userID,
+		Action:      action,
+		Reason:      utils.SanitizeText(reason),
+	}
+	err := s.postRepo.Transaction(func(repo repository.PostRepository) error {
+		if err := repo.Update(post); err != nil {
+			return err
+		}
+		return repo.AddModerationLog(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyModerated(post, entry)
+	response := entry.ToResponse()
+	return &response, nil
+}
+
+// moderationVerbs describe moderation actions in emails to authors
+var moderationVerbs = map[models.PostModerationAction]string{
+	models.PostModerationUnpublish: "unpublished",
+	models.PostModerationArchive:   "archived",
+}
+
+// notifyModerated emails the author of a moderated post. The action has
+// already been saved, so a failed email is only logged.
+func (s *postService) notifyModerated(post *models.Post, entry *models.PostModerationLog) {
+	if post.Author.Email == "" {
+		return
+	}
+
+	verb := moderationVerbs[entry.Action]
+	body := fmt.Sprintf("Hi %s,\n\nAn administrator %s your post \"%s\".\n", post.Author.FirstName, verb, post.Title)
+	if entry.Reason != "" {
+		body += fmt.Sprintf("\nReason: %s\n", entry.Reason)
+	}
+	body += "\nThe post is kept as it was and you can still edit it.\n"
+
+	msg := mailer.Message{
+		To:      post.Author.Email,
+		Subject: fmt.Sprintf("Your post \"%s\" was %s", post.Title, verb),
+		Body:    body,
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		log.Printf("Warning: failed to notify author of moderated post %d: %v", post.ID, err)
+	}
+}
+
+// attachModerationNote adds the latest moderation action to the response
+// for a post that is not published, when the viewer is its author, a
+// co-author or an admin
+func (s *postService) attachModerationNote(response *models.PostResponse, post *models.Post, viewerID uint, isAdmin bool) error {
+	if post.Status == models.PostStatusPublished || (!isAdmin && !post.IsEditor(viewerID)) {
+		return nil
+	}
+
+	entry, err := s.postRepo.GetLatestModerationLog(response.ID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load moderation note: %w", err)
+	}
+
+	note := entry.ToResponse()
+	response.ModerationNote = &note
+	return nil
+}
+
+// GetModerationLog returns every moderation action on a post, newest first
+func (s *postService) GetModerationLog(postID uint) ([]models.PostModerationResponse, error) {
+	if _, err := s.postRepo.GetAccess(postID); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.postRepo.GetModerationLogs(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load moderation log: %w", err)
+	}
+
+	responses := make([]models.PostModerationResponse, 0, len(entries))
+	for _, entry := range entries {
+		responses = append(responses, entry.ToResponse())
+	}
+	return responses, nil
+}
+
 // Helper methods
 
 func (s *postService) enrichPostResponse(post *models.Post) models.PostResponse {
 	response := post.ToResponse()
+	response.ContentHTML = s.renderContent(post)
 
 	// Add tags
 	var tagResponses []models.TagResponse
@@ -359,26 +1167,218 @@ func (s *postService) enrichPostResponse(post *models.Post) models.PostResponse
 	}
 	response.Tags = tagResponses
 
-	// Add comment count
+	// Add comment counts
 	commentCount, _ := s.commentRepo.CountByPost(post.ID)
 	response.CommentsCount = int(commentCount)
+	repliesCount, _ := s.commentRepo.CountRepliesByPost(post.ID)
+	response.RepliesCount = int(repliesCount)
+
+	// Add series navigation
+	if post.Series != nil {
+		response.Series = &models.PostSeriesResponse{ID: post.Series.ID, Title: post.Series.Title, Slug: post.Series.Slug}
+		previous, next, _ := s.postRepo.GetSeriesNeighbours(post)
+		if previous != nil {
+			response.Series.Previous = &models.SeriesPostLink{ID: previous.ID, Title: previous.Title, Slug: previous.Slug}
+		}
+		if next != nil {
+			response.Series.Next = &models.SeriesPostLink{ID: next.ID, Title: next.Title, Slug: next.Slug}
+		}
+	}
 
 	return response
 }
 
-func (s *postService) enrichPostListResponse(post *models.Post) models.PostListResponse {
-	response := post.ToListResponse()
+// contentPolicy returns the content policy for posts by trusted authors
+// (admins) or by everyone else
+func (s *postService) contentPolicy(trusted bool) utils.ContentPolicy {
+	return utils.ContentPolicy{
+		AllowImages:  s.content.AllowImages,
+		AllowIframes: trusted && s.content.TrustedIframes,
+		IframeHosts:  s.content.IframeHosts,
+	}
+}
+
+// sanitizeContent cleans Markdown before it is stored. It keeps what a
+// trusted author may use, since rendering applies the author's own policy.
+func (s *postService) sanitizeContent(content string) string {
+	return utils.SanitizeMarkdown(content, s.contentPolicy(true))
+}
 
-	// Add tags
-	var tagResponses []models.TagResponse
-	for _, tag := range post.Tags {
-		tagResponses = append(tagResponses, tag.ToResponse())
+// renderContent renders a post's content under its author's policy
+func (s *postService) renderContent(post *models.Post) string {
+	return utils.RenderContent(post.Content, s.contentPolicy(post.Author.IsAdmin))
+}
+
+// enrichPostListResponses converts a page of posts to list responses,
+// fetching comment counts for the whole page in a single query
+func (s *postService) enrichPostListResponses(posts []models.Post) []models.PostListResponse {
+	return postListResponses(s.commentRepo, posts)
+}
+
+// postListResponses converts posts loaded with their tags to list
+// responses with their comment counts
+func postListResponses(commentRepo repository.CommentRepository, posts []models.Post) []models.PostListResponse {
+	postIDs := make([]uint, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
 	}
-	response.Tags = tagResponses
+	commentCounts, _ := commentRepo.CountByPosts(postIDs)
 
-	// Add comment count
-	commentCount, _ := s.commentRepo.CountByPost(post.ID)
-	response.CommentsCount = int(commentCount)
+	responses := make([]models.PostListResponse, 0, len(posts))
+	for _, post := range posts {
+		response := post.ToListResponse()
 
-	return response
+		// Add tags
+		var tagResponses []models.TagResponse
+		for _, tag := range post.Tags {
+			tagResponses = append(tagResponses, tag.ToResponse())
+		}
+		response.Tags = tagResponses
+
+		// Add comment count
+		response.CommentsCount = int(commentCounts[post.ID])
+
+		responses = append(responses, response)
+	}
+
+	return responses
+}
+
+// AttachTag adds a tag to each of the requested posts in one transaction, reporting per
+// post whether it was attached or already had the tag
+func (s *postService) AttachTag(tagID uint, req *models.TagPostsRequest) (*models.TagPostsResponse, error) {
+	return s.bulkTag(tagID, req, true)
+}
+
+// DetachTag removes a tag from each of the requested posts in one transaction,
+// reporting per post whether it was detached or didn't have the tag
+func (s *postService) DetachTag(tagID uint, req *models.TagPostsRequest) (*models.TagPostsResponse, error) {
+	return s.bulkTag(tagID, req, false)
+}
+
+// bulkTag attaches or detaches a tag across posts. Every post must exist;
+// posts that already are in the wanted state are left alone.
+func (s *postService) bulkTag(tagID uint, req *models.TagPostsRequest, attach bool) (*models.TagPostsResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
+	existing, err := s.tagRepo.GetExistingIDs([]uint{tagID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up tag: %w", err)
+	}
+	if len(existing) == 0 {
+		return nil, apperrors.NotFound("tag not found")
+	}
+
+	postIDs, err := s.validatePostIDs(req.PostIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.TagPostsResponse{TagID: tagID, Results: make([]models.TagPostResult, 0, len(postIDs))}
+	err = s.postRepo.Transaction(func(repo repository.PostRepository) error {
+		taggedIDs, err := repo.GetIDsWithTag(tagID, postIDs)
+		if err != nil {
+			return err
+		}
+		tagged := make(map[uint]bool, len(taggedIDs))
+		for _, id := range taggedIDs {
+			tagged[id] = true
+		}
+
+		for _, postID := range postIDs {
+			var result models.TagPostOutcome
+			switch {
+			case attach && tagged[postID]:
+				result = models.TagPostAlreadyAttached
+			case attach:
+				if err := repo.AddTags(postID, []uint{tagID}); err != nil {
+					return err
+				}
+				result = models.TagPostAttached
+			case !tagged[postID]:
+				result = models.TagPostNotAttached
+			default:
+				if err := repo.RemoveTags(postID, []uint{tagID}); err != nil {
+					return err
+				}
+				result = models.TagPostDetached
+			}
+			response.Results = append(response.Results, models.TagPostResult{PostID: postID, Result: result})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post tags: %w", err)
+	}
+
+	response.PostsCount, err = s.tagRepo.CountPosts(tagID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tag posts: %w", err)
+	}
+	return response, nil
+}
+
+// validateTagIDs de-duplicates the requested tag IDs, keeping their order,
+// and rejects the request if any of them doesn't exist
+func (s *postService) validateTagIDs(ids []uint) ([]uint, error) {
+	return validateIDs(ids, "tag", s.tagRepo.GetExistingIDs)
+}
+
+// validatePostIDs is validateTagIDs for post IDs
+func (s *postService) validatePostIDs(ids []uint) ([]uint, error) {
+	return validateIDs(ids, "post", s.postRepo.GetExistingIDs)
+}
+
+// validateIDs de-duplicates ids, keeping their order, and rejects them if
+// getExisting doesn't find them all. kind names the resource in errors.
+func validateIDs(ids []uint, kind string, getExisting func(ids []uint) ([]uint, error)) ([]uint, error) {
+	unique := make([]uint, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return unique, nil
+	}
+
+	existing, err := getExisting(unique)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %ss: %w", kind, err)
+	}
+	found := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		found[id] = true
+	}
+
+	var invalid []uint
+	for _, id := range unique {
+		if !found[id] {
+			invalid = append(invalid, id)
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, apperrors.Validation(fmt.Sprintf("invalid %s IDs: %v", kind, invalid))
+	}
+	return unique, nil
+}
+
+// validatePostSort rejects sort values the listings don't support
+func validatePostSort(sort models.PostSort) error {
+	if !sort.IsValid() {
+		return apperrors.BadRequest(fmt.Sprintf("invalid sort %q: must be %s, %s, %s or %s", sort, models.PostSortNewest, models.PostSortOldest, models.PostSortPopular, models.PostSortComments))
+	}
+	return nil
+}
+
+// validatePublishedRange rejects a range whose start is after its end
+func validatePublishedRange(published models.PublishedRange) error {
+	if published.After != nil && published.Before != nil && published.After.After(*published.Before) {
+		return apperrors.BadRequest("published_after must not be later than published_before")
+	}
+	return nil
 }
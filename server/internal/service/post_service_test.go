@@ -0,0 +1,1315 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// recordedEvent is a webhook event captured by fakeWebhooks
+type recordedEvent struct {
+	Event webhook.Event
+	Data  interface{}
+}
+
+// fakeWebhooks records dispatched webhook events instead of delivering them
+type fakeWebhooks struct {
+	mu     sync.Mutex
+	events []recordedEvent
+}
+
+func (f *fakeWebhooks) Dispatch(event webhook.Event, data interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, recordedEvent{Event: event, Data: data})
+}
+
+func (f *fakeWebhooks) Wait() {}
+
+func (f *fakeWebhooks) Close(context.Context) error { return nil }
+
+// ptr returns a pointer to v, for optional request fields
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// tagIDsOf returns the IDs of the post's tags
+func tagIDsOf(post *models.PostResponse) []uint {
+	var ids []uint
+	for _, tag := range post.Tags {
+		ids = append(ids, tag.ID)
+	}
+	return ids
+}
+
+// newTestPostService wires a post service against an in-memory database
+func newTestPostService(t *testing.T) (service.PostService, *gorm.DB) {
+	t.Helper()
+
+	svc, db, _ := newTestPostServiceWithWebhooks(t)
+	return svc, db
+}
+
+// newTestPostServiceWithWebhooks is newTestPostService that also returns the
+// webhook events the service dispatches
+func newTestPostServiceWithWebhooks(t *testing.T) (service.PostService, *gorm.DB, *fakeWebhooks) {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	webhooks := &fakeWebhooks{}
+	svc := service.NewPostService(
+		repository.NewPostRepository(db),
+		repository.NewTagRepository(db),
+		repository.NewCommentRepository(db),
+		webhooks,
+		&fakeMailer{},
+		embedsForAdmins,
+		previewTokens,
+		nil,
+	)
+	return svc, db, webhooks
+}
+
+// previewTokens signs draft preview tokens valid for an hour
+var previewTokens = config.JWTConfig{Secret: "test-secret-key", PreviewExpiresIn: time.Hour}
+
+// embedsForAdmins allows images for everyone and YouTube embeds for admins
+var embedsForAdmins = config.ContentConfig{
+	AllowImages:    true,
+	TrustedIframes: true,
+	IframeHosts:    []string{"www.youtube.com"},
+}
+
+func TestPostService_Create_ConcurrentSlugs(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "slugracer")
+
+	const writers = 20
+	var wg sync.WaitGroup
+	slugs := make([]string, writers)
+	errs := make([]error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			post, err := svc.Create(author.ID, &models.PostCreateRequest{
+				Title:   "Same Title Everywhere",
+				Content: fmt.Sprintf("Body number %d of the same title", i),
+				Status:  models.PostStatusDraft,
+			})
+			errs[i] = err
+			if err == nil {
+				slugs[i] = post.Slug
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, writers)
+	for i := 0; i < writers; i++ {
+		require.NoError(t, errs[i], "writer %d", i)
+		assert.False(t, seen[slugs[i]], "duplicate slug %q", slugs[i])
+		seen[slugs[i]] = true
+	}
+	assert.True(t, seen["same-title-everywhere"])
+}
+
+func TestPostService_GetByID_DraftVisibility(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "draftauthor")
+	other := testutil.CreateUser(t, db, "snooper")
+
+	draft, err := svc.Create(author.ID, &models.PostCreateRequest{
+		Title:   "Unfinished thoughts",
+		Content: "Not ready for anyone else yet",
+		Status:  models.PostStatusDraft,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		viewerID uint
+		isAdmin  bool
+		visible  bool
+	}{
+		{"anonymous", 0, false, false},
+		{"other user", other.ID, false, false},
+		{"author", author.ID, false, true},
+		{"admin", other.ID, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			byID, err := svc.GetByID(context.Background(), draft.ID, tt.viewerID, tt.isAdmin)
+			bySlug, slugErr := svc.GetBySlug(context.Background(), draft.Slug, tt.viewerID, tt.isAdmin)
+
+			if tt.visible {
+				require.NoError(t, err)
+				require.NoError(t, slugErr)
+				assert.Equal(t, draft.ID, byID.ID)
+				assert.Equal(t, draft.ID, bySlug.ID)
+				return
+			}
+			assert.ErrorIs(t, err, apperrors.ErrNotFound)
+			assert.ErrorIs(t, slugErr, apperrors.ErrNotFound)
+		})
+	}
+}
+
+func TestPostService_Archive(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "archivist")
+	other := testutil.CreateUser(t, db, "notowner")
+
+	post, err := svc.Create(author.ID, &models.PostCreateRequest{
+		Title:   "Yesterday's news",
+		Content: "Once relevant, now archived",
+		Status:  models.PostStatusPublished,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, post.PublishedAt)
+
+	_, err = svc.Archive(post.ID, other.ID, false, &models.PostModerationRequest{})
+	require.ErrorIs(t, err, apperrors.ErrForbidden)
+
+	archived, err := svc.Archive(post.ID, author.ID, false, &models.PostModerationRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, models.PostStatusArchived, archived.Status)
+	require.NotNil(t, archived.PublishedAt)
+	assert.WithinDuration(t, *post.PublishedAt, *archived.PublishedAt, time.Second)
+
+	t.Run("excluded from public listings", func(t *testing.T) {
+		published, _, err := svc.GetPublishedPosts(context.Background(), "", models.PublishedRange{}, false, 1, 10)
+		require.NoError(t, err)
+		assert.Empty(t, published)
+
+		byAuthor, _, err := svc.GetPostsByAuthor(context.Background(), author.ID, 1, 10)
+		require.NoError(t, err)
+		assert.Empty(t, byAuthor)
+
+		found, _, err := svc.SearchPosts(context.Background(), "news", false, 1, 10)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+
+		_, err = svc.GetByID(context.Background(), post.ID, 0, false)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("listed as archived for the author", func(t *testing.T) {
+		posts, pagination, err := svc.GetPosts(context.Background(), models.PostFilter{Status: models.PostStatusArchived, AuthorID: author.ID}, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, pagination.Total)
+		require.Len(t, posts, 1)
+		assert.Equal(t, post.ID, posts[0].ID)
+	})
+
+	t.Run("publishing again keeps the original date", func(t *testing.T) {
+		republished, err := svc.Publish(post.ID, author.ID, false)
+		require.NoError(t, err)
+		assert.WithinDuration(t, *post.PublishedAt, *republished.PublishedAt, time.Second)
+	})
+}
+
+func TestPostService_Moderation(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	mail := &fakeMailer{}
+	svc := service.NewPostService(repository.NewPostRepository(db), repository.NewTagRepository(db), repository.NewCommentRepository(db), &fakeWebhooks{}, mail, embedsForAdmins, previewTokens, nil)
+	author := testutil.CreateUser(t, db, "moderated")
+	admin := testutil.CreateUser(t, db, "moderator")
+	reader := testutil.CreateUser(t, db, "bystander")
+	coAuthor := testutil.CreateUser(t, db, "accomplice")
+
+	create := func(title string) *models.PostResponse {
+		post, err := svc.Create(author.ID, &models.PostCreateRequest{Title: title, Content: "Content that breaks the rules", Status: models.PostStatusPublished})
+		require.NoError(t, err)
+		return post
+	}
+
+	post := create("Against the rules")
+	require.NoError(t, repository.NewPostRepository(db).AddCoAuthor(post.ID, coAuthor.ID))
+	unpublished, err := svc.Unpublish(post.ID, admin.ID, true, &models.PostModerationRequest{Reason: "Contains spam links"})
+	require.NoError(t, err)
+	assert.Equal(t, models.PostStatusDraft, unpublished.Status)
+	require.NotNil(t, unpublished.ModerationNote)
+	assert.Equal(t, models.PostModerationUnpublish, unpublished.ModerationNote.Action)
+
+	t.Run("reason is logged", func(t *testing.T) {
+		log, err := svc.GetModerationLog(post.ID)
+		require.NoError(t, err)
+		require.Len(t, log, 1)
+		assert.Equal(t, admin.ID, log[0].ModeratorID)
+		assert.Equal(t, models.PostModerationUnpublish, log[0].Action)
+		assert.Equal(t, "Contains spam links", log[0].Reason)
+	})
+
+	t.Run("author is notified", func(t *testing.T) {
+		require.Len(t, mail.sent, 1)
+		assert.Equal(t, author.Email, mail.sent[0].To)
+		assert.Contains(t, mail.sent[0].Subject, "unpublished")
+		assert.Contains(t, mail.sent[0].Body, "Reason: Contains spam links")
+	})
+
+	t.Run("note is shown to the authors and admins", func(t *testing.T) {
+		own, err := svc.GetByID(context.Background(), post.ID, author.ID, false)
+		require.NoError(t, err)
+		require.NotNil(t, own.ModerationNote)
+		assert.Equal(t, "Contains spam links", own.ModerationNote.Reason)
+
+		shared, err := svc.GetByID(context.Background(), post.ID, coAuthor.ID, false)
+		require.NoError(t, err)
+		assert.NotNil(t, shared.ModerationNote)
+
+		moderated, err := svc.GetByID(context.Background(), post.ID, reader.ID, true)
+		require.NoError(t, err)
+		assert.NotNil(t, moderated.ModerationNote)
+	})
+
+	t.Run("latest action wins", func(t *testing.T) {
+		_, err := svc.Archive(post.ID, admin.ID, true, &models.PostModerationRequest{})
+		require.NoError(t, err)
+		require.Len(t, mail.sent, 2)
+		assert.NotContains(t, mail.sent[1].Body, "Reason:")
+
+		own, err := svc.GetBySlug(context.Background(), post.Slug, author.ID, false)
+		require.NoError(t, err)
+		require.NotNil(t, own.ModerationNote)
+		assert.Equal(t, models.PostModerationArchive, own.ModerationNote.Action)
+		assert.Empty(t, own.ModerationNote.Reason)
+
+		log, err := svc.GetModerationLog(post.ID)
+		require.NoError(t, err)
+		require.Len(t, log, 2)
+		assert.Equal(t, models.PostModerationArchive, log[0].Action)
+	})
+
+	t.Run("note is dropped once republished", func(t *testing.T) {
+		_, err := svc.Publish(post.ID, author.ID, false)
+		require.NoError(t, err)
+
+		own, err := svc.GetByID(context.Background(), post.ID, author.ID, false)
+		require.NoError(t, err)
+		assert.Nil(t, own.ModerationNote)
+	})
+
+	t.Run("authors unpublishing their own posts aren't moderated", func(t *testing.T) {
+		own := create("Taken down by its author")
+		sent := len(mail.sent)
+
+		unpublished, err := svc.Unpublish(own.ID, author.ID, false, &models.PostModerationRequest{Reason: "Needs more work"})
+		require.NoError(t, err)
+		assert.Nil(t, unpublished.ModerationNote)
+		assert.Len(t, mail.sent, sent)
+
+		log, err := svc.GetModerationLog(own.ID)
+		require.NoError(t, err)
+		assert.Empty(t, log)
+	})
+}
+
+func TestPostService_RepublishKeepsPublishedAt(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "republisher")
+
+	original, err := svc.Create(author.ID, &models.PostCreateRequest{
+		Title:   "First edition",
+		Content: "Published, pulled, then published again",
+		Status:  models.PostStatusPublished,
+	})
+	require.NoError(t, err)
+
+	// Backdate the post so a newer one is published in between
+	firstPublished := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	require.NoError(t, db.Model(&models.Post{}).Where("id = ?", original.ID).Update("published_at", firstPublished).Error)
+	newer, err := svc.Create(author.ID, &models.PostCreateRequest{
+		Title:   "Second edition",
+		Content: "Published while the first was pulled",
+		Status:  models.PostStatusPublished,
+	})
+	require.NoError(t, err)
+
+	cycles := []struct {
+		name      string
+		unpublish func() (*models.PostResponse, error)
+		publish   func() (*models.PostResponse, error)
+	}{
+		{
+			"Unpublish and Publish",
+			func() (*models.PostResponse, error) {
+				return svc.Unpublish(original.ID, author.ID, false, &models.PostModerationRequest{})
+			},
+			func() (*models.PostResponse, error) { return svc.Publish(original.ID, author.ID, false) },
+		},
+		{
+			"status updates",
+			func() (*models.PostResponse, error) {
+				return svc.Update(original.ID, author.ID, &models.PostUpdateRequest{Status: ptr(models.PostStatusDraft)}, false)
+			},
+			func() (*models.PostResponse, error) {
+				return svc.Update(original.ID, author.ID, &models.PostUpdateRequest{Status: ptr(models.PostStatusPublished)}, false)
+			},
+		},
+	}
+
+	for _, cycle := range cycles {
+		t.Run(cycle.name, func(t *testing.T) {
+			draft, err := cycle.unpublish()
+			require.NoError(t, err)
+			assert.Equal(t, models.PostStatusDraft, draft.Status)
+			require.NotNil(t, draft.PublishedAt, "unpublishing keeps the publish date")
+			assert.WithinDuration(t, firstPublished, *draft.PublishedAt, time.Second)
+
+			published, _, err := svc.GetPublishedPosts(context.Background(), "", models.PublishedRange{}, false, 1, 10)
+			require.NoError(t, err)
+			require.Len(t, published, 1, "drafts must not be listed")
+
+			republished, err := cycle.publish()
+			require.NoError(t, err)
+			assert.Equal(t, models.PostStatusPublished, republished.Status)
+			require.NotNil(t, republished.PublishedAt)
+			assert.WithinDuration(t, firstPublished, *republished.PublishedAt, time.Second)
+
+			// The post is back in its original place, after the newer one
+			published, _, err = svc.GetPublishedPosts(context.Background(), "", models.PublishedRange{}, false, 1, 10)
+			require.NoError(t, err)
+			require.Len(t, published, 2)
+			assert.Equal(t, newer.ID, published[0].ID)
+			assert.Equal(t, original.ID, published[1].ID)
+		})
+	}
+}
+
+func TestPostService_Publish_DispatchesWebhook(t *testing.T) {
+	svc, db, webhooks := newTestPostServiceWithWebhooks(t)
+	author := testutil.CreateUser(t, db, "announcer")
+
+	post, err := svc.Create(author.ID, &models.PostCreateRequest{
+		Title:   "Going live",
+		Content: "Drafted first, published later",
+		Status:  models.PostStatusDraft,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, webhooks.events)
+
+	_, err = svc.Publish(post.ID, author.ID, false)
+	require.NoError(t, err)
+	require.Len(t, webhooks.events, 1)
+	assert.Equal(t, webhook.EventPostPublished, webhooks.events[0].Event)
+	published, ok := webhooks.events[0].Data.(models.PostResponse)
+	require.True(t, ok)
+	assert.Equal(t, post.ID, published.ID)
+
+	// Publishing an already published post isn't a new event
+	_, err = svc.Publish(post.ID, author.ID, false)
+	require.NoError(t, err)
+	assert.Len(t, webhooks.events, 1)
+}
+
+func TestPostService_GetAuthorStats(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "statsauthor")
+	reader := testutil.CreateUser(t, db, "statsreader")
+
+	create := func(authorID uint, slug string, status models.PostStatus, views int) *models.Post {
+		post := &models.Post{
+			Title:     "Stats " + slug,
+			Slug:      slug,
+			Content:   "Counting things",
+			Status:    status,
+			AuthorID:  authorID,
+			ViewCount: views,
+		}
+		require.NoError(t, db.Create(post).Error)
+		return post
+	}
+	comment := func(authorID uint, postID uint, status models.CommentStatus) {
+		require.NoError(t, db.Create(&models.Comment{
+			Content:  "Noted",
+			Status:   status,
+			AuthorID: &authorID,
+			PostID:   postID,
+		}).Error)
+	}
+
+	first := create(author.ID, "stats-first", models.PostStatusPublished, 10)
+	second := create(author.ID, "stats-second", models.PostStatusPublished, 15)
+	create(author.ID, "stats-draft", models.PostStatusDraft, 0)
+	create(author.ID, "stats-archived", models.PostStatusArchived, 100)
+	readerPost := create(reader.ID, "stats-readers", models.PostStatusPublished, 1000)
+
+	comment(reader.ID, first.ID, models.CommentStatusApproved)
+	comment(reader.ID, second.ID, models.CommentStatusApproved)
+	comment(reader.ID, second.ID, models.CommentStatusPending)
+	comment(author.ID, first.ID, models.CommentStatusApproved)
+	comment(author.ID, readerPost.ID, models.CommentStatusApproved)
+	comment(author.ID, readerPost.ID, models.CommentStatusRejected)
+
+	stats, err := svc.GetAuthorStats(author.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AuthorPostCounts{Total: 4, Draft: 1, Published: 2, Archived: 1}, stats.Posts)
+	assert.Equal(t, int64(25), stats.TotalViews, "only published posts count")
+	assert.Equal(t, int64(3), stats.ApprovedCommentsReceived)
+	assert.Equal(t, int64(3), stats.CommentsAuthored)
+
+	empty, err := svc.GetAuthorStats(testutil.CreateUser(t, db, "newauthor").ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AuthorStatsResponse{}, *empty)
+}
+
+func TestPostService_TagIDs(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "tagger")
+
+	tags := []models.Tag{{Name: "Go", Slug: "go"}, {Name: "Web", Slug: "web"}}
+	require.NoError(t, db.Create(&tags).Error)
+	goID, webID := tags[0].ID, tags[1].ID
+
+	t.Run("duplicates are attached once", func(t *testing.T) {
+		post, err := svc.Create(author.ID, &models.PostCreateRequest{
+			Title:   "Tagged twice",
+			Content: "Same tag, twice",
+			Status:  models.PostStatusDraft,
+			TagIDs:  []uint{goID, webID, goID},
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []uint{goID, webID}, tagIDsOf(post))
+	})
+
+	t.Run("unknown IDs reject the create", func(t *testing.T) {
+		_, err := svc.Create(author.ID, &models.PostCreateRequest{
+			Title:   "Bad tags",
+			Content: "Some of these tags don't exist",
+			Status:  models.PostStatusDraft,
+			TagIDs:  []uint{goID, 9998, 9999, 9998},
+		})
+		require.ErrorIs(t, err, apperrors.ErrValidation)
+		assert.Contains(t, err.Error(), "[9998 9999]")
+
+		var count int64
+		require.NoError(t, db.Model(&models.Post{}).Where("title = ?", "Bad tags").Count(&count).Error)
+		assert.Zero(t, count, "the post must not be created")
+	})
+
+	t.Run("unknown IDs reject the update", func(t *testing.T) {
+		post, err := svc.Create(author.ID, &models.PostCreateRequest{
+			Title:   "Retagged",
+			Content: "Tags change later",
+			Status:  models.PostStatusDraft,
+			TagIDs:  []uint{goID},
+		})
+		require.NoError(t, err)
+
+		_, err = svc.Update(post.ID, author.ID, &models.PostUpdateRequest{
+			Title:  ptr("Retagged with typos"),
+			TagIDs: []uint{webID, 4242},
+		}, false)
+		require.ErrorIs(t, err, apperrors.ErrValidation)
+		assert.Contains(t, err.Error(), "[4242]")
+
+		unchanged, err := svc.GetByID(context.Background(), post.ID, author.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, "Retagged", unchanged.Title)
+		assert.Equal(t, []uint{goID}, tagIDsOf(unchanged))
+
+		updated, err := svc.Update(post.ID, author.ID, &models.PostUpdateRequest{
+			TagIDs: []uint{webID, webID},
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{webID}, tagIDsOf(updated))
+	})
+}
+
+func TestPostService_TagFailureRollsBack(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "atomic")
+
+	tag := &models.Tag{Name: "Go", Slug: "go"}
+	require.NoError(t, db.Create(tag).Error)
+	existing, err := svc.Create(author.ID, &models.PostCreateRequest{
+		Title:   "Before the outage",
+		Content: "Written while tags still worked",
+		Status:  models.PostStatusDraft,
+	})
+	require.NoError(t, err)
+
+	// Without the join table every tag association fails
+	require.NoError(t, db.Migrator().DropTable("post_tags"))
+
+	t.Run("create", func(t *testing.T) {
+		_, err := svc.Create(author.ID, &models.PostCreateRequest{
+			Title:   "Half written",
+			Content: "This post must not be left behind",
+			Status:  models.PostStatusDraft,
+			TagIDs:  []uint{tag.ID},
+		})
+		require.Error(t, err)
+
+		var count int64
+		require.NoError(t, db.Model(&models.Post{}).Where("title = ?", "Half written").Count(&count).Error)
+		assert.Zero(t, count, "the post must not be created")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		_, err := svc.Update(existing.ID, author.ID, &models.PostUpdateRequest{
+			Title:  ptr("After the outage"),
+			TagIDs: []uint{tag.ID},
+		}, false)
+		require.Error(t, err)
+
+		var stored models.Post
+		require.NoError(t, db.First(&stored, existing.ID).Error)
+		assert.Equal(t, "Before the outage", stored.Title, "the update must be rolled back")
+	})
+}
+
+func TestPostService_GetTags(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "tagreader")
+
+	tags := []models.Tag{{Name: "Web", Slug: "web"}, {Name: "Go", Slug: "go"}, {Name: "Unused", Slug: "unused"}}
+	require.NoError(t, db.Create(&tags).Error)
+	webID, goID := tags[0].ID, tags[1].ID
+
+	published, err := svc.Create(author.ID, &models.PostCreateRequest{
+		Title:   "Tagged post",
+		Content: "Go on the web today",
+		Status:  models.PostStatusPublished,
+		TagIDs:  []uint{webID, goID},
+	})
+	require.NoError(t, err)
+	draft, err := svc.Create(author.ID, &models.PostCreateRequest{
+		Title:   "Tagged draft",
+		Content: "More about Go",
+		Status:  models.PostStatusDraft,
+		TagIDs:  []uint{goID},
+	})
+	require.NoError(t, err)
+
+	t.Run("returns the attached tags with published post counts", func(t *testing.T) {
+		got, err := svc.GetTags(context.Background(), published.ID, 0, false)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		assert.Equal(t, goID, got[0].ID)
+		assert.Equal(t, "Go", got[0].Name)
+		assert.Equal(t, 1, got[0].PostsCount, "the draft isn't counted")
+		assert.Equal(t, webID, got[1].ID)
+		assert.Equal(t, 1, got[1].PostsCount)
+	})
+
+	t.Run("drafts are hidden from other viewers", func(t *testing.T) {
+		_, err := svc.GetTags(context.Background(), draft.ID, 0, false)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		got, err := svc.GetTags(context.Background(), draft.ID, author.ID, false)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, goID, got[0].ID)
+	})
+
+	t.Run("missing post", func(t *testing.T) {
+		_, err := svc.GetTags(context.Background(), 9999, 0, true)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("untagged post gives an empty list", func(t *testing.T) {
+		untagged, err := svc.Create(author.ID, &models.PostCreateRequest{Title: "No tags", Content: "Plain and simple", Status: models.PostStatusPublished})
+		require.NoError(t, err)
+
+		got, err := svc.GetTags(context.Background(), untagged.ID, 0, false)
+		require.NoError(t, err)
+		assert.NotNil(t, got)
+		assert.Empty(t, got)
+	})
+}
+
+func TestPostService_GetCommenters(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "discussed")
+	alice := testutil.CreateUser(t, db, "alice")
+	bob := testutil.CreateUser(t, db, "bob")
+	carol := testutil.CreateUser(t, db, "carol")
+
+	post, err := svc.Create(author.ID, &models.PostCreateRequest{Title: "Lively thread", Content: "Discuss among yourselves", Status: models.PostStatusPublished})
+	require.NoError(t, err)
+
+	comment := func(authorID *uint, parentID *uint, status models.CommentStatus) *models.Comment {
+		c := &models.Comment{Content: "A comment", Status: status, AuthorID: authorID, PostID: post.ID, ParentID: parentID}
+		if authorID == nil {
+			c.GuestName, c.GuestEmail = "Guest", "guest@example.com"
+		}
+		require.NoError(t, db.Create(c).Error)
+		return c
+	}
+	top := comment(&bob.ID, nil, models.CommentStatusApproved)
+	comment(&alice.ID, &top.ID, models.CommentStatusApproved)
+	comment(&alice.ID, &top.ID, models.CommentStatusApproved)
+	comment(&bob.ID, &top.ID, models.CommentStatusApproved)
+	comment(&alice.ID, nil, models.CommentStatusApproved)
+	comment(&carol.ID, nil, models.CommentStatusPending)
+	comment(nil, nil, models.CommentStatusApproved)
+
+	got, err := svc.GetCommenters(context.Background(), post.ID, 0, false)
+	require.NoError(t, err)
+	require.Len(t, got, 2, "guests and pending comments are left out")
+	assert.Equal(t, alice.ID, got[0].User.ID)
+	assert.Equal(t, "alice", got[0].User.Username)
+	assert.Equal(t, int64(3), got[0].CommentsCount)
+	assert.Equal(t, bob.ID, got[1].User.ID)
+	assert.Equal(t, int64(2), got[1].CommentsCount)
+
+	draft, err := svc.Create(author.ID, &models.PostCreateRequest{Title: "Quiet draft", Content: "Nobody has seen this yet", Status: models.PostStatusDraft})
+	require.NoError(t, err)
+	_, err = svc.GetCommenters(context.Background(), draft.ID, 0, false)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	got, err = svc.GetCommenters(context.Background(), draft.ID, author.ID, false)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+func TestPostService_Update_PartialFields(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "patcher")
+
+	tag := models.Tag{Name: "Patched", Slug: "patched"}
+	require.NoError(t, db.Create(&tag).Error)
+
+	create := func(title string) *models.PostResponse {
+		post, err := svc.Create(author.ID, &models.PostCreateRequest{
+			Title:       title,
+			Content:     "Content that stays put",
+			Excerpt:     "A hand-written excerpt",
+			FeaturedImg: "https://example.com/cover.png",
+			Status:      models.PostStatusDraft,
+			TagIDs:      []uint{tag.ID},
+		})
+		require.NoError(t, err)
+		return post
+	}
+
+	t.Run("omitted fields are left unchanged", func(t *testing.T) {
+		post := create("Untouched fields")
+
+		updated, err := svc.Update(post.ID, author.ID, &models.PostUpdateRequest{Title: ptr("Renamed fields")}, false)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed fields", updated.Title)
+		assert.Equal(t, "A hand-written excerpt", updated.Excerpt)
+		assert.Equal(t, "https://example.com/cover.png", updated.FeaturedImg)
+		assert.Equal(t, []uint{tag.ID}, tagIDsOf(updated))
+	})
+
+	t.Run("empty values clear fields", func(t *testing.T) {
+		post := create("Cleared fields")
+
+		updated, err := svc.Update(post.ID, author.ID, &models.PostUpdateRequest{
+			Excerpt:     ptr(""),
+			FeaturedImg: ptr(""),
+			TagIDs:      []uint{},
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, "Cleared fields", updated.Title)
+		assert.Empty(t, updated.Excerpt)
+		assert.Empty(t, updated.FeaturedImg)
+		assert.Empty(t, updated.Tags)
+	})
+
+	t.Run("IgnoreEmpty keeps empty fields unchanged", func(t *testing.T) {
+		post := create("Ignored fields")
+
+		req := &models.PostUpdateRequest{Excerpt: ptr(""), FeaturedImg: ptr(""), TagIDs: []uint{}}
+		req.IgnoreEmpty()
+		updated, err := svc.Update(post.ID, author.ID, req, false)
+		require.NoError(t, err)
+		assert.Equal(t, "A hand-written excerpt", updated.Excerpt)
+		assert.Equal(t, "https://example.com/cover.png", updated.FeaturedImg)
+		assert.Equal(t, []uint{tag.ID}, tagIDsOf(updated))
+	})
+
+	t.Run("required fields can't be cleared", func(t *testing.T) {
+		post := create("Required fields")
+
+		_, err := svc.Update(post.ID, author.ID, &models.PostUpdateRequest{Title: ptr("")}, false)
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+	})
+}
+
+func TestPostService_MaxPostLength(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	author := testutil.CreateUser(t, db, "verbose")
+	content := embedsForAdmins
+	content.MaxPostLength = 20
+	svc := service.NewPostService(repository.NewPostRepository(db), repository.NewTagRepository(db), repository.NewCommentRepository(db), &fakeWebhooks{}, &fakeMailer{}, content, previewTokens, nil)
+
+	create := func(body string) (*models.PostResponse, error) {
+		return svc.Create(author.ID, &models.PostCreateRequest{Title: "Length limits", Content: body, Status: models.PostStatusDraft})
+	}
+
+	// The limit counts characters, not bytes
+	atLimit := strings.Repeat("é", 20)
+	post, err := create(atLimit)
+	require.NoError(t, err)
+
+	_, err = create(atLimit + "!")
+	var invalid *service.ValidationError
+	require.ErrorAs(t, err, &invalid)
+	require.Len(t, invalid.Fields, 1)
+	assert.Equal(t, "Content", invalid.Fields[0].Field)
+	assert.Equal(t, "max", invalid.Fields[0].Tag)
+	assert.Equal(t, "Content must be at most 20 characters long", invalid.Fields[0].Message)
+
+	t.Run("update", func(t *testing.T) {
+		_, err := svc.Update(post.ID, author.ID, &models.PostUpdateRequest{Content: ptr(atLimit + "!")}, false)
+		require.ErrorAs(t, err, &invalid)
+		assert.Equal(t, "Content", invalid.Fields[0].Field)
+
+		_, err = svc.Update(post.ID, author.ID, &models.PostUpdateRequest{Content: ptr(strings.Repeat("a", 20))}, false)
+		require.NoError(t, err)
+	})
+}
+
+func TestPostService_GetBySlug_FormerSlug(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "retitler")
+
+	post, err := svc.Create(author.ID, &models.PostCreateRequest{Title: "First title", Content: "Content under many titles", Status: models.PostStatusPublished})
+	require.NoError(t, err)
+	_, err = svc.Update(post.ID, author.ID, &models.PostUpdateRequest{Title: ptr("Second title")}, false)
+	require.NoError(t, err)
+	_, err = svc.Update(post.ID, author.ID, &models.PostUpdateRequest{Title: ptr("Third title")}, false)
+	require.NoError(t, err)
+
+	t.Run("old slugs resolve to the post", func(t *testing.T) {
+		for _, slug := range []string{"first-title", "second-title", "third-title"} {
+			found, err := svc.GetBySlug(context.Background(), slug, 0, false)
+			require.NoError(t, err, slug)
+			assert.Equal(t, post.ID, found.ID)
+			assert.Equal(t, "third-title", found.Slug)
+		}
+	})
+
+	t.Run("a reused slug belongs to the new post", func(t *testing.T) {
+		reused, err := svc.Create(author.ID, &models.PostCreateRequest{Title: "First title", Content: "A newcomer takes the slug", Status: models.PostStatusPublished})
+		require.NoError(t, err)
+		require.Equal(t, "first-title", reused.Slug)
+
+		found, err := svc.GetBySlug(context.Background(), "first-title", 0, false)
+		require.NoError(t, err)
+		assert.Equal(t, reused.ID, found.ID)
+
+		// Retitling the newcomer records the slug as its own history
+		_, err = svc.Update(reused.ID, author.ID, &models.PostUpdateRequest{Title: ptr("Newcomer retitled")}, false)
+		require.NoError(t, err)
+		found, err = svc.GetBySlug(context.Background(), "first-title", 0, false)
+		require.NoError(t, err)
+		assert.Equal(t, reused.ID, found.ID)
+	})
+
+	t.Run("deleting a post drops its history", func(t *testing.T) {
+		require.NoError(t, svc.Delete(post.ID, author.ID, false))
+
+		_, err := svc.GetBySlug(context.Background(), "second-title", 0, false)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestPostService_GetPostsByTag_SortPopular(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "popular")
+
+	tags := []models.Tag{{Name: "Go", Slug: "go"}, {Name: "Rust", Slug: "rust"}}
+	require.NoError(t, db.Create(&tags).Error)
+	goTag, rustTag := tags[0], tags[1]
+
+	base := time.Now().Add(-time.Hour)
+	create := func(slug string, views int, publishedAt time.Time, tag models.Tag) *models.Post {
+		post := &models.Post{
+			Title:       "Popular " + slug,
+			Slug:        slug,
+			Content:     "Read by many",
+			Status:      models.PostStatusPublished,
+			AuthorID:    author.ID,
+			ViewCount:   views,
+			PublishedAt: &publishedAt,
+			Tags:        []models.Tag{tag},
+		}
+		require.NoError(t, db.Create(post).Error)
+		return post
+	}
+
+	quiet := create("quiet", 5, base.Add(3*time.Minute), goTag)
+	viral := create("viral", 500, base.Add(time.Minute), goTag)
+	steady := create("steady", 50, base.Add(2*time.Minute), goTag)
+	create("elsewhere", 10000, base, rustTag)
+
+	ids := func(posts []models.PostListResponse) []uint {
+		var out []uint
+		for _, post := range posts {
+			out = append(out, post.ID)
+		}
+		return out
+	}
+
+	popular, meta, err := svc.GetPostsByTag(context.Background(), goTag.ID, models.PostSortPopular, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{viral.ID, steady.ID, quiet.ID}, ids(popular))
+	assert.Equal(t, 3, meta.Total)
+
+	newest, _, err := svc.GetPostsByTag(context.Background(), goTag.ID, "", 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{quiet.ID, steady.ID, viral.ID}, ids(newest))
+
+	_, _, err = svc.GetPostsByTag(context.Background(), goTag.ID, "trending", 1, 10)
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+}
+
+func TestPostService_GetPublishedPosts_Sort(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "sorter")
+
+	base := time.Now().Add(-time.Hour)
+	create := func(slug string, publishedAt time.Time, approved, pending int) *models.Post {
+		post := &models.Post{
+			Title:       "Sorted " + slug,
+			Slug:        slug,
+			Content:     "Ordered in many ways",
+			Status:      models.PostStatusPublished,
+			AuthorID:    author.ID,
+			PublishedAt: &publishedAt,
+		}
+		require.NoError(t, db.Create(post).Error)
+		for i := 0; i < approved+pending; i++ {
+			status := models.CommentStatusApproved
+			if i >= approved {
+				status = models.CommentStatusPending
+			}
+			require.NoError(t, db.Create(&models.Comment{Content: "Discussed", Status: status, AuthorID: &author.ID, PostID: post.ID}).Error)
+		}
+		return post
+	}
+
+	first := create("first", base, 1, 0)
+	second := create("second", base.Add(time.Minute), 3, 0)
+	third := create("third", base.Add(2*time.Minute), 0, 5)
+	fourth := create("fourth", base.Add(3*time.Minute), 2, 0)
+	require.NoError(t, db.Create(&models.Post{Title: "Sorted draft", Slug: "draft", Content: "Not listed", Status: models.PostStatusDraft, AuthorID: author.ID}).Error)
+
+	ids := func(posts []models.PostListResponse) []uint {
+		var out []uint
+		for _, post := range posts {
+			out = append(out, post.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		sort models.PostSort
+		want []uint
+	}{
+		{"", []uint{fourth.ID, third.ID, second.ID, first.ID}},
+		{models.PostSortOldest, []uint{first.ID, second.ID, third.ID, fourth.ID}},
+		// Pending comments don't count; ties fall back to newest first
+		{models.PostSortComments, []uint{second.ID, fourth.ID, first.ID, third.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run("sort="+string(tt.sort), func(t *testing.T) {
+			posts, meta, err := svc.GetPublishedPosts(context.Background(), tt.sort, models.PublishedRange{}, false, 1, 10)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(posts))
+			assert.Equal(t, 4, meta.Total)
+
+			// Pages split the same order
+			page1, meta, err := svc.GetPublishedPosts(context.Background(), tt.sort, models.PublishedRange{}, false, 1, 3)
+			require.NoError(t, err)
+			page2, _, err := svc.GetPublishedPosts(context.Background(), tt.sort, models.PublishedRange{}, false, 2, 3)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, append(ids(page1), ids(page2)...))
+			assert.Equal(t, 4, meta.Total)
+			assert.Equal(t, 2, meta.TotalPages)
+		})
+	}
+
+	_, _, err := svc.GetPublishedPosts(context.Background(), "trending", models.PublishedRange{}, false, 1, 10)
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+
+	// Ranges must not end before they start
+	after, before := time.Now(), time.Now().Add(-time.Hour)
+	_, _, err = svc.GetPublishedPosts(context.Background(), "", models.PublishedRange{After: &after, Before: &before}, false, 1, 10)
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+	_, _, err = svc.GetPosts(context.Background(), models.PostFilter{PublishedRange: models.PublishedRange{After: &after, Before: &before}}, 1, 10)
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+}
+
+func TestPostService_CoAuthors(t *testing.T) {
+	svc, db := newTestPostService(t)
+	ctx := context.Background()
+
+	author := testutil.CreateUser(t, db, "lead")
+	coAuthor := testutil.CreateUser(t, db, "helper")
+	stranger := testutil.CreateUser(t, db, "stranger")
+
+	post, err := svc.Create(author.ID, &models.PostCreateRequest{
+		Title:   "Written together",
+		Content: "Two people wrote this",
+		Status:  models.PostStatusDraft,
+	})
+	require.NoError(t, err)
+
+	t.Run("only the author or an admin adds co-authors", func(t *testing.T) {
+		_, err := svc.AddCoAuthor(post.ID, stranger.ID, false, &models.PostCoAuthorRequest{UserID: stranger.ID})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+		_, err = svc.AddCoAuthor(post.ID, author.ID, false, &models.PostCoAuthorRequest{UserID: author.ID})
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+		_, err = svc.AddCoAuthor(post.ID, author.ID, false, &models.PostCoAuthorRequest{UserID: 9999})
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		updated, err := svc.AddCoAuthor(post.ID, author.ID, false, &models.PostCoAuthorRequest{UserID: coAuthor.ID})
+		require.NoError(t, err)
+		require.Len(t, updated.CoAuthors, 1)
+		assert.Equal(t, coAuthor.ID, updated.CoAuthors[0].ID)
+
+		_, err = svc.AddCoAuthor(post.ID, author.ID, false, &models.PostCoAuthorRequest{UserID: coAuthor.ID})
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("a co-author can read and edit the post but a stranger cannot", func(t *testing.T) {
+		_, err := svc.GetByID(ctx, post.ID, coAuthor.ID, false)
+		require.NoError(t, err)
+		_, err = svc.GetByID(ctx, post.ID, stranger.ID, false)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		updated, err := svc.Update(post.ID, coAuthor.ID, &models.PostUpdateRequest{Content: ptr("Two people rewrote this")}, false)
+		require.NoError(t, err)
+		assert.Equal(t, "Two people rewrote this", updated.Content)
+		assert.Equal(t, author.ID, updated.AuthorID)
+
+		_, err = svc.Update(post.ID, stranger.ID, &models.PostUpdateRequest{Content: ptr("Someone else's words")}, false)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+		published, err := svc.Publish(post.ID, coAuthor.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, models.PostStatusPublished, published.Status)
+		_, err = svc.Publish(post.ID, stranger.ID, false)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+		assert.ErrorIs(t, svc.Delete(post.ID, stranger.ID, false), apperrors.ErrForbidden)
+	})
+
+	t.Run("co-authors can't manage other co-authors", func(t *testing.T) {
+		_, err := svc.AddCoAuthor(post.ID, coAuthor.ID, false, &models.PostCoAuthorRequest{UserID: stranger.ID})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		_, err = svc.RemoveCoAuthor(post.ID, coAuthor.ID, false, coAuthor.ID)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	t.Run("removed co-authors lose their edit rights", func(t *testing.T) {
+		updated, err := svc.RemoveCoAuthor(post.ID, author.ID, false, coAuthor.ID)
+		require.NoError(t, err)
+		assert.Empty(t, updated.CoAuthors)
+
+		_, err = svc.RemoveCoAuthor(post.ID, author.ID, false, coAuthor.ID)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		_, err = svc.Update(post.ID, coAuthor.ID, &models.PostUpdateRequest{Content: ptr("One more try at it")}, false)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	t.Run("a co-author can delete the post", func(t *testing.T) {
+		_, err := svc.AddCoAuthor(post.ID, 0, true, &models.PostCoAuthorRequest{UserID: coAuthor.ID})
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Delete(post.ID, coAuthor.ID, false))
+		_, err = svc.GetByID(ctx, post.ID, author.ID, false)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestPostService_Featured(t *testing.T) {
+	svc, db := newTestPostService(t)
+	ctx := context.Background()
+
+	author := testutil.CreateUser(t, db, "editor")
+	base := time.Now().Add(-time.Hour)
+	create := func(slug string, publishedAt time.Time) *models.Post {
+		post := &models.Post{Title: "Post " + slug, Slug: slug, Content: "Worth reading", Status: models.PostStatusPublished, PublishedAt: &publishedAt, AuthorID: author.ID}
+		require.NoError(t, db.Create(post).Error)
+		return post
+	}
+	oldest := create("oldest", base)
+	middle := create("middle", base.Add(time.Minute))
+	newest := create("newest", base.Add(2*time.Minute))
+	draft := &models.Post{Title: "Draft pick", Slug: "draft-pick", Content: "Not out yet", Status: models.PostStatusDraft, AuthorID: author.ID}
+	require.NoError(t, db.Create(draft).Error)
+
+	ids := func(posts []models.PostListResponse) []uint {
+		var out []uint
+		for _, post := range posts {
+			out = append(out, post.ID)
+		}
+		return out
+	}
+
+	featured, err := svc.Feature(oldest.ID, &models.PostFeatureRequest{Order: 2})
+	require.NoError(t, err)
+	assert.True(t, featured.Featured)
+	assert.Equal(t, 2, featured.FeaturedOrder)
+	_, err = svc.Feature(middle.ID, &models.PostFeatureRequest{Order: 1})
+	require.NoError(t, err)
+	_, err = svc.Feature(draft.ID, &models.PostFeatureRequest{})
+	require.NoError(t, err)
+
+	_, err = svc.Feature(newest.ID, &models.PostFeatureRequest{Order: -1})
+	var invalid *service.ValidationError
+	assert.ErrorAs(t, err, &invalid)
+	_, err = svc.Feature(9999, &models.PostFeatureRequest{})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	t.Run("featured listing follows the featured order and skips drafts", func(t *testing.T) {
+		posts, meta, err := svc.GetFeaturedPosts(ctx, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{middle.ID, oldest.ID}, ids(posts))
+		assert.Equal(t, 2, meta.Total)
+	})
+
+	t.Run("featured_first lists featured posts ahead of the sort", func(t *testing.T) {
+		posts, meta, err := svc.GetPublishedPosts(ctx, "", models.PublishedRange{}, true, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{middle.ID, oldest.ID, newest.ID}, ids(posts))
+		assert.Equal(t, 3, meta.Total)
+
+		posts, _, err = svc.GetPublishedPosts(ctx, "", models.PublishedRange{}, false, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{newest.ID, middle.ID, oldest.ID}, ids(posts))
+	})
+
+	t.Run("unfeature takes the post off the listing", func(t *testing.T) {
+		unfeatured, err := svc.Unfeature(middle.ID)
+		require.NoError(t, err)
+		assert.False(t, unfeatured.Featured)
+		assert.Zero(t, unfeatured.FeaturedOrder)
+
+		posts, _, err := svc.GetFeaturedPosts(ctx, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{oldest.ID}, ids(posts))
+
+		posts, _, err = svc.GetPublishedPosts(ctx, "", models.PublishedRange{}, true, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{oldest.ID, newest.ID, middle.ID}, ids(posts))
+	})
+}
+
+func TestPostService_GetPosts_AuthorAnyStatus(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "dashboard")
+	other := testutil.CreateUser(t, db, "someoneelse")
+
+	for _, post := range []models.Post{
+		{Title: "My draft", Slug: "my-draft", Status: models.PostStatusDraft, AuthorID: author.ID},
+		{Title: "My published", Slug: "my-published", Status: models.PostStatusPublished, AuthorID: author.ID},
+		{Title: "My archived", Slug: "my-archived", Status: models.PostStatusArchived, AuthorID: author.ID},
+		{Title: "Their draft", Slug: "their-draft", Status: models.PostStatusDraft, AuthorID: other.ID},
+	} {
+		post.Content = "Dashboard content"
+		require.NoError(t, db.Create(&post).Error)
+	}
+
+	titles := func(status models.PostStatus) []string {
+		posts, _, err := svc.GetPosts(context.Background(), models.PostFilter{Status: status, AuthorID: author.ID}, 1, 10)
+		require.NoError(t, err)
+		var out []string
+		for _, post := range posts {
+			out = append(out, post.Title)
+		}
+		return out
+	}
+
+	assert.ElementsMatch(t, []string{"My draft", "My published", "My archived"}, titles(""))
+	assert.Equal(t, []string{"My draft"}, titles(models.PostStatusDraft))
+	assert.Equal(t, []string{"My published"}, titles(models.PostStatusPublished))
+	assert.Equal(t, []string{"My archived"}, titles(models.PostStatusArchived))
+}
+
+func TestPostService_CommentCounts(t *testing.T) {
+	svc, db := newTestPostService(t)
+	author := testutil.CreateUser(t, db, "threaded")
+
+	post := &models.Post{Title: "Threads", Slug: "threads", Content: "Discuss below", Status: models.PostStatusPublished, AuthorID: author.ID}
+	require.NoError(t, db.Create(post).Error)
+
+	comment := func(status models.CommentStatus, parent *models.Comment) *models.Comment {
+		c := &models.Comment{Content: "Reply", Status: status, AuthorID: &author.ID, PostID: post.ID}
+		if parent != nil {
+			c.ParentID = &parent.ID
+		}
+		require.NoError(t, db.Create(c).Error)
+		return c
+	}
+
+	first := comment(models.CommentStatusApproved, nil)
+	reply := comment(models.CommentStatusApproved, first)
+	comment(models.CommentStatusApproved, reply)
+	comment(models.CommentStatusPending, first)
+	second := comment(models.CommentStatusApproved, nil)
+	comment(models.CommentStatusRejected, second)
+	comment(models.CommentStatusRejected, nil)
+
+	response, err := svc.GetByID(context.Background(), post.ID, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, response.CommentsCount)
+	assert.Equal(t, 2, response.RepliesCount)
+
+	require.NoError(t, svc.AttachComments(context.Background(), response))
+	assert.Len(t, response.Comments, response.CommentsCount)
+	assert.Equal(t, 4, response.CommentsCount)
+	assert.Equal(t, 2, response.RepliesCount)
+
+	var replies int
+	for _, c := range response.Comments {
+		assert.Equal(t, models.CommentStatusApproved, c.Status)
+		if c.ParentID != nil {
+			replies++
+		}
+	}
+	assert.Equal(t, response.RepliesCount, replies)
+}
+
+func TestPostService_SanitizesContent(t *testing.T) {
+	svc, db := newTestPostService(t)
+	writer := testutil.CreateUser(t, db, "writer")
+	admin := testutil.CreateUser(t, db, "editor")
+	require.NoError(t, db.Model(admin).Update("is_admin", true).Error)
+
+	embed := `<iframe src="https://www.youtube.com/embed/abc" allowfullscreen></iframe>`
+	payload := "Hello <script>alert(document.cookie)</script>\n\n" +
+		"[click me](javascript:alert(1)) <img src=x onerror=alert(1)>\n\n" + embed
+
+	post, err := svc.Create(writer.ID, &models.PostCreateRequest{Title: "Payload", Content: payload, Status: models.PostStatusDraft})
+	require.NoError(t, err)
+	assert.NotContains(t, post.Content, "<script")
+	assert.NotContains(t, post.Content, "javascript:")
+	assert.NotContains(t, post.Content, "onerror")
+	assert.NotContains(t, post.ContentHTML, "<script")
+	assert.NotContains(t, post.ContentHTML, "javascript:")
+	assert.NotContains(t, post.ContentHTML, "<iframe", "only trusted authors may embed")
+
+	updated, err := svc.Update(post.ID, writer.ID, &models.PostUpdateRequest{Content: ptr("<a href=\"javascript:x\">x</a> safe")}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "x safe", updated.Content)
+
+	adminPost, err := svc.Create(admin.ID, &models.PostCreateRequest{Title: "Video", Content: "Watch\n\n" + embed, Status: models.PostStatusDraft})
+	require.NoError(t, err)
+	assert.Contains(t, adminPost.ContentHTML, embed)
+}
+
+func TestPostService_Import(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	tagRepo := repository.NewTagRepository(db)
+	tags := service.NewTagService(tagRepo, time.Hour)
+	svc := service.NewPostService(repository.NewPostRepository(db), tagRepo, repository.NewCommentRepository(db), &fakeWebhooks{}, &fakeMailer{}, embedsForAdmins, previewTokens, tags)
+	author := testutil.CreateUser(t, db, "migrant")
+
+	existing := &models.Tag{Name: "Go", Slug: "go"}
+	require.NoError(t, db.Create(existing).Error)
+
+	document := "---\n" +
+		"title: Moving off Hugo\n" +
+		"slug: moving-off-hugo\n" +
+		"status: published\n" +
+		"date: 2024-01-02\n" +
+		"tags: [go, Static Sites, GO]\n" +
+		"---\n" +
+		"\n" +
+		"# Why we moved\n" +
+		"\n" +
+		"The site outgrew its generator.\n"
+
+	countTags := func() int64 {
+		var count int64
+		require.NoError(t, db.Model(&models.Tag{}).Count(&count).Error)
+		return count
+	}
+
+	t.Run("a dry run stores nothing", func(t *testing.T) {
+		preview, err := svc.PreviewImport(document)
+		require.NoError(t, err)
+		assert.Equal(t, "Moving off Hugo", preview.Title)
+		assert.Equal(t, "moving-off-hugo", preview.Slug)
+		assert.Equal(t, models.PostStatusPublished, preview.Status)
+		assert.Equal(t, []string{"Go", "Static Sites"}, preview.Tags)
+		assert.Equal(t, []string{"Static Sites"}, preview.NewTags)
+
+		var posts int64
+		require.NoError(t, db.Model(&models.Post{}).Count(&posts).Error)
+		assert.Zero(t, posts)
+		assert.Equal(t, int64(1), countTags())
+	})
+
+	t.Run("a valid document creates the post and its new tags", func(t *testing.T) {
+		cached, err := tags.GetAllTags()
+		require.NoError(t, err)
+		require.Len(t, cached, 1)
+
+		post, err := svc.Import(author.ID, document)
+		require.NoError(t, err)
+		assert.Equal(t, author.ID, post.AuthorID)
+		assert.Equal(t, "Moving off Hugo", post.Title)
+		assert.Equal(t, "moving-off-hugo", post.Slug)
+		assert.Equal(t, models.PostStatusPublished, post.Status)
+		assert.Contains(t, post.Content, "The site outgrew its generator.")
+		assert.NotContains(t, post.Content, "title:")
+
+		var names []string
+		for _, tag := range post.Tags {
+			names = append(names, tag.Name)
+		}
+		assert.ElementsMatch(t, []string{"Go", "Static Sites"}, names)
+		assert.Equal(t, int64(2), countTags(), "the existing tag is reused")
+
+		listed, err := tags.GetAllTags()
+		require.NoError(t, err)
+		assert.Len(t, listed, 2, "the tag list cache is invalidated")
+	})
+
+	t.Run("a taken slug conflicts", func(t *testing.T) {
+		_, err := svc.PreviewImport(document)
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("posts without a status are drafts under a derived slug", func(t *testing.T) {
+		post, err := svc.Import(author.ID, "---\ntitle: Just a draft\n---\nNot ready yet.\n")
+		require.NoError(t, err)
+		assert.Equal(t, models.PostStatusDraft, post.Status)
+		assert.Equal(t, "just-a-draft", post.Slug)
+	})
+
+	t.Run("malformed front matter is rejected", func(t *testing.T) {
+		for name, doc := range map[string]string{
+			"missing":  "# Just Markdown\n\nNo front matter here.\n",
+			"unclosed": "---\ntitle: Never closed\n\nBody text\n",
+			"bad yaml": "---\ntitle: [unterminated\n---\nBody text\n",
+		} {
+			_, err := svc.PreviewImport(doc)
+			assert.ErrorIs(t, err, apperrors.ErrBadRequest, name)
+			_, err = svc.Import(author.ID, doc)
+			assert.ErrorIs(t, err, apperrors.ErrBadRequest, name)
+		}
+	})
+
+	t.Run("invalid fields fail validation", func(t *testing.T) {
+		_, err := svc.Import(author.ID, "---\ntitle: Odd status\nstatus: pending\nslug: Not A Slug\n---\nA body long enough\n")
+		var validationErr *service.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 2)
+	})
+}
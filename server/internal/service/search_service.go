@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+)
+
+// allSearchTypes are the sections searched when no type is requested
+var allSearchTypes = []models.SearchType{models.SearchTypePosts, models.SearchTypeTags, models.SearchTypeUsers}
+
+type SearchService interface {
+	Search(ctx context.Context, query string, types []models.SearchType, page, perPage int) (*models.SearchResponse, error)
+}
+
+type searchService struct {
+	postService PostService
+	tagRepo     repository.TagRepository
+	userRepo    repository.UserRepository
+}
+
+func NewSearchService(postService PostService, tagRepo repository.TagRepository, userRepo repository.UserRepository) SearchService {
+	return &searchService{
+		postService: postService,
+		tagRepo:     tagRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// Search looks for query in each requested section, or in all of them when
+// types is empty. Every section is paginated independently with the same
+// page and page size. Only published posts and active users are returned.
+func (s *searchService) Search(ctx context.Context, query string, types []models.SearchType, page, perPage int) (*models.SearchResponse, error) {
+	if query == "" {
+		return nil, apperrors.BadRequest("search query is required")
+	}
+	if len(types) == 0 {
+		types = allSearchTypes
+	}
+
+	offset := (page - 1) * perPage
+	response := &models.SearchResponse{}
+	for _, searchType := range types {
+		switch searchType {
+		case models.SearchTypePosts:
+			if response.Posts != nil {
+				continue
+			}
+			posts, pagination, err := s.postService.SearchPosts(ctx, query, false, page, perPage)
+			if err != nil {
+				return nil, fmt.Errorf("failed to search posts: %w", err)
+			}
+			response.Posts = &models.PostSearchResults{Items: posts, Total: int64(pagination.Total)}
+		case models.SearchTypeTags:
+			if response.Tags != nil {
+				continue
+			}
+			tags, total, err := s.tagRepo.WithContext(ctx).Search(query, offset, perPage)
+			if err != nil {
+				return nil, fmt.Errorf("failed to search tags: %w", err)
+			}
+			results := &models.TagSearchResults{Items: make([]models.TagResponse, 0, len(tags)), Total: total}
+			for _, tag := range tags {
+				results.Items = append(results.Items, tag.ToResponse())
+			}
+			response.Tags = results
+		case models.SearchTypeUsers:
+			if response.Users != nil {
+				continue
+			}
+			users, total, err := s.userRepo.Search(query, offset, perPage)
+			if err != nil {
+				return nil, fmt.Errorf("failed to search users: %w", err)
+			}
+			results := &models.UserSearchResults{Items: make([]models.UserResponse, 0, len(users)), Total: total}
+			for _, user := range users {
+				results.Items = append(results.Items, user.ToResponse())
+			}
+			response.Users = results
+		default:
+			return nil, apperrors.BadRequest(fmt.Sprintf("invalid search type %q: must be posts, tags or users", searchType))
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,109 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestSearchService wires a search service against an in-memory database
+func newTestSearchService(t *testing.T) (service.SearchService, *gorm.DB) {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	tagRepo := repository.NewTagRepository(db)
+	postService := service.NewPostService(
+		repository.NewPostRepository(db),
+		tagRepo,
+		repository.NewCommentRepository(db),
+		&fakeWebhooks{},
+		&fakeMailer{},
+		config.ContentConfig{},
+		config.JWTConfig{},
+		nil,
+	)
+	return service.NewSearchService(postService, tagRepo, repository.NewUserRepository(db)), db
+}
+
+func TestSearchService_Search(t *testing.T) {
+	svc, db := newTestSearchService(t)
+
+	gopher := testutil.CreateUser(t, db, "gopher")
+	inactive := testutil.CreateUser(t, db, "gopherina")
+	require.NoError(t, db.Model(inactive).Update("is_active", false).Error)
+	testutil.CreateUser(t, db, "rustacean")
+
+	now := time.Now().Add(-time.Minute)
+	require.NoError(t, db.Create(&[]models.Post{
+		{Title: "Gopher tricks", Slug: "gopher-tricks", Content: "Published gopher content", Status: models.PostStatusPublished, AuthorID: gopher.ID, PublishedAt: &now},
+		{Title: "Gopher drafts", Slug: "gopher-drafts", Content: "Unfinished gopher content", Status: models.PostStatusDraft, AuthorID: gopher.ID},
+	}).Error)
+	require.NoError(t, db.Create(&[]models.Tag{
+		{Name: "Gopher", Slug: "gopher"},
+		{Name: "Crab", Slug: "crab"},
+	}).Error)
+
+	t.Run("searches every type by default", func(t *testing.T) {
+		results, err := svc.Search(context.Background(), "gopher", nil, 1, 10)
+		require.NoError(t, err)
+
+		require.NotNil(t, results.Posts)
+		assert.Equal(t, int64(1), results.Posts.Total, "drafts must not be found")
+		require.Len(t, results.Posts.Items, 1)
+		assert.Equal(t, "Gopher tricks", results.Posts.Items[0].Title)
+
+		require.NotNil(t, results.Tags)
+		require.Len(t, results.Tags.Items, 1)
+		assert.Equal(t, "gopher", results.Tags.Items[0].Slug)
+
+		require.NotNil(t, results.Users)
+		assert.Equal(t, int64(1), results.Users.Total, "inactive users must not be found")
+		require.Len(t, results.Users.Items, 1)
+		assert.Equal(t, gopher.ID, results.Users.Items[0].ID)
+	})
+
+	t.Run("filters by type", func(t *testing.T) {
+		posts, err := svc.Search(context.Background(), "gopher", []models.SearchType{models.SearchTypePosts}, 1, 10)
+		require.NoError(t, err)
+		assert.NotNil(t, posts.Posts)
+		assert.Nil(t, posts.Tags)
+		assert.Nil(t, posts.Users)
+
+		tags, err := svc.Search(context.Background(), "crab", []models.SearchType{models.SearchTypeTags}, 1, 10)
+		require.NoError(t, err)
+		assert.Nil(t, tags.Posts)
+		require.NotNil(t, tags.Tags)
+		assert.Equal(t, int64(1), tags.Tags.Total)
+		assert.Nil(t, tags.Users)
+
+		users, err := svc.Search(context.Background(), "rust", []models.SearchType{models.SearchTypeUsers, models.SearchTypeTags}, 1, 10)
+		require.NoError(t, err)
+		assert.Nil(t, users.Posts)
+		require.NotNil(t, users.Tags)
+		assert.Zero(t, users.Tags.Total)
+		require.NotNil(t, users.Users)
+		assert.Equal(t, int64(1), users.Users.Total)
+	})
+
+	t.Run("caps each section at the page size", func(t *testing.T) {
+		results, err := svc.Search(context.Background(), "e", []models.SearchType{models.SearchTypeUsers}, 1, 1)
+		require.NoError(t, err)
+		assert.Len(t, results.Users.Items, 1)
+		assert.Equal(t, int64(2), results.Users.Total)
+	})
+
+	t.Run("rejects unknown types", func(t *testing.T) {
+		_, err := svc.Search(context.Background(), "gopher", []models.SearchType{"comments"}, 1, 10)
+		assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+	})
+}
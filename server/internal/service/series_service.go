@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+)
+
+type SeriesService interface {
+	Create(authorID uint, req *models.SeriesCreateRequest) (*models.SeriesResponse, error)
+	GetBySlug(ctx context.Context, slug string) (*models.SeriesResponse, error)
+	GetSeries(ctx context.Context, page, perPage int) ([]models.SeriesResponse, models.PaginationMeta, error)
+	Update(seriesID, userID uint, isAdmin bool, req *models.SeriesUpdateRequest) (*models.SeriesResponse, error)
+	Delete(seriesID, userID uint, isAdmin bool) error
+	AddPost(seriesID, userID uint, isAdmin bool, postID uint, req *models.SeriesPostRequest) (*models.SeriesResponse, error)
+	RemovePost(seriesID, userID uint, isAdmin bool, postID uint) (*models.SeriesResponse, error)
+}
+
+type seriesService struct {
+	seriesRepo  repository.SeriesRepository
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+}
+
+func NewSeriesService(seriesRepo repository.SeriesRepository, postRepo repository.PostRepository, commentRepo repository.CommentRepository) SeriesService {
+	return &seriesService{
+		seriesRepo:  seriesRepo,
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+	}
+}
+
+// withContext returns a copy of the service whose repositories run with ctx
+func (s *seriesService) withContext(ctx context.Context) *seriesService {
+	return &seriesService{
+		seriesRepo:  s.seriesRepo.WithContext(ctx),
+		postRepo:    s.postRepo.WithContext(ctx),
+		commentRepo: s.commentRepo.WithContext(ctx),
+	}
+}
+
+func (s *seriesService) Create(authorID uint, req *models.SeriesCreateRequest) (*models.SeriesResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
+	series := &models.Series{
+		Title:       utils.SanitizeText(req.Title),
+		Slug:        s.uniqueSlug(utils.GenerateSlug(req.Title), 0),
+		Description: utils.SanitizeText(req.Description),
+		AuthorID:    authorID,
+	}
+	if err := s.seriesRepo.Create(series); err != nil {
+		return nil, fmt.Errorf("failed to create series: %w", err)
+	}
+
+	created, err := s.seriesRepo.GetByID(series.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve created series: %w", err)
+	}
+	response := created.ToResponse()
+	return &response, nil
+}
+
+// uniqueSlug returns base, or base with the first free -N suffix if another
+// series than excludeID uses it
+func (s *seriesService) uniqueSlug(base string, excludeID uint) string {
+	slug := base
+	for counter := 1; s.seriesRepo.IsSlugTaken(slug, excludeID); counter++ {
+		slug = fmt.Sprintf("%s-%d", base, counter)
+	}
+	return slug
+}
+
+// GetBySlug returns a series with its published posts in reading order
+func (s *seriesService) GetBySlug(ctx context.Context, slug string) (*models.SeriesResponse, error) {
+	s = s.withContext(ctx)
+
+	series, err := s.seriesRepo.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	return s.withPosts(series, true)
+}
+
+func (s *seriesService) GetSeries(ctx context.Context, page, perPage int) ([]models.SeriesResponse, models.PaginationMeta, error) {
+	s = s.withContext(ctx)
+
+	offset := (page - 1) * perPage
+	series, total, err := s.seriesRepo.List(offset, perPage)
+	if err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+
+	seriesIDs := make([]uint, len(series))
+	for i, item := range series {
+		seriesIDs[i] = item.ID
+	}
+	counts, err := s.seriesRepo.CountPublishedPosts(seriesIDs)
+	if err != nil {
+		return nil, models.PaginationMeta{}, err
+	}
+
+	responses := make([]models.SeriesResponse, 0, len(series))
+	for _, item := range series {
+		response := item.ToResponse()
+		response.PostsCount = int(counts[item.ID])
+		responses = append(responses, response)
+	}
+
+	return responses, utils.CalculatePagination(page, perPage, total), nil
+}
+
+func (s *seriesService) Update(seriesID, userID uint, isAdmin bool, req *models.SeriesUpdateRequest) (*models.SeriesResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
+	series, err := s.ownedSeries(seriesID, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		series.Title = utils.SanitizeText(*req.Title)
+		if newSlug := utils.GenerateSlug(*req.Title); newSlug != series.Slug {
+			series.Slug = s.uniqueSlug(newSlug, series.ID)
+		}
+	}
+	if req.Description != nil {
+		series.Description = utils.SanitizeText(*req.Description)
+	}
+
+	if err := s.seriesRepo.Update(series); err != nil {
+		return nil, fmt.Errorf("failed to update series: %w", err)
+	}
+	return s.withPosts(series, true)
+}
+
+// Delete removes a series. Its posts stay, outside any series.
+func (s *seriesService) Delete(seriesID, userID uint, isAdmin bool) error {
+	if _, err := s.ownedSeries(seriesID, userID, isAdmin); err != nil {
+		return err
+	}
+	return s.seriesRepo.Delete(seriesID)
+}
+
+// AddPost puts a post in a series, or moves it within the series or out of
+// another one. Callers must own the series and be able to edit the post;
+// admins can do both. The response lists every post in the series, drafts
+// included.
+func (s *seriesService) AddPost(seriesID, userID uint, isAdmin bool, postID uint, req *models.SeriesPostRequest) (*models.SeriesResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
+	series, err := s.ownedSeries(seriesID, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && !post.IsEditor(userID) {
+		return nil, apperrors.Forbidden("unauthorized: you can only add your own posts to a series")
+	}
+
+	if err := s.seriesRepo.AddPost(series.ID, post.ID, req.Order); err != nil {
+		return nil, fmt.Errorf("failed to add post to series: %w", err)
+	}
+	return s.withPosts(series, false)
+}
+
+// RemovePost takes a post out of a series. Only the series' author and
+// admins can remove posts.
+func (s *seriesService) RemovePost(seriesID, userID uint, isAdmin bool, postID uint) (*models.SeriesResponse, error) {
+	series, err := s.ownedSeries(seriesID, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.seriesRepo.RemovePost(series.ID, postID); err != nil {
+		return nil, err
+	}
+	return s.withPosts(series, false)
+}
+
+// ownedSeries returns the series userID may change: their own, or any for
+// admins
+func (s *seriesService) ownedSeries(seriesID, userID uint, isAdmin bool) (*models.Series, error) {
+	series, err := s.seriesRepo.GetByID(seriesID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && series.AuthorID != userID {
+		return nil, apperrors.Forbidden("unauthorized: you can only change your own series")
+	}
+	return series, nil
+}
+
+// withPosts returns the response for series listing its posts in reading
+// order, only the published ones when publishedOnly is set
+func (s *seriesService) withPosts(series *models.Series, publishedOnly bool) (*models.SeriesResponse, error) {
+	posts, err := s.seriesRepo.GetPosts(series.ID, publishedOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve series posts: %w", err)
+	}
+
+	response := series.ToResponse()
+	response.Posts = postListResponses(s.commentRepo, posts)
+	for _, post := range posts {
+		if post.Status == models.PostStatusPublished {
+			response.PostsCount++
+		}
+	}
+	return &response, nil
+}
@@ -0,0 +1,150 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesService(t *testing.T) {
+	postService, db := newTestPostService(t)
+	seriesService := service.NewSeriesService(repository.NewSeriesRepository(db), repository.NewPostRepository(db), repository.NewCommentRepository(db))
+	ctx := context.Background()
+
+	author := testutil.CreateUser(t, db, "serialist")
+	stranger := testutil.CreateUser(t, db, "outsider")
+
+	create := func(userID uint, title string, status models.PostStatus) *models.PostResponse {
+		post, err := postService.Create(userID, &models.PostCreateRequest{Title: title, Content: "A chapter of the series", Status: status})
+		require.NoError(t, err)
+		return post
+	}
+	first := create(author.ID, "Part one: setup", models.PostStatusPublished)
+	second := create(author.ID, "Part two: build", models.PostStatusPublished)
+	third := create(author.ID, "Part three: ship", models.PostStatusPublished)
+	draft := create(author.ID, "Part four: later", models.PostStatusDraft)
+	foreign := create(stranger.ID, "Someone else's part", models.PostStatusPublished)
+
+	series, err := seriesService.Create(author.ID, &models.SeriesCreateRequest{Title: "Building a blog", Description: "Start to finish"})
+	require.NoError(t, err)
+	assert.Equal(t, "building-a-blog", series.Slug)
+
+	ids := func(posts []models.PostListResponse) []uint {
+		var out []uint
+		for _, post := range posts {
+			out = append(out, post.ID)
+		}
+		return out
+	}
+
+	t.Run("posts are ordered by their series order, appended without one", func(t *testing.T) {
+		_, err := seriesService.AddPost(series.ID, author.ID, false, third.ID, &models.SeriesPostRequest{Order: 3})
+		require.NoError(t, err)
+		_, err = seriesService.AddPost(series.ID, author.ID, false, first.ID, &models.SeriesPostRequest{Order: 1})
+		require.NoError(t, err)
+		_, err = seriesService.AddPost(series.ID, author.ID, false, second.ID, &models.SeriesPostRequest{Order: 2})
+		require.NoError(t, err)
+		updated, err := seriesService.AddPost(series.ID, author.ID, false, draft.ID, &models.SeriesPostRequest{})
+		require.NoError(t, err)
+
+		// Managing the series shows drafts, readers only see published posts
+		assert.Equal(t, []uint{first.ID, second.ID, third.ID, draft.ID}, ids(updated.Posts))
+		assert.Equal(t, 3, updated.PostsCount)
+
+		found, err := seriesService.GetBySlug(ctx, "building-a-blog")
+		require.NoError(t, err)
+		assert.Equal(t, []uint{first.ID, second.ID, third.ID}, ids(found.Posts))
+	})
+
+	t.Run("posts link to their published neighbours", func(t *testing.T) {
+		post, err := postService.GetByID(ctx, second.ID, 0, false)
+		require.NoError(t, err)
+		require.NotNil(t, post.Series)
+		assert.Equal(t, series.ID, post.Series.ID)
+		require.NotNil(t, post.Series.Previous)
+		assert.Equal(t, first.ID, post.Series.Previous.ID)
+		require.NotNil(t, post.Series.Next)
+		assert.Equal(t, third.ID, post.Series.Next.ID)
+
+		// The draft after it isn't linked
+		post, err = postService.GetByID(ctx, third.ID, 0, false)
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, post.Series.Previous.ID)
+		assert.Nil(t, post.Series.Next)
+
+		post, err = postService.GetByID(ctx, foreign.ID, 0, false)
+		require.NoError(t, err)
+		assert.Nil(t, post.Series)
+	})
+
+	t.Run("reordering moves a post within the series", func(t *testing.T) {
+		// It shares the draft's order, and was written before it
+		updated, err := seriesService.AddPost(series.ID, author.ID, false, first.ID, &models.SeriesPostRequest{Order: 4})
+		require.NoError(t, err)
+		assert.Equal(t, []uint{second.ID, third.ID, first.ID, draft.ID}, ids(updated.Posts))
+
+		_, err = seriesService.AddPost(series.ID, author.ID, false, first.ID, &models.SeriesPostRequest{Order: 1})
+		require.NoError(t, err)
+	})
+
+	t.Run("editing a post keeps it in its series", func(t *testing.T) {
+		updated, err := postService.Update(third.ID, author.ID, &models.PostUpdateRequest{Title: ptr("Part three: shipped")}, false)
+		require.NoError(t, err)
+		require.NotNil(t, updated.Series)
+		assert.Equal(t, series.ID, updated.Series.ID)
+	})
+
+	t.Run("only the series' author adds their own posts", func(t *testing.T) {
+		_, err := seriesService.AddPost(series.ID, stranger.ID, false, foreign.ID, &models.SeriesPostRequest{})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		_, err = seriesService.AddPost(series.ID, author.ID, false, foreign.ID, &models.SeriesPostRequest{})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		_, err = seriesService.AddPost(series.ID, author.ID, false, 9999, &models.SeriesPostRequest{})
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		_, err = seriesService.Update(series.ID, stranger.ID, false, &models.SeriesUpdateRequest{Title: ptr("Hijacked series")})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	t.Run("removed posts leave the series and its navigation", func(t *testing.T) {
+		updated, err := seriesService.RemovePost(series.ID, author.ID, false, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{first.ID, third.ID, draft.ID}, ids(updated.Posts))
+
+		_, err = seriesService.RemovePost(series.ID, author.ID, false, second.ID)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		post, err := postService.GetByID(ctx, first.ID, 0, false)
+		require.NoError(t, err)
+		assert.Equal(t, third.ID, post.Series.Next.ID)
+		post, err = postService.GetByID(ctx, second.ID, 0, false)
+		require.NoError(t, err)
+		assert.Nil(t, post.Series)
+	})
+
+	t.Run("a new title changes the slug", func(t *testing.T) {
+		updated, err := seriesService.Update(series.ID, author.ID, false, &models.SeriesUpdateRequest{Title: ptr("Shipping a blog")})
+		require.NoError(t, err)
+		assert.Equal(t, "shipping-a-blog", updated.Slug)
+		assert.Equal(t, "Start to finish", updated.Description)
+
+		_, err = seriesService.GetBySlug(ctx, "building-a-blog")
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("deleting a series keeps its posts", func(t *testing.T) {
+		require.NoError(t, seriesService.Delete(series.ID, author.ID, false))
+
+		post, err := postService.GetByID(ctx, first.ID, 0, false)
+		require.NoError(t, err)
+		assert.Nil(t, post.Series)
+		_, err = seriesService.GetBySlug(ctx, "shipping-a-blog")
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
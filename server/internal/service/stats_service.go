@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+)
+
+type StatsService interface {
+	GetSiteStats() (*models.SiteStatsResponse, error)
+}
+
+type statsService struct {
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+	tagRepo     repository.TagRepository
+	userRepo    repository.UserRepository
+	ttl         time.Duration
+
+	mu        sync.Mutex
+	cached    *models.SiteStatsResponse
+	expiresAt time.Time
+}
+
+// NewStatsService returns a service whose site stats are computed at most
+// once per ttl and served from memory in between
+func NewStatsService(postRepo repository.PostRepository, commentRepo repository.CommentRepository, tagRepo repository.TagRepository, userRepo repository.UserRepository, ttl time.Duration) StatsService {
+	return &statsService{
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		tagRepo:     tagRepo,
+		userRepo:    userRepo,
+		ttl:         ttl,
+	}
+}
+
+// GetSiteStats returns the public site totals. They change slowly, so a
+// cached copy is returned until it is ttl old; concurrent callers wait for
+// a single refresh rather than each running the counts.
+func (s *statsService) GetSiteStats() (*models.SiteStatsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Now().Before(s.expiresAt) {
+		stats := *s.cached
+		return &stats, nil
+	}
+
+	stats, err := s.countSiteStats()
+	if err != nil {
+		return nil, err
+	}
+	s.cached = stats
+	s.expiresAt = time.Now().Add(s.ttl)
+
+	result := *stats
+	return &result, nil
+}
+
+func (s *statsService) countSiteStats() (*models.SiteStatsResponse, error) {
+	var stats models.SiteStatsResponse
+	var err error
+
+	if stats.PublishedPosts, err = s.postRepo.CountPublished(); err != nil {
+		return nil, fmt.Errorf("failed to count published posts: %w", err)
+	}
+	if stats.ApprovedComments, err = s.commentRepo.CountApprovedOnPublished(); err != nil {
+		return nil, fmt.Errorf("failed to count approved comments: %w", err)
+	}
+	if stats.Tags, err = s.tagRepo.Count(); err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	if stats.ActiveAuthors, err = s.userRepo.CountActiveAuthors(); err != nil {
+		return nil, fmt.Errorf("failed to count active authors: %w", err)
+	}
+	return &stats, nil
+}
@@ -0,0 +1,69 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsService_GetSiteStats(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	svc := service.NewStatsService(
+		repository.NewPostRepository(db),
+		repository.NewCommentRepository(db),
+		repository.NewTagRepository(db),
+		repository.NewUserRepository(db),
+		time.Hour,
+	)
+
+	published := time.Now().Add(-time.Hour)
+	writer := testutil.CreateUser(t, db, "writer")
+	drafter := testutil.CreateUser(t, db, "drafter")
+	retired := testutil.CreateUser(t, db, "retired")
+	require.NoError(t, db.Model(retired).Update("is_active", false).Error)
+
+	post := &models.Post{Title: "Live", Slug: "live", Content: "Body", Status: models.PostStatusPublished, PublishedAt: &published, AuthorID: writer.ID}
+	require.NoError(t, db.Create(post).Error)
+	require.NoError(t, db.Create(&models.Post{Title: "Old", Slug: "old", Content: "Body", Status: models.PostStatusPublished, PublishedAt: &published, AuthorID: retired.ID}).Error)
+	draft := &models.Post{Title: "Draft", Slug: "draft", Content: "Body", Status: models.PostStatusDraft, AuthorID: drafter.ID}
+	require.NoError(t, db.Create(draft).Error)
+
+	require.NoError(t, db.Create(&models.Comment{Content: "Approved", AuthorID: &drafter.ID, PostID: post.ID, Status: models.CommentStatusApproved}).Error)
+	require.NoError(t, db.Create(&models.Comment{Content: "Pending", AuthorID: &drafter.ID, PostID: post.ID, Status: models.CommentStatusPending}).Error)
+	require.NoError(t, db.Create(&models.Comment{Content: "On a draft", AuthorID: &writer.ID, PostID: draft.ID, Status: models.CommentStatusApproved}).Error)
+
+	require.NoError(t, db.Create(&models.Tag{Name: "Go", Slug: "go"}).Error)
+	require.NoError(t, db.Create(&models.Tag{Name: "SQL", Slug: "sql"}).Error)
+
+	stats, err := svc.GetSiteStats()
+	require.NoError(t, err)
+	assert.Equal(t, &models.SiteStatsResponse{
+		PublishedPosts:   2,
+		ApprovedComments: 1,
+		Tags:             2,
+		ActiveAuthors:    1,
+	}, stats)
+
+	// Until the cache expires, new content doesn't change the numbers
+	require.NoError(t, db.Create(&models.Post{Title: "Another", Slug: "another", Content: "Body", Status: models.PostStatusPublished, PublishedAt: &published, AuthorID: drafter.ID}).Error)
+	cached, err := svc.GetSiteStats()
+	require.NoError(t, err)
+	assert.Equal(t, stats, cached)
+
+	fresh, err := service.NewStatsService(
+		repository.NewPostRepository(db),
+		repository.NewCommentRepository(db),
+		repository.NewTagRepository(db),
+		repository.NewUserRepository(db),
+		time.Hour,
+	).GetSiteStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), fresh.PublishedPosts)
+	assert.Equal(t, int64(2), fresh.ActiveAuthors)
+}
@@ -1,9 +1,13 @@
 package service
 
 import (
-	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
@@ -13,49 +17,137 @@ type TagService interface {
 	Create(req *models.TagCreateRequest) (*models.TagResponse, error)
 	GetByID(id uint) (*models.TagResponse, error)
 	GetBySlug(slug string) (*models.TagResponse, error)
+	GetByFormerSlug(slug string) (*models.TagResponse, error)
 	Update(tagID uint, req *models.TagUpdateRequest) (*models.TagResponse, error)
-	Delete(tagID uint) error
+	Delete(tagID uint, force bool) error
 	GetTags(page, perPage int) ([]models.TagResponse, models.PaginationMeta, error)
 	GetAllTags() ([]models.TagResponse, error)
 	GetPopularTags(limit int) ([]models.TagResponse, error)
+	InvalidateCache()
 }
 
+// TagListCache is the cache of tag lists kept by the tag service, for
+// other services that change tags to invalidate
+type TagListCache interface {
+	InvalidateCache()
+}
+
+// TagInUseError is returned when deleting a tag that is still attached to
+// posts without forcing it. It is a conflict.
+type TagInUseError struct {
+	PostsCount int64
+}
+
+func (e *TagInUseError) Error() string {
+	return fmt.Sprintf("tag is attached to %d post(s); pass force=true to delete it anyway", e.PostsCount)
+}
+
+// Unwrap makes the error match apperrors.ErrConflict
+func (e *TagInUseError) Unwrap() error {
+	return apperrors.ErrConflict
+}
+
+// defaultTagColor is used when a tag is created without a color
+const defaultTagColor = "#3b82f6"
+
 type tagService struct {
-	tagRepo repository.TagRepository
+	tagRepo  repository.TagRepository
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	lists map[int]*cachedTags
+}
+
+// allTagsList keys the full tag list among the cached lists, which are
+// otherwise the popular tags keyed by their limit
+const allTagsList = 0
+
+// cachedTags is a tag list held in memory until expiresAt
+type cachedTags struct {
+	tags      []models.TagResponse
+	expiresAt time.Time
 }
 
-func NewTagService(tagRepo repository.TagRepository) TagService {
+// NewTagService returns a tag service that keeps the full and popular tag
+// lists in memory for cacheTTL. Zero turns the cache off.
+func NewTagService(tagRepo repository.TagRepository, cacheTTL time.Duration) TagService {
 	return &tagService{
-		tagRepo: tagRepo,
+		tagRepo:  tagRepo,
+		cacheTTL: cacheTTL,
+		lists:    make(map[int]*cachedTags),
 	}
 }
 
+// cachedList returns the list cached under key while it's fresh, or loads
+// it and caches it for cacheTTL. Concurrent callers wait for a single load.
+// Callers get their own copy of the list.
+func (s *tagService) cachedList(key int, load func() ([]models.TagResponse, error)) ([]models.TagResponse, error) {
+	if s.cacheTTL <= 0 {
+		return load()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.lists[key]; ok && time.Now().Before(entry.expiresAt) {
+		return slices.Clone(entry.tags), nil
+	}
+
+	tags, err := load()
+	if err != nil {
+		return nil, err
+	}
+	s.lists[key] = &cachedTags{tags: tags, expiresAt: time.Now().Add(s.cacheTTL)}
+	return slices.Clone(tags), nil
+}
+
+// InvalidateCache drops the cached tag lists after a tag changes. Tag
+// changes made elsewhere, like posts being retagged, show up once the
+// lists expire.
+func (s *tagService) InvalidateCache() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.lists)
+}
+
 func (s *tagService) Create(req *models.TagCreateRequest) (*models.TagResponse, error) {
+	req.Slug = strings.TrimSpace(req.Slug)
+
 	// Validate request
-	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
-		return nil, fmt.Errorf("validation failed: %v", validationErrors)
+	validationErrors := utils.ValidateStruct(req)
+	if req.Slug != "" && !utils.IsValidSlug(req.Slug) {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Field:   "Slug",
+			Tag:     "slug",
+			Value:   req.Slug,
+			Message: "Slug may only contain lowercase letters, digits and single hyphens, and can't start or end with a hyphen",
+		})
+	}
+	if len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
 	}
 
 	// Check if name is already taken
 	if s.tagRepo.IsNameTaken(req.Name, 0) {
-		return nil, errors.New("tag name is already taken")
+		return nil, apperrors.Conflict("tag name is already taken")
 	}
 
-	// Generate slug from name
-	slug := utils.GenerateSlug(req.Name)
-	originalSlug := slug
-
-	// Ensure slug is unique
-	counter := 1
-	for s.tagRepo.IsSlugTaken(slug, 0) {
-		slug = fmt.Sprintf("%s-%d", originalSlug, counter)
-		counter++
+	// Use the requested slug, or generate one from the name
+	base := req.Slug
+	if base == "" {
+		base = utils.GenerateSlug(req.Name)
 	}
+	slug := s.uniqueSlug(base, 0)
 
 	// Set default color if not provided
-	color := req.Color
-	if color == "" {
-		color = "#3B82F6" // Default blue color
+	color := defaultTagColor
+	if req.Color != "" {
+		normalized, err := normalizeTagColor(req.Color)
+		if err != nil {
+			return nil, err
+		}
+		color = normalized
 	}
 
 	// Create tag
@@ -69,11 +161,38 @@ func (s *tagService) Create(req *models.TagCreateRequest) (*models.TagResponse,
 	if err := s.tagRepo.Create(tag); err != nil {
 		return nil, fmt.Errorf("failed to create tag: %w", err)
 	}
+	s.InvalidateCache()
 
 	response := tag.ToResponse()
 	return &response, nil
 }
 
+// uniqueSlug returns base, or base with the first free -N suffix if another
+// tag than excludeID uses it
+func (s *tagService) uniqueSlug(base string, excludeID uint) string {
+	slug := base
+	for counter := 1; s.tagRepo.IsSlugTaken(slug, excludeID); counter++ {
+		slug = fmt.Sprintf("%s-%d", base, counter)
+	}
+	return slug
+}
+
+// normalizeTagColor stores colors as lowercase #rrggbb so equal colors
+// compare equal, accepting the 3-digit shorthand and a missing #
+func normalizeTagColor(color string) (string, error) {
+	normalized, ok := utils.NormalizeHexColor(color)
+	if !ok {
+		validationErrors := []models.ValidationError{{
+			Field:   "Color",
+			Tag:     "hexcolor",
+			Value:   color,
+			Message: "Color must be a valid hex color such as #3b82f6 or #fff",
+		}}
+		return "", &ValidationError{Fields: validationErrors}
+	}
+	return normalized, nil
+}
+
 func (s *tagService) GetByID(id uint) (*models.TagResponse, error) {
 	tag, err := s.tagRepo.GetByID(id)
 	if err != nil {
@@ -87,6 +206,19 @@ func (s *tagService) GetByID(id uint) (*models.TagResponse, error) {
 	return &response, nil
 }
 
+// GetByFormerSlug returns the tag that used slug before it was renamed
+func (s *tagService) GetByFormerSlug(slug string) (*models.TagResponse, error) {
+	tag, err := s.tagRepo.GetByFormerSlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	response := tag.ToResponse()
+	response.PostsCount = len(tag.Posts)
+
+	return &response, nil
+}
+
 func (s *tagService) GetBySlug(slug string) (*models.TagResponse, error) {
 	tag, err := s.tagRepo.GetBySlug(slug)
 	if err != nil {
@@ -103,7 +235,7 @@ func (s *tagService) GetBySlug(slug string) (*models.TagResponse, error) {
 func (s *tagService) Update(tagID uint, req *models.TagUpdateRequest) (*models.TagResponse, error) {
 	// Validate request
 	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
-		return nil, fmt.Errorf("validation failed: %v", validationErrors)
+		return nil, &ValidationError{Fields: validationErrors}
 	}
 
 	// Get existing tag
@@ -111,20 +243,18 @@ func (s *tagService) Update(tagID uint, req *models.TagUpdateRequest) (*models.T
 	if err != nil {
 		return nil, err
 	}
+	oldSlug := tag.Slug
 
 	// Check if name is already taken (excluding current tag)
 	if req.Name != "" && req.Name != tag.Name {
 		if s.tagRepo.IsNameTaken(req.Name, tagID) {
-			return nil, errors.New("tag name is already taken")
+			return nil, apperrors.Conflict("tag name is already taken")
 		}
 
 		tag.Name = utils.SanitizeText(req.Name)
 
-		// Regenerate slug if name changed
-		newSlug := utils.GenerateSlug(req.Name)
-		if newSlug != tag.Slug && !s.tagRepo.IsSlugTaken(newSlug, tagID) {
-			tag.Slug = newSlug
-		}
+		// The slug always follows the name, suffixed if another tag has it
+		tag.Slug = s.uniqueSlug(utils.GenerateSlug(req.Name), tagID)
 	}
 
 	// Update other fields
@@ -133,25 +263,51 @@ func (s *tagService) Update(tagID uint, req *models.TagUpdateRequest) (*models.T
 	}
 
 	if req.Color != "" {
-		tag.Color = req.Color
+		color, err := normalizeTagColor(req.Color)
+		if err != nil {
+			return nil, err
+		}
+		tag.Color = color
 	}
 
-	if err := s.tagRepo.Update(tag); err != nil {
+	if tag.Slug != oldSlug {
+		err = s.tagRepo.Rename(tag, oldSlug)
+	} else {
+		err = s.tagRepo.Update(tag)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to update tag: %w", err)
 	}
+	s.InvalidateCache()
 
 	response := tag.ToResponse()
 	return &response, nil
 }
 
-func (s *tagService) Delete(tagID uint) error {
+// Delete removes a tag. A tag still attached to posts is only deleted, and
+// detached from those posts, when force is set.
+func (s *tagService) Delete(tagID uint, force bool) error {
 	// Check if tag exists
 	_, err := s.tagRepo.GetByID(tagID)
 	if err != nil {
 		return err
 	}
 
-	return s.tagRepo.Delete(tagID)
+	if !force {
+		count, err := s.tagRepo.CountPosts(tagID)
+		if err != nil {
+			return fmt.Errorf("failed to count tag posts: %w", err)
+		}
+		if count > 0 {
+			return &TagInUseError{PostsCount: count}
+		}
+	}
+
+	if err := s.tagRepo.Delete(tagID); err != nil {
+		return err
+	}
+	s.InvalidateCache()
+	return nil
 }
 
 func (s *tagService) GetTags(page, perPage int) ([]models.TagResponse, models.PaginationMeta, error) {
@@ -161,7 +317,7 @@ func (s *tagService) GetTags(page, perPage int) ([]models.TagResponse, models.Pa
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.TagResponse
+	responses := make([]models.TagResponse, 0, len(tags))
 	for _, tag := range tags {
 		responses = append(responses, tag.ToResponse())
 	}
@@ -170,13 +326,18 @@ func (s *tagService) GetTags(page, perPage int) ([]models.TagResponse, models.Pa
 	return responses, pagination, nil
 }
 
+// GetAllTags returns every tag, served from memory while cached
 func (s *tagService) GetAllTags() ([]models.TagResponse, error) {
+	return s.cachedList(allTagsList, s.loadAllTags)
+}
+
+func (s *tagService) loadAllTags() ([]models.TagResponse, error) {
 	tags, err := s.tagRepo.GetAll()
 	if err != nil {
 		return nil, err
 	}
 
-	var responses []models.TagResponse
+	responses := make([]models.TagResponse, 0, len(tags))
 	for _, tag := range tags {
 		responses = append(responses, tag.ToResponse())
 	}
@@ -184,17 +345,25 @@ func (s *tagService) GetAllTags() ([]models.TagResponse, error) {
 	return responses, nil
 }
 
+// GetPopularTags returns the limit tags on the most posts, each limit's
+// list served from memory while cached
 func (s *tagService) GetPopularTags(limit int) ([]models.TagResponse, error) {
 	if limit <= 0 || limit > 50 {
 		limit = 10 // Default limit
 	}
 
+	return s.cachedList(limit, func() ([]models.TagResponse, error) {
+		return s.loadPopularTags(limit)
+	})
+}
+
+func (s *tagService) loadPopularTags(limit int) ([]models.TagResponse, error) {
 	tags, err := s.tagRepo.GetPopular(limit)
 	if err != nil {
 		return nil, err
 	}
 
-	var responses []models.TagResponse
+	responses := make([]models.TagResponse, 0, len(tags))
 	for _, tag := range tags {
 		response := tag.ToResponse()
 		// Note: The posts_count is already calculated in the repository query
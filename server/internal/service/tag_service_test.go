@@ -0,0 +1,282 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestTagService wires a tag service against an in-memory database
+func newTestTagService(t *testing.T) (service.TagService, *gorm.DB) {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	return service.NewTagService(repository.NewTagRepository(db), 0), db
+}
+
+func TestTagService_ColorNormalization(t *testing.T) {
+	svc, _ := newTestTagService(t)
+
+	tests := []struct {
+		name  string
+		color string
+		want  string
+	}{
+		{"short uppercase", "#FFF", "#ffffff"},
+		{"full lowercase", "#ffffff", "#ffffff"},
+		{"no hash", "fff", "#ffffff"},
+		{"full uppercase", "#3B82F6", "#3b82f6"},
+		{"default", "", "#3b82f6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := svc.Create(&models.TagCreateRequest{Name: "Color " + tt.name, Color: tt.color})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tag.Color)
+		})
+	}
+
+	t.Run("update", func(t *testing.T) {
+		tag, err := svc.Create(&models.TagCreateRequest{Name: "Recolored"})
+		require.NoError(t, err)
+
+		updated, err := svc.Update(tag.ID, &models.TagUpdateRequest{Color: "ABC"})
+		require.NoError(t, err)
+		assert.Equal(t, "#aabbcc", updated.Color)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := svc.Create(&models.TagCreateRequest{Name: "Bad color", Color: "#12345g"})
+		require.ErrorIs(t, err, apperrors.ErrValidation)
+		assert.Contains(t, err.Error(), "Color")
+
+		tag, err := svc.Create(&models.TagCreateRequest{Name: "Keeps color", Color: "#123456"})
+		require.NoError(t, err)
+		_, err = svc.Update(tag.ID, &models.TagUpdateRequest{Color: "red"})
+		require.ErrorIs(t, err, apperrors.ErrValidation)
+	})
+}
+
+func TestTagService_Delete_InUse(t *testing.T) {
+	svc, db := newTestTagService(t)
+	author := testutil.CreateUser(t, db, "tagdeleter")
+
+	tag, err := svc.Create(&models.TagCreateRequest{Name: "Popular"})
+	require.NoError(t, err)
+	for _, slug := range []string{"first-tagged", "second-tagged"} {
+		post := &models.Post{Title: "Tagged " + slug, Slug: slug, Content: "Tagged content", Status: models.PostStatusDraft, AuthorID: author.ID}
+		require.NoError(t, db.Create(post).Error)
+		require.NoError(t, repository.NewPostRepository(db).AddTags(post.ID, []uint{tag.ID}))
+	}
+
+	t.Run("guarded", func(t *testing.T) {
+		err := svc.Delete(tag.ID, false)
+		require.ErrorIs(t, err, apperrors.ErrConflict)
+		var inUse *service.TagInUseError
+		require.ErrorAs(t, err, &inUse)
+		assert.Equal(t, int64(2), inUse.PostsCount)
+
+		_, err = svc.GetByID(tag.ID)
+		assert.NoError(t, err, "tag must survive")
+	})
+
+	t.Run("forced", func(t *testing.T) {
+		require.NoError(t, svc.Delete(tag.ID, true))
+
+		_, err := svc.GetByID(tag.ID)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		var links int64
+		require.NoError(t, db.Table("post_tags").Where("tag_id = ?", tag.ID).Count(&links).Error)
+		assert.Zero(t, links)
+	})
+
+	t.Run("unused tags need no force", func(t *testing.T) {
+		unused, err := svc.Create(&models.TagCreateRequest{Name: "Lonely"})
+		require.NoError(t, err)
+		assert.NoError(t, svc.Delete(unused.ID, false))
+	})
+}
+
+func TestTagService_Update_Rename(t *testing.T) {
+	svc, _ := newTestTagService(t)
+
+	existing, err := svc.Create(&models.TagCreateRequest{Name: "Golang"})
+	require.NoError(t, err)
+	tag, err := svc.Create(&models.TagCreateRequest{Name: "Go"})
+	require.NoError(t, err)
+
+	t.Run("slug collision gets a suffix", func(t *testing.T) {
+		renamed, err := svc.Update(tag.ID, &models.TagUpdateRequest{Name: "GoLang!"})
+		require.NoError(t, err)
+		assert.Equal(t, "GoLang!", renamed.Name)
+		assert.Equal(t, "golang-1", renamed.Slug)
+
+		unchanged, err := svc.GetByID(existing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "golang", unchanged.Slug)
+	})
+
+	t.Run("former slugs resolve to the tag", func(t *testing.T) {
+		_, err := svc.Update(tag.ID, &models.TagUpdateRequest{Name: "Go Language"})
+		require.NoError(t, err)
+
+		for _, slug := range []string{"go", "golang-1"} {
+			found, err := svc.GetByFormerSlug(slug)
+			require.NoError(t, err, slug)
+			assert.Equal(t, tag.ID, found.ID)
+			assert.Equal(t, "go-language", found.Slug)
+		}
+
+		_, err = svc.GetByFormerSlug("go-language")
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("reused slugs stop redirecting", func(t *testing.T) {
+		reused, err := svc.Create(&models.TagCreateRequest{Name: "Go"})
+		require.NoError(t, err)
+		assert.Equal(t, "go", reused.Slug)
+
+		_, err = svc.GetByFormerSlug("go")
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("deleting a tag drops its history", func(t *testing.T) {
+		require.NoError(t, svc.Delete(tag.ID, false))
+
+		_, err := svc.GetByFormerSlug("golang-1")
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestTagService_Create_CustomSlug(t *testing.T) {
+	svc, _ := newTestTagService(t)
+
+	t.Run("provided", func(t *testing.T) {
+		tag, err := svc.Create(&models.TagCreateRequest{Name: "Kubernetes (k8s) ☸", Slug: "kubernetes"})
+		require.NoError(t, err)
+		assert.Equal(t, "kubernetes", tag.Slug)
+	})
+
+	t.Run("colliding", func(t *testing.T) {
+		first, err := svc.Create(&models.TagCreateRequest{Name: "Golang", Slug: "go"})
+		require.NoError(t, err)
+		assert.Equal(t, "go", first.Slug)
+
+		second, err := svc.Create(&models.TagCreateRequest{Name: "Go language", Slug: "go"})
+		require.NoError(t, err)
+		assert.Equal(t, "go-1", second.Slug)
+
+		// A derived slug makes way for the custom one the same way
+		derived, err := svc.Create(&models.TagCreateRequest{Name: "Go"})
+		require.NoError(t, err)
+		assert.Equal(t, "go-2", derived.Slug)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, slug := range []string{"Has Caps", "under_score", "-leading", "trailing-", "double--hyphen"} {
+			_, err := svc.Create(&models.TagCreateRequest{Name: "Bad slug " + slug, Slug: slug})
+			var invalid *service.ValidationError
+			require.ErrorAs(t, err, &invalid, slug)
+			assert.Equal(t, "Slug", invalid.Fields[0].Field, slug)
+		}
+	})
+}
+
+func TestTagService_ListCache(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	svc := service.NewTagService(repository.NewTagRepository(db), time.Hour)
+
+	names := func(tags []models.TagResponse) []string {
+		var out []string
+		for _, tag := range tags {
+			out = append(out, tag.Name)
+		}
+		return out
+	}
+
+	// Popular tags are the ones on posts
+	author := testutil.CreateUser(t, db, "tagwriter")
+	post := &models.Post{Title: "Tagged", Slug: "tagged", Content: "Body", Status: models.PostStatusPublished, AuthorID: author.ID}
+	require.NoError(t, db.Create(post).Error)
+	tagPost := func(name string) {
+		tag := &models.Tag{Name: name, Slug: strings.ToLower(name)}
+		require.NoError(t, db.Create(tag).Error)
+		require.NoError(t, db.Model(post).Association("Tags").Append(tag))
+	}
+
+	tagPost("Go")
+	all, err := svc.GetAllTags()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Go"}, names(all))
+	popular, err := svc.GetPopularTags(5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Go"}, names(popular))
+
+	t.Run("repeat calls are served from memory", func(t *testing.T) {
+		// Written behind the service's back, so only a fresh query sees it
+		tagPost("Rust")
+
+		all, err := svc.GetAllTags()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Go"}, names(all))
+		popular, err := svc.GetPopularTags(5)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Go"}, names(popular))
+
+		// Callers get their own copy
+		all[0].Name = "Changed"
+		again, err := svc.GetAllTags()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Go"}, names(again))
+	})
+
+	t.Run("tag changes drop the cached lists", func(t *testing.T) {
+		created, err := svc.Create(&models.TagCreateRequest{Name: "SQL"})
+		require.NoError(t, err)
+
+		all, err := svc.GetAllTags()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Go", "Rust", "SQL"}, names(all))
+		popular, err := svc.GetPopularTags(5)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Go", "Rust"}, names(popular))
+
+		_, err = svc.Update(created.ID, &models.TagUpdateRequest{Name: "Postgres"})
+		require.NoError(t, err)
+		all, err = svc.GetAllTags()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Go", "Rust", "Postgres"}, names(all))
+
+		require.NoError(t, svc.Delete(created.ID, false))
+		all, err = svc.GetAllTags()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Go", "Rust"}, names(all))
+	})
+}
+
+func TestTagService_ListCacheOff(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	svc := service.NewTagService(repository.NewTagRepository(db), 0)
+
+	require.NoError(t, db.Create(&models.Tag{Name: "Go", Slug: "go"}).Error)
+	all, err := svc.GetAllTags()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+
+	// Without a TTL every call goes to the database
+	require.NoError(t, db.Create(&models.Tag{Name: "Rust", Slug: "rust"}).Error)
+	all, err = svc.GetAllTags()
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+)
+
+// TokenService revokes access and refresh tokens before they expire, so a
+// logout also ends the session for anyone who copied the token
+type TokenService interface {
+	Revoke(claims *utils.JWTClaims) error
+	RevokeToken(token string) error
+	IsRevoked(jti string) (bool, error)
+	CheckToken(token string) error
+	PurgeExpired() (int64, error)
+}
+
+type tokenService struct {
+	tokenRepo repository.TokenRepository
+	config    *config.Config
+}
+
+func NewTokenService(tokenRepo repository.TokenRepository, cfg *config.Config) TokenService {
+	return &tokenService{
+		tokenRepo: tokenRepo,
+		config:    cfg,
+	}
+}
+
+// Revoke blocks the token with claims until it expires. Tokens issued
+// without an ID can't be revoked and are left alone.
+func (s *tokenService) Revoke(claims *utils.JWTClaims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	err := s.tokenRepo.Revoke(&models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// RevokeToken validates a signed token, such as a refresh token sent at
+// logout, and revokes it
+func (s *tokenService) RevokeToken(token string) error {
+	claims, err := utils.ValidateToken(token, s.config)
+	if err != nil {
+		return apperrors.BadRequest("invalid or expired token")
+	}
+	return s.Revoke(claims)
+}
+
+func (s *tokenService) IsRevoked(jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+	return s.tokenRepo.IsRevoked(jti)
+}
+
+// CheckToken rejects a revoked token. Tokens that don't validate are left
+// for the caller to reject.
+func (s *tokenService) CheckToken(token string) error {
+	claims, err := utils.ValidateToken(token, s.config)
+	if err != nil {
+		return nil
+	}
+
+	revoked, err := s.IsRevoked(claims.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return apperrors.Unauthorized("token has been revoked")
+	}
+	return nil
+}
+
+// PurgeExpired deletes the revocations of tokens that have expired since,
+// which their expiry rejects anyway
+func (s *tokenService) PurgeExpired() (int64, error) {
+	return s.tokenRepo.DeleteExpired(time.Now())
+}
+
+// RunTokenPurgeScheduler purges expired token revocations every interval
+// until ctx is cancelled
+func RunTokenPurgeScheduler(ctx context.Context, tokens TokenService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := tokens.PurgeExpired()
+			if err != nil {
+				log.Printf("Token revocation purge failed: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("🗑️ Purged %d expired token revocation(s)", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenService(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour}}
+	svc := service.NewTokenService(repository.NewTokenRepository(db), cfg)
+
+	user := &models.User{ID: 7, Email: "revoker@example.com", Username: "revoker"}
+	token, err := utils.GenerateToken(user, cfg)
+	require.NoError(t, err)
+	claims, err := utils.ValidateToken(token, cfg)
+	require.NoError(t, err)
+
+	t.Run("revoked tokens are rejected", func(t *testing.T) {
+		require.NoError(t, svc.CheckToken(token))
+
+		require.NoError(t, svc.Revoke(claims))
+		revoked, err := svc.IsRevoked(claims.ID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+		assert.ErrorIs(t, svc.CheckToken(token), apperrors.ErrUnauthorized)
+
+		// Revoking it again changes nothing
+		require.NoError(t, svc.Revoke(claims))
+	})
+
+	t.Run("other tokens are unaffected", func(t *testing.T) {
+		refresh, err := utils.GenerateRefreshToken(user, cfg, 24*time.Hour)
+		require.NoError(t, err)
+		assert.NoError(t, svc.CheckToken(refresh))
+
+		require.NoError(t, svc.RevokeToken(refresh))
+		assert.ErrorIs(t, svc.CheckToken(refresh), apperrors.ErrUnauthorized)
+
+		assert.ErrorIs(t, svc.RevokeToken("not-a-token"), apperrors.ErrBadRequest)
+	})
+
+	t.Run("revocations are purged once the token expires", func(t *testing.T) {
+		require.NoError(t, db.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).
+			Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+		purged, err := svc.PurgeExpired()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		revoked, err := svc.IsRevoked(claims.ID)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+
+		var remaining int64
+		require.NoError(t, db.Model(&models.RevokedToken{}).Count(&remaining).Error)
+		assert.Equal(t, int64(1), remaining, "the unexpired refresh token stays revoked")
+	})
+}
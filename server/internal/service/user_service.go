@@ -1,11 +1,19 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/breach"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
@@ -16,40 +24,94 @@ type UserService interface {
 	Login(req *models.UserLoginRequest) (*models.AuthResponse, error)
 	GetProfile(userID uint) (*models.UserResponse, error)
 	UpdateProfile(userID uint, req *models.UserUpdateRequest) (*models.UserResponse, error)
-	GetUsers(page, perPage int) ([]models.UserResponse, models.PaginationMeta, error)
+	GetUsers(filter models.UserFilter, sort models.UserSort, page, perPage int) ([]models.AdminUserResponse, models.PaginationMeta, error)
 	GetUserByID(id uint) (*models.UserResponse, error)
-	DeactivateUser(id uint) error
+	DeactivateUser(id uint, req *models.UserDeactivateRequest) error
 	ActivateUser(id uint) error
+	RestoreUser(id uint) error
+	DeleteUser(id uint, cascade, reassign bool) error
+	PurgeDeletedUsers() (int, error)
 	ChangePassword(userID uint, oldPassword, newPassword string) error
+	RequestEmailChange(userID uint, req *models.EmailChangeRequest) (*models.UserResponse, error)
+	ConfirmEmailChange(token string) (*models.UserResponse, error)
 	RefreshToken(token string) (*models.AuthResponse, error)
+	IsUsernameAvailable(username string) bool
+	IsEmailAvailable(email string) bool
+}
+
+// UserHasContentError is returned when deleting a user who still has posts
+// or comments without saying what should happen to them. It is a conflict.
+type UserHasContentError struct {
+	PostsCount    int64
+	CommentsCount int64
+}
+
+func (e *UserHasContentError) Error() string {
+	return fmt.Sprintf("user has %d post(s) and %d comment(s); pass cascade=true to delete them or reassign=true to move them to the deleted user account",
+		e.PostsCount, e.CommentsCount)
+}
+
+// Unwrap makes the error match apperrors.ErrConflict
+func (e *UserHasContentError) Unwrap() error {
+	return apperrors.ErrConflict
 }
 
 type userService struct {
 	userRepo repository.UserRepository
+	breaches breach.Checker
+	mailer   mailer.Mailer
 	config   *config.Config
 }
 
-func NewUserService(userRepo repository.UserRepository, config *config.Config) UserService {
+func NewUserService(userRepo repository.UserRepository, breaches breach.Checker, m mailer.Mailer, config *config.Config) UserService {
 	return &userService{
 		userRepo: userRepo,
+		breaches: breaches,
+		mailer:   m,
 		config:   config,
 	}
 }
 
+// checkPassword applies the password policy to a new password, looking it
+// up in the breach API when that check is enabled. A failed lookup is logged
+// and doesn't block the password.
+func (s *userService) checkPassword(password string) []models.ValidationError {
+	validationErrors := utils.ValidatePassword(password, s.config.Password)
+
+	breached, err := s.breaches.Breached(context.Background(), password)
+	if err != nil {
+		log.Printf("Warning: password breach check failed: %v", err)
+	} else if breached {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Field:   "Password",
+			Tag:     "breached",
+			Message: "Password has appeared in a data breach; choose a different one",
+		})
+	}
+	return validationErrors
+}
+
 func (s *userService) Register(req *models.UserCreateRequest) (*models.UserResponse, error) {
+	// Normalize email so case variants can't register separate accounts
+	req.Email = utils.NormalizeEmail(req.Email)
+
 	// Validate request
-	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
-		return nil, fmt.Errorf("validation failed: %v", validationErrors)
+	validationErrors := utils.ValidateStruct(req)
+	if req.Password != "" {
+		validationErrors = append(validationErrors, s.checkPassword(req.Password)...)
+	}
+	if len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
 	}
 
 	// Check if email is already taken
 	if s.userRepo.IsEmailTaken(req.Email, 0) {
-		return nil, errors.New("email is already registered")
+		return nil, apperrors.Conflict("email is already registered")
 	}
 
 	// Check if username is already taken
 	if s.userRepo.IsUsernameTaken(req.Username, 0) {
-		return nil, errors.New("username is already taken")
+		return nil, apperrors.Conflict("username is already taken")
 	}
 
 	// Create user
@@ -66,6 +128,9 @@ func (s *userService) Register(req *models.UserCreateRequest) (*models.UserRespo
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
+		if errors.Is(err, repository.ErrUserTaken) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -76,23 +141,27 @@ func (s *userService) Register(req *models.UserCreateRequest) (*models.UserRespo
 func (s *userService) Login(req *models.UserLoginRequest) (*models.AuthResponse, error) {
 	// Validate request
 	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
-		return nil, fmt.Errorf("validation failed: %v", validationErrors)
+		return nil, &ValidationError{Fields: validationErrors}
 	}
 
 	// Find user by email or username
 	user, err := s.userRepo.GetByEmailOrUsername(req.EmailOrUsername)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, apperrors.Unauthorized("invalid credentials")
 	}
 
 	// Check if user is active
 	if !user.IsActive {
-		return nil, errors.New("account is deactivated")
+		return nil, apperrors.Unauthorized("account is deactivated")
 	}
 
 	// Verify password
 	if !user.CheckPassword(req.Password) {
-		return nil, errors.New("invalid credentials")
+		return nil, apperrors.Unauthorized("invalid credentials")
+	}
+
+	if err := s.userRepo.RecordLogin(user.ID, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
 	}
 
 	// Generate JWT token
@@ -101,12 +170,25 @@ func (s *userService) Login(req *models.UserLoginRequest) (*models.AuthResponse,
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
+	// Remember-me sessions get a longer-lived refresh token
+	refreshExpiresIn := s.config.JWT.RefreshExpiresIn
+	if req.RememberMe {
+		refreshExpiresIn = s.config.JWT.RememberMeExpiresIn
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken(user, s.config, refreshExpiresIn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
 	userResponse := user.ToResponse()
 	return &models.AuthResponse{
-		User:      userResponse,
-		Token:     token,
-		TokenType: "Bearer",
-		ExpiresIn: int(s.config.JWT.ExpiresIn.Seconds()),
+		User:             userResponse,
+		Token:            token,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(s.config.JWT.ExpiresIn.Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: int(refreshExpiresIn.Seconds()),
 	}, nil
 }
 
@@ -121,9 +203,12 @@ func (s *userService) GetProfile(userID uint) (*models.UserResponse, error) {
 }
 
 func (s *userService) UpdateProfile(userID uint, req *models.UserUpdateRequest) (*models.UserResponse, error) {
+	// Normalize email the same way registration does
+	req.Email = utils.NormalizeEmail(req.Email)
+
 	// Validate request
 	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
-		return nil, fmt.Errorf("validation failed: %v", validationErrors)
+		return nil, &ValidationError{Fields: validationErrors}
 	}
 
 	// Get existing user
@@ -132,18 +217,15 @@ func (s *userService) UpdateProfile(userID uint, req *models.UserUpdateRequest)
 		return nil, err
 	}
 
-	// Check if email is already taken (excluding current user)
+	// A new email has to be confirmed from the address itself first
 	if req.Email != "" && req.Email != user.Email {
-		if s.userRepo.IsEmailTaken(req.Email, userID) {
-			return nil, errors.New("email is already registered")
-		}
-		user.Email = req.Email
+		return nil, apperrors.Validation("email can only be changed through /auth/change-email")
 	}
 
 	// Check if username is already taken (excluding current user)
 	if req.Username != "" && req.Username != user.Username {
 		if s.userRepo.IsUsernameTaken(req.Username, userID) {
-			return nil, errors.New("username is already taken")
+			return nil, apperrors.Conflict("username is already taken")
 		}
 		user.Username = req.Username
 	}
@@ -161,6 +243,9 @@ func (s *userService) UpdateProfile(userID uint, req *models.UserUpdateRequest)
 	}
 
 	if err := s.userRepo.Update(user); err != nil {
+		if errors.Is(err, repository.ErrUserTaken) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
@@ -168,16 +253,29 @@ func (s *userService) UpdateProfile(userID uint, req *models.UserUpdateRequest)
 	return &response, nil
 }
 
-func (s *userService) GetUsers(page, perPage int) ([]models.UserResponse, models.PaginationMeta, error) {
+// GetUsers returns a page of the users matching filter in the given order,
+// with their post and comment counts. A filter nothing matches gives an
+// empty page.
+func (s *userService) GetUsers(filter models.UserFilter, sort models.UserSort, page, perPage int) ([]models.AdminUserResponse, models.PaginationMeta, error) {
+	if !sort.IsValid() {
+		return nil, models.PaginationMeta{}, apperrors.BadRequest(fmt.Sprintf("invalid sort %q: must be %s, %s or %s", sort, models.UserSortCreatedAt, models.UserSortLastLogin, models.UserSortUsername))
+	}
+
 	offset := (page - 1) * perPage
-	users, total, err := s.userRepo.List(offset, perPage)
+	users, total, err := s.userRepo.ListWithCounts(filter, sort, offset, perPage)
 	if err != nil {
 		return nil, models.PaginationMeta{}, err
 	}
 
-	var responses []models.UserResponse
+	responses := make([]models.AdminUserResponse, 0, len(users))
 	for _, user := range users {
-		responses = append(responses, user.ToResponse())
+		responses = append(responses, models.AdminUserResponse{
+			UserResponse:  user.ToResponse(),
+			LastLoginAt:   user.LastLoginAt,
+			PostsCount:    user.PostsCount,
+			CommentsCount: user.CommentsCount,
+			PurgeAt:       user.PurgeAt,
+		})
 	}
 
 	pagination := utils.CalculatePagination(page, perPage, total)
@@ -194,14 +292,95 @@ func (s *userService) GetUserByID(id uint) (*models.UserResponse, error) {
 	return &response, nil
 }
 
-func (s *userService) DeactivateUser(id uint) error {
+// DeactivateUser deactivates a user. When req asks for it, the user is also
+// soft-deleted and purged once DeleteAfterDays have passed, unless restored
+// first; like deletion, that can't be done to the last admin.
+func (s *userService) DeactivateUser(id uint, req *models.UserDeactivateRequest) error {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return &ValidationError{Fields: validationErrors}
+	}
+
 	user, err := s.userRepo.GetByID(id)
 	if err != nil {
 		return err
 	}
 
-	user.IsActive = false
-	return s.userRepo.Update(user)
+	if req.DeleteAfterDays == 0 {
+		user.IsActive = false
+		return s.userRepo.Update(user)
+	}
+
+	if err := s.checkNotLastAdmin(user); err != nil {
+		return err
+	}
+	purgeAt := time.Now().AddDate(0, 0, req.DeleteAfterDays)
+	return s.userRepo.ScheduleDeletion(id, purgeAt)
+}
+
+// RestoreUser cancels a user's scheduled deletion. The account stays
+// deactivated until an admin activates it.
+func (s *userService) RestoreUser(id uint) error {
+	return s.userRepo.Restore(id)
+}
+
+// PurgeDeletedUsers permanently deletes the users whose scheduled deletion
+// is due. Their posts and comments are handed over to the deleted user
+// placeholder, as with DeleteUser's reassign. It returns how many users were
+// purged; one failing doesn't stop the others.
+func (s *userService) PurgeDeletedUsers() (int, error) {
+	users, err := s.userRepo.GetPurgeable(time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to load users due for purging: %w", err)
+	}
+
+	purged := 0
+	var errs []error
+	for _, user := range users {
+		if err := s.purgeUser(user.ID); err != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", user.ID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+func (s *userService) purgeUser(id uint) error {
+	posts, comments, err := s.userRepo.CountContent(id)
+	if err != nil {
+		return fmt.Errorf("failed to count user content: %w", err)
+	}
+	if posts == 0 && comments == 0 {
+		return s.userRepo.Delete(id)
+	}
+
+	placeholder, err := deletedUserPlaceholder()
+	if err != nil {
+		return err
+	}
+	return s.userRepo.ReassignAndDelete(id, placeholder)
+}
+
+// RunUserPurgeScheduler purges the users due for deletion every interval
+// until ctx is cancelled
+func RunUserPurgeScheduler(ctx context.Context, users UserService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := users.PurgeDeletedUsers()
+			if err != nil {
+				log.Printf("User purge failed: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("🗑️ Purged %d deleted user(s)", purged)
+			}
+		}
+	}
 }
 
 func (s *userService) ActivateUser(id uint) error {
@@ -214,6 +393,78 @@ func (s *userService) ActivateUser(id uint) error {
 	return s.userRepo.Update(user)
 }
 
+// DeleteUser deletes a user. Their posts and comments are deleted with them
+// when cascade is set, or handed over to the deleted user placeholder when
+// reassign is set; with neither, a user who has any is not deleted. The
+// last admin can't be deleted.
+func (s *userService) DeleteUser(id uint, cascade, reassign bool) error {
+	if cascade && reassign {
+		return apperrors.Validation("cascade and reassign cannot both be set")
+	}
+
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkNotLastAdmin(user); err != nil {
+		return err
+	}
+
+	switch {
+	case cascade:
+		return s.userRepo.DeleteWithContent(id)
+	case reassign:
+		placeholder, err := deletedUserPlaceholder()
+		if err != nil {
+			return err
+		}
+		return s.userRepo.ReassignAndDelete(id, placeholder)
+	}
+
+	posts, comments, err := s.userRepo.CountContent(id)
+	if err != nil {
+		return fmt.Errorf("failed to count user content: %w", err)
+	}
+	if posts > 0 || comments > 0 {
+		return &UserHasContentError{PostsCount: posts, CommentsCount: comments}
+	}
+	return s.userRepo.Delete(id)
+}
+
+// checkNotLastAdmin refuses to delete the only admin account
+func (s *userService) checkNotLastAdmin(user *models.User) error {
+	if !user.IsAdmin {
+		return nil
+	}
+	admins, err := s.userRepo.CountAdmins()
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if admins <= 1 {
+		return apperrors.Conflict("the last admin cannot be deleted")
+	}
+	return nil
+}
+
+// deletedUserPlaceholder returns the account that takes over the content of
+// deleted users. It is inactive and gets a random password, so nobody can
+// sign in as it.
+func deletedUserPlaceholder() (*models.User, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate placeholder password: %w", err)
+	}
+
+	return &models.User{
+		FirstName: "Deleted",
+		LastName:  "User",
+		Email:     models.DeletedUserUsername + "@users.invalid",
+		Username:  models.DeletedUserUsername,
+		Password:  hex.EncodeToString(secret),
+	}, nil
+}
+
 func (s *userService) ChangePassword(userID uint, oldPassword, newPassword string) error {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
@@ -222,30 +473,118 @@ func (s *userService) ChangePassword(userID uint, oldPassword, newPassword strin
 
 	// Verify old password
 	if !user.CheckPassword(oldPassword) {
-		return errors.New("invalid current password")
+		return apperrors.Unauthorized("invalid current password")
 	}
 
 	// Validate new password
-	if len(newPassword) < 8 {
-		return errors.New("new password must be at least 8 characters long")
+	if validationErrors := s.checkPassword(newPassword); len(validationErrors) > 0 {
+		return &ValidationError{Fields: validationErrors}
 	}
 
-	// Update password (will be hashed by BeforeCreate hook)
-	user.Password = newPassword
+	if user.CheckPassword(newPassword) {
+		return apperrors.Validation("new password must differ from the current password")
+	}
+
+	if err := user.SetPassword(newPassword); err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.MustChangePassword = false
 	user.UpdatedAt = time.Now()
 
 	return s.userRepo.Update(user)
 }
 
-func (s *userService) RefreshToken(token string) (*models.AuthResponse, error) {
-	// Validate and refresh token
-	newToken, err := utils.RefreshToken(token, s.config)
+// RequestEmailChange records req.Email as the user's pending email and sends
+// a confirmation link to it. The current email stays in use until the link
+// is followed; asking again replaces the pending email.
+func (s *userService) RequestEmailChange(userID uint, req *models.EmailChangeRequest) (*models.UserResponse, error) {
+	req.Email = utils.NormalizeEmail(req.Email)
+
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, &ValidationError{Fields: validationErrors}
+	}
+
+	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Extract user info from token
-	claims, err := utils.ValidateToken(newToken, s.config)
+	if !user.CheckPassword(req.Password) {
+		return nil, apperrors.Unauthorized("invalid current password")
+	}
+
+	if req.Email == user.Email {
+		return nil, apperrors.Validation("new email must differ from the current email")
+	}
+	if s.userRepo.IsEmailTaken(req.Email, userID) {
+		return nil, apperrors.Conflict("email is already registered")
+	}
+
+	token, _, err := utils.GenerateEmailChangeToken(user.ID, req.Email, &s.config.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate email change token: %w", err)
+	}
+
+	user.PendingEmail = req.Email
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	msg := mailer.Message{
+		To:      req.Email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nFollow this link to start using this address for your account:\n\n   %s/api/v1/auth/confirm-email-change?token=%s\n\nThe link expires in %s. If you didn't ask for this, ignore this email and nothing will change.\n",
+			user.FirstName, s.config.App.BaseURL, token, s.config.JWT.EmailChangeExpiresIn),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		return nil, fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// ConfirmEmailChange makes the pending email from an email change token the
+// user's email. A token for an email that is no longer pending, because it
+// was confirmed or replaced since, is rejected.
+func (s *userService) ConfirmEmailChange(token string) (*models.UserResponse, error) {
+	claims, err := utils.ValidateEmailChangeToken(token, &s.config.JWT)
+	if err != nil {
+		return nil, apperrors.Unauthorized("email change link is invalid or has expired")
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.PendingEmail == "" || user.PendingEmail != claims.Email {
+		return nil, apperrors.Unauthorized("email change link is invalid or has expired")
+	}
+
+	// Someone may have registered the address while the link was unused
+	if s.userRepo.IsEmailTaken(claims.Email, user.ID) {
+		return nil, apperrors.Conflict("email is already registered")
+	}
+
+	user.Email = claims.Email
+	user.PendingEmail = ""
+	if err := s.userRepo.Update(user); err != nil {
+		if errors.Is(err, repository.ErrUserTaken) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access token. The token
+// is issued from the stored user, so changes since login, such as a lost
+// admin role or a password that has to be changed, take effect.
+func (s *userService) RefreshToken(token string) (*models.AuthResponse, error) {
+	claims, err := utils.ValidateRefreshToken(token, s.config)
 	if err != nil {
 		return nil, err
 	}
@@ -258,7 +597,12 @@ func (s *userService) RefreshToken(token string) (*models.AuthResponse, error) {
 
 	// Check if user is still active
 	if !user.IsActive {
-		return nil, errors.New("account is deactivated")
+		return nil, apperrors.Unauthorized("account is deactivated")
+	}
+
+	newToken, err := utils.GenerateToken(user, s.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	userResponse := user.ToResponse()
@@ -269,3 +613,14 @@ func (s *userService) RefreshToken(token string) (*models.AuthResponse, error) {
 		ExpiresIn: int(s.config.JWT.ExpiresIn.Seconds()),
 	}, nil
 }
+
+// IsUsernameAvailable reports whether a username can still be registered
+func (s *userService) IsUsernameAvailable(username string) bool {
+	return !s.userRepo.IsUsernameTaken(strings.TrimSpace(username), 0)
+}
+
+// IsEmailAvailable reports whether an email can still be registered. The
+// email is normalized the same way registration does.
+func (s *userService) IsEmailAvailable(email string) bool {
+	return !s.userRepo.IsEmailTaken(utils.NormalizeEmail(email), 0)
+}
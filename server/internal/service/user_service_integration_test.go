@@ -6,7 +6,9 @@ import (
 	"os"
 	"testing"
 
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/breach"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
@@ -22,14 +24,16 @@ var (
 )
 
 func TestMain(m *testing.M) {
-	// Set test environment
+	// Set test environment. Database settings can be overridden, e.g.
+	// DB_DRIVER=sqlite DB_NAME=:memory: to run without Postgres.
 	os.Setenv("GIN_MODE", "test")
-	os.Setenv("DB_HOST", "localhost")
-	os.Setenv("DB_PORT", "5432")
-	os.Setenv("DB_USER", "postgres")
-	os.Setenv("DB_PASSWORD", "postgres")
-	os.Setenv("DB_NAME", "golang_multiuser_blog_test")
-	os.Setenv("DB_SSLMODE", "disable")
+	setDefaultEnv("DB_DRIVER", "postgres")
+	setDefaultEnv("DB_HOST", "localhost")
+	setDefaultEnv("DB_PORT", "5432")
+	setDefaultEnv("DB_USER", "postgres")
+	setDefaultEnv("DB_PASSWORD", "postgres")
+	setDefaultEnv("DB_NAME", "golang_multiuser_blog_test")
+	setDefaultEnv("DB_SSLMODE", "disable")
 	os.Setenv("JWT_SECRET", "test-secret-key")
 
 	// Load test config
@@ -47,7 +51,7 @@ func TestMain(m *testing.M) {
 
 	// Initialize repositories and services
 	userRepo = repository.NewUserRepository(testDB)
-	userSvc = service.NewUserService(userRepo, cfg)
+	userSvc = service.NewUserService(userRepo, breach.NewChecker(cfg.Password), mailer.New(cfg.Mail), cfg)
 
 	// Run tests
 	code := m.Run()
@@ -156,3 +160,10 @@ func TestUserService_Login_InvalidCredentials(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid credentials")
 }
+
+// setDefaultEnv sets an environment variable unless it's already set
+func setDefaultEnv(key, value string) {
+	if _, ok := os.LookupEnv(key); !ok {
+		os.Setenv(key, value)
+	}
+}
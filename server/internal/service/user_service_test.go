@@ -0,0 +1,666 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/breach"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/mailer"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/repository"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/service"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/testutil"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestUserService wires a user service against an in-memory database
+func newTestUserService(t *testing.T) service.UserService {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	cfg := &config.Config{
+		JWT: config.JWTConfig{
+			Secret:              "test-secret-key",
+			ExpiresIn:           time.Hour,
+			RefreshExpiresIn:    24 * time.Hour,
+			RememberMeExpiresIn: 30 * 24 * time.Hour,
+		},
+		Password: config.PasswordConfig{MinLength: 8},
+	}
+	return service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), &fakeMailer{}, cfg)
+}
+
+func TestUserService_EmailNormalization(t *testing.T) {
+	svc := newTestUserService(t)
+
+	user, err := svc.Register(&models.UserCreateRequest{
+		FirstName: "Mixed",
+		LastName:  "Case",
+		Email:     "  Mixed.Case@Example.COM ",
+		Username:  "mixedcase",
+		Password:  "password123",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "mixed.case@example.com", user.Email)
+
+	t.Run("login with differing case", func(t *testing.T) {
+		auth, err := svc.Login(&models.UserLoginRequest{
+			EmailOrUsername: "MIXED.case@example.com",
+			Password:        "password123",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, auth.User.ID)
+	})
+
+	t.Run("login with surrounding whitespace", func(t *testing.T) {
+		_, err := svc.Login(&models.UserLoginRequest{
+			EmailOrUsername: " mixed.case@example.com\t",
+			Password:        "password123",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("case variant cannot register again", func(t *testing.T) {
+		_, err := svc.Register(&models.UserCreateRequest{
+			FirstName: "Other",
+			LastName:  "Person",
+			Email:     "MIXED.CASE@example.com",
+			Username:  "otherperson",
+			Password:  "password123",
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "email is already registered")
+	})
+}
+
+func TestUserService_Availability(t *testing.T) {
+	svc := newTestUserService(t)
+
+	_, err := svc.Register(&models.UserCreateRequest{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane@example.com",
+		Username:  "janedoe",
+		Password:  "password123",
+	})
+	require.NoError(t, err)
+
+	assert.False(t, svc.IsEmailAvailable(" JANE@example.com "))
+	assert.True(t, svc.IsEmailAvailable("someone@example.com"))
+	assert.False(t, svc.IsUsernameAvailable(" janedoe"))
+	assert.True(t, svc.IsUsernameAvailable("someone"))
+}
+
+func TestUserService_GetUsers_NoMatch(t *testing.T) {
+	svc := newTestUserService(t)
+
+	users, pagination, err := svc.GetUsers(models.UserFilter{Query: "nobody"}, "", 1, 10)
+	require.NoError(t, err)
+	assert.NotNil(t, users, "an empty page serializes as [] rather than null")
+	assert.Empty(t, users)
+	assert.Zero(t, pagination.Total)
+}
+
+func TestUserService_Login_Expiry(t *testing.T) {
+	svc := newTestUserService(t)
+
+	_, err := svc.Register(&models.UserCreateRequest{
+		FirstName: "Remy",
+		LastName:  "Member",
+		Email:     "remy@example.com",
+		Username:  "remy",
+		Password:  "password123",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		rememberMe     bool
+		refreshExpires time.Duration
+	}{
+		{name: "default", rememberMe: false, refreshExpires: 24 * time.Hour},
+		{name: "remember me", rememberMe: true, refreshExpires: 30 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := svc.Login(&models.UserLoginRequest{
+				EmailOrUsername: "remy",
+				Password:        "password123",
+				RememberMe:      tt.rememberMe,
+			})
+			require.NoError(t, err)
+
+			// Access token lifetime doesn't depend on remember me
+			assert.Equal(t, int(time.Hour.Seconds()), auth.ExpiresIn)
+			assert.Equal(t, int(tt.refreshExpires.Seconds()), auth.RefreshExpiresIn)
+
+			cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret-key"}}
+			claims, err := utils.ValidateToken(auth.RefreshToken, cfg)
+			require.NoError(t, err)
+			assert.Equal(t, utils.TokenTypeRefresh, claims.TokenType)
+			assert.WithinDuration(t, time.Now().Add(tt.refreshExpires), claims.ExpiresAt.Time, time.Minute)
+
+			claims, err = utils.ValidateToken(auth.Token, cfg)
+			require.NoError(t, err)
+			assert.Empty(t, claims.TokenType)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt.Time, time.Minute)
+		})
+	}
+}
+
+func TestUserService_RefreshToken(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	cfg := &config.Config{
+		JWT:      config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour, RefreshExpiresIn: 24 * time.Hour},
+		Password: config.PasswordConfig{MinLength: 8},
+	}
+	svc := service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), &fakeMailer{}, cfg)
+
+	user, err := svc.Register(&models.UserCreateRequest{FirstName: "Rhea", LastName: "Fresh", Email: "rhea@example.com", Username: "rhea", Password: "password123"})
+	require.NoError(t, err)
+	auth, err := svc.Login(&models.UserLoginRequest{EmailOrUsername: "rhea", Password: "password123"})
+	require.NoError(t, err)
+
+	t.Run("access tokens can't renew themselves", func(t *testing.T) {
+		_, err := svc.RefreshToken(auth.Token)
+		assert.Error(t, err)
+	})
+
+	t.Run("the new token reflects the stored user", func(t *testing.T) {
+		require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{"is_admin": true, "username": "rhea2"}).Error)
+
+		refreshed, err := svc.RefreshToken(auth.RefreshToken)
+		require.NoError(t, err)
+		claims, err := utils.ValidateToken(refreshed.Token, cfg)
+		require.NoError(t, err)
+		assert.Empty(t, claims.TokenType, "an access token")
+		assert.True(t, claims.IsAdmin)
+		assert.Equal(t, "rhea2", claims.Username)
+	})
+}
+
+func TestUserService_ForcedPasswordChange(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour}}
+	svc := service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), &fakeMailer{}, cfg)
+
+	user := testutil.CreateUser(t, db, "rotate")
+	require.NoError(t, db.Model(user).Update("must_change_password", true).Error)
+
+	auth, err := svc.Login(&models.UserLoginRequest{EmailOrUsername: "rotate", Password: "password123"})
+	require.NoError(t, err)
+	assert.True(t, auth.User.MustChangePassword)
+	claims, err := utils.ValidateToken(auth.Token, cfg)
+	require.NoError(t, err)
+	assert.True(t, claims.MustChangePassword)
+
+	err = svc.ChangePassword(user.ID, "password123", "password123")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+
+	require.NoError(t, svc.ChangePassword(user.ID, "password123", "n3w-password"))
+
+	_, err = svc.Login(&models.UserLoginRequest{EmailOrUsername: "rotate", Password: "password123"})
+	require.Error(t, err)
+	auth, err = svc.Login(&models.UserLoginRequest{EmailOrUsername: "rotate", Password: "n3w-password"})
+	require.NoError(t, err)
+	assert.False(t, auth.User.MustChangePassword)
+	claims, err = utils.ValidateToken(auth.Token, cfg)
+	require.NoError(t, err)
+	assert.False(t, claims.MustChangePassword)
+}
+
+// racingUserRepo reports every email and username as available, like a
+// request whose availability check ran before a concurrent one saved
+type racingUserRepo struct {
+	repository.UserRepository
+}
+
+func (racingUserRepo) IsEmailTaken(string, uint) bool    { return false }
+func (racingUserRepo) IsUsernameTaken(string, uint) bool { return false }
+
+func TestUserService_PasswordPolicy(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	cfg := &config.Config{Password: config.PasswordConfig{MinLength: 10, RequireDigit: true, RequireSymbol: true}}
+	svc := service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), &fakeMailer{}, cfg)
+
+	register := func(password string) error {
+		_, err := svc.Register(&models.UserCreateRequest{
+			FirstName: "Policy",
+			LastName:  "Tester",
+			Email:     "policy@example.com",
+			Username:  "policytester",
+			Password:  password,
+		})
+		return err
+	}
+
+	err := register("password")
+	var invalid *service.ValidationError
+	require.ErrorAs(t, err, &invalid)
+	require.Len(t, invalid.Fields, 3)
+	assert.Equal(t, "Password must be at least 10 characters long", invalid.Fields[0].Message)
+	assert.Equal(t, "digit", invalid.Fields[1].Tag)
+	assert.Equal(t, "symbol", invalid.Fields[2].Tag)
+
+	require.NoError(t, register("c0rrect-horse"))
+
+	user := testutil.CreateUser(t, db, "policychanger")
+	err = svc.ChangePassword(user.ID, "password123", "n0symbolshere")
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "symbol", invalid.Fields[0].Tag)
+	require.NoError(t, svc.ChangePassword(user.ID, "password123", "battery-staple-9"))
+}
+
+// stubBreaches reports the passwords in breached as breached, or fails every
+// lookup with err
+type stubBreaches struct {
+	breached map[string]bool
+	err      error
+}
+
+func (s stubBreaches) Breached(ctx context.Context, password string) (bool, error) {
+	return s.breached[password], s.err
+}
+
+func TestUserService_BreachedPasswords(t *testing.T) {
+	cfg := &config.Config{Password: config.PasswordConfig{MinLength: 8}}
+	register := func(svc service.UserService, password string) error {
+		_, err := svc.Register(&models.UserCreateRequest{
+			FirstName: "Breach",
+			LastName:  "Tester",
+			Email:     "breach@example.com",
+			Username:  "breachtester",
+			Password:  password,
+		})
+		return err
+	}
+
+	t.Run("breached passwords are rejected", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		svc := service.NewUserService(repository.NewUserRepository(db), stubBreaches{breached: map[string]bool{"password123": true}}, &fakeMailer{}, cfg)
+
+		err := register(svc, "password123")
+		var invalid *service.ValidationError
+		require.ErrorAs(t, err, &invalid)
+		require.Len(t, invalid.Fields, 1)
+		assert.Equal(t, "breached", invalid.Fields[0].Tag)
+		require.NoError(t, register(svc, "c0rrect-horse-battery"))
+
+		user := testutil.CreateUser(t, db, "breachchanger")
+		err = svc.ChangePassword(user.ID, "password123", "password123")
+		require.ErrorAs(t, err, &invalid)
+		assert.Equal(t, "breached", invalid.Fields[0].Tag)
+	})
+
+	t.Run("an unavailable breach API doesn't block passwords", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		svc := service.NewUserService(repository.NewUserRepository(db), stubBreaches{err: errors.New("connection refused")}, &fakeMailer{}, cfg)
+
+		require.NoError(t, register(svc, "c0rrect-horse-battery"))
+	})
+}
+func TestUserService_UniqueConstraintRace(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	mail := &fakeMailer{}
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret-key", EmailChangeExpiresIn: time.Hour}}
+	svc := service.NewUserService(racingUserRepo{repository.NewUserRepository(db)}, breach.NewChecker(config.PasswordConfig{}), mail, cfg)
+
+	register := func(email, username string) (*models.UserResponse, error) {
+		return svc.Register(&models.UserCreateRequest{
+			FirstName: "Race",
+			LastName:  "Condition",
+			Email:     email,
+			Username:  username,
+			Password:  "password123",
+		})
+	}
+
+	first, err := register("first@example.com", "first")
+	require.NoError(t, err)
+	second, err := register("second@example.com", "second")
+	require.NoError(t, err)
+
+	t.Run("register with a taken email", func(t *testing.T) {
+		_, err := register("first@example.com", "third")
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("register with a taken username", func(t *testing.T) {
+		_, err := register("third@example.com", "first")
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("confirm a change to a taken email", func(t *testing.T) {
+		_, err := svc.RequestEmailChange(second.ID, &models.EmailChangeRequest{Email: first.Email, Password: "password123"})
+		require.NoError(t, err)
+		require.Len(t, mail.sent, 1)
+
+		_, err = svc.ConfirmEmailChange(emailChangeToken(t, mail.sent[0]))
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("update to a taken username", func(t *testing.T) {
+		_, err := svc.UpdateProfile(second.ID, &models.UserUpdateRequest{Username: first.Username})
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+}
+
+// emailChangeToken returns the token from the link in an email change
+// confirmation
+func emailChangeToken(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+
+	match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(msg.Body)
+	require.NotNil(t, match, "no confirmation link in %q", msg.Body)
+	return match[1]
+}
+
+func TestUserService_EmailChange(t *testing.T) {
+	setup := func(t *testing.T, expiresIn time.Duration) (service.UserService, *fakeMailer, *models.UserResponse) {
+		db := testutil.NewTestDB(t)
+		mail := &fakeMailer{}
+		cfg := &config.Config{
+			JWT: config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour, EmailChangeExpiresIn: expiresIn},
+			App: config.AppConfig{BaseURL: "https://blog.example.com"},
+		}
+		svc := service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), mail, cfg)
+
+		user, err := svc.Register(&models.UserCreateRequest{
+			FirstName: "Ema",
+			LastName:  "Mover",
+			Email:     "old@example.com",
+			Username:  "mover",
+			Password:  "password123",
+		})
+		require.NoError(t, err)
+		return svc, mail, user
+	}
+
+	login := func(svc service.UserService, email string) error {
+		_, err := svc.Login(&models.UserLoginRequest{EmailOrUsername: email, Password: "password123"})
+		return err
+	}
+
+	t.Run("pending until confirmed", func(t *testing.T) {
+		svc, mail, user := setup(t, time.Hour)
+
+		pending, err := svc.RequestEmailChange(user.ID, &models.EmailChangeRequest{Email: " New@Example.com ", Password: "password123"})
+		require.NoError(t, err)
+		assert.Equal(t, "old@example.com", pending.Email)
+		assert.Equal(t, "new@example.com", pending.PendingEmail)
+
+		require.Len(t, mail.sent, 1)
+		assert.Equal(t, "new@example.com", mail.sent[0].To)
+		assert.Contains(t, mail.sent[0].Body, "https://blog.example.com/api/v1/auth/confirm-email-change?token=")
+
+		// The old address keeps working until the link is followed
+		assert.NoError(t, login(svc, "old@example.com"))
+		assert.Error(t, login(svc, "new@example.com"))
+
+		confirmed, err := svc.ConfirmEmailChange(emailChangeToken(t, mail.sent[0]))
+		require.NoError(t, err)
+		assert.Equal(t, "new@example.com", confirmed.Email)
+		assert.Empty(t, confirmed.PendingEmail)
+
+		assert.NoError(t, login(svc, "new@example.com"))
+		assert.Error(t, login(svc, "old@example.com"))
+
+		t.Run("link can't be reused", func(t *testing.T) {
+			_, err := svc.ConfirmEmailChange(emailChangeToken(t, mail.sent[0]))
+			assert.ErrorIs(t, err, apperrors.ErrUnauthorized)
+		})
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		svc, mail, user := setup(t, -time.Minute)
+
+		_, err := svc.RequestEmailChange(user.ID, &models.EmailChangeRequest{Email: "new@example.com", Password: "password123"})
+		require.NoError(t, err)
+		require.Len(t, mail.sent, 1)
+
+		_, err = svc.ConfirmEmailChange(emailChangeToken(t, mail.sent[0]))
+		assert.ErrorIs(t, err, apperrors.ErrUnauthorized)
+
+		profile, err := svc.GetProfile(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "old@example.com", profile.Email)
+	})
+
+	t.Run("superseded link", func(t *testing.T) {
+		svc, mail, user := setup(t, time.Hour)
+
+		for _, email := range []string{"first@example.com", "second@example.com"} {
+			_, err := svc.RequestEmailChange(user.ID, &models.EmailChangeRequest{Email: email, Password: "password123"})
+			require.NoError(t, err)
+		}
+		require.Len(t, mail.sent, 2)
+
+		_, err := svc.ConfirmEmailChange(emailChangeToken(t, mail.sent[0]))
+		assert.ErrorIs(t, err, apperrors.ErrUnauthorized)
+
+		confirmed, err := svc.ConfirmEmailChange(emailChangeToken(t, mail.sent[1]))
+		require.NoError(t, err)
+		assert.Equal(t, "second@example.com", confirmed.Email)
+	})
+
+	t.Run("rejected requests", func(t *testing.T) {
+		svc, mail, user := setup(t, time.Hour)
+
+		_, err := svc.RequestEmailChange(user.ID, &models.EmailChangeRequest{Email: "new@example.com", Password: "wrong-password"})
+		assert.ErrorIs(t, err, apperrors.ErrUnauthorized)
+
+		_, err = svc.RequestEmailChange(user.ID, &models.EmailChangeRequest{Email: "OLD@example.com", Password: "password123"})
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+
+		_, err = svc.UpdateProfile(user.ID, &models.UserUpdateRequest{Email: "new@example.com"})
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+
+		assert.Empty(t, mail.sent)
+	})
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	// seed gives author a post with a reply thread from reader, and a
+	// comment of their own on reader's post
+	seed := func(t *testing.T) (service.UserService, *gorm.DB, *models.User, *models.User) {
+		db := testutil.NewTestDB(t)
+		svc := service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), &fakeMailer{}, &config.Config{})
+
+		author := testutil.CreateUser(t, db, "author")
+		reader := testutil.CreateUser(t, db, "reader")
+		tag := &models.Tag{Name: "Go", Slug: "go"}
+		require.NoError(t, db.Create(tag).Error)
+
+		post := &models.Post{Title: "Author's post", Slug: "authors-post", Content: "Body", Status: models.PostStatusPublished, AuthorID: author.ID, Tags: []models.Tag{*tag}}
+		require.NoError(t, db.Create(post).Error)
+		question := &models.Comment{Content: "Question", AuthorID: &reader.ID, PostID: post.ID}
+		require.NoError(t, db.Create(question).Error)
+		require.NoError(t, db.Create(&models.Comment{Content: "Answer", AuthorID: &author.ID, PostID: post.ID, ParentID: &question.ID}).Error)
+
+		readerPost := &models.Post{Title: "Reader's post", Slug: "readers-post", Content: "Body", Status: models.PostStatusPublished, AuthorID: reader.ID}
+		require.NoError(t, db.Create(readerPost).Error)
+		remark := &models.Comment{Content: "Remark", AuthorID: &author.ID, PostID: readerPost.ID}
+		require.NoError(t, db.Create(remark).Error)
+		require.NoError(t, db.Create(&models.Comment{Content: "Reply to remark", AuthorID: &reader.ID, PostID: readerPost.ID, ParentID: &remark.ID}).Error)
+
+		return svc, db, author, reader
+	}
+
+	t.Run("content blocks deletion without a flag", func(t *testing.T) {
+		svc, db, author, _ := seed(t)
+
+		err := svc.DeleteUser(author.ID, false, false)
+		require.ErrorIs(t, err, apperrors.ErrConflict)
+		var hasContent *service.UserHasContentError
+		require.ErrorAs(t, err, &hasContent)
+		assert.Equal(t, int64(1), hasContent.PostsCount)
+		assert.Equal(t, int64(2), hasContent.CommentsCount)
+
+		var users int64
+		db.Model(&models.User{}).Where("id = ?", author.ID).Count(&users)
+		assert.Equal(t, int64(1), users)
+	})
+
+	t.Run("cascade deletes the user's content", func(t *testing.T) {
+		svc, db, author, reader := seed(t)
+
+		require.NoError(t, svc.DeleteUser(author.ID, true, false))
+
+		var posts []models.Post
+		db.Find(&posts)
+		require.Len(t, posts, 1)
+		assert.Equal(t, reader.ID, posts[0].AuthorID)
+
+		var comments int64
+		db.Unscoped().Model(&models.Comment{}).Count(&comments)
+		assert.Zero(t, comments, "comments on the post and replies to the user's comments go too")
+
+		var links int64
+		db.Table("post_tags").Count(&links)
+		assert.Zero(t, links)
+
+		_, err := svc.GetUserByID(author.ID)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("reassign moves content to the placeholder", func(t *testing.T) {
+		svc, db, author, _ := seed(t)
+
+		require.NoError(t, svc.DeleteUser(author.ID, false, true))
+
+		var placeholder models.User
+		require.NoError(t, db.Where("username = ?", models.DeletedUserUsername).First(&placeholder).Error)
+		assert.False(t, placeholder.IsActive)
+
+		var posts, comments int64
+		db.Model(&models.Post{}).Where("author_id = ?", placeholder.ID).Count(&posts)
+		db.Model(&models.Comment{}).Where("author_id = ?", placeholder.ID).Count(&comments)
+		assert.Equal(t, int64(1), posts)
+		assert.Equal(t, int64(2), comments)
+
+		_, err := svc.GetUserByID(author.ID)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		// A second deletion reuses the same placeholder
+		other := testutil.CreateUser(t, db, "other")
+		require.NoError(t, db.Create(&models.Post{Title: "Other", Slug: "other", Content: "Body", AuthorID: other.ID}).Error)
+		require.NoError(t, svc.DeleteUser(other.ID, false, true))
+		db.Model(&models.Post{}).Where("author_id = ?", placeholder.ID).Count(&posts)
+		assert.Equal(t, int64(2), posts)
+	})
+
+	t.Run("last admin cannot be deleted", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		svc := service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), &fakeMailer{}, &config.Config{})
+		admin := testutil.CreateUser(t, db, "admin")
+		require.NoError(t, db.Model(admin).Update("is_admin", true).Error)
+
+		err := svc.DeleteUser(admin.ID, true, false)
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+
+		second := testutil.CreateUser(t, db, "second")
+		require.NoError(t, db.Model(second).Update("is_admin", true).Error)
+		assert.NoError(t, svc.DeleteUser(admin.ID, false, false))
+	})
+}
+
+func TestUserService_ScheduledDeletion(t *testing.T) {
+	seed := func(t *testing.T) (service.UserService, *gorm.DB, *models.User) {
+		db := testutil.NewTestDB(t)
+		svc := service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), &fakeMailer{}, &config.Config{
+			JWT:      config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour, RefreshExpiresIn: 24 * time.Hour},
+			Password: config.PasswordConfig{MinLength: 8},
+		})
+
+		user := testutil.CreateUser(t, db, "leaving")
+		require.NoError(t, db.Create(&models.Post{Title: "Farewell", Slug: "farewell", Content: "Body", Status: models.PostStatusPublished, AuthorID: user.ID}).Error)
+
+		require.NoError(t, svc.DeactivateUser(user.ID, &models.UserDeactivateRequest{DeleteAfterDays: 7}))
+		return svc, db, user
+	}
+
+	t.Run("soft-deleted users are hidden", func(t *testing.T) {
+		svc, _, user := seed(t)
+
+		_, err := svc.GetUserByID(user.ID)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		_, err = svc.Login(&models.UserLoginRequest{EmailOrUsername: user.Username, Password: "password123"})
+		assert.Error(t, err)
+
+		users, _, err := svc.GetUsers(models.UserFilter{}, "", 1, 10)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		deleted, _, err := svc.GetUsers(models.UserFilter{Deleted: true}, "", 1, 10)
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		require.NotNil(t, deleted[0].PurgeAt)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 7), *deleted[0].PurgeAt, time.Minute)
+
+		assert.False(t, svc.IsUsernameAvailable(user.Username), "the username is held until the user is purged")
+	})
+
+	t.Run("restore cancels the deletion", func(t *testing.T) {
+		svc, db, user := seed(t)
+
+		require.NoError(t, svc.RestoreUser(user.ID))
+
+		restored, err := svc.GetUserByID(user.ID)
+		require.NoError(t, err)
+		assert.False(t, restored.IsActive, "restored users stay deactivated")
+
+		// Nothing is left for the purge job
+		require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Update("purge_at", time.Now().Add(-time.Hour)).Error)
+		purged, err := svc.PurgeDeletedUsers()
+		require.NoError(t, err)
+		assert.Zero(t, purged)
+
+		assert.ErrorIs(t, svc.RestoreUser(user.ID), apperrors.ErrNotFound)
+	})
+
+	t.Run("purge deletes users past the grace period", func(t *testing.T) {
+		svc, db, user := seed(t)
+
+		purged, err := svc.PurgeDeletedUsers()
+		require.NoError(t, err)
+		assert.Zero(t, purged, "the grace period hasn't ended")
+
+		require.NoError(t, db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Update("purge_at", time.Now().Add(-time.Hour)).Error)
+		purged, err = svc.PurgeDeletedUsers()
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+
+		var remaining int64
+		db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Count(&remaining)
+		assert.Zero(t, remaining)
+
+		var placeholder models.User
+		require.NoError(t, db.Where("username = ?", models.DeletedUserUsername).First(&placeholder).Error)
+		var posts int64
+		db.Model(&models.Post{}).Where("author_id = ?", placeholder.ID).Count(&posts)
+		assert.Equal(t, int64(1), posts)
+
+		assert.ErrorIs(t, svc.RestoreUser(user.ID), apperrors.ErrNotFound)
+	})
+
+	t.Run("last admin cannot be scheduled", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		svc := service.NewUserService(repository.NewUserRepository(db), breach.NewChecker(config.PasswordConfig{}), &fakeMailer{}, &config.Config{})
+		admin := testutil.CreateUser(t, db, "admin")
+		require.NoError(t, db.Model(admin).Update("is_admin", true).Error)
+
+		err := svc.DeactivateUser(admin.ID, &models.UserDeactivateRequest{DeleteAfterDays: 7})
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+}
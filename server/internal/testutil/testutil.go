@@ -0,0 +1,67 @@
+// Package testutil provides helpers shared by tests that need a database.
+package testutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewTestDB opens an isolated in-memory SQLite database with the schema
+// migrated, so tests run without an external database
+func NewTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_pragma=foreign_keys(1)", name)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Tag{},
+		&models.TagSlugHistory{},
+		&models.Series{},
+		&models.Post{},
+		&models.PostSlugHistory{},
+		&models.PostModerationLog{},
+		&models.Comment{},
+		&models.CommentReport{},
+		&models.RevokedToken{},
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+// CreateUser inserts an active user with the given username and an email
+// derived from it. The password is "password123".
+func CreateUser(t *testing.T, db *gorm.DB, username string) *models.User {
+	t.Helper()
+
+	user := &models.User{
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     username + "@example.com",
+		Username:  username,
+		Password:  "password123",
+		IsActive:  true,
+	}
+	require.NoError(t, db.Create(user).Error)
+	return user
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"strings"
+)
+
+// SplitFrontMatter splits a Markdown document into its front matter, the
+// lines between a "---" first line and the next "---" (or "...") line, and
+// the body after it. ok is false when the document has no front matter.
+func SplitFrontMatter(document string) (frontMatter, body string, ok bool) {
+	document = strings.ReplaceAll(document, "\r\n", "\n")
+	document = strings.TrimPrefix(document, "\ufeff")
+
+	lines := strings.SplitAfter(document, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return "", document, false
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if line := strings.TrimSpace(lines[i]); line == "---" || line == "..." {
+			return strings.Join(lines[1:i], ""), strings.Join(lines[i+1:], ""), true
+		}
+	}
+	return "", document, false
+}
@@ -2,88 +2,240 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
 )
 
+// TokenTypeRefresh marks refresh tokens, which can only be exchanged for a
+// new access token and are rejected by the auth middleware
+const TokenTypeRefresh = "refresh"
+
+// TokenTypePreview marks draft preview tokens, which only let their holder
+// read one post and are rejected everywhere else
+const TokenTypePreview = "preview"
+
+// TokenTypeEmailChange marks email change tokens, which only confirm a
+// user's new email address and are rejected everywhere else
+const TokenTypeEmailChange = "email_change"
+
+// JWTClaims are the claims of access and refresh tokens. Each token gets a
+// unique ID, the registered jti claim, so it can be revoked on its own.
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Email    string `json:"email"`
-	Username string `json:"username"`
-	IsAdmin  bool   `json:"is_admin"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	IsAdmin   bool   `json:"is_admin"`
+	TokenType string `json:"token_type,omitempty"`
+
+	// MustChangePassword limits the token to changing the password
+	MustChangePassword bool `json:"must_change_password,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for a user
+// GenerateToken generates a JWT access token for a user
 func GenerateToken(user *models.User, config *config.Config) (string, error) {
+	return generateToken(user, config, config.JWT.ExpiresIn, "")
+}
+
+// GenerateRefreshToken generates a refresh token for a user that expires
+// after expiresIn
+func GenerateRefreshToken(user *models.User, config *config.Config, expiresIn time.Duration) (string, error) {
+	return generateToken(user, config, expiresIn, TokenTypeRefresh)
+}
+
+func generateToken(user *models.User, config *config.Config, expiresIn time.Duration, tokenType string) (string, error) {
 	claims := JWTClaims{
-		UserID:   user.ID,
-		Email:    user.Email,
-		Username: user.Username,
-		IsAdmin:  user.IsAdmin,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Username:  user.Username,
+		IsAdmin:   user.IsAdmin,
+		TokenType: tokenType,
+
+		MustChangePassword: user.MustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.JWT.ExpiresIn)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "golang-multiuser-blog",
 			Subject:   user.Email,
+			ID:        uuid.NewString(),
+		},
+	}
+
+	return signToken(claims, &config.JWT)
+}
+
+// PreviewClaims are the claims of a draft preview token
+type PreviewClaims struct {
+	PostID    uint   `json:"post_id"`
+	TokenType string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+// GeneratePreviewToken generates a token granting read access to one post
+// until it expires after the configured preview lifetime
+func GeneratePreviewToken(postID uint, cfg *config.JWTConfig) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(cfg.PreviewExpiresIn)
+	claims := PreviewClaims{
+		PostID:    postID,
+		TokenType: TokenTypePreview,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "golang-multiuser-blog",
+			Subject:   fmt.Sprintf("post:%d", postID),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.JWT.Secret))
+	token, err := signToken(claims, cfg)
+	return token, expiresAt, err
+}
+
+// ValidatePreviewToken validates a draft preview token and returns its
+// claims. Access and refresh tokens are rejected.
+func ValidatePreviewToken(tokenString string, cfg *config.JWTConfig) (*PreviewClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &PreviewClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return verificationKey(cfg)
+	}, jwt.WithValidMethods([]string{jwtAlgorithm(cfg)}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*PreviewClaims); ok && token.Valid && claims.TokenType == TokenTypePreview {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid preview token")
+}
+
+// EmailChangeClaims are the claims of an email change token
+type EmailChangeClaims struct {
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"new_email"`
+	TokenType string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+// GenerateEmailChangeToken generates a token confirming that the holder of
+// email wants it to become userID's address, expiring after the configured
+// email change lifetime
+func GenerateEmailChangeToken(userID uint, email string, cfg *config.JWTConfig) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(cfg.EmailChangeExpiresIn)
+	claims := EmailChangeClaims{
+		UserID:    userID,
+		Email:     email,
+		TokenType: TokenTypeEmailChange,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "golang-multiuser-blog",
+			Subject:   fmt.Sprintf("user:%d", userID),
+		},
+	}
+
+	token, err := signToken(claims, cfg)
+	return token, expiresAt, err
+}
+
+// ValidateEmailChangeToken validates an email change token and returns its
+// claims. Every other kind of token is rejected.
+func ValidateEmailChangeToken(tokenString string, cfg *config.JWTConfig) (*EmailChangeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &EmailChangeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return verificationKey(cfg)
+	}, jwt.WithValidMethods([]string{jwtAlgorithm(cfg)}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*EmailChangeClaims); ok && token.Valid && claims.TokenType == TokenTypeEmailChange {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid email change token")
 }
 
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string, config *config.Config) (*JWTClaims, error) {
+	// Only accept the configured algorithm, so a token signed with a
+	// different one (e.g. HS256 using the RSA public key as the secret)
+	// is rejected before its signature is checked
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(config.JWT.Secret), nil
-	})
+		return verificationKey(&config.JWT)
+	}, jwt.WithValidMethods([]string{jwtAlgorithm(&config.JWT)}))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+	// Only access and refresh tokens authenticate; preview and email change
+	// tokens grant one narrow action
+	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid && (claims.TokenType == "" || claims.TokenType == TokenTypeRefresh) {
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
 
-// RefreshToken generates a new token from a valid token
-func RefreshToken(tokenString string, config *config.Config) (string, error) {
+// ValidateRefreshToken validates a refresh token and returns its claims.
+// Access tokens are rejected, so only a refresh token can be exchanged for
+// a new access token and its lifetime bounds the session.
+func ValidateRefreshToken(tokenString string, config *config.Config) (*JWTClaims, error) {
 	claims, err := ValidateToken(tokenString, config)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeRefresh {
+		return nil, errors.New("not a refresh token")
 	}
+	return claims, nil
+}
 
-	// Check if token is not expired yet (allow refresh within 24 hours before expiry)
-	if time.Until(claims.ExpiresAt.Time) < 0 {
-		return "", errors.New("token has expired")
+// signToken signs claims with the configured algorithm and key
+func signToken(claims jwt.Claims, cfg *config.JWTConfig) (string, error) {
+	switch jwtAlgorithm(cfg) {
+	case config.JWTAlgorithmHS256:
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.Secret))
+	case config.JWTAlgorithmRS256:
+		if cfg.PrivateKey == nil {
+			return "", errors.New("RS256 private key is not configured")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(cfg.PrivateKey)
+	default:
+		return "", errors.New("unsupported JWT signing algorithm")
 	}
+}
 
-	// Create new token with fresh expiry
-	newClaims := JWTClaims{
-		UserID:   claims.UserID,
-		Email:    claims.Email,
-		Username: claims.Username,
-		IsAdmin:  claims.IsAdmin,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.JWT.ExpiresIn)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "golang-multiuser-blog",
-			Subject:   claims.Email,
-		},
+// verificationKey returns the key used to verify tokens for the configured
+// algorithm
+func verificationKey(cfg *config.JWTConfig) (interface{}, error) {
+	switch jwtAlgorithm(cfg) {
+	case config.JWTAlgorithmHS256:
+		return []byte(cfg.Secret), nil
+	case config.JWTAlgorithmRS256:
+		if cfg.PublicKey == nil {
+			return nil, errors.New("RS256 public key is not configured")
+		}
+		return cfg.PublicKey, nil
+	default:
+		return nil, errors.New("unsupported JWT signing algorithm")
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims)
-	return token.SignedString([]byte(config.JWT.Secret))
+// jwtAlgorithm returns the configured algorithm, defaulting to HS256
+func jwtAlgorithm(cfg *config.JWTConfig) string {
+	if cfg.Algorithm == "" {
+		return config.JWTAlgorithmHS256
+	}
+	return cfg.Algorithm
 }
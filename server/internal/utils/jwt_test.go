@@ -0,0 +1,190 @@
+package utils_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHS256Config() *config.Config {
+	return &config.Config{
+		JWT: config.JWTConfig{
+			Algorithm: config.JWTAlgorithmHS256,
+			Secret:    "test-secret-key",
+			ExpiresIn: time.Hour,
+		},
+	}
+}
+
+func newRS256Config(t *testing.T) *config.Config {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return &config.Config{
+		JWT: config.JWTConfig{
+			Algorithm:  config.JWTAlgorithmRS256,
+			ExpiresIn:  time.Hour,
+			PrivateKey: key,
+			PublicKey:  &key.PublicKey,
+		},
+	}
+}
+
+func TestGenerateAndValidateToken(t *testing.T) {
+	user := &models.User{ID: 7, Email: "jane@example.com", Username: "jane", IsAdmin: true}
+
+	configs := map[string]*config.Config{
+		"HS256":   newHS256Config(),
+		"RS256":   newRS256Config(t),
+		"default": {JWT: config.JWTConfig{Secret: "test-secret-key", ExpiresIn: time.Hour}},
+	}
+
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			token, err := utils.GenerateToken(user, cfg)
+			require.NoError(t, err)
+
+			claims, err := utils.ValidateToken(token, cfg)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, claims.UserID)
+			assert.Equal(t, user.Username, claims.Username)
+			assert.True(t, claims.IsAdmin)
+
+			_, err = utils.ValidateRefreshToken(token, cfg)
+			require.Error(t, err, "access tokens can't be refreshed")
+
+			refresh, err := utils.GenerateRefreshToken(user, cfg, time.Hour)
+			require.NoError(t, err)
+			claims, err = utils.ValidateRefreshToken(refresh, cfg)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, claims.UserID)
+		})
+	}
+}
+
+func TestValidateToken_AlgorithmMismatch(t *testing.T) {
+	user := &models.User{ID: 1, Email: "john@example.com", Username: "john"}
+
+	t.Run("HS256 token rejected by RS256 config", func(t *testing.T) {
+		token, err := utils.GenerateToken(user, newHS256Config())
+		require.NoError(t, err)
+
+		_, err = utils.ValidateToken(token, newRS256Config(t))
+		require.Error(t, err)
+	})
+
+	t.Run("RS256 token rejected by HS256 config", func(t *testing.T) {
+		token, err := utils.GenerateToken(user, newRS256Config(t))
+		require.NoError(t, err)
+
+		_, err = utils.ValidateToken(token, newHS256Config())
+		require.Error(t, err)
+	})
+
+	t.Run("HS256 token signed with the public key is rejected", func(t *testing.T) {
+		cfg := newRS256Config(t)
+		pubDER, err := x509.MarshalPKIXPublicKey(cfg.JWT.PublicKey)
+		require.NoError(t, err)
+
+		forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.JWTClaims{
+			UserID:  1,
+			IsAdmin: true,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}).SignedString(pubDER)
+		require.NoError(t, err)
+
+		_, err = utils.ValidateToken(forged, cfg)
+		require.Error(t, err)
+	})
+
+	t.Run("unsigned token is rejected", func(t *testing.T) {
+		unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, utils.JWTClaims{UserID: 1}).
+			SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		_, err = utils.ValidateToken(unsigned, newHS256Config())
+		require.Error(t, err)
+	})
+}
+
+func TestPreviewToken(t *testing.T) {
+	cfg := newHS256Config()
+	cfg.JWT.PreviewExpiresIn = time.Hour
+
+	token, expiresAt, err := utils.GeneratePreviewToken(42, &cfg.JWT)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	claims, err := utils.ValidatePreviewToken(token, &cfg.JWT)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), claims.PostID)
+
+	t.Run("is not an access token", func(t *testing.T) {
+		_, err := utils.ValidateToken(token, cfg)
+		require.Error(t, err)
+
+		_, err = utils.ValidateRefreshToken(token, cfg)
+		require.Error(t, err)
+	})
+
+	t.Run("access tokens are not preview tokens", func(t *testing.T) {
+		access, err := utils.GenerateToken(&models.User{ID: 42, Email: "jane@example.com"}, cfg)
+		require.NoError(t, err)
+
+		_, err = utils.ValidatePreviewToken(access, &cfg.JWT)
+		require.Error(t, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expiredCfg := cfg.JWT
+		expiredCfg.PreviewExpiresIn = -time.Minute
+		expired, _, err := utils.GeneratePreviewToken(42, &expiredCfg)
+		require.NoError(t, err)
+
+		_, err = utils.ValidatePreviewToken(expired, &cfg.JWT)
+		require.Error(t, err)
+	})
+}
+
+func TestEmailChangeToken(t *testing.T) {
+	cfg := newHS256Config()
+	cfg.JWT.EmailChangeExpiresIn = time.Hour
+
+	token, expiresAt, err := utils.GenerateEmailChangeToken(42, "new@example.com", &cfg.JWT)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	claims, err := utils.ValidateEmailChangeToken(token, &cfg.JWT)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), claims.UserID)
+	assert.Equal(t, "new@example.com", claims.Email)
+
+	t.Run("is not an access token", func(t *testing.T) {
+		_, err := utils.ValidateToken(token, cfg)
+		require.Error(t, err)
+
+		_, err = utils.ValidateRefreshToken(token, cfg)
+		require.Error(t, err)
+	})
+
+	t.Run("access tokens are not email change tokens", func(t *testing.T) {
+		access, err := utils.GenerateToken(&models.User{ID: 42, Email: "jane@example.com"}, cfg)
+		require.NoError(t, err)
+
+		_, err = utils.ValidateEmailChangeToken(access, &cfg.JWT)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Patterns used to render Markdown as HTML. Inline patterns run on text
+// that has already been HTML-escaped.
+var (
+	markdownFence       = regexp.MustCompile("^\\s{0,3}```")
+	markdownATXHeading  = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	markdownQuoteLine   = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	markdownBulletItem  = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	markdownOrderedItem = regexp.MustCompile(`^\s{0,3}\d+[.)]\s+(.*)$`)
+	markdownImageTag    = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	markdownLinkTag     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	markdownBold        = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	markdownItalic      = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	markdownUnderscored = regexp.MustCompile(`\b_([^_]+)_\b`)
+)
+
+// RenderMarkdown converts post content written in Markdown to HTML. It
+// covers paragraphs, headings, fenced code, block quotes, lists, inline
+// code, emphasis, links and images. Any HTML in the content is escaped
+// rather than passed through, and links and images only keep http(s),
+// mailto and relative URLs, so the output is safe to embed.
+func RenderMarkdown(content string) string {
+	return renderMarkdown(content, false)
+}
+
+// renderMarkdown renders content, passing lines holding a canonical iframe
+// through as HTML when embeds is set. Callers must sanitize the result.
+func renderMarkdown(content string, embeds bool) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var b strings.Builder
+	var paragraph []string
+	var list string
+	var quote []string
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + renderInlineMarkdown(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	flushQuote := func() {
+		if len(quote) > 0 {
+			b.WriteString("<blockquote><p>" + renderInlineMarkdown(strings.Join(quote, " ")) + "</p></blockquote>\n")
+			quote = nil
+		}
+	}
+	flush := func() {
+		flushParagraph()
+		closeList()
+		flushQuote()
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if markdownFence.MatchString(line) {
+			flush()
+			var code []string
+			for i++; i < len(lines) && !markdownFence.MatchString(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		if embeds && markdownEmbedTag.MatchString(line) {
+			flush()
+			b.WriteString(strings.TrimSpace(line) + "\n")
+			continue
+		}
+
+		if match := markdownATXHeading.FindStringSubmatch(line); match != nil {
+			flush()
+			level := string(rune('0' + len(match[1])))
+			b.WriteString("<h" + level + ">" + renderInlineMarkdown(match[2]) + "</h" + level + ">\n")
+			continue
+		}
+
+		if match := markdownQuoteLine.FindStringSubmatch(line); match != nil {
+			flushParagraph()
+			closeList()
+			quote = append(quote, match[1])
+			continue
+		}
+		flushQuote()
+
+		item, kind := "", ""
+		if match := markdownBulletItem.FindStringSubmatch(line); match != nil {
+			item, kind = match[1], "ul"
+		} else if match := markdownOrderedItem.FindStringSubmatch(line); match != nil {
+			item, kind = match[1], "ol"
+		}
+		if kind != "" {
+			flushParagraph()
+			if list != kind {
+				closeList()
+				b.WriteString("<" + kind + ">\n")
+				list = kind
+			}
+			b.WriteString("<li>" + renderInlineMarkdown(item) + "</li>\n")
+			continue
+		}
+
+		closeList()
+		paragraph = append(paragraph, strings.TrimSpace(line))
+	}
+	flush()
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// renderInlineMarkdown escapes text and renders its inline code, images,
+// links and emphasis. Nothing inside a code span is formatted.
+func renderInlineMarkdown(text string) string {
+	parts := strings.Split(text, "`")
+
+	var b strings.Builder
+	for i, part := range parts {
+		escaped := html.EscapeString(part)
+		if i%2 == 1 {
+			if i < len(parts)-1 {
+				b.WriteString("<code>" + escaped + "</code>")
+				continue
+			}
+			// An unmatched backtick is kept as is
+			b.WriteString("`")
+		}
+		b.WriteString(formatInlineMarkdown(escaped))
+	}
+	return b.String()
+}
+
+func formatInlineMarkdown(escaped string) string {
+	text := markdownImageTag.ReplaceAllStringFunc(escaped, func(match string) string {
+		parts := markdownImageTag.FindStringSubmatch(match)
+		if !isSafeMarkdownURL(parts[2]) {
+			return parts[1]
+		}
+		return `<img src="` + parts[2] + `" alt="` + parts[1] + `">`
+	})
+	text = markdownLinkTag.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownLinkTag.FindStringSubmatch(match)
+		if !isSafeMarkdownURL(parts[2]) {
+			return parts[1]
+		}
+		return `<a href="` + parts[2] + `">` + parts[1] + `</a>`
+	})
+	text = markdownBold.ReplaceAllString(text, "<strong>$1</strong>")
+	text = markdownItalic.ReplaceAllString(text, "<em>$1</em>")
+	return markdownUnderscored.ReplaceAllString(text, "<em>$1</em>")
+}
+
+// isSafeMarkdownURL reports whether an (escaped) link target may be used in
+// an href or src. Schemes such as javascript: are refused.
+func isSafeMarkdownURL(escaped string) bool {
+	url := strings.ToLower(html.UnescapeString(escaped))
+	for _, prefix := range []string{"http://", "https://", "mailto:", "/", "#"} {
+		if strings.HasPrefix(url, prefix) {
+			return !strings.HasPrefix(url, "//")
+		}
+	}
+	return false
+}
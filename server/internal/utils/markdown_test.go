@@ -0,0 +1,63 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			"paragraphs and headings",
+			"# Getting Started\n\nGo is **simple**\nand _fast_.\n\nSecond *paragraph*.",
+			"<h1>Getting Started</h1>\n<p>Go is <strong>simple</strong> and <em>fast</em>.</p>\n<p>Second <em>paragraph</em>.</p>",
+		},
+		{
+			"links and images",
+			"See [the docs](https://go.dev) ![logo](/logo.png)",
+			`<p>See <a href="https://go.dev">the docs</a> <img src="/logo.png" alt="logo"></p>`,
+		},
+		{
+			"lists",
+			"- one\n- two\n\n1. first\n2. second",
+			"<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>",
+		},
+		{
+			"code is not formatted",
+			"Run `go **vet**`\n\n```go\nfunc main() { a := b * c * d }\n```",
+			"<p>Run <code>go **vet**</code></p>\n<pre><code>func main() { a := b * c * d }</code></pre>",
+		},
+		{
+			"block quotes",
+			"> Quoted\n> text",
+			"<blockquote><p>Quoted text</p></blockquote>",
+		},
+		{
+			"identifiers with underscores",
+			"call snake_case_name now",
+			"<p>call snake_case_name now</p>",
+		},
+		{
+			"HTML is escaped",
+			`<script>alert("hi")</script>`,
+			"<p>&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt;</p>",
+		},
+		{
+			"unsafe links keep only their text",
+			"[click](javascript:alert) ![x](data:image/png)",
+			"<p>click x</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.RenderMarkdown(tt.markdown))
+		})
+	}
+}
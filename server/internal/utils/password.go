@@ -0,0 +1,84 @@
+package utils
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords holds the bundled list of common passwords, lowercased
+var commonPasswords = parseCommonPasswords(commonPasswordList)
+
+func parseCommonPasswords(list string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return passwords
+}
+
+// IsCommonPassword reports whether password, ignoring case, is on the
+// bundled list of common passwords
+func IsCommonPassword(password string) bool {
+	_, ok := commonPasswords[strings.ToLower(password)]
+	return ok
+}
+
+// ValidatePassword checks password against policy, returning one error for
+// each rule it breaks, or none if it complies
+func ValidatePassword(password string, policy config.PasswordConfig) []models.ValidationError {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	var validationErrors []models.ValidationError
+	fail := func(tag, message string) {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Field:   "Password",
+			Tag:     tag,
+			Message: message,
+		})
+	}
+
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		fail("min", fmt.Sprintf("Password must be at least %d characters long", policy.MinLength))
+	}
+	if policy.RequireUpper && !hasUpper {
+		fail("uppercase", "Password must contain an upper-case letter")
+	}
+	if policy.RequireLower && !hasLower {
+		fail("lowercase", "Password must contain a lower-case letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		fail("digit", "Password must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		fail("symbol", "Password must contain a symbol such as ! or #")
+	}
+	if policy.RejectCommon && IsCommonPassword(password) {
+		fail("common", "Password is too common; choose one that is harder to guess")
+	}
+
+	return validationErrors
+}
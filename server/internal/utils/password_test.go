@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePassword(t *testing.T) {
+	strict := config.PasswordConfig{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name     string
+		password string
+		policy   config.PasswordConfig
+		want     string
+	}{
+		{"too short", "Sh0rt!", strict, "min"},
+		{"length counts characters, not bytes", "pässwörd", config.PasswordConfig{MinLength: 9}, "min"},
+		{"no upper-case letter", "lowercase1!", strict, "uppercase"},
+		{"no lower-case letter", "UPPERCASE1!", strict, "lowercase"},
+		{"no digit", "NoDigitsHere!", strict, "digit"},
+		{"no symbol", "NoSymbols123", strict, "symbol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validationErrors := utils.ValidatePassword(tt.password, tt.policy)
+			require.Len(t, validationErrors, 1)
+			assert.Equal(t, "Password", validationErrors[0].Field)
+			assert.Equal(t, tt.want, validationErrors[0].Tag)
+			assert.Empty(t, validationErrors[0].Value, "the password isn't echoed back")
+		})
+	}
+
+	t.Run("compliant password", func(t *testing.T) {
+		assert.Empty(t, utils.ValidatePassword("C0rrect-Horse", strict))
+	})
+
+	t.Run("default policy only checks length", func(t *testing.T) {
+		assert.Empty(t, utils.ValidatePassword("password", config.PasswordConfig{MinLength: 8}))
+	})
+
+	t.Run("common passwords", func(t *testing.T) {
+		policy := config.PasswordConfig{MinLength: 8, RejectCommon: true}
+
+		validationErrors := utils.ValidatePassword("Password123", policy)
+		require.Len(t, validationErrors, 1)
+		assert.Equal(t, "common", validationErrors[0].Tag)
+		assert.Empty(t, utils.ValidatePassword("c0rrect-horse-battery", policy))
+
+		// Only rejected when the policy asks for it
+		assert.Empty(t, utils.ValidatePassword("password123", config.PasswordConfig{MinLength: 8}))
+	})
+
+	t.Run("reports every broken rule", func(t *testing.T) {
+		assert.Len(t, utils.ValidatePassword("abc", strict), 4)
+	})
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ContentPolicy lists the optional constructs post content may contain.
+// Inline HTML and links with unsafe schemes are never allowed.
+type ContentPolicy struct {
+	AllowImages bool
+
+	// AllowIframes keeps iframes whose src is an https URL on one of
+	// IframeHosts
+	AllowIframes bool
+	IframeHosts  []string
+}
+
+// Patterns used to sanitize Markdown and HTML
+var (
+	htmlComment      = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlScriptOrCSS  = regexp.MustCompile(`(?is)<(script|style)\b[^>]*>.*?</(script|style)\s*>`)
+	htmlIframe       = regexp.MustCompile(`(?is)<iframe\b[^>]*>(\s*</iframe\s*>)?`)
+	htmlAnyTag       = regexp.MustCompile(`(?s)</?[a-zA-Z][a-zA-Z0-9-]*(\s[^>]*)?/?>`)
+	htmlElementTag   = regexp.MustCompile(`(?s)<(/?)([a-zA-Z][a-zA-Z0-9]*)((?:\s+[a-zA-Z_:][-a-zA-Z0-9_:.]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'<>=]+))?)*)\s*/?>`)
+	htmlAttribute    = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'<>=]+)))?`)
+	markdownEmbedTag = regexp.MustCompile(`^\s{0,3}<iframe src="[^"<>]+" allowfullscreen></iframe>\s*$`)
+)
+
+// allowedHTMLTags are the tags SanitizeHTML keeps regardless of policy,
+// which are the ones RenderMarkdown produces
+var allowedHTMLTags = map[string]bool{
+	"p": true, "br": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"pre": true, "code": true, "blockquote": true, "ul": true, "ol": true, "li": true,
+	"strong": true, "em": true, "a": true,
+}
+
+// SanitizeMarkdown strips dangerous constructs from Markdown before it is
+// stored. HTML tags and comments outside code are removed, except iframes
+// the policy allows, which are rewritten to a canonical form on a line of
+// their own. Links and images with unsafe URLs, such as javascript:, are
+// reduced to their text.
+func SanitizeMarkdown(content string, policy ContentPolicy) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	inFence := false
+	for i, line := range lines {
+		if markdownFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		// Code spans are left alone, since they are escaped when rendered
+		parts := strings.Split(line, "`")
+		for j := range parts {
+			if j%2 == 0 || j == len(parts)-1 {
+				parts[j] = sanitizeMarkdownText(parts[j], policy)
+			}
+		}
+		lines[i] = strings.Join(parts, "`")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func sanitizeMarkdownText(text string, policy ContentPolicy) string {
+	text = htmlComment.ReplaceAllString(text, "")
+	text = htmlScriptOrCSS.ReplaceAllString(text, "")
+
+	// Allowed iframes go on a line of their own so they render as a block;
+	// every other tag is removed
+	var b strings.Builder
+	last := 0
+	for _, loc := range htmlIframe.FindAllStringIndex(text, -1) {
+		b.WriteString(htmlAnyTag.ReplaceAllString(text[last:loc[0]], ""))
+		if src, ok := iframeSource(text[loc[0]:loc[1]], policy); ok {
+			if strings.TrimSpace(text[:loc[0]]) != "" {
+				b.WriteString("\n")
+			}
+			b.WriteString(iframeTag(src))
+			if strings.TrimSpace(text[loc[1]:]) != "" {
+				b.WriteString("\n")
+			}
+		}
+		last = loc[1]
+	}
+	b.WriteString(htmlAnyTag.ReplaceAllString(text[last:], ""))
+	text = b.String()
+
+	text = markdownImageTag.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownImageTag.FindStringSubmatch(match)
+		if !isSafeMarkdownURL(parts[2]) {
+			return parts[1]
+		}
+		return match
+	})
+	return markdownLinkTag.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownLinkTag.FindStringSubmatch(match)
+		if !isSafeMarkdownURL(parts[2]) {
+			return parts[1]
+		}
+		return match
+	})
+}
+
+// RenderContent renders post content as HTML under policy: the Markdown is
+// rendered, with iframes passed through when the policy allows them, and
+// the result is filtered by SanitizeHTML
+func RenderContent(content string, policy ContentPolicy) string {
+	return SanitizeHTML(renderMarkdown(content, policy.AllowIframes), policy)
+}
+
+// SanitizeHTML filters HTML through an allowlist. The tags RenderMarkdown
+// produces are kept, plus images and iframes when the policy allows them;
+// other tags are dropped, and script and style elements are removed with
+// their content. Only href on links, src and alt on images and src on
+// iframes survive, and only with safe URLs.
+func SanitizeHTML(input string, policy ContentPolicy) string {
+	input = htmlComment.ReplaceAllString(input, "")
+	input = htmlScriptOrCSS.ReplaceAllString(input, "")
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range htmlElementTag.FindAllStringSubmatchIndex(input, -1) {
+		b.WriteString(escapeHTMLText(input[last:loc[0]]))
+		last = loc[1]
+
+		closing := input[loc[2]:loc[3]] == "/"
+		name := strings.ToLower(input[loc[4]:loc[5]])
+		attrs := parseHTMLAttributes(input[loc[6]:loc[7]])
+
+		switch {
+		case name == "img" && policy.AllowImages && !closing:
+			if src := attrs["src"]; isSafeMarkdownURL(src) {
+				b.WriteString(`<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(attrs["alt"]) + `">`)
+			}
+		case name == "iframe" && policy.AllowIframes:
+			if closing {
+				continue
+			}
+			if src, ok := iframeSource(input[loc[0]:loc[1]], policy); ok {
+				b.WriteString(iframeTag(src))
+			}
+		case name == "a" && !closing:
+			if href := attrs["href"]; isSafeMarkdownURL(href) {
+				b.WriteString(`<a href="` + html.EscapeString(href) + `">`)
+			} else {
+				b.WriteString("<a>")
+			}
+		case allowedHTMLTags[name]:
+			if closing {
+				b.WriteString("</" + name + ">")
+			} else {
+				b.WriteString("<" + name + ">")
+			}
+		}
+	}
+	b.WriteString(escapeHTMLText(input[last:]))
+
+	return b.String()
+}
+
+// escapeHTMLText escapes the angle brackets left in text between tags,
+// leaving entities as they are
+func escapeHTMLText(text string) string {
+	return strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// parseHTMLAttributes returns the unescaped attribute values of a tag by
+// lowercase name
+func parseHTMLAttributes(raw string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range htmlAttribute.FindAllStringSubmatch(raw, -1) {
+		attrs[strings.ToLower(match[1])] = html.UnescapeString(match[2] + match[3] + match[4])
+	}
+	return attrs
+}
+
+// iframeSource returns the src of an iframe tag if the policy allows it
+func iframeSource(tag string, policy ContentPolicy) (string, bool) {
+	if !policy.AllowIframes {
+		return "", false
+	}
+	match := htmlElementTag.FindStringSubmatch(tag)
+	if match == nil {
+		return "", false
+	}
+	src := parseHTMLAttributes(match[3])["src"]
+
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme != "https" {
+		return "", false
+	}
+	for _, host := range policy.IframeHosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			return u.String(), true
+		}
+	}
+	return "", false
+}
+
+// iframeTag is the canonical form iframes are stored and rendered in
+func iframeTag(src string) string {
+	return `<iframe src="` + html.EscapeString(src) + `" allowfullscreen></iframe>`
+}
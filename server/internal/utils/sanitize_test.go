@@ -0,0 +1,103 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var embedPolicy = utils.ContentPolicy{
+	AllowImages:  true,
+	AllowIframes: true,
+	IframeHosts:  []string{"www.youtube.com"},
+}
+
+func TestSanitizeMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"script element", "Hi <script>alert(document.cookie)</script>there", "Hi there"},
+		{"event handler", `<img src=x onerror="alert(1)">Caption`, "Caption"},
+		{"inline tags", "<div onclick=\"steal()\"><b>bold</b></div>", "bold"},
+		{"comment", "before<!-- <script>alert(1)</script> -->after", "beforeafter"},
+		{"javascript link", "[click](javascript:alert(1))", "click)"},
+		{"mixed case scheme", "[click](JaVaScRiPt:alert)", "click"},
+		{"entity encoded scheme", "[click](&#106;avascript:alert)", "click"},
+		{"data image", "![pic](data:text/html;base64,PHNjcmlwdD4=)", "pic"},
+		{"safe link kept", "[docs](https://go.dev) and ![logo](/logo.png)", "[docs](https://go.dev) and ![logo](/logo.png)"},
+		{"code span kept", "Use `<script>` tags", "Use `<script>` tags"},
+		{"fenced code kept", "```\n<script>alert(1)</script>\n```", "```\n<script>alert(1)</script>\n```"},
+		{
+			"allowed iframe canonicalized",
+			`Watch <iframe width="560" src="https://www.youtube.com/embed/abc" onload="alert(1)"></iframe>`,
+			"Watch \n" + `<iframe src="https://www.youtube.com/embed/abc" allowfullscreen></iframe>`,
+		},
+		{"iframe from other host", `<iframe src="https://evil.example/x"></iframe>`, ""},
+		{"iframe with javascript src", `<iframe src="javascript:alert(1)"></iframe>`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.SanitizeMarkdown(tt.markdown, embedPolicy))
+		})
+	}
+
+	t.Run("iframes removed unless allowed", func(t *testing.T) {
+		got := utils.SanitizeMarkdown(`<iframe src="https://www.youtube.com/embed/abc"></iframe>`, utils.ContentPolicy{IframeHosts: []string{"www.youtube.com"}})
+		assert.Empty(t, got)
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		once := utils.SanitizeMarkdown(`<iframe src="https://www.youtube.com/embed/abc"></iframe>`, embedPolicy)
+		assert.Equal(t, once, utils.SanitizeMarkdown(once, embedPolicy))
+	})
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		html   string
+		policy utils.ContentPolicy
+		want   string
+	}{
+		{"allowed tags kept", "<p>Hi <strong>there</strong></p>", utils.ContentPolicy{}, "<p>Hi <strong>there</strong></p>"},
+		{"attributes dropped", `<p class="x" onclick="alert(1)">Hi</p>`, utils.ContentPolicy{}, "<p>Hi</p>"},
+		{"script removed with content", "<p>a<script>alert(1)</script>b</p>", utils.ContentPolicy{}, "<p>ab</p>"},
+		{"unknown tags dropped", "<div><svg onload=alert(1)>x</svg></div>", utils.ContentPolicy{}, "x"},
+		{"unsafe href dropped", `<a href="javascript:alert(1)">x</a>`, utils.ContentPolicy{}, "<a>x</a>"},
+		{"safe href kept", `<a href="https://go.dev" target="_blank">x</a>`, utils.ContentPolicy{}, `<a href="https://go.dev">x</a>`},
+		{"images need the policy", `<img src="/a.png" alt="a">`, utils.ContentPolicy{}, ""},
+		{"images allowed", `<img src="/a.png" alt="a" onerror="alert(1)">`, utils.ContentPolicy{AllowImages: true}, `<img src="/a.png" alt="a">`},
+		{"unsafe image src", `<img src="javascript:alert(1)">`, utils.ContentPolicy{AllowImages: true}, ""},
+		{"iframes need the policy", `<iframe src="https://www.youtube.com/embed/abc"></iframe>`, utils.ContentPolicy{}, ""},
+		{"allowed iframe", `<iframe src="https://www.youtube.com/embed/abc" srcdoc="x"></iframe>`, embedPolicy, `<iframe src="https://www.youtube.com/embed/abc" allowfullscreen></iframe>`},
+		{"stray brackets escaped", "1 < 2 > 0", utils.ContentPolicy{}, "1 &lt; 2 &gt; 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.SanitizeHTML(tt.html, tt.policy))
+		})
+	}
+}
+
+func TestRenderContent(t *testing.T) {
+	markdown := "Intro\n\n<iframe src=\"https://www.youtube.com/embed/abc\" allowfullscreen></iframe>\n\n![pic](/p.png)"
+
+	t.Run("trusted", func(t *testing.T) {
+		assert.Equal(t,
+			"<p>Intro</p>\n<iframe src=\"https://www.youtube.com/embed/abc\" allowfullscreen></iframe>\n<p><img src=\"/p.png\" alt=\"pic\"></p>",
+			utils.RenderContent(markdown, embedPolicy))
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		// The iframe line is escaped as text rather than embedded
+		got := utils.RenderContent(markdown, utils.ContentPolicy{})
+		assert.NotContains(t, got, "<iframe")
+		assert.NotContains(t, got, "<img")
+		assert.Contains(t, got, "<p>Intro</p>")
+	})
+}
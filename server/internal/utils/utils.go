@@ -1,13 +1,22 @@
 package utils
 
 import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"html"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/apperrors"
 	"github.com/kaungmyathan22/golang-multiuser-blog/internal/models"
+	"golang.org/x/text/unicode/norm"
 )
 
 var validate *validator.Validate
@@ -16,10 +25,17 @@ func init() {
 	validate = validator.New()
 }
 
+// slugTransliterations maps Latin letters that don't decompose into an ASCII
+// base letter plus combining marks
+var slugTransliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d",
+	'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i", 'ħ': "h",
+}
+
 // GenerateSlug creates a URL-friendly slug from a string
 func GenerateSlug(text string) string {
-	// Convert to lowercase
-	slug := strings.ToLower(text)
+	// Convert to lowercase and transliterate accented Latin characters
+	slug := transliterate(strings.ToLower(text))
 
 	// Replace spaces and special characters with hyphens
 	reg := regexp.MustCompile(`[^a-z0-9]+`)
@@ -28,6 +44,13 @@ func GenerateSlug(text string) string {
 	// Remove leading/trailing hyphens
 	slug = strings.Trim(slug, "-")
 
+	// Titles with nothing ASCII-able (CJK, emoji, punctuation) fall back to a
+	// short, stable hash so the slug is never empty
+	if slug == "" {
+		sum := sha1.Sum([]byte(strings.TrimSpace(text)))
+		slug = hex.EncodeToString(sum[:])[:8]
+	}
+
 	// Limit length
 	if len(slug) > 100 {
 		slug = slug[:100]
@@ -37,6 +60,23 @@ func GenerateSlug(text string) string {
 	return slug
 }
 
+// transliterate strips diacritics from Latin characters ("é" -> "e") and
+// expands ligatures and special letters ("ß" -> "ss")
+func transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if replacement, ok := slugTransliterations[r]; ok {
+			b.WriteString(replacement)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 // ValidateStruct validates a struct using struct tags
 func ValidateStruct(s interface{}) []models.ValidationError {
 	var validationErrors []models.ValidationError
@@ -56,6 +96,21 @@ func ValidateStruct(s interface{}) []models.ValidationError {
 	return validationErrors
 }
 
+// ValidateMaxLength reports value as too long when it has more than max
+// characters, the way ValidateStruct reports a max tag. It is for limits
+// that come from configuration rather than struct tags; a max of zero
+// allows any length.
+func ValidateMaxLength(field, value string, max int) []models.ValidationError {
+	if max <= 0 || utf8.RuneCountInString(value) <= max {
+		return nil
+	}
+	return []models.ValidationError{{
+		Field:   field,
+		Tag:     "max",
+		Message: fmt.Sprintf("%s must be at most %d characters long", field, max),
+	}}
+}
+
 // getValidationMessage returns a user-friendly validation message
 func getValidationMessage(err validator.FieldError) string {
 	field := err.Field()
@@ -84,6 +139,33 @@ func getValidationMessage(err validator.FieldError) string {
 	}
 }
 
+// NormalizeEmail trims whitespace and lowercases an email address so that
+// case variants of the same address map to a single account
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizeHexColor converts a hex color to lowercase #rrggbb form, expanding
+// the 3-digit shorthand. The leading # is optional. It reports false for
+// anything that isn't a 3- or 6-digit hex color.
+func NormalizeHexColor(color string) (string, bool) {
+	digits := strings.TrimPrefix(strings.TrimSpace(color), "#")
+	if len(digits) != 3 && len(digits) != 6 {
+		return "", false
+	}
+	for _, r := range digits {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return "", false
+		}
+	}
+
+	digits = strings.ToLower(digits)
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	return "#" + digits, true
+}
+
 // IsValidSlug checks if a string is a valid slug format
 func IsValidSlug(slug string) bool {
 	if slug == "" {
@@ -109,19 +191,43 @@ func IsValidSlug(slug string) bool {
 	return true
 }
 
-// TruncateText truncates text to specified length and adds ellipsis
+// htmlEntityPattern matches a named or numeric HTML entity at the start of a string
+var htmlEntityPattern = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+
+// TruncateText truncates text to specified length and adds ellipsis. The
+// length is counted in runes, and the cut never splits a multi-byte
+// character or an HTML entity such as &amp;.
 func TruncateText(text string, maxLength int) string {
-	if len(text) <= maxLength {
+	runes := []rune(text)
+	if len(runes) <= maxLength {
 		return text
 	}
 
+	cut := maxLength
+
+	// Move the cut before an entity that would otherwise be split
+	for i := cut - 1; i >= 0 && runes[i] != ';'; i-- {
+		if runes[i] == '&' {
+			if match := htmlEntityPattern.FindString(string(runes[i:])); match != "" && i+len([]rune(match)) > cut {
+				cut = i
+			}
+			break
+		}
+	}
+
 	// Find the last space before the limit
-	lastSpace := strings.LastIndex(text[:maxLength], " ")
+	lastSpace := -1
+	for i := cut - 1; i >= 0; i-- {
+		if runes[i] == ' ' {
+			lastSpace = i
+			break
+		}
+	}
 	if lastSpace == -1 {
-		lastSpace = maxLength
+		lastSpace = cut
 	}
 
-	return text[:lastSpace] + "..."
+	return string(runes[:lastSpace]) + "..."
 }
 
 // SanitizeText removes extra whitespace and normalizes text
@@ -146,35 +252,155 @@ func IsAlphaNumericWithSpaces(text string) bool {
 	return true
 }
 
+// Patterns used to reduce Markdown to plain text for excerpts
+var (
+	markdownCodeFence  = regexp.MustCompile("(?s)```.*?(```|$)")
+	markdownImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink       = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownInlineCode = regexp.MustCompile("`([^`]*)`")
+	markdownHeading    = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	markdownBlockquote = regexp.MustCompile(`(?m)^\s{0,3}>\s?`)
+	markdownListMarker = regexp.MustCompile(`(?m)^\s*([-*+]|\d+[.)])\s+`)
+	markdownStrong     = regexp.MustCompile(`\*{1,3}([^*\n]+?)\*{1,3}`)
+	markdownUnderscore = regexp.MustCompile(`\b_{1,3}([^_\n]+?)_{1,3}\b`)
+	htmlTag            = regexp.MustCompile(`<[^>]*>`)
+)
+
+// StripMarkdown removes common Markdown syntax (code fences, headings,
+// links, images, emphasis, lists and quotes), keeping the readable text
+func StripMarkdown(content string) string {
+	text := markdownCodeFence.ReplaceAllString(content, " ")
+	text = markdownImage.ReplaceAllString(text, "$1")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = markdownInlineCode.ReplaceAllString(text, "$1")
+	text = markdownHeading.ReplaceAllString(text, "")
+	text = markdownBlockquote.ReplaceAllString(text, "")
+	text = markdownListMarker.ReplaceAllString(text, "")
+	text = markdownStrong.ReplaceAllString(text, "$1")
+	text = markdownUnderscore.ReplaceAllString(text, "$1")
+	return text
+}
+
 // ExtractExcerpt extracts excerpt from content
 func ExtractExcerpt(content string, maxLength int) string {
-	// Remove HTML tags (basic)
-	reg := regexp.MustCompile(`<[^>]*>`)
-	plainText := reg.ReplaceAllString(content, "")
+	// Remove Markdown syntax and HTML tags
+	plainText := StripMarkdown(content)
+	plainText = htmlTag.ReplaceAllString(plainText, "")
 
 	// Sanitize and truncate
 	plainText = SanitizeText(plainText)
 	return TruncateText(plainText, maxLength)
 }
 
-// CalculatePagination calculates pagination values
-func CalculatePagination(page, perPage int, total int64) models.PaginationMeta {
-	if page < 1 {
-		page = 1
+// HighlightSnippet finds the first case-insensitive match of query in
+// content and returns the match with up to radius characters of context on
+// each side. The match is wrapped in <mark> tags and everything else is
+// HTML-escaped. The position is the rune offset of the match in content,
+// or -1 when there is no match, in which case the snippet is the escaped
+// start of the content.
+func HighlightSnippet(content, query string, radius int) (string, int) {
+	runes := []rune(content)
+	needle := []rune(strings.TrimSpace(query))
+
+	position := indexFold(runes, needle)
+	if position < 0 {
+		return html.EscapeString(TruncateText(SanitizeText(content), 2*radius)), -1
+	}
+
+	start := max(0, position-radius)
+	matchEnd := position + len(needle)
+	end := min(len(runes), matchEnd+radius)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
 	}
-	if perPage < 1 {
-		perPage = 10
+	b.WriteString(html.EscapeString(collapseSpace(string(runes[start:position]))))
+	b.WriteString("<mark>")
+	b.WriteString(html.EscapeString(string(runes[position:matchEnd])))
+	b.WriteString("</mark>")
+	b.WriteString(html.EscapeString(collapseSpace(string(runes[matchEnd:end]))))
+	if end < len(runes) {
+		b.WriteString("...")
+	}
+	return b.String(), position
+}
+
+// indexFold returns the rune index of the first case-insensitive occurrence
+// of needle in haystack, or -1
+func indexFold(haystack, needle []rune) int {
+	if len(needle) == 0 {
+		return -1
 	}
-	if perPage > 100 {
-		perPage = 100
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		matched := true
+		for j, r := range needle {
+			if unicode.ToLower(haystack[i+j]) != unicode.ToLower(r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
 	}
+	return -1
+}
 
-	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+// collapseSpace replaces runs of whitespace with a single space, keeping
+// leading and trailing space so snippet pieces still join up
+func collapseSpace(text string) string {
+	return whitespaceRun.ReplaceAllString(text, " ")
+}
+
+var whitespaceRun = regexp.MustCompile(`\s+`)
+
+// CalculatePagination calculates pagination values. page and perPage are
+// expected to come from PaginationMiddleware, which applies the configured
+// defaults and limits, so they are reported as given.
+func CalculatePagination(page, perPage int, total int64) models.PaginationMeta {
+	totalPages := 0
+	if perPage > 0 {
+		totalPages = int((total + int64(perPage) - 1) / int64(perPage))
+	}
 
 	return models.PaginationMeta{
 		Page:       page,
 		PerPage:    perPage,
 		Total:      int(total),
 		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
+
+// EncodeCursor encodes a (published_at, id) position into an opaque cursor
+func EncodeCursor(publishedAt time.Time, id uint) string {
+	raw := fmt.Sprintf("%s|%d", publishedAt.UTC().Format(time.RFC3339Nano), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor decodes a cursor produced by EncodeCursor
+func DecodeCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, apperrors.BadRequest("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, apperrors.BadRequest("invalid cursor")
+	}
+
+	publishedAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, 0, apperrors.BadRequest("invalid cursor")
 	}
+
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return time.Time{}, 0, apperrors.BadRequest("invalid cursor")
+	}
+
+	return publishedAt, uint(id), nil
 }
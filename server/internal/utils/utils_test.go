@@ -0,0 +1,238 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	publishedAt := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+
+	cursor := utils.EncodeCursor(publishedAt, 42)
+	decodedAt, decodedID, err := utils.DecodeCursor(cursor)
+
+	require.NoError(t, err)
+	assert.True(t, publishedAt.Equal(decodedAt))
+	assert.Equal(t, uint(42), decodedID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, cursor := range []string{"not-base64!", "bm9waXBl", "eHx5"} {
+		_, _, err := utils.DecodeCursor(cursor)
+		assert.Error(t, err, cursor)
+	}
+}
+
+func TestGenerateSlug(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"plain ASCII", "Hello World", "hello-world"},
+		{"accented Latin", "Café Déjà Vu", "cafe-deja-vu"},
+		{"special Latin letters", "Straße Ærø Łódź", "strasse-aero-lodz"},
+		{"mixed CJK and ASCII", "日本語 Guide", "guide"},
+		{"surrounding punctuation", "  --Hello, World!--  ", "hello-world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.GenerateSlug(tt.title))
+		})
+	}
+}
+
+func TestGenerateSlug_Fallback(t *testing.T) {
+	for _, title := range []string{"日本語", "🎉🚀", "!!! ??? ...", "Ελληνικά"} {
+		t.Run(title, func(t *testing.T) {
+			slug := utils.GenerateSlug(title)
+
+			assert.NotEmpty(t, slug)
+			assert.True(t, utils.IsValidSlug(slug), slug)
+			// The fallback is deterministic
+			assert.Equal(t, slug, utils.GenerateSlug(title))
+		})
+	}
+
+	assert.NotEqual(t, utils.GenerateSlug("日本語"), utils.GenerateSlug("中文"))
+}
+
+func TestGenerateSlug_LengthCap(t *testing.T) {
+	title := strings.Repeat("é", 150)
+	slug := utils.GenerateSlug(title)
+
+	assert.Len(t, slug, 100)
+	assert.False(t, strings.HasSuffix(slug, "-"))
+
+	slug = utils.GenerateSlug(strings.Repeat("ab ", 60))
+	assert.LessOrEqual(t, len(slug), 100)
+	assert.False(t, strings.HasPrefix(slug, "-"))
+	assert.False(t, strings.HasSuffix(slug, "-"))
+}
+
+func TestTruncateText(t *testing.T) {
+	t.Run("short text is unchanged", func(t *testing.T) {
+		assert.Equal(t, "short", utils.TruncateText("short", 10))
+	})
+
+	t.Run("cuts on word boundary", func(t *testing.T) {
+		assert.Equal(t, "hello...", utils.TruncateText("hello wonderful world", 12))
+	})
+
+	t.Run("counts runes, not bytes", func(t *testing.T) {
+		text := "héllo wörld ünïcödé"
+		truncated := utils.TruncateText(text, 13)
+
+		assert.True(t, utf8.ValidString(truncated))
+		assert.Equal(t, "héllo wörld...", truncated)
+	})
+
+	t.Run("never splits a multi-byte rune without spaces", func(t *testing.T) {
+		truncated := utils.TruncateText("日本語のテキストです", 4)
+
+		assert.True(t, utf8.ValidString(truncated))
+		assert.Equal(t, "日本語の...", truncated)
+	})
+
+	t.Run("does not cut inside an HTML entity", func(t *testing.T) {
+		// The limit falls inside "&amp;"
+		truncated := utils.TruncateText("Tom&amp;Jerry forever", 6)
+		assert.Equal(t, "Tom...", truncated)
+
+		truncated = utils.TruncateText("quote&#8217;s here", 8)
+		assert.Equal(t, "quote...", truncated)
+	})
+
+	t.Run("keeps complete entities", func(t *testing.T) {
+		truncated := utils.TruncateText("A &amp; B and more", 8)
+		assert.Equal(t, "A &amp;...", truncated)
+	})
+}
+
+func TestExtractExcerpt(t *testing.T) {
+	t.Run("strips Markdown syntax", func(t *testing.T) {
+		content := "# Getting Started\n\n" +
+			"Go is **simple** and _fast_. See [the docs](https://go.dev) for more.\n\n" +
+			"```go\nfunc main() {}\n```\n\n" +
+			"- Use `gofmt`\n" +
+			"> Quoted text\n" +
+			"![logo](https://go.dev/logo.png)"
+
+		excerpt := utils.ExtractExcerpt(content, 500)
+
+		assert.Equal(t, "Getting Started Go is simple and fast. See the docs for more. Use gofmt Quoted text logo", excerpt)
+	})
+
+	t.Run("keeps identifiers with underscores", func(t *testing.T) {
+		assert.Equal(t, "call snake_case_name now", utils.ExtractExcerpt("call snake_case_name now", 100))
+	})
+
+	t.Run("strips HTML tags", func(t *testing.T) {
+		assert.Equal(t, "Hello world", utils.ExtractExcerpt("<p>Hello <b>world</b></p>", 100))
+	})
+
+	t.Run("truncates multi-byte content safely", func(t *testing.T) {
+		content := "## Café\n\nDéjà vu — ça arrive très souvent à Montréal"
+		excerpt := utils.ExtractExcerpt(content, 20)
+
+		assert.True(t, utf8.ValidString(excerpt))
+		assert.Equal(t, "Café Déjà vu — ça...", excerpt)
+	})
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	t.Run("matches case-insensitively", func(t *testing.T) {
+		snippet, position := utils.HighlightSnippet("Learning GoLang is fun", "golang", 100)
+
+		assert.Equal(t, 9, position)
+		assert.Equal(t, "Learning <mark>GoLang</mark> is fun", snippet)
+	})
+
+	t.Run("trims context to the radius", func(t *testing.T) {
+		snippet, position := utils.HighlightSnippet("aaaaaaaaaa needle bbbbbbbbbb", "Needle", 3)
+
+		assert.Equal(t, 11, position)
+		assert.Equal(t, "...aa <mark>needle</mark> bb...", snippet)
+	})
+
+	t.Run("counts the position in characters", func(t *testing.T) {
+		snippet, position := utils.HighlightSnippet("Déjà vu à Montréal", "montréal", 20)
+
+		assert.Equal(t, 10, position)
+		assert.Equal(t, "Déjà vu à <mark>Montréal</mark>", snippet)
+	})
+
+	t.Run("escapes HTML around the match", func(t *testing.T) {
+		snippet, _ := utils.HighlightSnippet(`<script>alert("x")</script> find <b>me</b>`, "find", 100)
+
+		assert.Equal(t, "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>find</mark> &lt;b&gt;me&lt;/b&gt;", snippet)
+	})
+
+	t.Run("falls back to the start of the content without a match", func(t *testing.T) {
+		snippet, position := utils.HighlightSnippet("Nothing  to\nsee <here> at all", "missing", 5)
+
+		assert.Equal(t, -1, position)
+		assert.Equal(t, "Nothing...", snippet)
+
+		snippet, position = utils.HighlightSnippet("Short & sweet", "missing", 50)
+		assert.Equal(t, -1, position)
+		assert.Equal(t, "Short &amp; sweet", snippet)
+	})
+}
+
+func TestCalculatePagination(t *testing.T) {
+	first := utils.CalculatePagination(1, 10, 25)
+	assert.Equal(t, 3, first.TotalPages)
+	assert.True(t, first.HasNext)
+	assert.False(t, first.HasPrev)
+
+	middle := utils.CalculatePagination(2, 10, 25)
+	assert.True(t, middle.HasNext)
+	assert.True(t, middle.HasPrev)
+
+	last := utils.CalculatePagination(3, 10, 25)
+	assert.False(t, last.HasNext)
+	assert.True(t, last.HasPrev)
+
+	empty := utils.CalculatePagination(1, 10, 0)
+	assert.Zero(t, empty.TotalPages)
+	assert.False(t, empty.HasNext)
+	assert.False(t, empty.HasPrev)
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	assert.Equal(t, "john@example.com", utils.NormalizeEmail("  John@Example.COM\n"))
+	assert.Equal(t, "", utils.NormalizeEmail("   "))
+}
+
+func TestNormalizeHexColor(t *testing.T) {
+	tests := []struct {
+		color string
+		want  string
+		ok    bool
+	}{
+		{"#FFF", "#ffffff", true},
+		{"#ffffff", "#ffffff", true},
+		{"fff", "#ffffff", true},
+		{"#3B82F6", "#3b82f6", true},
+		{" a1b ", "#aa11bb", true},
+		{"#ggg", "", false},
+		{"#ffff", "", false},
+		{"##fff", "", false},
+		{"blue", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := utils.NormalizeHexColor(tt.color)
+		assert.Equal(t, tt.ok, ok, tt.color)
+		assert.Equal(t, tt.want, got, tt.color)
+	}
+}
@@ -0,0 +1,28 @@
+// Package version holds the build information of the running binary. The
+// variables are set at build time with -ldflags, for example:
+//
+//	go build -ldflags "-X github.com/kaungmyathan22/golang-multiuser-blog/internal/version.Version=v1.2.0 \
+//		-X github.com/kaungmyathan22/golang-multiuser-blog/internal/version.Commit=$(git rev-parse --short HEAD) \
+//		-X github.com/kaungmyathan22/golang-multiuser-blog/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Binaries built without the flags, such as with go run, report "dev".
+package version
+
+// Set with -ldflags "-X ..." at build time
+var (
+	Version   = "dev"
+	Commit    = "dev"
+	BuildTime = "dev"
+)
+
+// Info is the build information reported by the /version endpoint
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{Version: Version, Commit: Commit, BuildTime: BuildTime}
+}
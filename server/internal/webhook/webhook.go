@@ -0,0 +1,215 @@
+// Package webhook notifies external integrations of blog events.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+)
+
+// Event names a kind of webhook notification
+type Event string
+
+const (
+	EventPostPublished   Event = "post.published"
+	EventCommentCreated  Event = "comment.created"
+	EventCommentApproved Event = "comment.approved"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the request body,
+	// prefixed with "sha256="
+	SignatureHeader = "X-Webhook-Signature"
+	// EventHeader carries the event name
+	EventHeader = "X-Webhook-Event"
+)
+
+// Payload is the JSON body posted to webhook endpoints
+type Payload struct {
+	Event     Event       `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher delivers events to the configured webhook endpoints
+type Dispatcher interface {
+	// Dispatch queues the event for delivery and returns immediately
+	Dispatch(event Event, data interface{})
+	// Wait blocks until queued deliveries have finished
+	Wait()
+	// Close stops accepting events and blocks until the queued deliveries
+	// have finished or ctx is done
+	Close(ctx context.Context) error
+}
+
+// NewDispatcher returns a dispatcher posting to the configured URLs from a
+// pool of workers, or one that drops events when no URLs are configured
+func NewDispatcher(cfg config.WebhookConfig) Dispatcher {
+	if len(cfg.URLs) == 0 {
+		return nopDispatcher{}
+	}
+	workers := max(cfg.Workers, 1)
+	d := &httpDispatcher{
+		config:         cfg,
+		client:         &http.Client{Timeout: cfg.Timeout},
+		initialBackoff: time.Second,
+		queue:          make(chan delivery, max(cfg.QueueSize, 1)),
+	}
+	d.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// Sign returns the signature header value for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type httpDispatcher struct {
+	config config.WebhookConfig
+	client *http.Client
+
+	// initialBackoff is the delay before the first retry; it doubles on
+	// each further attempt
+	initialBackoff time.Duration
+
+	queue   chan delivery
+	pending sync.WaitGroup // queued and running deliveries
+	workers sync.WaitGroup
+
+	// mu guards closed, so nothing is queued once the queue is closed
+	mu     sync.RWMutex
+	closed bool
+}
+
+// delivery is an event waiting to be posted to one endpoint
+type delivery struct {
+	url       string
+	event     Event
+	body      []byte
+	signature string
+}
+
+// Dispatch queues a delivery of the event to each endpoint. Deliveries that
+// don't fit in the queue are dropped rather than blocking the caller.
+func (d *httpDispatcher) Dispatch(event Event, data interface{}) {
+	body, err := json.Marshal(Payload{Event: event, Timestamp: time.Now().UTC(), Data: data})
+	if err != nil {
+		log.Printf("Warning: failed to encode %s webhook: %v", event, err)
+		return
+	}
+	signature := Sign(d.config.Secret, body)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		log.Printf("Warning: dropped %s webhook sent during shutdown", event)
+		return
+	}
+	for _, url := range d.config.URLs {
+		d.pending.Add(1)
+		select {
+		case d.queue <- delivery{url: url, event: event, body: body, signature: signature}:
+		default:
+			d.pending.Done()
+			log.Printf("Warning: webhook queue is full, dropped %s webhook to %s", event, url)
+		}
+	}
+}
+
+// work delivers queued events until the queue is closed
+func (d *httpDispatcher) work() {
+	defer d.workers.Done()
+	for job := range d.queue {
+		if err := d.deliver(job.url, job.event, job.body, job.signature); err != nil {
+			log.Printf("Warning: %s webhook to %s failed: %v", job.event, job.url, err)
+		}
+		d.pending.Done()
+	}
+}
+
+func (d *httpDispatcher) Wait() {
+	d.pending.Wait()
+}
+
+func (d *httpDispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver posts body to url, retrying with exponential backoff until the
+// endpoint answers with a 2xx status or the retries run out
+func (d *httpDispatcher) deliver(url string, event Event, body []byte, signature string) error {
+	backoff := d.initialBackoff
+	var err error
+	for attempt := 0; attempt <= d.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = d.post(url, event, body, signature); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", d.config.MaxRetries+1, err)
+}
+
+func (d *httpDispatcher) post(url string, event Event, body []byte, signature string) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event))
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// nopDispatcher drops events when no webhooks are configured
+type nopDispatcher struct{}
+
+func (nopDispatcher) Dispatch(Event, interface{}) {}
+
+func (nopDispatcher) Wait() {}
+
+func (nopDispatcher) Close(context.Context) error { return nil }
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaungmyathan22/golang-multiuser-blog/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDispatcher returns a dispatcher for url with a short backoff
+func newTestDispatcher(url string, maxRetries int) *httpDispatcher {
+	d := NewDispatcher(config.WebhookConfig{
+		URLs:       []string{url},
+		Secret:     "s3cret",
+		MaxRetries: maxRetries,
+		Timeout:    time.Second,
+	}).(*httpDispatcher)
+	d.initialBackoff = time.Millisecond
+	return d
+}
+
+func TestDispatcher_SignsPayload(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []*http.Request
+		bodies   [][]byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(server.URL, 3)
+	d.Dispatch(EventPostPublished, map[string]interface{}{"id": 42, "title": "Hello"})
+	d.Wait()
+
+	require.Len(t, received, 1)
+	req, body := received[0], bodies[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, string(EventPostPublished), req.Header.Get(EventHeader))
+	assert.Equal(t, Sign("s3cret", body), req.Header.Get(SignatureHeader))
+	assert.NotEqual(t, Sign("wrong", body), req.Header.Get(SignatureHeader))
+
+	var payload struct {
+		Event     Event                  `json:"event"`
+		Timestamp time.Time              `json:"timestamp"`
+		Data      map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, EventPostPublished, payload.Event)
+	assert.WithinDuration(t, time.Now(), payload.Timestamp, time.Minute)
+	assert.Equal(t, float64(42), payload.Data["id"])
+	assert.Equal(t, "Hello", payload.Data["title"])
+}
+
+func TestDispatcher_RetriesFailures(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(server.URL, 3)
+	d.Dispatch(EventCommentCreated, map[string]int{"id": 1})
+	d.Wait()
+	assert.Equal(t, 3, attempts)
+}
+
+func TestDispatcher_GivesUpAfterMaxRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(server.URL, 2)
+	start := time.Now()
+	d.Dispatch(EventCommentApproved, nil)
+	assert.Less(t, time.Since(start), 50*time.Millisecond, "dispatch must not block the caller")
+	d.Wait()
+	assert.Equal(t, 3, attempts)
+}
+
+func TestDispatcher_BoundedQueue(t *testing.T) {
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	delivered := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		delivered++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(config.WebhookConfig{URLs: []string{server.URL}, Secret: "s3cret", Timeout: time.Second, Workers: 1, QueueSize: 1})
+
+	// The only worker is busy with the first event, so the second waits in
+	// the queue and the third doesn't fit
+	d.Dispatch(EventPostPublished, map[string]int{"id": 1})
+	<-started
+	d.Dispatch(EventPostPublished, map[string]int{"id": 2})
+	d.Dispatch(EventPostPublished, map[string]int{"id": 3})
+	close(release)
+
+	require.NoError(t, d.Close(context.Background()), "close drains the queue")
+	assert.Equal(t, 2, delivered)
+
+	d.Dispatch(EventPostPublished, map[string]int{"id": 4})
+	d.Wait()
+	assert.Equal(t, 2, delivered, "events after close are dropped")
+}
+
+func TestDispatcher_CloseGivesUpWithContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	d := newTestDispatcher(server.URL, 0)
+	d.Dispatch(EventCommentCreated, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, d.Close(ctx), context.DeadlineExceeded)
+}
+
+func TestNewDispatcher_WithoutURLs(t *testing.T) {
+	d := NewDispatcher(config.WebhookConfig{})
+	d.Dispatch(EventPostPublished, nil)
+	d.Wait()
+	assert.IsType(t, nopDispatcher{}, d)
+}
@@ -38,6 +38,7 @@ COMMAND=""
 FORCE=""
 CLEAN=""
 HELP=""
+EXTRA_ARGS=()
 
 while [[ $# -gt 0 ]]; do
     case $1 in
@@ -57,6 +58,10 @@ while [[ $# -gt 0 ]]; do
             HELP="true"
             shift
             ;;
+        --users|--posts|--comments|--rand-seed|--file)
+            EXTRA_ARGS+=("$1" "$2")
+            shift 2
+            ;;
         *)
             print_warning "Unknown option: $1"
             shift
@@ -75,9 +80,17 @@ if [ "$HELP" = "true" ]; then
     echo "  ./seed.sh --clean         Clean the database (remove seeded data)"
     echo "  ./seed.sh --help          Show this help message"
     echo ""
+    echo "Generated datasets (instead of the curated sample data):"
+    echo "  --users N       Number of users to generate"
+    echo "  --posts N       Number of posts to generate"
+    echo "  --comments N    Maximum comments per generated post (default 3)"
+    echo "  --rand-seed N   Random seed; the same seed reproduces the same data"
+    echo "  --file PATH     JSON file with users, posts, comments_per_post and seed"
+    echo ""
     echo "Examples:"
     echo "  ./seed.sh --seed"
     echo "  ./seed.sh --seed --force"
+    echo "  ./seed.sh --seed --users 200 --posts 10000 --rand-seed 42"
     echo "  ./seed.sh --clean"
     exit 0
 fi
@@ -95,7 +108,7 @@ print_status 0 "Seeder built successfully"
 # Run the seeder with the specified command
 if [ "$COMMAND" = "seed" ]; then
     print_info "Seeding database..."
-    ./seeder --seed $FORCE
+    ./seeder --seed $FORCE "${EXTRA_ARGS[@]}"
     SEED_RESULT=$?
     if [ $SEED_RESULT -ne 0 ]; then
         print_status 1 "Failed to seed database"